@@ -0,0 +1,187 @@
+// sginctl 是 sgin 管理接口的命令行工具
+//
+// 用法：
+//
+//	sginctl [-addr http://127.0.0.1:8080/api] [-token <token>] log-level list
+//	sginctl log-level set [-name gorm] [-ttl 10m] debug
+//	sginctl log-level reset -name gorm
+//
+// token 也可以通过环境变量 SGIN_TOKEN 提供。
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type client struct {
+	addr  string
+	token string
+	http  *http.Client
+}
+
+type response struct {
+	TraceID string          `json:"trace_id"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func main() {
+	addr := flag.String("addr", envOr("SGIN_ADDR", "http://127.0.0.1:8080/api"), "sgin api address including ApiPrefix")
+	token := flag.String("token", os.Getenv("SGIN_TOKEN"), "login token")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	c := &client{
+		addr:  strings.TrimRight(*addr, "/"),
+		token: *token,
+		http:  &http.Client{Timeout: 10 * time.Second},
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "log-level":
+		err = c.logLevel(flag.Args()[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: sginctl [flags] <command> [args]
+
+commands:
+  log-level list                          show global and module log levels
+  log-level set [-name N] [-ttl D] LEVEL  change a log level, optionally reverting after D
+  log-level reset -name N                 drop the module level so it follows the global level
+
+flags:
+`)
+	flag.PrintDefaults()
+}
+
+func (c *client) logLevel(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing log-level sub command")
+	}
+
+	fs := flag.NewFlagSet("log-level "+args[0], flag.ExitOnError)
+	name := fs.String("name", "", "logger name, empty for the global level")
+	ttl := fs.Duration("ttl", 0, "revert automatically after this duration, e.g. 10m")
+	fs.Parse(args[1:])
+
+	var (
+		path string
+		body interface{}
+	)
+	switch args[0] {
+	case "list":
+		path = "/v1/sys_log_level/list"
+		body = struct{}{}
+	case "set":
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: log-level set [-name N] [-ttl D] LEVEL")
+		}
+		path = "/v1/sys_log_level/update"
+		body = map[string]interface{}{
+			"name":  *name,
+			"level": fs.Arg(0),
+			"ttl":   int(ttl.Seconds()),
+		}
+	case "reset":
+		if *name == "" {
+			return fmt.Errorf("usage: log-level reset -name N")
+		}
+		path = "/v1/sys_log_level/reset"
+		body = map[string]interface{}{"name": *name}
+	default:
+		return fmt.Errorf("unknown log-level sub command %q", args[0])
+	}
+
+	var levels []struct {
+		Name      string     `json:"name"`
+		Level     string     `json:"level"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.post(path, body, &levels); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLEVEL\tEXPIRES")
+	for _, lv := range levels {
+		n := lv.Name
+		if n == "" {
+			n = "(global)"
+		}
+		expires := "-"
+		if lv.ExpiresAt != nil {
+			expires = lv.ExpiresAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", n, lv.Level, expires)
+	}
+	return w.Flush()
+}
+
+func (c *client) post(path string, in interface{}, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.addr+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("X-Token", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var r response
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return fmt.Errorf("unexpected response (http %d): %s", resp.StatusCode, string(raw))
+	}
+	if r.Code != http.StatusOK {
+		return fmt.Errorf("%s (code %d, trace %s)", r.Message, r.Code, r.TraceID)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Data, out)
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -8,7 +8,8 @@ import (
 )
 
 type AppController struct {
-	AppService *service.AppService
+	AppService    *service.AppService
+	AppKeyService *service.AppKeyService
 }
 
 // @Tags App
@@ -36,11 +37,11 @@ func (ac *AppController) GetAppList(c *app.Context) {
 
 // @Tags App
 // @Summary 创建应用
-// @Description 创建应用
+// @Description 创建应用，UUID、SecKey 与第一个 API Key 由服务端生成，api_key 与 sec_key 只在此时返回一次
 // @Accept  json
 // @Produce  json
 // @Param params body model.App true "Create app"
-// @Success 200 {object} model.AppInfoResponse
+// @Success 200 {object} model.ResAppCreate
 // @Router /api/v1/app/create [post]
 func (ac *AppController) CreateApp(c *app.Context) {
 	var app model.App
@@ -49,7 +50,7 @@ func (ac *AppController) CreateApp(c *app.Context) {
 		return
 	}
 
-	err := ac.AppService.CreateApp(c, &app)
+	res, err := ac.AppService.CreateApp(c, &app)
 	if err != nil {
 		c.JSONErrLog(ecode.InternalError(err.Error()), "create app failed", "name", app.Name)
 		return
@@ -61,12 +62,12 @@ func (ac *AppController) CreateApp(c *app.Context) {
 		"app_uuid", app.UUID,
 		"app_name", app.Name,
 	)
-	c.JSONSuccess(app)
+	c.JSONSuccess(res)
 }
 
 // @Tags App
 // @Summary 更新应用
-// @Description 更新应用
+// @Description 更新应用名称、所属用户与状态，密钥通过 /app/key/* 与 /app/secret/rotate 管理
 // @Accept  json
 // @Produce  json
 // @Param params body model.App true "Update app"
@@ -122,3 +123,141 @@ func (ac *AppController) DeleteApp(c *app.Context) {
 	)
 	c.JSONSuccess("删除成功")
 }
+
+// writeAppKeyError API Key 管理接口的错误响应
+func writeAppKeyError(c *app.Context, err error, msg string, kv ...interface{}) {
+	var e *ecode.APIError
+	switch {
+	case err == service.ErrAppKeyNotFound || err.Error() == "app not found":
+		e = ecode.NotFound(err.Error())
+	case err == service.ErrAppKeyLimitExceeded:
+		e = ecode.Conflict(err.Error())
+	case err == service.ErrAppKeyInvalidArgument:
+		e = ecode.BadRequest(err.Error())
+	default:
+		e = ecode.InternalError(err.Error())
+	}
+	c.JSONErrLog(e, msg, kv...)
+}
+
+// @Tags App
+// @Summary 应用的 API Key 列表
+// @Description 返回应用的全部 API Key，只包含前缀等信息，不包含密钥明文
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqAppKeyList true "应用UUID"
+// @Success 200 {array} model.AppKey
+// @Router /api/v1/app/key/list [post]
+func (ac *AppController) GetAppKeys(c *app.Context) {
+	param := &model.ReqAppKeyList{}
+	if err := c.ShouldBindJSON(param); err != nil {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "bind list app key params failed")
+		return
+	}
+	keys, err := ac.AppKeyService.ListAppKeys(c, param.AppUuid)
+	if err != nil {
+		c.JSONErrLog(ecode.InternalError(err.Error()), "list app keys failed", "app_uuid", param.AppUuid)
+		return
+	}
+	c.JSONSuccess(keys)
+}
+
+// @Tags App
+// @Summary 创建 API Key
+// @Description 为应用新建 API Key，可限定 scope（接口模块）、来源 IP 与过期时间；key 只在此时返回一次
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqAppKeyCreate true "API Key"
+// @Success 200 {object} model.ResAppKeyCreate
+// @Router /api/v1/app/key/create [post]
+func (ac *AppController) CreateAppKey(c *app.Context) {
+	param := &model.ReqAppKeyCreate{}
+	if err := c.ShouldBindJSON(param); err != nil {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "bind create app key params failed")
+		return
+	}
+	res, err := ac.AppKeyService.CreateAppKey(c, param)
+	if err != nil {
+		writeAppKeyError(c, err, "create app key failed", "app_uuid", param.AppUuid)
+		return
+	}
+	c.Logger.Infow("app key created",
+		"client_ip", c.ClientIP(),
+		"app_uuid", param.AppUuid,
+		"key_prefix", res.Prefix,
+	)
+	c.JSONSuccess(res)
+}
+
+// @Tags App
+// @Summary 轮换 API Key
+// @Description 生成新的 API Key，旧密钥在宽限期（grace_minutes）结束后失效；key 只在此时返回一次
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqAppKeyRotate true "API Key"
+// @Success 200 {object} model.ResAppKeyCreate
+// @Router /api/v1/app/key/rotate [post]
+func (ac *AppController) RotateAppKey(c *app.Context) {
+	param := &model.ReqAppKeyRotate{}
+	if err := c.ShouldBindJSON(param); err != nil {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "bind rotate app key params failed")
+		return
+	}
+	res, err := ac.AppKeyService.RotateAppKey(c, param)
+	if err != nil {
+		writeAppKeyError(c, err, "rotate app key failed", "key_uuid", param.Uuid)
+		return
+	}
+	c.Logger.Infow("app key rotated",
+		"client_ip", c.ClientIP(),
+		"app_uuid", res.AppUuid,
+		"old_key_uuid", param.Uuid,
+		"key_prefix", res.Prefix,
+	)
+	c.JSONSuccess(res)
+}
+
+// @Tags App
+// @Summary 吊销 API Key
+// @Description 立即吊销 API Key
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqUuidParam true "API Key UUID"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/app/key/revoke [post]
+func (ac *AppController) RevokeAppKey(c *app.Context) {
+	param := &model.ReqUuidParam{}
+	if err := c.ShouldBindJSON(param); err != nil {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "bind revoke app key params failed")
+		return
+	}
+	if err := ac.AppKeyService.RevokeAppKey(c, param.Uuid); err != nil {
+		writeAppKeyError(c, err, "revoke app key failed", "key_uuid", param.Uuid)
+		return
+	}
+	c.Logger.Infow("app key revoked", "client_ip", c.ClientIP(), "key_uuid", param.Uuid)
+	c.JSONSuccess("ok")
+}
+
+// @Tags App
+// @Summary 重新生成 SecKey
+// @Description 重新生成应用的 SecKey，旧 SecKey 立即失效，已签发的应用访问令牌全部吊销；sec_key 只在此时返回一次
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqUuidParam true "应用UUID"
+// @Success 200 {object} model.ResAppSecret
+// @Router /api/v1/app/secret/rotate [post]
+func (ac *AppController) RotateAppSecret(c *app.Context) {
+	param := &model.ReqUuidParam{}
+	if err := c.ShouldBindJSON(param); err != nil {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "bind rotate app secret params failed")
+		return
+	}
+	res, err := ac.AppService.RotateSecret(c, param.Uuid)
+	if err != nil {
+		writeAppKeyError(c, err, "rotate app secret failed", "app_uuid", param.Uuid)
+		return
+	}
+	c.Logger.Infow("app secret rotated", "client_ip", c.ClientIP(), "app_uuid", param.Uuid)
+	c.JSONSuccess(res)
+}
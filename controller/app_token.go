@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/service"
+)
+
+// AppTokenController 应用的 OAuth2 令牌端点。响应遵循 RFC 6749/7662/7009 的格式，不使用统一的响应结构
+type AppTokenController struct {
+	AppTokenService *service.AppTokenService
+}
+
+// oauthError 返回 OAuth2 错误响应
+func oauthError(ctx *app.Context, status int, code, desc string) {
+	if status == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", `Basic realm="sgin"`)
+	}
+	ctx.Set("code", status)
+	ctx.Set("message", code)
+	ctx.Logger.Warnw("oauth token endpoint error", "error", code, "description", desc, "client_ip", ctx.ClientIP())
+	ctx.JSON(status, &model.ResOAuthError{Error: code, ErrorDescription: desc})
+}
+
+// oauthJSON 令牌相关响应不允许缓存
+func oauthJSON(ctx *app.Context, data interface{}) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.Set("code", http.StatusOK)
+	ctx.JSON(http.StatusOK, data)
+}
+
+// authenticateClient 从 HTTP Basic 认证或表单中取客户端凭据并认证，失败时已写入响应
+func (a *AppTokenController) authenticateClient(ctx *app.Context, clientID, clientSecret string) (*model.App, bool) {
+	if id, secret, ok := ctx.Request.BasicAuth(); ok {
+		// RFC 6749 2.3.1：Basic 认证中的凭据先做 form-urlencoded 编码
+		clientID, _ = url.QueryUnescape(id)
+		clientSecret, _ = url.QueryUnescape(secret)
+	}
+	caller, err := a.AppTokenService.Authenticate(ctx, clientID, clientSecret)
+	if err == service.ErrInvalidClient {
+		oauthError(ctx, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return nil, false
+	}
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", err.Error())
+		return nil, false
+	}
+	return caller, true
+}
+
+// @Summary 获取应用访问令牌
+// @Description OAuth2 client credentials：client_id 为应用UUID，client_secret 为应用 SecKey，可通过 HTTP Basic 认证或表单传递；
+// @Description scope 为接口模块，空格分隔，为空时授予应用有权限的全部模块
+// @Tags App
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param grant_type formData string true "client_credentials"
+// @Param client_id formData string false "应用UUID"
+// @Param client_secret formData string false "应用 SecKey"
+// @Param scope formData string false "scope"
+// @Success 200 {object} model.ResAppToken
+// @Failure 400 {object} model.ResOAuthError
+// @Failure 401 {object} model.ResOAuthError
+// @Router /api/v1/oauth/token [post]
+func (a *AppTokenController) Token(ctx *app.Context) {
+	param := &model.ReqAppToken{}
+	if err := ctx.ShouldBind(param); err != nil {
+		oauthError(ctx, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if param.GrantType != "client_credentials" {
+		oauthError(ctx, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+		return
+	}
+	caller, ok := a.authenticateClient(ctx, param.ClientID, param.ClientSecret)
+	if !ok {
+		return
+	}
+	res, err := a.AppTokenService.Issue(ctx, caller, param.Scope)
+	if err == service.ErrInvalidScope {
+		oauthError(ctx, http.StatusBadRequest, "invalid_scope", "requested scope is not granted to the client")
+		return
+	}
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	ctx.Set("app_id", caller.UUID)
+	ctx.Logger.Infow("app token issued", "app_id", caller.UUID, "scope", res.Scope)
+	oauthJSON(ctx, res)
+}
+
+// @Summary 应用访问令牌内省
+// @Description RFC 7662，需要客户端认证；只能查询自己的令牌，其他令牌返回 active=false
+// @Tags App
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param token formData string true "访问令牌"
+// @Success 200 {object} model.ResAppTokenIntrospection
+// @Router /api/v1/oauth/introspect [post]
+func (a *AppTokenController) Introspect(ctx *app.Context) {
+	param := &model.ReqAppTokenTarget{}
+	if err := ctx.ShouldBind(param); err != nil || param.Token == "" {
+		oauthError(ctx, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+	caller, ok := a.authenticateClient(ctx, param.ClientID, param.ClientSecret)
+	if !ok {
+		return
+	}
+	oauthJSON(ctx, a.AppTokenService.Introspect(ctx, caller, param.Token))
+}
+
+// @Summary 吊销应用访问令牌
+// @Description RFC 7009，需要客户端认证；无效的令牌同样返回 200
+// @Tags App
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param token formData string true "访问令牌"
+// @Success 200
+// @Router /api/v1/oauth/revoke [post]
+func (a *AppTokenController) Revoke(ctx *app.Context) {
+	param := &model.ReqAppTokenTarget{}
+	if err := ctx.ShouldBind(param); err != nil || param.Token == "" {
+		oauthError(ctx, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+	caller, ok := a.authenticateClient(ctx, param.ClientID, param.ClientSecret)
+	if !ok {
+		return
+	}
+	if err := a.AppTokenService.Revoke(ctx, caller, param.Token); err != nil {
+		oauthError(ctx, http.StatusServiceUnavailable, "temporarily_unavailable", err.Error())
+		return
+	}
+	oauthJSON(ctx, struct{}{})
+}
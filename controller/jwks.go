@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/service"
+)
+
+type JWKSController struct {
+	AuthService *service.AuthService
+}
+
+// @Summary 获取令牌验证公钥
+// @Description 以 JWKS 格式返回验证访问令牌的公钥，包括轮换后仍在验证期内的旧密钥；HS256 密钥不会发布
+// @Tags 用户
+// @Produce  json
+// @Success 200 {object} token.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (j *JWKSController) GetJWKS(ctx *app.Context) {
+	set, err := j.AuthService.JWKS()
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get jwks failed")
+		return
+	}
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, set)
+}
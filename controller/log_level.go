@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"io"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/pkg/logger"
+)
+
+// LogLevelController 运行时查看与修改日志级别
+type LogLevelController struct {
+}
+
+// @Summary 获取日志级别
+// @Description 获取全局及各模块当前生效的日志级别
+// @Tags 日志级别
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []logger.LevelInfo
+// @Router /api/v1/sys_log_level/list [post]
+func (l *LogLevelController) GetLogLevels(ctx *app.Context) {
+	levels := ctx.Logger.Levels()
+	if levels == nil {
+		ctx.JSONErrLog(ecode.ServiceUnavailable("dynamic log level not supported"), "logger has no level registry")
+		return
+	}
+	ctx.JSONSuccess(levels.List())
+}
+
+// @Summary 修改日志级别
+// @Description 修改全局或指定模块的日志级别，ttl 大于 0 时到期自动恢复
+// @Tags 日志级别
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqLogLevelParam true "日志级别参数"
+// @Success 200 {object} []logger.LevelInfo
+// @Router /api/v1/sys_log_level/update [post]
+func (l *LogLevelController) UpdateLogLevel(ctx *app.Context) {
+	param := &model.ReqLogLevelParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind update log level params failed")
+		return
+	}
+
+	levels := ctx.Logger.Levels()
+	if levels == nil {
+		ctx.JSONErrLog(ecode.ServiceUnavailable("dynamic log level not supported"), "logger has no level registry")
+		return
+	}
+
+	lv, err := logger.ParseLevel(param.Level)
+	if err != nil {
+		ctx.JSONErrLog(ecode.BadRequest("invalid level"), "parse log level failed", "level", param.Level)
+		return
+	}
+
+	levels.SetLevel(param.Name, lv, time.Duration(param.TTL)*time.Second)
+	ctx.Logger.Warnw("log level changed",
+		"name", param.Name,
+		"level", lv.String(),
+		"ttl", param.TTL,
+		"user_id", ctx.GetString("user_id"),
+	)
+	ctx.JSONSuccess(levels.List())
+}
+
+// @Summary 重置模块日志级别
+// @Description 删除模块的单独日志级别，使其回退到全局级别
+// @Tags 日志级别
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqLogLevelResetParam true "模块名称"
+// @Success 200 {object} []logger.LevelInfo
+// @Router /api/v1/sys_log_level/reset [post]
+func (l *LogLevelController) ResetLogLevel(ctx *app.Context) {
+	param := &model.ReqLogLevelResetParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind reset log level params failed")
+		return
+	}
+
+	levels := ctx.Logger.Levels()
+	if levels == nil {
+		ctx.JSONErrLog(ecode.ServiceUnavailable("dynamic log level not supported"), "logger has no level registry")
+		return
+	}
+
+	if err := levels.ResetLevel(param.Name); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "reset log level failed", "name", param.Name)
+		return
+	}
+	ctx.Logger.Warnw("log level reset", "name", param.Name, "user_id", ctx.GetString("user_id"))
+	ctx.JSONSuccess(levels.List())
+}
+
+// @Summary 获取单请求调试名单
+// @Description 获取以 debug 级别记录日志的 app_id/user_id 名单
+// @Tags 日志级别
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []logger.DebugSubject
+// @Router /api/v1/sys_log_level/debug_subject/list [post]
+func (l *LogLevelController) GetDebugSubjects(ctx *app.Context) {
+	levels := ctx.Logger.Levels()
+	if levels == nil {
+		ctx.JSONErrLog(ecode.ServiceUnavailable("dynamic log level not supported"), "logger has no level registry")
+		return
+	}
+	ctx.JSONSuccess(levels.DebugSubjects())
+}
+
+// @Summary 添加单请求调试名单
+// @Description 命中名单的 app_id/user_id 的请求以 debug 级别记录日志、SQL 以及完整请求/响应体
+// @Tags 日志级别
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqDebugSubjectParam true "调试名单参数"
+// @Success 200 {object} []logger.DebugSubject
+// @Router /api/v1/sys_log_level/debug_subject/add [post]
+func (l *LogLevelController) AddDebugSubject(ctx *app.Context) {
+	param := &model.ReqDebugSubjectParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind add debug subject params failed")
+		return
+	}
+
+	levels := ctx.Logger.Levels()
+	if levels == nil {
+		ctx.JSONErrLog(ecode.ServiceUnavailable("dynamic log level not supported"), "logger has no level registry")
+		return
+	}
+
+	if err := levels.AddDebugSubject(param.Kind, param.Id, time.Duration(param.TTL)*time.Second); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "add debug subject failed", "kind", param.Kind, "id", param.Id)
+		return
+	}
+	ctx.Logger.Warnw("debug subject added",
+		"kind", param.Kind,
+		"id", param.Id,
+		"ttl", param.TTL,
+		"user_id", ctx.GetString("user_id"),
+	)
+	ctx.JSONSuccess(levels.DebugSubjects())
+}
+
+// @Summary 移除单请求调试名单
+// @Description 移除单请求调试名单中的 app_id/user_id
+// @Tags 日志级别
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqDebugSubjectParam true "调试名单参数"
+// @Success 200 {object} []logger.DebugSubject
+// @Router /api/v1/sys_log_level/debug_subject/remove [post]
+func (l *LogLevelController) RemoveDebugSubject(ctx *app.Context) {
+	param := &model.ReqDebugSubjectParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind remove debug subject params failed")
+		return
+	}
+
+	levels := ctx.Logger.Levels()
+	if levels == nil {
+		ctx.JSONErrLog(ecode.ServiceUnavailable("dynamic log level not supported"), "logger has no level registry")
+		return
+	}
+
+	levels.RemoveDebugSubject(param.Kind, param.Id)
+	ctx.Logger.Warnw("debug subject removed", "kind", param.Kind, "id", param.Id, "user_id", ctx.GetString("user_id"))
+	ctx.JSONSuccess(levels.DebugSubjects())
+}
+
+// @Summary 签发单请求调试令牌
+// @Description 签发 X-Debug-Token 请求头，携带该请求头的请求以 debug 级别记录日志
+// @Tags 日志级别
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqDebugTokenParam false "调试令牌参数"
+// @Success 200 {object} model.ResDebugToken
+// @Router /api/v1/sys_log_level/debug_token [post]
+func (l *LogLevelController) CreateDebugToken(ctx *app.Context) {
+	param := &model.ReqDebugTokenParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil && err != io.EOF {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind create debug token params failed")
+		return
+	}
+
+	secret := ctx.Config.LogConfig.DebugSecret
+	if secret == "" {
+		ctx.JSONErrLog(ecode.BadRequest("LogConfig.DebugSecret is not configured"), "debug secret is empty")
+		return
+	}
+
+	ttl := time.Duration(param.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	ctx.Logger.Warnw("debug token issued", "expires_at", expiresAt, "user_id", ctx.GetString("user_id"))
+	ctx.JSONSuccess(model.ResDebugToken{
+		Header:    logger.DebugTokenHeader,
+		Token:     logger.SignDebugToken(secret, expiresAt),
+		ExpiresAt: expiresAt.Unix(),
+	})
+}
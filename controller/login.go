@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
 	"github.com/luxingwen/sgin/pkg/ecode"
-	"github.com/luxingwen/sgin/pkg/utils"
 	"github.com/luxingwen/sgin/service"
 
 	"github.com/mileusna/useragent"
@@ -13,6 +16,10 @@ import (
 type LoginController struct {
 	UserService        *service.UserService
 	SysLoginLogService *service.SysLoginLogService
+	AuthService        *service.AuthService
+	LoginGuardService  *service.LoginGuardService
+	TwoFactorService   *service.TwoFactorService
+	OAuthService       *service.OAuthService
 }
 
 // 用户登录
@@ -30,39 +37,257 @@ func (c *LoginController) Login(ctx *app.Context) {
 		return
 	}
 
-	user, err := c.UserService.GetUserByUsernameOrEmail(ctx, param.Username)
-	if err != nil {
-		if err.Error() == "user not found" {
-			ctx.JSONErrLog(ecode.BadRequest("用户名或密码错误"), "user not found", "username", param.Username)
+	// 锁定与递增延迟对不存在的用户名同样生效
+	if block := c.LoginGuardService.Check(ctx, param.Username); block != nil {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(block.RetryAfter.Seconds()))))
+		if block.Locked {
+			ctx.JSONErrLog(ecode.TooManyRequests("登录失败次数过多，请稍后再试"), "login locked", "username", param.Username)
+			c.CreateSysLoginLog(ctx, model.LoginStatusLocked, param.Username, "已锁定")
 		} else {
-			ctx.JSONErrLog(ecode.InternalError(err.Error()), "get user by username failed", "username", param.Username)
+			ctx.JSONErrLog(ecode.TooManyRequests("登录过于频繁，请稍后再试"), "login throttled", "username", param.Username)
+			c.CreateSysLoginLog(ctx, model.LoginStatusFail, param.Username, "登录过于频繁")
 		}
+		return
+	}
+
+	user, err := c.UserService.GetUserByUsernameOrEmail(ctx, param.Username)
+	if err != nil && err.Error() != "user not found" {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get user by username failed", "username", param.Username)
 		c.CreateSysLoginLog(ctx, model.LoginStatusFail, param.Username, err.Error())
 		return
 	}
 
-	// 获取密码加密密钥，如果为空则使用默认值（与创建用户时保持一致）
-	passwdKey := ctx.Config.PasswdKey
-	if passwdKey == "" {
-		passwdKey = "default-secret-key"
+	// 用户不存在时同样执行一次密码验证，响应内容与耗时都与密码错误一致
+	if user == nil {
+		c.UserService.SimulatePasswordCheck(ctx, param.Password)
+		c.loginFailed(ctx, param.Username, "用户不存在", "用户名或密码错误")
+		return
+	}
+
+	// 旧格式或参数已变化的哈希在验证成功后重新生成
+	if !c.UserService.VerifyPassword(ctx, user, param.Password) {
+		c.loginFailed(ctx, param.Username, "密码错误", "用户名或密码错误")
+		return
+	}
+
+	// 已禁用或删除的用户不能登录，响应与密码错误一致
+	if !user.Active() {
+		c.loginFailed(ctx, param.Username, "用户已禁用或删除", "用户名或密码错误")
+		return
+	}
+
+	if c.finishLogin(ctx, user, param.Username, "登录成功") {
+		c.LoginGuardService.RecordSuccess(ctx, param.Username)
+	}
+}
+
+// finishLogin 第一步验证通过后：已启用或按权限要求启用两步验证时只返回临时凭据，通过 /login/2fa 完成登录，
+// 否则签发令牌并记录登录日志；返回是否已签发令牌
+func (c *LoginController) finishLogin(ctx *app.Context, user *model.User, username, msg string) bool {
+	enabled, required, err := c.TwoFactorService.LoginState(ctx, user.Uuid)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get two factor state failed", "user_uuid", user.Uuid)
+		return false
+	}
+	if enabled || required {
+		challenge, err := c.TwoFactorService.IssueChallenge(ctx, user.Uuid)
+		if err != nil {
+			ctx.JSONErrLog(ecode.InternalError(err.Error()), "issue two factor challenge failed", "user_uuid", user.Uuid)
+			return false
+		}
+		ctx.JSONSuccess(&model.ResUserLogin{TwoFactorRequired: true, TwoFactorToken: challenge, TwoFactorEnroll: !enabled})
+		return false
+	}
+
+	res, err := c.AuthService.IssueTokens(ctx, user.Uuid, "")
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "generate token failed", "user_uuid", user.Uuid)
+		return false
+	}
+
+	ctx.JSONSuccess(res)
+	c.CreateSysLoginLog(ctx, model.LoginStatusSuccess, username, msg)
+	return true
+}
+
+// 两步验证登录
+// @Summary 两步验证登录
+// @Description 使用登录返回的 two_factor_token 与验证码（或恢复码）完成登录；需要绑定验证器时，先调用 /login/2fa/enroll，再提交验证码确认绑定，响应中返回恢复码
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Param params body model.ReqTwoFactorLogin true "两步验证参数"
+// @Success 200 {object} model.ResUserLogin
+// @Router /api/v1/login/2fa [post]
+func (c *LoginController) LoginTwoFactor(ctx *app.Context) {
+	param := &model.ReqTwoFactorLogin{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind two factor login params failed")
+		return
+	}
+	user, ok := c.challengeUser(ctx, param.TwoFactorToken)
+	if !ok {
+		return
+	}
+
+	if block := c.LoginGuardService.Check(ctx, user.Username); block != nil {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(block.RetryAfter.Seconds()))))
+		ctx.JSONErrLog(ecode.TooManyRequests("验证失败次数过多，请稍后再试"), "two factor login locked", "username", user.Username)
+		c.CreateSysLoginLog(ctx, model.LoginStatusLocked, user.Username, "已锁定")
+		return
 	}
 
-	if utils.CheckPasswordHashWithSalt(param.Password, user.Password, passwdKey) == false {
-		ctx.JSONErrLog(ecode.BadRequest("用户名或密码错误"), "password mismatch", "username", param.Username)
-		c.CreateSysLoginLog(ctx, model.LoginStatusFail, param.Username, "密码错误")
+	enabled, _, err := c.TwoFactorService.LoginState(ctx, user.Uuid)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get two factor state failed", "user_uuid", user.Uuid)
 		return
 	}
+	var recoveryCodes []string
+	if enabled {
+		err = c.TwoFactorService.Verify(ctx, user.Uuid, param.Code, param.RecoveryCode)
+	} else {
+		// 登录过程中完成绑定
+		recoveryCodes, err = c.TwoFactorService.Confirm(ctx, user.Uuid, param.Code)
+	}
+	switch err {
+	case nil:
+	case service.ErrTwoFactorInvalidCode:
+		c.loginFailed(ctx, user.Username, "两步验证失败", "验证码错误")
+		return
+	case service.ErrTwoFactorInvalidArgument, service.ErrTwoFactorNotEnrolled:
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "two factor login failed", "user_uuid", user.Uuid)
+		return
+	default:
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "two factor login failed", "user_uuid", user.Uuid)
+		return
+	}
+	c.LoginGuardService.RecordSuccess(ctx, user.Username)
 
-	token, err := utils.GenerateToken(user.Uuid)
+	res, err := c.AuthService.IssueTokens(ctx, user.Uuid, "")
 	if err != nil {
 		ctx.JSONErrLog(ecode.InternalError(err.Error()), "generate token failed", "user_uuid", user.Uuid)
 		return
 	}
+	res.RecoveryCodes = recoveryCodes
+	ctx.JSONSuccess(res)
+	c.CreateSysLoginLog(ctx, model.LoginStatusSuccess, user.Username, "登录成功（两步验证）")
+}
+
+// 登录时绑定验证器
+// @Summary 登录时绑定验证器
+// @Description 必须启用两步验证但尚未绑定的用户，使用 two_factor_token 获取绑定信息，再通过 /login/2fa 提交验证码
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Param params body model.ReqTwoFactorToken true "临时凭据"
+// @Success 200 {object} model.ResTwoFactorEnroll
+// @Router /api/v1/login/2fa/enroll [post]
+func (c *LoginController) LoginTwoFactorEnroll(ctx *app.Context) {
+	param := &model.ReqTwoFactorToken{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind two factor enroll params failed")
+		return
+	}
+	user, ok := c.challengeUser(ctx, param.TwoFactorToken)
+	if !ok {
+		return
+	}
+	res, err := c.TwoFactorService.Enroll(ctx, user)
+	if err == service.ErrTwoFactorAlreadyEnabled {
+		ctx.JSONErrLog(ecode.Conflict(err.Error()), "two factor already enabled", "user_uuid", user.Uuid)
+		return
+	}
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "two factor enroll failed", "user_uuid", user.Uuid)
+		return
+	}
+	ctx.JSONSuccess(res)
+}
+
+// challengeUser 校验两步验证临时凭据并返回用户，失败时已写入响应
+func (c *LoginController) challengeUser(ctx *app.Context, challenge string) (*model.User, bool) {
+	userUuid, err := c.TwoFactorService.ParseChallenge(challenge)
+	if err != nil {
+		ctx.JSONErrLog(ecode.Unauthorized("登录已过期，请重新登录"), "invalid two factor token")
+		return nil, false
+	}
+	user, err := c.UserService.GetUserByUUID(ctx, userUuid)
+	if err != nil || !user.Active() {
+		ctx.JSONErrLog(ecode.Unauthorized("登录已过期，请重新登录"), "two factor user unavailable", "user_uuid", userUuid)
+		return nil, false
+	}
+	return user, true
+}
+
+// 刷新令牌
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌与刷新令牌，旧刷新令牌随即失效；已使用过的刷新令牌再次使用时吊销整个令牌链
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Param params body model.ReqRefreshToken true "刷新令牌"
+// @Success 200 {object} model.ResUserLogin
+// @Router /api/v1/refresh [post]
+func (c *LoginController) Refresh(ctx *app.Context) {
+	param := &model.ReqRefreshToken{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind refresh params failed")
+		return
+	}
+
+	res, err := c.AuthService.Refresh(ctx, param.RefreshToken)
+	if err != nil {
+		if err == service.ErrInvalidRefreshToken {
+			ctx.JSONErrLog(ecode.Unauthorized(err.Error()), "refresh token failed")
+			return
+		}
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "refresh token failed")
+		return
+	}
+	ctx.JSONSuccess(res)
+}
+
+// 退出登录
+// @Summary 退出登录
+// @Description 吊销当前访问令牌；传入 refresh_token 时吊销其令牌链，all 为 true 时吊销当前用户的所有令牌
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Param params body model.ReqLogout false "登出参数"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/logout [post]
+func (c *LoginController) Logout(ctx *app.Context) {
+	param := &model.ReqLogout{}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(param); err != nil {
+			ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind logout params failed")
+			return
+		}
+	}
+
+	if err := c.AuthService.Logout(ctx, ctx.GetString("user_id"), param); err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "logout failed")
+		return
+	}
+	ctx.JSONSuccess("ok")
+}
 
-	ctx.JSONSuccess(model.ResUserLogin{
-		Token: token,
-	})
-	c.CreateSysLoginLog(ctx, model.LoginStatusSuccess, param.Username, "登录成功")
+// loginFailed 记录失败次数与登录日志；本次失败触发锁定时返回 429，否则返回 errMsg
+func (c *LoginController) loginFailed(ctx *app.Context, username, msg, errMsg string) {
+	locks := c.LoginGuardService.RecordFailure(ctx, username)
+	c.CreateSysLoginLog(ctx, model.LoginStatusFail, username, msg)
+	if len(locks) == 0 {
+		ctx.JSONErrLog(ecode.BadRequest(errMsg), "login failed", "username", username, "reason", msg)
+		return
+	}
+	for _, l := range locks {
+		target := "用户名"
+		if l.Kind == model.LoginLockIP {
+			target = "IP " + l.Subject
+		}
+		c.CreateSysLoginLog(ctx, model.LoginStatusLocked, username, fmt.Sprintf("%s连续失败 %d 次，锁定至 %s", target, l.Failures, l.Until))
+	}
+	ctx.JSONErrLog(ecode.TooManyRequests("登录失败次数过多，请稍后再试"), "login locked", "username", username, "reason", msg)
 }
 
 func (c *LoginController) CreateSysLoginLog(ctx *app.Context, status int, username string, msg string) {
@@ -77,6 +302,7 @@ func (c *LoginController) CreateSysLoginLog(ctx *app.Context, status int, userna
 		Os:        ua.OS,
 		Device:    ua.Device,
 		Message:   msg,
+		TraceID:   ctx.TraceID,
 	}
 	if err := c.SysLoginLogService.CreateLoginLog(ctx, &sysLoginLog); err != nil {
 		ctx.Logger.Error(err)
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/pkg/oidc"
+	"github.com/luxingwen/sgin/service"
+)
+
+// oauthBindingCookie 发起授权的浏览器持有的随机值，回调时与 state 中的摘要比对
+const oauthBindingCookie = "sgin_oauth_binding"
+
+// setOAuthBinding 生成绑定值并写入 Cookie，作用范围限定在 /oauth 路径下
+func setOAuthBinding(ctx *app.Context) string {
+	binding := oidc.RandomString(16)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oauthBindingCookie, binding, 30*60, oauthCookiePath(ctx), "", isHTTPS(ctx), true)
+	return binding
+}
+
+func oauthCookiePath(ctx *app.Context) string {
+	prefix := ""
+	if ctx.Config != nil {
+		prefix = ctx.Config.ApiPrefix
+	}
+	return prefix + "/v1/oauth"
+}
+
+func isHTTPS(ctx *app.Context) bool {
+	return ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+}
+
+// 外部登录方式
+// @Summary 外部登录方式
+// @Description 返回已配置的身份提供方（OpenID Connect / OAuth2）
+// @Tags 用户
+// @Produce json
+// @Success 200 {array} model.ResOAuthProvider
+// @Router /api/v1/oauth/providers [get]
+func (c *LoginController) GetOAuthProviders(ctx *app.Context) {
+	ctx.JSONSuccess(c.OAuthService.Providers(ctx))
+}
+
+// 跳转到身份提供方
+// @Summary 跳转到身份提供方
+// @Description 生成 state、nonce 与 PKCE 参数后 302 跳转到提供方的授权页面，提供方回调 /oauth/{provider}/callback 完成登录
+// @Tags 用户
+// @Param provider path string true "身份提供方"
+// @Success 302
+// @Router /api/v1/oauth/{provider}/authorize [get]
+func (c *LoginController) OAuthAuthorize(ctx *app.Context) {
+	provider := ctx.Param("provider")
+	u, err := c.OAuthService.AuthorizeURL(ctx, provider, setOAuthBinding(ctx), "")
+	if err == service.ErrOAuthUnknownProvider {
+		ctx.JSONErrLog(ecode.NotFound(err.Error()), "unknown identity provider", "provider", provider)
+		return
+	}
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "oauth authorize failed", "provider", provider)
+		return
+	}
+	ctx.Redirect(http.StatusFound, u)
+}
+
+// 外部登录回调
+// @Summary 外部登录回调
+// @Description 提供方授权后携带 code 与 state 回调（GET），也可以由前端页面接收后转交（POST JSON）；
+// @Description 校验通过后按关联的用户签发令牌，用户启用了两步验证时与密码登录一样返回 two_factor_token
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Param provider path string true "身份提供方"
+// @Param params body model.ReqOAuthCallback true "回调参数"
+// @Success 200 {object} model.ResUserLogin
+// @Router /api/v1/oauth/{provider}/callback [post]
+func (c *LoginController) OAuthCallback(ctx *app.Context) {
+	provider := ctx.Param("provider")
+	param := &model.ReqOAuthCallback{}
+	if err := ctx.ShouldBind(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind oauth callback params failed")
+		return
+	}
+	// 绑定值只能使用一次
+	binding, _ := ctx.Cookie(oauthBindingCookie)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oauthBindingCookie, "", -1, oauthCookiePath(ctx), "", isHTTPS(ctx), true)
+
+	logName := "oauth:" + provider
+	if param.Error != "" {
+		c.CreateSysLoginLog(ctx, model.LoginStatusFail, logName, "提供方返回错误："+param.Error)
+		ctx.JSONErrLog(ecode.BadRequest("外部登录失败："+param.Error), "oauth provider returned error", "provider", provider, "reason", param.ErrorDescription)
+		return
+	}
+
+	user, linked, err := c.OAuthService.Callback(ctx, provider, param.Code, param.State, binding)
+	if err != nil {
+		var e *ecode.APIError
+		switch err {
+		case service.ErrOAuthUnknownProvider:
+			e = ecode.NotFound(err.Error())
+		case service.ErrOAuthInvalidState:
+			e = ecode.BadRequest("登录已过期，请重新登录")
+		case service.ErrOAuthVerifyFailed:
+			e = ecode.Unauthorized(err.Error())
+		case service.ErrOAuthNotLinked, service.ErrOAuthDomainNotAllowed:
+			e = ecode.Forbidden(err.Error())
+		case service.ErrOAuthAlreadyLinked, service.ErrOAuthProviderLinked, service.ErrOAuthEmailInUse:
+			e = ecode.Conflict(err.Error())
+		case service.ErrOAuthEmailRequired:
+			e = ecode.BadRequest(err.Error())
+		default:
+			e = ecode.InternalError(err.Error())
+		}
+		c.CreateSysLoginLog(ctx, model.LoginStatusFail, logName, err.Error())
+		ctx.JSONErrLog(e, "oauth callback failed", "provider", provider)
+		return
+	}
+	if linked {
+		ctx.JSONSuccess("ok")
+		return
+	}
+	if !user.Active() {
+		c.CreateSysLoginLog(ctx, model.LoginStatusFail, user.Username, "用户已禁用或删除")
+		ctx.JSONErrLog(ecode.Forbidden("用户不可用"), "oauth user unavailable", "user_uuid", user.Uuid)
+		return
+	}
+	c.finishLogin(ctx, user, user.Username, "登录成功（"+provider+"）")
+}
+
+type OAuthController struct {
+	OAuthService *service.OAuthService
+}
+
+// @Summary 已关联的外部身份
+// @Description 获取当前用户关联的外部身份
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} model.UserIdentity
+// @Router /api/v1/oauth/identities [post]
+func (o *OAuthController) GetIdentities(ctx *app.Context) {
+	list, err := o.OAuthService.ListIdentities(ctx, ctx.GetString("user_id"))
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "list user identities failed")
+		return
+	}
+	ctx.JSONSuccess(list)
+}
+
+// @Summary 关联外部身份
+// @Description 返回提供方授权地址，用户在提供方授权后回调 /oauth/{provider}/callback，将外部身份关联到当前用户
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqOAuthProvider true "身份提供方"
+// @Success 200 {object} model.ResOAuthAuthorize
+// @Router /api/v1/oauth/link [post]
+func (o *OAuthController) Link(ctx *app.Context) {
+	param := &model.ReqOAuthProvider{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind oauth link params failed")
+		return
+	}
+	u, err := o.OAuthService.AuthorizeURL(ctx, param.Provider, setOAuthBinding(ctx), ctx.GetString("user_id"))
+	if err == service.ErrOAuthUnknownProvider {
+		ctx.JSONErrLog(ecode.NotFound(err.Error()), "unknown identity provider", "provider", param.Provider)
+		return
+	}
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "oauth link failed", "provider", param.Provider)
+		return
+	}
+	ctx.JSONSuccess(&model.ResOAuthAuthorize{Url: u})
+}
+
+// @Summary 解除外部身份关联
+// @Description 解除当前用户与提供方的关联
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqOAuthProvider true "身份提供方"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/oauth/unlink [post]
+func (o *OAuthController) Unlink(ctx *app.Context) {
+	param := &model.ReqOAuthProvider{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind oauth unlink params failed")
+		return
+	}
+	if err := o.OAuthService.Unlink(ctx, ctx.GetString("user_id"), param.Provider); err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "oauth unlink failed", "provider", param.Provider)
+		return
+	}
+	ctx.JSONSuccess("ok")
+}
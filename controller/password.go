@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/pkg/passwd"
+	"github.com/luxingwen/sgin/service"
+)
+
+type PasswordController struct {
+	PasswordResetService *service.PasswordResetService
+}
+
+// writePasswordResetError 将找回密码的错误映射为响应
+func writePasswordResetError(ctx *app.Context, err error, msg string, kv ...interface{}) {
+	var limit *service.PasswordResetLimitError
+	switch {
+	case errors.As(err, &limit):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(limit.RetryAfter.Seconds()))))
+		ctx.JSONErrLog(ecode.TooManyRequests("请求过于频繁，请稍后再试"), msg, kv...)
+	case err == service.ErrPasswordResetDisabled:
+		ctx.JSONErrLog(ecode.ServiceUnavailable(err.Error()), msg, kv...)
+	case err == service.ErrPasswordResetInvalidToken:
+		ctx.JSONErrLog(ecode.BadRequest("重置链接无效或已过期"), msg, kv...)
+	case errors.Is(err, passwd.ErrWeakPassword):
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), msg, kv...)
+	default:
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), msg, kv...)
+	}
+}
+
+// @Summary 忘记密码
+// @Description 向邮箱发送一次性的重置密码链接；邮箱未注册时同样返回成功。同一邮箱与同一 IP 的请求次数受限
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqPasswordForgot true "邮箱"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/password/forgot [post]
+func (p *PasswordController) Forgot(ctx *app.Context) {
+	param := &model.ReqPasswordForgot{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind password forgot params failed")
+		return
+	}
+	if err := p.PasswordResetService.Request(ctx, param.Email); err != nil {
+		writePasswordResetError(ctx, err, "request password reset failed", "email", param.Email)
+		return
+	}
+	ctx.JSONSuccess("如果该邮箱已注册，您将收到重置密码的邮件")
+}
+
+// @Summary 重置密码
+// @Description 使用重置链接中的 token 设置新密码，成功后所有设备上的登录失效
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqPasswordReset true "令牌与新密码"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/password/reset [post]
+func (p *PasswordController) Reset(ctx *app.Context) {
+	param := &model.ReqPasswordReset{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind password reset params failed")
+		return
+	}
+	if err := p.PasswordResetService.Reset(ctx, param.Token, param.Password); err != nil {
+		writePasswordResetError(ctx, err, "reset password failed")
+		return
+	}
+	ctx.JSONSuccess("ok")
+}
@@ -1,12 +1,14 @@
 package controller
 
 import (
+	"errors"
+
 	"github.com/luxingwen/sgin/service"
 
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
 	"github.com/luxingwen/sgin/pkg/ecode"
-	"github.com/luxingwen/sgin/pkg/utils"
+	"github.com/luxingwen/sgin/pkg/passwd"
 
 	"github.com/google/uuid"
 )
@@ -47,7 +49,8 @@ func (rc *RegisterController) Register(c *app.Context) {
 	}
 
 	if needVerify {
-		ok, err := rc.VerificationCodeService.CheckVerificationCode(c, params.Code, params.Email, params.Phone)
+		// 校验并使用验证码，同一验证码只能注册一次
+		ok, err := rc.VerificationCodeService.ConsumeVerificationCode(c, model.VerificationPurposeRegister, params.Code, params.Email, params.Phone)
 		if err != nil {
 			c.JSONErrLog(ecode.InternalError(err.Error()), "check verification code failed")
 			return
@@ -57,30 +60,22 @@ func (rc *RegisterController) Register(c *app.Context) {
 			c.JSONErrLog(ecode.BadRequest("验证码错误"), "verification code mismatch", "email", params.Email, "phone", params.Phone)
 			return
 		}
-		// 更新验证码状态
-		err = rc.VerificationCodeService.UpdateVerificationCode(c, params.Code, params.Email, params.Phone)
-		if err != nil {
-			c.JSONErrLog(ecode.InternalError(err.Error()), "update verification code failed")
-			return
-		}
 	}
 
-	// 获取密码加密密钥，如果为空则使用默认值（与登录验证保持一致）
-	passwdKey := c.Config.PasswdKey
-	if passwdKey == "" {
-		passwdKey = "default-secret-key"
-	}
-
-	// 创建用户
+	// 创建用户，密码由 CreateUser 检查并哈希
 	user := model.User{
 		Uuid:     uuid.New().String(),
 		Username: params.Username,
-		Password: utils.HashPasswordWithSalt(params.Password, passwdKey),
+		Password: params.Password,
 		Email:    params.Email,
 		Phone:    params.Phone,
 	}
 
 	err := rc.UserService.CreateUser(c, &user)
+	if errors.Is(err, passwd.ErrWeakPassword) {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "weak password", "username", user.Username)
+		return
+	}
 	if err != nil {
 		c.JSONErrLog(ecode.InternalError(err.Error()), "create user failed", "username", user.Username, "email", user.Email)
 		return
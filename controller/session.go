@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/service"
+)
+
+type SessionController struct {
+	SessionService *service.SessionService
+}
+
+// @Summary 我的登录会话
+// @Description 返回当前用户的登录会话（设备、IP、最近活动时间），current 为发起请求的会话
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} model.UserSession
+// @Router /api/v1/session/list [post]
+func (s *SessionController) GetSessions(ctx *app.Context) {
+	list, err := s.SessionService.List(ctx, ctx.GetString("user_id"), ctx.GetString(service.CtxSessionID))
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "list sessions failed")
+		return
+	}
+	ctx.JSONSuccess(list)
+}
+
+// @Summary 结束登录会话
+// @Description 结束当前用户的一个会话，该会话的访问令牌与刷新令牌随即失效
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqSessionRevoke true "会话ID"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/session/revoke [post]
+func (s *SessionController) Revoke(ctx *app.Context) {
+	param := &model.ReqSessionRevoke{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind revoke session params failed")
+		return
+	}
+	err := s.SessionService.Revoke(ctx, ctx.GetString("user_id"), param.Uuid)
+	if err == service.ErrSessionNotFound {
+		ctx.JSONErrLog(ecode.NotFound(err.Error()), "revoke session failed", "session_id", param.Uuid)
+		return
+	}
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "revoke session failed", "session_id", param.Uuid)
+		return
+	}
+	ctx.JSONSuccess("ok")
+}
+
+// @Summary 用户的登录会话
+// @Description 管理员查询指定用户的登录会话，需要 Auth.SessionAdminPermissions 中的权限
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqUserSessions true "用户UUID"
+// @Success 200 {array} model.UserSession
+// @Router /api/v1/session/user_list [post]
+func (s *SessionController) GetUserSessions(ctx *app.Context) {
+	param := &model.ReqUserSessions{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind list user sessions params failed")
+		return
+	}
+	list, err := s.SessionService.List(ctx, param.UserUuid, "")
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "list user sessions failed", "user_uuid", param.UserUuid)
+		return
+	}
+	ctx.JSONSuccess(list)
+}
+
+// @Summary 强制下线
+// @Description 管理员结束用户的指定会话；不传 uuid 时结束用户的全部会话并吊销全部令牌；需要 Auth.SessionAdminPermissions 中的权限
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqSessionForceLogout true "用户与会话"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/session/force_logout [post]
+func (s *SessionController) ForceLogout(ctx *app.Context) {
+	param := &model.ReqSessionForceLogout{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind force logout params failed")
+		return
+	}
+	err := s.SessionService.ForceLogout(ctx, param.UserUuid, param.Uuid)
+	if err == service.ErrSessionNotFound {
+		ctx.JSONErrLog(ecode.NotFound(err.Error()), "force logout failed", "user_uuid", param.UserUuid, "session_id", param.Uuid)
+		return
+	}
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "force logout failed", "user_uuid", param.UserUuid, "session_id", param.Uuid)
+		return
+	}
+	ctx.Logger.Infow("user sessions force logged out", "user_uuid", param.UserUuid, "session_id", param.Uuid, "operator", ctx.GetString("user_id"))
+	ctx.JSONSuccess("ok")
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/service"
+)
+
+type SysAuditLogController struct {
+	SysAuditLogService *service.SysAuditLogService
+}
+
+// @Summary 获取审计日志列表
+// @Description 按实体、操作人、请求ID与时间范围查询实体变更审计日志；需要 AdminPermissions 中的权限
+// @Tags 审计日志
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqAuditLogQueryParam true "查询参数"
+// @Success 200 {object} model.PagedResponse
+// @Router /api/v1/sys_audit_log/list [post]
+func (s *SysAuditLogController) GetSysAuditLogList(ctx *app.Context) {
+	param := &model.ReqAuditLogQueryParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind list audit logs params failed")
+		return
+	}
+
+	logs, err := s.SysAuditLogService.GetSysAuditLogList(ctx, param)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "list audit logs failed")
+		return
+	}
+
+	ctx.JSONSuccess(logs)
+}
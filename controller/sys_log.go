@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/service"
+)
+
+// SysLogController 请求日志与操作日志的管理接口
+type SysLogController struct {
+}
+
+// @Summary 获取异步日志管道统计
+// @Description 获取请求日志、操作日志异步写入管道的缓冲、写入、丢弃与溢出统计
+// @Tags 系统日志
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []logpipe.Stats
+// @Router /api/v1/sys_log/pipeline_stats [post]
+func (s *SysLogController) GetPipelineStats(ctx *app.Context) {
+	ctx.JSONSuccess(service.LogPipelineStats(ctx))
+}
+
+// @Summary 获取日志表保留统计
+// @Description 获取各日志表的行数、最早记录时间、保留策略与最近一次清理结果
+// @Tags 系统日志
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []model.ResLogTableStats
+// @Router /api/v1/sys_log/retention_stats [post]
+func (s *SysLogController) GetRetentionStats(ctx *app.Context) {
+	stats, err := service.NewLogRetentionService().GetTableStats(ctx)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get log retention stats failed")
+		return
+	}
+	ctx.JSONSuccess(stats)
+}
+
+// @Summary 立即清理日志表
+// @Description 按保留策略立即清理日志表，配置了归档目录时先归档再删除
+// @Tags 系统日志
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []model.SysLogPurge
+// @Router /api/v1/sys_log/purge [post]
+func (s *SysLogController) PurgeLogs(ctx *app.Context) {
+	records, err := service.NewLogRetentionService().Purge(ctx)
+	if err != nil {
+		if errors.Is(err, service.ErrLogPurgeRunning) {
+			ctx.JSONErrLog(ecode.Conflict(err.Error()), "purge logs failed")
+			return
+		}
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "purge logs failed")
+		return
+	}
+	ctx.JSONSuccess(records)
+}
+
+// @Summary 获取请求日志列表
+// @Description 按请求ID、用户、调用方、路径、状态与时间范围查询请求日志，请求头与请求/响应体已脱敏
+// @Tags 系统日志
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqLogQueryParam true "查询参数"
+// @Success 200 {object} model.PagedResponse
+// @Router /api/v1/sys_log/request/list [post]
+func (s *SysLogController) GetRequestLogList(ctx *app.Context) {
+	param := &model.ReqLogQueryParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind list request logs params failed")
+		return
+	}
+
+	logs, err := service.NewLogService().GetLogList(ctx, param)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "list request logs failed")
+		return
+	}
+	ctx.JSONSuccess(logs)
+}
+
+// @Summary 获取请求时间线
+// @Description 按请求ID返回请求日志、操作日志、登录日志与审计日志组成的时间线
+// @Tags 系统日志
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqTraceParam true "请求ID"
+// @Success 200 {object} model.ResTraceView
+// @Router /api/v1/sys_log/trace [post]
+func (s *SysLogController) GetTrace(ctx *app.Context) {
+	param := &model.ReqTraceParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind get trace params failed")
+		return
+	}
+
+	view, err := service.NewLogService().GetTrace(ctx, param.TraceID)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get trace failed", "trace", param.TraceID)
+		return
+	}
+	ctx.JSONSuccess(view)
+}
@@ -8,7 +8,8 @@ import (
 )
 
 type SysLoginLogController struct {
-	LoginLogService *service.SysLoginLogService
+	LoginLogService   *service.SysLoginLogService
+	LoginGuardService *service.LoginGuardService
 }
 
 // @Summary 获取登录日志
@@ -78,3 +79,40 @@ func (l *SysLoginLogController) GetLoginLogList(ctx *app.Context) {
 
 	ctx.JSONSuccess(loginLogs)
 }
+
+// @Summary 获取登录锁定列表
+// @Description 获取当前因登录失败次数过多而锁定的用户名与IP；需要 AdminPermissions 中的权限
+// @Tags 登录日志
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []model.ResLoginLock
+// @Router /api/v1/login_lock/list [post]
+func (l *SysLoginLogController) GetLoginLockList(ctx *app.Context) {
+	locks, err := l.LoginGuardService.ListLocks(ctx)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get login lock list failed")
+		return
+	}
+	ctx.JSONSuccess(locks)
+}
+
+// @Summary 解除登录锁定
+// @Description 解除用户名或IP的登录锁定，并清除失败计数；需要 AdminPermissions 中的权限
+// @Tags 登录日志
+// @Accept  json
+// @Produce  json
+// @Param param body model.ReqLoginLockClear true "锁定对象"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/login_lock/clear [post]
+func (l *SysLoginLogController) ClearLoginLock(ctx *app.Context) {
+	param := &model.ReqLoginLockClear{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind clear login lock params failed")
+		return
+	}
+	if err := l.LoginGuardService.ClearLock(ctx, param.Kind, param.Subject); err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "clear login lock failed", "kind", param.Kind, "subject", param.Subject)
+		return
+	}
+	ctx.JSONSuccess("ok")
+}
@@ -1,6 +1,14 @@
 package controller
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/luxingwen/sgin/pkg/ecode"
 	"github.com/luxingwen/sgin/service"
 
@@ -120,3 +128,118 @@ func (s *SysOpLogController) GetSysOpLogList(ctx *app.Context) {
 
 	ctx.JSONSuccess(logs)
 }
+
+// @Summary 导出操作日志
+// @Description 按查询条件流式导出操作日志，format 为 csv（默认）或 jsonl，单次最多 100000 行
+// @Tags 操作日志
+// @Accept  json
+// @Produce  octet-stream
+// @Param param body model.ReqOpLogExportParam true "导出参数"
+// @Success 200 {file} file
+// @Router /api/v1/sysoplog/export [post]
+func (s *SysOpLogController) ExportSysOpLog(ctx *app.Context) {
+	param := &model.ReqOpLogExportParam{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind export op logs params failed")
+		return
+	}
+	if param.Format == "" {
+		param.Format = "csv"
+	}
+	if param.Format != "csv" && param.Format != "jsonl" {
+		ctx.JSONErrLog(ecode.BadRequest("format must be csv or jsonl"), "bind export op logs params failed", "format", param.Format)
+		return
+	}
+
+	filename := fmt.Sprintf("sys_op_logs-%s.%s", time.Now().Format("20060102150405"), param.Format)
+	contentType := "text/csv; charset=utf-8"
+	if param.Format == "jsonl" {
+		contentType = "application/x-ndjson"
+	}
+	flusher, _ := ctx.Writer.(http.Flusher)
+	n := 0
+	var write func(log *model.SysOpLogRes) error
+	var done func() error
+	// 响应头在查询成功、取得第一行（或确认没有数据）后才写出，查询失败时仍可返回 JSON 错误
+	started := false
+	start := func() {
+		started = true
+		ctx.Header("Content-Type", contentType)
+		ctx.Header("Content-Disposition", "attachment; filename="+filename)
+		ctx.Status(http.StatusOK)
+		if param.Format == "jsonl" {
+			enc := json.NewEncoder(ctx.Writer)
+			write = func(log *model.SysOpLogRes) error { return enc.Encode(log) }
+			done = func() error { return nil }
+			return
+		}
+		w := csv.NewWriter(ctx.Writer)
+		// UTF-8 BOM，便于 Excel 识别中文
+		ctx.Writer.WriteString("\xEF\xBB\xBF")
+		w.Write(opLogCSVHeader)
+		write = func(log *model.SysOpLogRes) error { return w.Write(opLogCSVRecord(log)) }
+		done = func() error { w.Flush(); return w.Error() }
+	}
+
+	err := s.SysOpLogService.ExportSysOpLogs(ctx, &param.ReqOpLogQueryParam, func(log *model.SysOpLogRes) error {
+		if !started {
+			start()
+		}
+		if err := write(log); err != nil {
+			return err
+		}
+		if n++; n%500 == 0 {
+			if err := done(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err != nil && !started {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "export op logs failed")
+		return
+	}
+	if !started {
+		start()
+	}
+	if ferr := done(); err == nil {
+		err = ferr
+	}
+	// 响应头已发送，只能记录错误
+	if err != nil {
+		ctx.Logger.Errorw("export op logs failed", "trace_id", ctx.TraceID, "rows", n, "error", err.Error())
+	}
+}
+
+var opLogCSVHeader = []string{"id", "created_at", "request_id", "user_uuid", "username", "module", "name", "method", "path", "status", "code", "message", "duration", "ip", "params"}
+
+func opLogCSVRecord(log *model.SysOpLogRes) []string {
+	return []string{
+		strconv.FormatUint(uint64(log.ID), 10),
+		log.CreatedAt,
+		log.RequestId,
+		log.UserUuid,
+		csvSafe(log.Username),
+		csvSafe(log.Module),
+		csvSafe(log.Name),
+		log.Method,
+		csvSafe(log.Path),
+		strconv.Itoa(log.Status),
+		strconv.Itoa(log.Code),
+		csvSafe(log.Message),
+		strconv.FormatInt(log.Duration, 10),
+		log.Ip,
+		csvSafe(log.Params),
+	}
+}
+
+// csvSafe 避免以 = + - @ 开头的单元格在电子表格中被当作公式执行
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
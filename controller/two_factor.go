@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/service"
+)
+
+type TwoFactorController struct {
+	TwoFactorService *service.TwoFactorService
+	UserService      *service.UserService
+}
+
+// @Summary 获取两步验证状态
+// @Description 获取当前用户是否已启用两步验证、是否必须启用以及剩余恢复码数量
+// @Tags 两步验证
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.ResTwoFactorStatus
+// @Router /api/v1/2fa/status [post]
+func (t *TwoFactorController) GetStatus(ctx *app.Context) {
+	res, err := t.TwoFactorService.Status(ctx, ctx.GetString("user_id"))
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get two factor status failed")
+		return
+	}
+	ctx.JSONSuccess(res)
+}
+
+// @Summary 绑定验证器
+// @Description 生成新的 TOTP 密钥，返回 otpauth URI 与二维码；调用 /2fa/confirm 提交验证码后生效
+// @Tags 两步验证
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.ResTwoFactorEnroll
+// @Router /api/v1/2fa/enroll [post]
+func (t *TwoFactorController) Enroll(ctx *app.Context) {
+	userUuid := ctx.GetString("user_id")
+	user, err := t.UserService.GetUserByUUID(ctx, userUuid)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get user failed", "user_uuid", userUuid)
+		return
+	}
+	res, err := t.TwoFactorService.Enroll(ctx, user)
+	if err == service.ErrTwoFactorAlreadyEnabled {
+		ctx.JSONErrLog(ecode.Conflict(err.Error()), "two factor already enabled", "user_uuid", userUuid)
+		return
+	}
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "two factor enroll failed", "user_uuid", userUuid)
+		return
+	}
+	ctx.JSONSuccess(res)
+}
+
+// @Summary 确认绑定验证器
+// @Description 提交验证器中的验证码启用两步验证，返回恢复码（只返回一次）
+// @Tags 两步验证
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqTwoFactorCode true "验证码"
+// @Success 200 {object} model.ResRecoveryCodes
+// @Router /api/v1/2fa/confirm [post]
+func (t *TwoFactorController) Confirm(ctx *app.Context) {
+	param := &model.ReqTwoFactorCode{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind two factor confirm params failed")
+		return
+	}
+	userUuid := ctx.GetString("user_id")
+	codes, err := t.TwoFactorService.Confirm(ctx, userUuid, param.Code)
+	if err != nil {
+		t.writeError(ctx, err, "two factor confirm failed")
+		return
+	}
+	ctx.JSONSuccess(&model.ResRecoveryCodes{RecoveryCodes: codes})
+}
+
+// @Summary 关闭两步验证
+// @Description 验证密码与验证码后关闭两步验证；按权限要求必须启用的用户不能关闭
+// @Tags 两步验证
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqTwoFactorDisable true "密码与验证码"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/2fa/disable [post]
+func (t *TwoFactorController) Disable(ctx *app.Context) {
+	param := &model.ReqTwoFactorDisable{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind two factor disable params failed")
+		return
+	}
+	userUuid := ctx.GetString("user_id")
+	required, err := t.TwoFactorService.IsRequired(ctx, userUuid)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "check two factor requirement failed", "user_uuid", userUuid)
+		return
+	}
+	if required {
+		ctx.JSONErrLog(ecode.Forbidden(service.ErrTwoFactorRequired.Error()), "two factor required", "user_uuid", userUuid)
+		return
+	}
+
+	user, err := t.UserService.GetUserByUUID(ctx, userUuid)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "get user failed", "user_uuid", userUuid)
+		return
+	}
+	if !t.UserService.VerifyPassword(ctx, user, param.Password) {
+		ctx.JSONErrLog(ecode.BadRequest("密码错误"), "two factor disable password mismatch", "user_uuid", userUuid)
+		return
+	}
+	if err := t.TwoFactorService.Verify(ctx, userUuid, param.Code, ""); err != nil {
+		t.writeError(ctx, err, "two factor disable failed")
+		return
+	}
+	if err := t.TwoFactorService.Disable(ctx, userUuid); err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "two factor disable failed", "user_uuid", userUuid)
+		return
+	}
+	ctx.JSONSuccess("ok")
+}
+
+// @Summary 重新生成恢复码
+// @Description 验证验证码后生成新的恢复码，旧恢复码全部失效
+// @Tags 两步验证
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqTwoFactorCode true "验证码"
+// @Success 200 {object} model.ResRecoveryCodes
+// @Router /api/v1/2fa/recovery_codes [post]
+func (t *TwoFactorController) RegenerateRecoveryCodes(ctx *app.Context) {
+	param := &model.ReqTwoFactorCode{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind recovery codes params failed")
+		return
+	}
+	userUuid := ctx.GetString("user_id")
+	if err := t.TwoFactorService.Verify(ctx, userUuid, param.Code, ""); err != nil {
+		t.writeError(ctx, err, "regenerate recovery codes failed")
+		return
+	}
+	codes, err := t.TwoFactorService.RegenerateRecoveryCodes(ctx, userUuid)
+	if err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "regenerate recovery codes failed", "user_uuid", userUuid)
+		return
+	}
+	ctx.JSONSuccess(&model.ResRecoveryCodes{RecoveryCodes: codes})
+}
+
+// @Summary 重置用户的两步验证
+// @Description 管理员为丢失验证器的用户关闭两步验证，用户下次登录时按要求重新绑定；需要 TwoFactor.ResetPermissions 中的权限
+// @Tags 两步验证
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqTwoFactorReset true "用户UUID"
+// @Success 200 {string} string "ok"
+// @Router /api/v1/2fa/reset [post]
+func (t *TwoFactorController) Reset(ctx *app.Context) {
+	param := &model.ReqTwoFactorReset{}
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), "bind two factor reset params failed")
+		return
+	}
+	if err := t.TwoFactorService.Disable(ctx, param.UserUuid); err != nil {
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), "two factor reset failed", "user_uuid", param.UserUuid)
+		return
+	}
+	ctx.Logger.Infow("two factor reset", "user_uuid", param.UserUuid, "operator", ctx.GetString("user_id"))
+	ctx.JSONSuccess("ok")
+	// 操作日志记录操作人（user_uuid）与被重置的用户
+	ctx.Set("message", "重置用户 "+param.UserUuid+" 的两步验证")
+}
+
+func (t *TwoFactorController) writeError(ctx *app.Context, err error, msg string) {
+	switch err {
+	case service.ErrTwoFactorInvalidCode, service.ErrTwoFactorInvalidArgument:
+		ctx.JSONErrLog(ecode.BadRequest("验证码错误"), msg)
+	case service.ErrTwoFactorNotEnrolled:
+		ctx.JSONErrLog(ecode.BadRequest(err.Error()), msg)
+	case service.ErrTwoFactorAlreadyEnabled:
+		ctx.JSONErrLog(ecode.Conflict(err.Error()), msg)
+	default:
+		ctx.JSONErrLog(ecode.InternalError(err.Error()), msg)
+	}
+}
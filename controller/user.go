@@ -1,11 +1,13 @@
 package controller
 
 import (
+	"errors"
 	"path/filepath"
 
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
 	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/pkg/passwd"
 	"github.com/luxingwen/sgin/service"
 )
 
@@ -20,17 +22,23 @@ type UserController struct {
 // @Tags 用户
 // @Accept  json
 // @Produce  json
-// @Param user body model.User true "Create user"
+// @Param user body model.ReqUserSaveParam true "Create user"
 // @Success 200 {object} model.UserInfoResponse
 // @Router /api/v1/user/create [post]
 func (uc *UserController) CreateUser(c *app.Context) {
-	var user model.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	param := &model.ReqUserSaveParam{}
+	if err := c.ShouldBindJSON(param); err != nil {
 		c.JSONErrLog(ecode.BadRequest(err.Error()), "bind create user failed")
 		return
 	}
+	user := param.User
+	user.Password = param.Password
 
 	err := uc.Service.CreateUser(c, &user)
+	if errors.Is(err, passwd.ErrWeakPassword) {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "weak password", "username", user.Username)
+		return
+	}
 	if err != nil {
 		c.JSONErrLog(ecode.InternalError(err.Error()), "create user failed", "username", user.Username, "email", user.Email)
 		return
@@ -75,17 +83,23 @@ func (uc *UserController) GetUserByUUID(c *app.Context) {
 // @Tags 用户
 // @Accept  json
 // @Produce  json
-// @Param user body model.User true "Update user"
+// @Param user body model.ReqUserSaveParam true "Update user"
 // @Success 200 {object} model.UserInfoResponse
 // @Router /api/v1/user/update [post]
 func (uc *UserController) UpdateUser(c *app.Context) {
-	var user model.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	param := &model.ReqUserSaveParam{}
+	if err := c.ShouldBindJSON(param); err != nil {
 		c.JSONErrLog(ecode.BadRequest(err.Error()), "bind update user failed")
 		return
 	}
+	user := param.User
+	user.Password = param.Password
 
 	err := uc.Service.UpdateUser(c, &user)
+	if errors.Is(err, passwd.ErrWeakPassword) {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "weak password", "uuid", user.Uuid)
+		return
+	}
 	if err != nil {
 		c.JSONErrLog(ecode.InternalError(err.Error()), "update user failed", "uuid", user.Uuid)
 		return
@@ -99,6 +113,42 @@ func (uc *UserController) UpdateUser(c *app.Context) {
 	c.JSONSuccess(user)
 }
 
+// UpdateUserStatus updates the status of a User.
+// @Summary 修改用户状态
+// @Description Update the status of a user, disabling or deleting revokes all of the user's tokens
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param params body model.ReqUserStatusParam true "User status"
+// @Success 200 {object} app.Response
+// @Router /api/v1/user/status [post]
+func (uc *UserController) UpdateUserStatus(c *app.Context) {
+	params := &model.ReqUserStatusParam{}
+	if err := c.ShouldBindJSON(params); err != nil {
+		c.JSONErrLog(ecode.BadRequest(err.Error()), "bind update user status failed")
+		return
+	}
+
+	if params.Uuid == c.GetString("user_id") && *params.Status != model.UserStatusEnabled {
+		c.JSONErrLog(ecode.BadRequest("You can't disable yourself"), "self disable not allowed", "uuid", params.Uuid)
+		return
+	}
+
+	err := uc.Service.UpdateUserStatus(c, params.Uuid, *params.Status)
+	if err != nil {
+		c.JSONErrLog(ecode.InternalError(err.Error()), "update user status failed", "uuid", params.Uuid, "status", *params.Status)
+		return
+	}
+	c.Logger.Infow("user status updated",
+		"path", c.FullPath(),
+		"method", c.Request.Method,
+		"client_ip", c.ClientIP(),
+		"user_uuid", params.Uuid,
+		"status", *params.Status,
+	)
+	c.JSONSuccess("ok")
+}
+
 // DeleteUser deletes a User by UUID.
 // @Summary 删除用户
 // @Description Delete a user by its UUID
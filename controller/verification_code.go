@@ -1,33 +1,45 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
 
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
 	"github.com/luxingwen/sgin/pkg/ecode"
 	"github.com/luxingwen/sgin/pkg/mail"
+	"github.com/luxingwen/sgin/pkg/sms"
 	"github.com/luxingwen/sgin/service"
 )
 
 type VerificationCodeController struct {
 	VerificationCodeService *service.VerificationCodeService
+	SmsService              *service.SmsService
 }
 
-var registerMailContent = `
+var verificationMailContent = `
 <html>
 <body>
-    <h2>注册验证码</h2>
-    <p>尊敬的用户，您的注册验证码为：<strong>%s</strong></p>
-    <p>请在注册页面输入该验证码完成注册。</p>
-    <p>请勿将验证码透露给他人。</p>
+    <h2>%s验证码</h2>
+    <p>尊敬的用户，您的%s验证码为：<strong>%s</strong>，%d 分钟内有效。</p>
+    <p>请勿将验证码透露给他人。如果这不是您本人的操作，请忽略本邮件。</p>
 </body>
 </html>
 `
 
+// verificationPurposeNames 邮件标题与内容中的用途名称
+var verificationPurposeNames = map[string]string{
+	model.VerificationPurposeRegister:    "注册",
+	model.VerificationPurposeReset:       "重置密码",
+	model.VerificationPurposeLogin:       "登录",
+	model.VerificationPurposeChangeEmail: "修改邮箱",
+}
+
 // CreateVerificationCode 创建验证码
 // @Summary 创建验证码
-// @Description 创建验证码
+// @Description 按用途创建验证码，有邮箱时发往邮箱，否则通过短信发往手机号；同一接收方与 IP 的发送频率受限
 // @Tags 验证码
 // @Accept json
 // @Produce json
@@ -45,33 +57,65 @@ func (v *VerificationCodeController) CreateVerificationCode(ctx *app.Context) {
 		ctx.JSONErrLog(ecode.BadRequest("邮箱和手机号码不能同时为空"), "missing email and phone")
 		return
 	}
+	if param.Purpose == "" {
+		param.Purpose = model.VerificationPurposeRegister
+	}
 
-	code, err := v.VerificationCodeService.CreateVerificationCode(ctx, param.Email, param.Phone)
-	if err != nil {
+	if param.Email == "" && !v.SmsService.Enabled(ctx) {
+		ctx.JSONErrLog(ecode.ServiceUnavailable("短信服务未配置"), "sms provider is not configured", "phone", param.Phone)
+		return
+	}
+
+	code, err := v.VerificationCodeService.CreateVerificationCode(ctx, param.Purpose, param.Email, param.Phone)
+	var limit *service.VerificationCodeLimitError
+	switch {
+	case errors.As(err, &limit):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(limit.RetryAfter.Seconds()))))
+		ctx.JSONErrLog(ecode.TooManyRequests(limit.Message), "verification code rate limited",
+			"purpose", param.Purpose, "email", param.Email, "phone", param.Phone)
+		return
+	case err == service.ErrVerificationCodePurpose:
+		ctx.JSONErrLog(ecode.BadRequest("验证码用途不正确"), "invalid verification code purpose", "purpose", param.Purpose)
+		return
+	case err == service.ErrVerificationCodeKey:
+		ctx.JSONErrLog(ecode.ServiceUnavailable("验证码服务未配置"), "verification code hash key is not configured")
+		return
+	case err == sms.ErrInvalidPhone:
+		ctx.JSONErrLog(ecode.BadRequest("手机号码格式不正确"), "invalid phone number", "phone", param.Phone)
+		return
+	case err != nil:
 		ctx.JSONErrLog(ecode.InternalError(err.Error()), "create verification code failed")
 		return
 	}
 
+	minutes := v.VerificationCodeService.TTLMinutes(ctx)
 	if param.Email != "" {
 		// 发送邮件
-
+		name := verificationPurposeNames[param.Purpose]
+		subject := name + "验证码"
+		if param.Purpose == model.VerificationPurposeRegister && ctx.Config.MailConfig.RegisterTile != "" {
+			subject = ctx.Config.MailConfig.RegisterTile
+		}
 		err = mail.Send(&mail.Options{
 			MailHost: ctx.Config.MailConfig.Host,
 			MailPort: ctx.Config.MailConfig.Port,
 			MailUser: ctx.Config.MailConfig.Username,
 			MailPass: ctx.Config.MailConfig.Password,
 			MailTo:   param.Email,
-			Subject:  ctx.Config.MailConfig.RegisterTile,
-			Body:     fmt.Sprintf(registerMailContent, code),
+			Subject:  subject,
+			Body:     fmt.Sprintf(verificationMailContent, name, name, code, minutes),
 		})
 		if err != nil {
 			ctx.JSONErrLog(ecode.InternalError(err.Error()), "send verification mail failed", "email", param.Email)
 			return
 		}
-	}
-
-	if param.Phone != "" {
-		// 发送短信
+	} else {
+		// 发送短信，发送结果记录在短信发送记录中
+		err = v.SmsService.SendVerificationCode(ctx, param.Phone, code, minutes)
+		if err != nil {
+			ctx.JSONErrLog(ecode.InternalError(err.Error()), "send verification sms failed", "phone", param.Phone)
+			return
+		}
 	}
 
 	ctx.JSONSuccess("验证码发送成功")
@@ -79,11 +123,11 @@ func (v *VerificationCodeController) CreateVerificationCode(ctx *app.Context) {
 
 // CheckVerificationCode 检查验证码
 // @Summary 检查验证码
-// @Description 检查验证码
+// @Description 检查验证码是否正确，不标记为已使用；连续错误达到上限后验证码作废
 // @Tags 验证码
 // @Accept json
 // @Produce json
-// @Param params body string true "验证码信息"
+// @Param params body model.ReqVerificationCodeParam true "验证码信息"
 // @Success 200 {string} string "Successfully fetched user data"
 // @Router /api/v1/verification_code/check [post]
 func (v *VerificationCodeController) CheckVerificationCode(ctx *app.Context) {
@@ -102,8 +146,11 @@ func (v *VerificationCodeController) CheckVerificationCode(ctx *app.Context) {
 		ctx.JSONErrLog(ecode.BadRequest("验证码不能为空"), "code is empty")
 		return
 	}
+	if param.Purpose == "" {
+		param.Purpose = model.VerificationPurposeRegister
+	}
 
-	ok, err := v.VerificationCodeService.CheckVerificationCode(ctx, param.Code, param.Email, param.Phone)
+	ok, err := v.VerificationCodeService.CheckVerificationCode(ctx, param.Purpose, param.Code, param.Email, param.Phone)
 	if err != nil {
 		ctx.JSONErrLog(ecode.InternalError(err.Error()), "check verification code failed")
 		return
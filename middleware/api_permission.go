@@ -7,7 +7,8 @@ import (
 	"github.com/luxingwen/sgin/service"
 )
 
-// API权限校验中间件
+// API权限校验中间件，应用通过 X-Api-Key 或应用访问令牌（Authorization: Bearer）认证，
+// 使用访问令牌或限定了 scope 的 API Key 时，接口所属模块必须在 scope 中
 func ApiPermission() app.HandlerFunc {
 	return func(c *app.Context) {
 
@@ -26,39 +27,58 @@ func ApiPermission() app.HandlerFunc {
 			err     error
 		)
 
+		// 应用访问令牌（OAuth2 client credentials）限定了可访问的 scope
+		var scopes []string
+		hasScopes := false
+
 		appinfo0, ok := c.Get("app_info")
-		if !ok {
-			if apikey == "" {
-				c.JSONErrLog(ecode.Forbidden("missing api key"), "missing api key",
+		if !ok && apikey == "" && bearerToken(c) != "" {
+			claims, info, err := service.NewAppTokenService().ParseToken(c, bearerToken(c))
+			if err != nil {
+				c.JSONErrLog(ecode.Unauthorized("invalid access token"), "parse app token failed",
 					"trace_id", c.TraceID,
 					"path", c.FullPath(),
 					"method", apiMethod,
 					"client_ip", c.ClientIP(),
+					"cause", err.Error(),
 				)
 				c.Abort()
 				return
 			}
-
-			appinfo, err = service.NewAppService().GetAppByApiKey(c, apikey)
-			if err != nil {
-				c.JSONErrLog(ecode.Forbidden("invalid api key"), "get app by apikey failed",
+			appinfo, scopes, hasScopes = info, claims.Scopes, true
+			c.Set("app_info", appinfo)
+			c.Set("app_id", appinfo.UUID)
+			c.Set("app_scopes", scopes)
+		} else if !ok {
+			if apikey == "" {
+				c.JSONErrLog(ecode.Forbidden("missing api key"), "missing api key",
 					"trace_id", c.TraceID,
 					"path", c.FullPath(),
 					"method", apiMethod,
 					"client_ip", c.ClientIP(),
-					"cause", err.Error(),
 				)
 				c.Abort()
 				return
 			}
+
+			if appinfo, ok = verifyApiKey(c, apikey); !ok {
+				c.Abort()
+				return
+			}
+			if v, ok := c.Get("app_scopes"); ok {
+				scopes, hasScopes = v.([]string), true
+			}
 		} else {
 			appinfo = appinfo0.(*model.App)
+			if v, ok := c.Get("app_scopes"); ok {
+				scopes, hasScopes = v.([]string), true
+			}
 		}
 
-		// 根据app信息获取app权限
+		// 根据app信息获取app权限，路径同时匹配请求路径与路由模板
 
-		appPermission, err := service.NewAppPermissionService().GetAPIPermissionByNamePathMethod(c, appinfo.UUID, apiPath, apiMethod)
-		if err != nil || appPermission == nil {
+		api, err := service.NewAppPermissionService().GetAppAPIByPathMethod(c, appinfo.UUID, apiMethod, apiPath, c.FullPath())
+		if err != nil || api == nil {
 			c.JSONErrLog(ecode.Forbidden("permission denied"), "api permission denied",
 				"trace_id", c.TraceID,
 				"path", c.FullPath(),
@@ -75,6 +95,27 @@ func ApiPermission() app.HandlerFunc {
 			c.Abort()
 			return
 		}
-		_ = appPermission
+		if hasScopes && !service.ScopeAllowed(scopes, service.APIScope(api)) {
+			c.JSONErrLog(ecode.Forbidden("insufficient scope"), "api scope denied",
+				"trace_id", c.TraceID,
+				"path", c.FullPath(),
+				"method", apiMethod,
+				"client_ip", c.ClientIP(),
+				"app_id", appinfo.UUID,
+				"scope", service.APIScope(api),
+			)
+			c.Abort()
+			return
+		}
+	}
+}
+
+// bearerToken 返回 Authorization: Bearer 中的令牌
+func bearerToken(c *app.Context) string {
+	auth := c.GetHeader("Authorization")
+	const prefix = "Bearer "
+	if len(auth) > len(prefix) && auth[:len(prefix)] == prefix {
+		return auth[len(prefix):]
 	}
+	return ""
 }
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"strings"
+
+	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
 	"github.com/luxingwen/sgin/pkg/ecode"
 	"github.com/luxingwen/sgin/service"
@@ -14,21 +17,37 @@ func AppKeyCheck() app.HandlerFunc {
 		apikey := c.GetHeader("X-Api-Key")
 
 		// 根据apikey获取app信息
-
-		appInfo, err := service.NewAppService().GetAppByApiKey(c, apikey)
-		if err != nil {
-			c.JSONErrLog(ecode.Forbidden("invalid api key"), "get app by apikey failed",
-				"trace_id", c.TraceID,
-				"path", c.FullPath(),
-				"method", c.Request.Method,
-				"client_ip", c.ClientIP(),
-				"cause", err.Error(),
-			)
+		if _, ok := verifyApiKey(c, apikey); !ok {
 			c.Abort()
 			return
 		}
-		c.Set("app_info", appInfo)
-		// 同步设置 app_id 方便后续限流等
-		c.Set("app_id", appInfo.UUID)
 	}
 }
+
+// verifyApiKey 校验 API Key 并将应用信息写入上下文，失败时已写入响应；
+// 限定了 scope 的密钥同时写入 app_scopes，由 ApiPermission 校验
+func verifyApiKey(c *app.Context, apikey string) (*model.App, bool) {
+	appInfo, key, err := service.NewAppKeyService().Verify(c, apikey, c.ClientIP())
+	if err != nil {
+		e := ecode.Forbidden("invalid api key")
+		if err == service.ErrAppKeyIPNotAllowed {
+			e = ecode.Forbidden(err.Error())
+		}
+		c.JSONErrLog(e, "verify api key failed",
+			"trace_id", c.TraceID,
+			"path", c.FullPath(),
+			"method", c.Request.Method,
+			"client_ip", c.ClientIP(),
+			"cause", err.Error(),
+		)
+		return nil, false
+	}
+	c.Set("app_info", appInfo)
+	// 同步设置 app_id 方便后续限流等
+	c.Set("app_id", appInfo.UUID)
+	c.Set("app_key", key.Prefix)
+	if key.Scopes != "" {
+		c.Set("app_scopes", strings.Fields(key.Scopes))
+	}
+	return appInfo, true
+}
@@ -37,6 +37,14 @@ func ForwardByPrefix(use ...app.HandlerFunc) app.HandlerFunc {
 					return
 				}
 
+				c.Logger.Named("proxy").Debugw("forward request",
+					"trace_id", c.TraceID,
+					"path", upath,
+					"method", c.Request.Method,
+					"prefix", prefix,
+					"forward_address", c.Config.ForwardAddress,
+				)
+
 				proxy := httputil.NewSingleHostReverseProxy(remote)
 				// 定义我们自己的director
 				proxy.Director = func(req *http.Request) {
@@ -7,11 +7,11 @@ import (
 
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/redact"
 	"github.com/luxingwen/sgin/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.uber.org/zap"
 )
 
 const (
@@ -56,19 +56,7 @@ func LogMiddleware() app.HandlerFunc {
 	return func(c *app.Context) {
 		// 获取请求信息
 		// 复制并脱敏敏感头
-		header := c.Request.Header.Clone()
-		if header.Get("Authorization") != "" {
-			header.Set("Authorization", "*****")
-		}
-		if header.Get("X-Token") != "" {
-			header.Set("X-Token", "*****")
-		}
-		if header.Get("X-Signature") != "" {
-			header.Set("X-Signature", "*****")
-		}
-		if header.Get("Cookie") != "" {
-			header.Set("Cookie", "*****")
-		}
+		header := redact.Header(c.Request.Header)
 		headerByte, _ := json.Marshal(header)
 		ip := c.ClientIP()
 
@@ -104,7 +92,7 @@ func LogMiddleware() app.HandlerFunc {
 			Ip:       ip,
 			Path:     path,
 			Method:   method,
-			ReqBody:  string(bodyBytes),
+			ReqBody:  string(c.Redactor().Body(ct, bodyBytes)),
 			AppId:    appid,
 			UserUUID: userId,
 			TraceID:  c.TraceID,
@@ -112,20 +100,6 @@ func LogMiddleware() app.HandlerFunc {
 			Action:   c.FullPath(),
 		}
 
-		// 将日志信息写入数据库
-		if err = service.NewLogService().CreateLog(c, &logInfo); err != nil {
-			// 不中断业务，仅记录错误
-			c.Logger.Errorw("create request log failed",
-				"error", err.Error(),
-				"trace_id", c.TraceID,
-				"path", path,
-				"method", method,
-				"client_ip", ip,
-				"app_id", appid,
-				"user_id", userId,
-			)
-		}
-
 		// 包装 ResponseWriter 以捕获响应体
 		bw := &bodyWriter{ResponseWriter: c.Writer}
 		c.Writer = bw
@@ -134,7 +108,7 @@ func LogMiddleware() app.HandlerFunc {
 
 		// 获取响应信息
 		status := c.Writer.Status()
-		respBody := bw.buf.String()
+		respBody := string(c.Redactor().Body(c.Writer.Header().Get("Content-Type"), bw.buf.Bytes()))
 		// 从 context 中获取 message（由统一响应设置）
 		if msgVal, exists := c.Get("message"); exists {
 			if s, ok := msgVal.(string); ok {
@@ -143,10 +117,26 @@ func LogMiddleware() app.HandlerFunc {
 		}
 		logInfo.Status = status
 		logInfo.RespBody = respBody
+		// 登录/签名校验可能在本中间件之后执行，响应后再取一次
+		if logInfo.UserUUID == "" {
+			logInfo.UserUUID = c.GetString("user_id")
+		}
+		if logInfo.AppId == "" {
+			logInfo.AppId = c.GetString("app_id")
+		}
 
-		// 更新日志
-		if err = service.NewLogService().UpdateLog(c, &logInfo); err != nil {
-			c.Logger.Error("update log error", zap.Error(err))
+		// 响应完成后写入一次，启用异步日志管道时不阻塞请求
+		if err = service.NewLogService().CreateLogAsync(c, &logInfo); err != nil {
+			// 不中断业务，仅记录错误
+			c.Logger.Errorw("create request log failed",
+				"error", err.Error(),
+				"trace_id", c.TraceID,
+				"path", path,
+				"method", method,
+				"client_ip", ip,
+				"app_id", appid,
+				"user_id", userId,
+			)
 		}
 
 	}
@@ -4,6 +4,7 @@ import (
 	"github.com/luxingwen/sgin/pkg/app"
 	"github.com/luxingwen/sgin/pkg/ecode"
 	"github.com/luxingwen/sgin/pkg/utils"
+	"github.com/luxingwen/sgin/service"
 )
 
 // 登录中间件
@@ -33,7 +34,7 @@ func LoginCheck() app.HandlerFunc {
 		}
 
 		// 根据token获取用户信息
-		userId, err := utils.ParseTokenGetUserID(token)
+		claims, err := utils.ParseTokenClaims(token)
 		if err != nil {
 			// 避免在日志中输出完整 token（敏感），但记录一个 masked 片段方便排查
 			snippet := token
@@ -52,7 +53,24 @@ func LoginCheck() app.HandlerFunc {
 			return
 		}
 
+		// 检查令牌是否已被吊销（登出、刷新令牌泄露、用户被禁用或删除）
+		if err := service.NewAuthService().CheckAccessToken(c, claims); err != nil {
+			if err == service.ErrTokenRevoked {
+				c.JSONErrLog(ecode.Unauthorized("token revoked"), "token revoked",
+					"user_id", claims.UserID,
+					"jti", claims.JTI,
+				)
+			} else {
+				c.JSONErrLog(ecode.ServiceUnavailable(err.Error()), "check token revocation failed")
+			}
+			c.Abort()
+			return
+		}
+
 		// 将用户信息放入上下文
-		c.Set("user_id", userId)
+		c.Set("user_id", claims.UserID)
+		c.Set(service.CtxTokenJTI, claims.JTI)
+		c.Set(service.CtxTokenExp, claims.ExpiresAt)
+		c.Set(service.CtxSessionID, claims.SessionID)
 	}
 }
@@ -1,19 +1,44 @@
 package middleware
 
 import (
-	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/luxingwen/sgin/pkg/app"
 	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/service"
 )
 
-// 防重放攻击中间件
+// maxNonceLength nonce 的最大长度
+const maxNonceLength = 128
+
+// nonceWindow 返回 X-Timestamp 允许早于与晚于服务器时间的最大偏差
+func nonceWindow(c *app.Context) (past, future time.Duration) {
+	past, future = 60*time.Second, 5*time.Second
+	if c.Config != nil {
+		if c.Config.Nonce.Window > 0 {
+			past = time.Duration(c.Config.Nonce.Window) * time.Second
+		}
+		if c.Config.Nonce.FutureSkew > 0 {
+			future = time.Duration(c.Config.Nonce.FutureSkew) * time.Second
+		}
+	}
+	return past, future
+}
+
+// nonceScope nonce 按应用隔离，只使用 AppKeyCheck/ApiPermission/签名中间件认证后的 app_id；
+// 未认证时返回空，请求头中的 X-App-Id 可以伪造，不能作为隔离依据
+func nonceScope(c *app.Context) string {
+	return c.GetString("app_id")
+}
+
+// 防重放攻击中间件：X-Timestamp 须在时间窗口内，同一应用的 X-Nonce 在窗口内只能使用一次；
+// 须挂在认证应用的中间件之后
 func NonceHandler() app.HandlerFunc {
 	return func(c *app.Context) {
 
-		if c.Redis == nil {
+		store := service.NewNonceStore(c)
+		if store == nil {
 			c.JSONErrLog(ecode.ServiceUnavailable("nonce service unavailable"), "nonce service unavailable",
 				"trace_id", c.TraceID,
 				"path", c.FullPath(),
@@ -27,6 +52,17 @@ func NonceHandler() app.HandlerFunc {
 		nonce := c.GetHeader("X-Nonce")
 		timestamp := c.GetHeader("X-Timestamp")
 
+		if nonce == "" || len(nonce) > maxNonceLength {
+			c.JSONErrLog(ecode.Forbidden("nonce error"), "nonce missing or too long",
+				"trace_id", c.TraceID,
+				"path", c.FullPath(),
+				"method", c.Request.Method,
+				"client_ip", c.ClientIP(),
+			)
+			c.Abort()
+			return
+		}
+
 		timestampInt, err := strconv.ParseInt(timestamp, 10, 64)
 		if err != nil {
 			c.JSONErrLog(ecode.Forbidden("timestamp error"), "timestamp parse error",
@@ -40,10 +76,11 @@ func NonceHandler() app.HandlerFunc {
 			return
 		}
 
-		// 检查时间戳是否过期
-
-		if time.Now().Unix()-timestampInt > 60 {
-			c.JSONErrLog(ecode.Forbidden("timestamp expired"), "timestamp expired",
+		// 检查时间戳是否在窗口内，过早与过晚都拒绝
+		past, future := nonceWindow(c)
+		skew := time.Since(time.Unix(timestampInt, 0))
+		if skew > past || skew < -future {
+			c.JSONErrLog(ecode.Forbidden("timestamp expired"), "timestamp out of window",
 				"trace_id", c.TraceID,
 				"path", c.FullPath(),
 				"method", c.Request.Method,
@@ -55,44 +92,40 @@ func NonceHandler() app.HandlerFunc {
 			return
 		}
 
-		// 检查nonce是否已经存在
-		noncestr := fmt.Sprintf("Nonce_%s", nonce)
-
-		rval, err := c.Redis.Get(c.Ctx, noncestr)
-		if err != nil {
-			c.JSONErrLog(ecode.InternalError("nonce error"), "redis get nonce failed",
+		// 原子地记录 nonce，保存到时间戳不再被接受为止
+		scope := nonceScope(c)
+		if scope == "" {
+			c.JSONErrLog(ecode.Forbidden("app is not authenticated"), "nonce handler requires an authenticated app",
 				"trace_id", c.TraceID,
 				"path", c.FullPath(),
 				"method", c.Request.Method,
 				"client_ip", c.ClientIP(),
-				"key", noncestr,
-				"cause", err.Error(),
 			)
 			c.Abort()
 			return
 		}
-
-		if rval != "" {
-			c.JSONErrLog(ecode.Forbidden("nonce error"), "nonce replay detected",
+		ok, err := store.Claim(c.Ctx, scope, nonce, past+future)
+		if err != nil {
+			c.JSONErrLog(ecode.ServiceUnavailable("nonce service unavailable"), "claim nonce failed",
 				"trace_id", c.TraceID,
 				"path", c.FullPath(),
 				"method", c.Request.Method,
 				"client_ip", c.ClientIP(),
-				"key", noncestr,
+				"app_id", scope,
+				"cause", err.Error(),
 			)
 			c.Abort()
 			return
 		}
 
-		err = c.Redis.Set(c.Ctx, noncestr, timestamp, 60*time.Second)
-		if err != nil {
-			c.JSONErrLog(ecode.InternalError("nonce error"), "redis set nonce failed",
+		if !ok {
+			c.JSONErrLog(ecode.Forbidden("nonce error"), "nonce replay detected",
 				"trace_id", c.TraceID,
 				"path", c.FullPath(),
 				"method", c.Request.Method,
 				"client_ip", c.ClientIP(),
-				"key", noncestr,
-				"cause", err.Error(),
+				"app_id", scope,
+				"nonce", nonce,
 			)
 			c.Abort()
 			return
@@ -2,78 +2,176 @@ package middleware
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/config"
 	"github.com/luxingwen/sgin/pkg/ecode"
-	"github.com/luxingwen/sgin/pkg/utils"
+	"github.com/luxingwen/sgin/pkg/sign"
 	"github.com/luxingwen/sgin/service"
 )
 
 const maxSignBodyBytes = 1 << 20 // 1MB
 
-// 签名校验中间件
+// SignatureOptions 签名校验选项
+type SignatureOptions struct {
+	Required bool     // 是否必须签名；为 false 时没有 X-Signature 的请求直接放行
+	Headers  []string // 除 x-app-id、x-nonce、x-timestamp 外必须参与签名的请求头，如 content-type
+}
+
+// 签名校验中间件，兼容旧版本：没有 X-Signature 的请求直接放行，带签名的请求必须使用 v2
 func Signature() app.HandlerFunc {
-	return func(c *app.Context) {
-		signature := c.GetHeader("X-Signature")
-		if signature == "" {
-			c.Next()
-			return
-		}
+	return SignatureWithOptions(SignatureOptions{})
+}
 
-		appId := c.GetHeader("X-App-Id")
-		if appId == "" {
-			c.JSONErrLog(ecode.Forbidden("X-App-Id is empty"), "signature missing app id",
+// RequireSignature 必须使用 v2 签名的中间件，按路由组挂载
+func RequireSignature(headers ...string) app.HandlerFunc {
+	return SignatureWithOptions(SignatureOptions{Required: true, Headers: headers})
+}
+
+// AppSignatureGroup 供应用调用的接口在 Signature.Groups 中的默认组名
+const AppSignatureGroup = "app"
+
+// GroupSignature 按 Signature.Groups[name] 校验签名，组未配置时必须使用 v2 签名；配置在挂载时读取
+func GroupSignature(cfg config.SignatureConfig, name string) app.HandlerFunc {
+	g := cfg.Groups[strings.ToLower(name)]
+	return SignatureWithOptions(SignatureOptions{Required: !g.Optional, Headers: g.Headers})
+}
+
+func signatureWindow(c *app.Context) time.Duration {
+	if c.Config != nil && c.Config.Signature.Window > 0 {
+		return time.Duration(c.Config.Signature.Window) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// claimSignNonce nonce 在窗口内只能使用一次，按应用隔离
+func claimSignNonce(c *app.Context, appId, nonce string, ttl time.Duration) (bool, error) {
+	store := service.NewNonceStore(c)
+	if store == nil {
+		return false, errors.New("nonce store is not available")
+	}
+	return store.Claim(c.Ctx, "sign:"+appId, nonce, ttl)
+}
+
+// SignatureWithOptions 按选项校验应用请求签名，签名规则见 pkg/sign。
+// v2 签名覆盖方法、路径、查询参数、指定请求头、时间戳、nonce 与请求体，时间戳须在窗口内且 nonce 不能重复
+func SignatureWithOptions(opts SignatureOptions) app.HandlerFunc {
+	required := sign.NormalizeHeaders(opts.Headers)
+	return func(c *app.Context) {
+		fail := func(e *ecode.APIError, msg string, kv ...interface{}) {
+			kv = append(kv,
 				"trace_id", c.TraceID,
 				"path", c.FullPath(),
 				"method", c.Request.Method,
 				"client_ip", c.ClientIP(),
 			)
+			c.JSONErrLog(e, msg, kv...)
 			c.Abort()
+		}
+
+		signature := c.GetHeader(sign.HeaderSignature)
+		if signature == "" {
+			if !opts.Required {
+				c.Next()
+				return
+			}
+			fail(ecode.Unauthorized("signature is required"), "signature missing")
+			return
+		}
+
+		// 只对请求体签名的 v1 依赖明文保存的 API Key，已不再支持
+		if version := c.GetHeader(sign.HeaderVersion); version != sign.V2 {
+			fail(ecode.Forbidden("unsupported signature version, use v2"), "unsupported signature version", "version", version)
+			return
+		}
+
+		appId := c.GetHeader(sign.HeaderAppID)
+		if appId == "" {
+			fail(ecode.Forbidden("X-App-Id is empty"), "signature missing app id")
+			return
+		}
+		// 已通过 API Key 或访问令牌认证时，签名的应用必须是同一个
+		if authed := c.GetString("app_id"); authed != "" && authed != appId {
+			fail(ecode.Forbidden("X-App-Id does not match the authenticated app"), "signature app id mismatch",
+				"app_id", appId, "authenticated_app_id", authed)
 			return
 		}
 
 		appInfo, err := service.NewAppService().GetAppByUUID(c, appId)
-		if err != nil {
-			c.JSONErrLog(ecode.Forbidden("invalid app"), "get app by uuid failed",
-				"app_id", appId,
-				"trace_id", c.TraceID,
-				"path", c.FullPath(),
-				"method", c.Request.Method,
-				"client_ip", c.ClientIP(),
-				"cause", err.Error(),
-			)
-			c.Abort()
+		if err != nil || appInfo.Status != 1 {
+			cause := "app is disabled"
+			if err != nil {
+				cause = err.Error()
+			}
+			fail(ecode.Forbidden("invalid app"), "get app by uuid failed", "app_id", appId, "cause", cause)
 			return
 		}
 
-		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSignBodyBytes))
+		// API Key 只保存摘要，签名使用应用的 SecKey
+		secret, err := service.NewAppService().Secret(c, appInfo)
+		if err != nil || secret == "" {
+			fail(ecode.Forbidden("app secret is not available"), "get app secret failed", "app_id", appId)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSignBodyBytes+1))
 		if err != nil {
-			c.JSONErrLog(ecode.BadRequest("read request body failed"), "read request body failed",
-				"trace_id", c.TraceID,
-				"path", c.FullPath(),
-				"method", c.Request.Method,
-				"client_ip", c.ClientIP(),
-				"app_id", appId,
-				"cause", err.Error(),
-			)
+			fail(ecode.BadRequest("read request body failed"), "read request body failed", "app_id", appId, "cause", err.Error())
+			return
+		}
+		if len(body) > maxSignBodyBytes {
+			fail(ecode.New(http.StatusRequestEntityTooLarge, "request body too large"), "signed request body too large", "app_id", appId)
 			return
 		}
 
 		// 将 body 内容写回
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
-		serverSign := utils.SignBody(body, []byte(appInfo.ApiKey))
+		window := signatureWindow(c)
+		ts, err := strconv.ParseInt(c.GetHeader(sign.HeaderTimestamp), 10, 64)
+		if err != nil {
+			fail(ecode.Forbidden("timestamp error"), "signature timestamp parse error", "app_id", appId)
+			return
+		}
+		if skew := time.Since(time.Unix(ts, 0)); skew > window || skew < -window {
+			fail(ecode.Forbidden("timestamp expired"), "signature timestamp out of window",
+				"app_id", appId, "server_time", time.Now().Unix(), "client_time", ts)
+			return
+		}
+		signed := map[string]bool{}
+		for _, h := range sign.ParseSignedHeaders(c.GetHeader(sign.HeaderSignedHeaders)) {
+			signed[h] = true
+		}
+		for _, h := range required {
+			if !signed[h] {
+				fail(ecode.Forbidden("header "+h+" must be signed"), "required header not signed", "app_id", appId, "header", h)
+				return
+			}
+		}
+		if err := sign.Verify(c.Request, body, secret); err != nil {
+			fail(ecode.Forbidden("signature is invalid"), "signature is invalid", "app_id", appId, "cause", err.Error())
+			return
+		}
 
-		if serverSign != signature {
-			c.JSONErrLog(ecode.Forbidden("signature is invalid"), "signature is invalid",
-				"trace_id", c.TraceID,
-				"path", c.FullPath(),
-				"method", c.Request.Method,
-				"client_ip", c.ClientIP(),
-				"app_id", appId,
-			)
-			c.Abort()
+		// 签名通过后再记录 nonce，避免伪造的请求占用 nonce
+		nonce := c.GetHeader(sign.HeaderNonce)
+		if len(nonce) > maxNonceLength {
+			fail(ecode.Forbidden("nonce error"), "signature nonce too long", "app_id", appId)
+			return
+		}
+		ok, err := claimSignNonce(c, appId, nonce, 2*window)
+		if err != nil {
+			fail(ecode.ServiceUnavailable("nonce service unavailable"), "claim signature nonce failed", "app_id", appId, "cause", err.Error())
+			return
+		}
+		if !ok {
+			fail(ecode.Forbidden("nonce error"), "signature nonce replay detected", "app_id", appId, "nonce", nonce)
 			return
 		}
 
@@ -55,7 +55,7 @@ func SysOpLogMiddleware(logservice *service.SysOpLogService) app.HandlerFunc {
 		if method == "POST" {
 			// 获取content-type
 			contentType := c.GetHeader("Content-Type")
-			if strings.HasPrefix(contentType, "application/json") {
+			if strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
 				// 读取请求体
 				bodyBytes, err = io.ReadAll(c.Request.Body)
 				if err != nil {
@@ -69,6 +69,8 @@ func SysOpLogMiddleware(logservice *service.SysOpLogService) app.HandlerFunc {
 					return
 				}
 				c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+				// 脱敏后再记录，避免保存密码等敏感参数
+				bodyBytes = c.Redactor().Body(contentType, bodyBytes)
 			}
 		}
 
@@ -99,8 +101,8 @@ func SysOpLogMiddleware(logservice *service.SysOpLogService) app.HandlerFunc {
 		logInfo.Duration = time.Since(startTime).Milliseconds()
 		// logInfo.Response = string(c.Writer.Body.Bytes()) // 获取响应体
 
-		// 将日志信息写入数据库
-		err = logservice.CreateSysOpLog(c, &logInfo)
+		// 将日志信息写入数据库，启用异步日志管道时不阻塞请求
+		err = logservice.CreateSysOpLogAsync(c, &logInfo)
 		if err != nil {
 			c.Logger.Errorw("create sys op log failed",
 				"trace_id", c.TraceID,
@@ -1,6 +1,10 @@
 package middleware
 
-import "github.com/luxingwen/sgin/pkg/app"
+import (
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/ecode"
+	"github.com/luxingwen/sgin/service"
+)
 
 // 用户权限中间件
 
@@ -16,3 +20,32 @@ func UserPermission() app.HandlerFunc {
 		_ = userId
 	}
 }
+
+// RequirePermission 要求登录用户拥有 names 中任一权限（Permission.Name），names 为空时拒绝所有请求；
+// 需要放在 LoginCheck 之后
+func RequirePermission(names []string) app.HandlerFunc {
+	return func(c *app.Context) {
+		userId := c.GetString("user_id")
+		ok, err := service.NewUserPermissionService().HasAnyPermission(c, userId, names)
+		if err != nil {
+			c.JSONErrLog(ecode.InternalError(err.Error()), "check user permission failed",
+				"trace_id", c.TraceID,
+				"path", c.FullPath(),
+				"user_id", userId,
+			)
+			c.Abort()
+			return
+		}
+		if !ok {
+			c.JSONErrLog(ecode.Forbidden("permission denied"), "user permission denied",
+				"trace_id", c.TraceID,
+				"path", c.FullPath(),
+				"method", c.Request.Method,
+				"client_ip", c.ClientIP(),
+				"user_id", userId,
+			)
+			c.Abort()
+			return
+		}
+	}
+}
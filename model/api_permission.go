@@ -11,3 +11,6 @@ type AppPermission struct {
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`      // CreatedAt 记录了应用接口权限创建的时间
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`      // UpdatedAt 记录了应用接口权限信息最后更新的时间
 }
+
+// AuditEntity 纳入实体变更审计
+func (AppPermission) AuditEntity() string { return "app_permission" }
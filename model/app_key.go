@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// AppKey 应用的 API Key，一个应用可以有多个同时有效的密钥。
+// 只保存密钥摘要，明文只在创建与轮换时返回一次；Prefix 是密钥的可识别部分，用于列表展示与排查
+type AppKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Uuid       string     `json:"uuid" gorm:"type:char(36);uniqueIndex"`
+	AppUuid    string     `json:"app_uuid" gorm:"type:char(36);index"`            // 应用UUID
+	Name       string     `json:"name" gorm:"type:varchar(100)"`                  // 名称
+	Prefix     string     `json:"prefix" gorm:"type:varchar(32);index"`           // 密钥前缀，如 sgk_1a2b3c4d
+	KeyHash    string     `json:"-" gorm:"type:char(64);uniqueIndex"`             // 密钥摘要
+	Scopes     string     `json:"scopes" gorm:"type:varchar(500)"`                // 可访问的接口模块，空格分隔，为空不限制
+	AllowedIPs string     `json:"allowed_ips" gorm:"type:varchar(1000)"`          // 允许调用的 IP 或 CIDR，逗号分隔，为空不限制
+	ExpiresAt  *time.Time `json:"expires_at"`                                     // 过期时间，为空不过期；轮换后为旧密钥宽限期的结束时间
+	LastUsedAt *time.Time `json:"last_used_at" audit:"-"`                         // 最近使用时间
+	LastUsedIP string     `json:"last_used_ip" gorm:"type:varchar(64)" audit:"-"` // 最近使用的 IP
+	RevokedAt  *time.Time `json:"revoked_at"`                                     // 吊销时间
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// AuditEntity 纳入实体变更审计
+func (AppKey) AuditEntity() string { return "app_key" }
+
+// Active 密钥在 now 时是否有效
+func (k *AppKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"log"
+
 	"gorm.io/gorm"
 )
 
@@ -9,6 +11,7 @@ func MigrateDbTable(db *gorm.DB) {
 		&AppPermission{},
 		&API{},
 		&App{},
+		&AppKey{},
 		&Log{},
 		&Menu{},
 		&Role{},
@@ -18,8 +21,18 @@ func MigrateDbTable(db *gorm.DB) {
 		&Team{},
 		&TeamMember{},
 		&VerificationCode{},
+		&VerificationSendCount{},
+		&SmsLog{},
 		&SysLoginLog{},
 		&SysOpLog{},
+		&SysLogPurge{},
+		&SysAuditLog{},
+		&RefreshToken{},
+		&UserSession{},
+		&TokenRevocation{},
+		&UserTwoFactor{},
+		&UserRecoveryCode{},
+		&UserIdentity{},
 		&SysAPI{},
 		&Permission{},
 		&PermissionMenu{},
@@ -27,6 +40,11 @@ func MigrateDbTable(db *gorm.DB) {
 		&MenuAPI{},
 	)
 
+	// 删除旧版本明文保存的验证码
+	if err := MigrateVerificationCodes(db); err != nil {
+		log.Println("Failed to migrate verification codes:", err)
+	}
+
 	// 创建默认用户
 	// var user User
 	// // 查询用户是否存在
@@ -19,6 +19,9 @@ type Permission struct {
 	UpdatedAt  string `gorm:"autoUpdateTime" json:"updated_at"`       // UpdatedAt 记录了权限信息最后更新的时间
 }
 
+// AuditEntity 纳入实体变更审计
+func (Permission) AuditEntity() string { return "permission" }
+
 type PermissionMenu struct {
 	Id             uint   `gorm:"primary_key" json:"id"`                      // ID 是权限菜单的主键
 	Uuid           string `gorm:"type:char(36);primary_key" json:"uuid"`      // UUID 是权限菜单的唯一标识符
@@ -28,6 +31,9 @@ type PermissionMenu struct {
 	UpdatedAt      string `gorm:"autoUpdateTime" json:"-"`                    // UpdatedAt 记录了权限菜单信息最后更新的时间
 }
 
+// AuditEntity 纳入实体变更审计
+func (PermissionMenu) AuditEntity() string { return "permission_menu" }
+
 type ReqPermissionMenuCreate struct {
 	PermissionUuid string   `json:"permission_uuid" binding:"required"` // PermissionUuid 是权限的 UUID
 	MenuUuids      []string `json:"menu_uuids" binding:"required"`      // MenuUuids 是菜单的 UUID 列表
@@ -44,6 +50,9 @@ type UserPermission struct {
 	UpdatedAt      string `gorm:"autoUpdateTime" json:"updated_at"`           // UpdatedAt 记录了用户权限关联信息最后更新的时间
 }
 
+// AuditEntity 纳入实体变更审计
+func (UserPermission) AuditEntity() string { return "user_permission" }
+
 type ReqPermissionUserCreate struct {
 	UserUuid        string   `json:"user_uuid" binding:"required"`        // UserUuid 是用户的 UUID
 	PermissionUuids []string `json:"permission_uuids" binding:"required"` // PermissionUuids 是权限的 UUID 列表
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Pagination struct {
 	PageSize  int    `form:"pageSize" json:"pageSize"`
 	Current   int    `form:"current" json:"current"`
@@ -19,6 +21,15 @@ type ReqRoleQueryParam struct {
 	Pagination
 }
 
+type ReqRefreshToken struct {
+	RefreshToken string `json:"refresh_token" binding:"required"` // 刷新令牌
+}
+
+type ReqLogout struct {
+	RefreshToken string `json:"refresh_token"` // 同时吊销该刷新令牌所在的令牌链
+	All          bool   `json:"all"`           // 吊销当前用户的所有令牌
+}
+
 type ReqUserLogin struct {
 	// 用户名或邮箱
 	Username string `json:"username" binding:"required"`
@@ -43,6 +54,18 @@ type ReqMenuQueryParam struct {
 	Pagination
 }
 
+// 创建、更新用户参数；User.Password 不参与 JSON 编解码，明文密码单独接收
+type ReqUserSaveParam struct {
+	User
+	Password string `json:"password"` // 密码，更新时为空表示不修改
+}
+
+// 修改用户状态参数
+type ReqUserStatusParam struct {
+	Uuid   string `json:"uuid" binding:"required"`
+	Status *int   `json:"status" binding:"required,oneof=0 1 2"` // 状态 0:禁用 1:启用 2:删除
+}
+
 // 删除用户删除参数
 type ReqUserDeleteParam struct {
 	Uuid string `json:"uuid" binding:"required"`
@@ -63,9 +86,9 @@ type ReqApiQueryParam struct {
 
 // 查询app的参数
 type ReqAppQueryParam struct {
-	Name   string `json:"name"`    // 名称
-	ApiKey string `json:"api_key"` // api_key
-	Status int    `json:"status"`  // 状态
+	Name      string `json:"name"`       // 名称
+	KeyPrefix string `json:"key_prefix"` // API Key 前缀，如 sgk_1a2b3c4d
+	Status    int    `json:"status"`     // 状态
 	Pagination
 }
 
@@ -82,9 +105,10 @@ type ReqApiPermissionParam struct {
 }
 
 type ReqVerificationCodeParam struct {
-	Email string `json:"email"`
-	Phone string `json:"phone"`
-	Code  string `json:"code"`
+	Email   string `json:"email"`   // 邮箱，与手机号同时填写时发往邮箱
+	Phone   string `json:"phone"`   // 手机号
+	Code    string `json:"code"`    // 验证码，检查时必填
+	Purpose string `json:"purpose"` // 用途：register | reset | login | change_email，默认 register
 }
 
 type ReqRegisterParam struct {
@@ -119,11 +143,51 @@ type ReqTeamMemberQueryParam struct {
 	Pagination
 }
 
+// 操作日志查询参数，StartTime/EndTime 按创建时间过滤
 type ReqOpLogQueryParam struct {
-	UserName string `form:"user_name"`
-	Path     string `form:"path"`
-	Method   string `form:"method"`
-	Status   int    `form:"status"`
+	UserName    string `json:"user_name" form:"user_name"`       // 用户名或昵称，模糊匹配
+	UserUuid    string `json:"user_uuid" form:"user_uuid"`       // 用户UUID
+	Module      string `json:"module" form:"module"`             // 接口模块
+	Path        string `json:"path" form:"path"`                 // 请求路径，模糊匹配
+	Method      string `json:"method" form:"method"`             // 请求方法
+	Status      int    `json:"status" form:"status"`             // HTTP 状态
+	Code        int    `json:"code" form:"code"`                 // 业务状态码
+	MinDuration int64  `json:"min_duration" form:"min_duration"` // 最小耗时（毫秒）
+	MaxDuration int64  `json:"max_duration" form:"max_duration"` // 最大耗时（毫秒）
+	SortField   string `json:"sort_field" form:"sort_field"`     // 排序字段 created_at|duration|status|code，默认按 ID
+	SortOrder   string `json:"sort_order" form:"sort_order"`     // asc|desc，默认 desc
+	Pagination
+}
+
+// 操作日志导出参数
+type ReqOpLogExportParam struct {
+	ReqOpLogQueryParam
+	Format string `json:"format" form:"format"` // csv|jsonl，默认 csv
+}
+
+// 请求日志查询参数，StartTime/EndTime 按创建时间过滤
+type ReqLogQueryParam struct {
+	TraceID  string `json:"trace_id"`  // 请求ID
+	UserUuid string `json:"user_uuid"` // 用户UUID
+	AppId    string `json:"app_id"`    // 调用方ID
+	Path     string `json:"path"`      // 请求路径，模糊匹配
+	Method   string `json:"method"`    // 请求方法
+	Status   int    `json:"status"`    // HTTP 状态
+	Pagination
+}
+
+// 按请求ID查询
+type ReqTraceParam struct {
+	TraceID string `json:"trace_id" binding:"required"` // 请求ID
+}
+
+// 审计日志查询参数，StartTime/EndTime 按创建时间过滤
+type ReqAuditLogQueryParam struct {
+	Entity    string `json:"entity"`     // 实体类型，如 user、permission
+	EntityId  string `json:"entity_id"`  // 实体主键
+	Operation string `json:"operation"`  // create/update/delete
+	UserUuid  string `json:"user_uuid"`  // 操作人UUID
+	RequestId string `json:"request_id"` // 请求ID
 	Pagination
 }
 
@@ -132,6 +196,117 @@ type ReqLoginLogQueryParam struct {
 	Pagination
 }
 
+// ReqTwoFactorCode 提交验证器中的验证码
+type ReqTwoFactorCode struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ReqTwoFactorLogin 登录第二步，Code 与 RecoveryCode 二选一
+type ReqTwoFactorLogin struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"` // 登录第一步返回的临时凭据
+	Code           string `json:"code"`                                // 验证码
+	RecoveryCode   string `json:"recovery_code"`                       // 恢复码
+}
+
+// ReqTwoFactorToken 登录过程中绑定验证器
+type ReqTwoFactorToken struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+}
+
+// ReqTwoFactorDisable 关闭两步验证
+type ReqTwoFactorDisable struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// ReqTwoFactorReset 管理员重置用户的两步验证
+type ReqTwoFactorReset struct {
+	UserUuid string `json:"user_uuid" binding:"required"`
+}
+
+// ReqOAuthCallback 身份提供方回调参数，可以由提供方直接跳转（GET 查询参数），也可以由前端转交（POST JSON）
+type ReqOAuthCallback struct {
+	Code             string `form:"code" json:"code"`
+	State            string `form:"state" json:"state" binding:"required"`
+	Error            string `form:"error" json:"error"`                         // 用户拒绝授权等情况下提供方返回的错误
+	ErrorDescription string `form:"error_description" json:"error_description"` // 错误说明
+}
+
+// ReqOAuthProvider 指定身份提供方
+type ReqOAuthProvider struct {
+	Provider string `json:"provider" binding:"required"`
+}
+
+// ReqAppToken 应用获取访问令牌（OAuth2 client credentials），表单格式；
+// 客户端凭据也可以通过 HTTP Basic 认证传递
+type ReqAppToken struct {
+	GrantType    string `form:"grant_type"`
+	ClientID     string `form:"client_id"`     // 应用UUID
+	ClientSecret string `form:"client_secret"` // 应用 SecKey
+	Scope        string `form:"scope"`         // 空格分隔，为空时授予应用的全部 scope
+}
+
+// ReqAppTokenTarget 令牌内省与吊销，表单格式
+type ReqAppTokenTarget struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// ReqAppKeyList 查询应用的 API Key
+type ReqAppKeyList struct {
+	AppUuid string `json:"app_uuid" binding:"required"`
+}
+
+// ReqAppKeyCreate 创建 API Key
+type ReqAppKeyCreate struct {
+	AppUuid    string     `json:"app_uuid" binding:"required"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`      // 可访问的接口模块，为空不限制
+	AllowedIPs []string   `json:"allowed_ips"` // 允许调用的 IP 或 CIDR，为空不限制
+	ExpiresAt  *time.Time `json:"expires_at"`  // 过期时间，为空不过期
+}
+
+// ReqAppKeyRotate 轮换 API Key：生成新密钥（沿用名称、scope 与 IP 限制），旧密钥在宽限期后失效
+type ReqAppKeyRotate struct {
+	Uuid         string `json:"uuid" binding:"required"` // 被轮换的密钥UUID
+	GraceMinutes *int   `json:"grace_minutes"`           // 旧密钥宽限期（分钟），为空使用配置，0 表示立即失效
+}
+
+// ReqPasswordForgot 申请重置密码
+type ReqPasswordForgot struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ReqPasswordReset 通过重置链接设置新密码
+type ReqPasswordReset struct {
+	Token    string `json:"token" binding:"required"`    // 重置链接中的 token 参数
+	Password string `json:"password" binding:"required"` // 新密码
+}
+
+// ReqSessionRevoke 结束自己的会话
+type ReqSessionRevoke struct {
+	Uuid string `json:"uuid" binding:"required"` // 会话ID
+}
+
+// ReqUserSessions 查询用户的会话
+type ReqUserSessions struct {
+	UserUuid string `json:"user_uuid" binding:"required"`
+}
+
+// ReqSessionForceLogout 管理员结束用户的会话
+type ReqSessionForceLogout struct {
+	UserUuid string `json:"user_uuid" binding:"required"`
+	Uuid     string `json:"uuid"` // 会话ID，为空时结束用户的全部会话
+}
+
+// ReqLoginLockClear 解除登录锁定
+type ReqLoginLockClear struct {
+	Kind    string `json:"kind" binding:"required,oneof=user ip"` // user: 用户名 ip: IP地址
+	Subject string `json:"subject" binding:"required"`            // 用户名或IP
+}
+
 type ReqAPIQueryParam struct {
 	Name   string `json:"name"`
 	Module string `json:"module"`
@@ -165,3 +340,27 @@ type ReqMenuAPIQueryParam struct {
 	APIUUID  string `json:"api_uuid"`
 	Pagination
 }
+
+// 日志级别修改参数
+type ReqLogLevelParam struct {
+	Name  string `json:"name"`                     // Logger 名称，为空表示全局级别
+	Level string `json:"level" binding:"required"` // 日志级别 debug|info|warn|error
+	TTL   int    `json:"ttl"`                      // 有效时长(秒)，大于 0 时到期自动恢复
+}
+
+// 日志级别重置参数
+type ReqLogLevelResetParam struct {
+	Name string `json:"name" binding:"required"` // Logger 名称
+}
+
+// 单请求调试名单参数
+type ReqDebugSubjectParam struct {
+	Kind string `json:"kind" binding:"required"` // app | user
+	Id   string `json:"id" binding:"required"`   // app_id 或 user_id
+	TTL  int    `json:"ttl"`                     // 有效时长(秒)，为 0 表示不过期
+}
+
+// 单请求调试令牌参数
+type ReqDebugTokenParam struct {
+	TTL int `json:"ttl"` // 有效时长(秒)，默认 600
+}
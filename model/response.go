@@ -8,7 +8,15 @@ type PagedResponse struct {
 }
 
 type ResUserLogin struct {
-	Token string `json:"token"`
+	Token            string `json:"token"`                        // 访问令牌
+	ExpiresAt        int64  `json:"expires_at,omitempty"`         // 访问令牌过期时间戳
+	RefreshToken     string `json:"refresh_token,omitempty"`      // 刷新令牌
+	RefreshExpiresAt int64  `json:"refresh_expires_at,omitempty"` // 刷新令牌过期时间戳
+
+	TwoFactorRequired bool     `json:"two_factor_required,omitempty"` // 需要完成两步验证，此时不返回令牌
+	TwoFactorToken    string   `json:"two_factor_token,omitempty"`    // 提交验证码时使用的临时凭据
+	TwoFactorEnroll   bool     `json:"two_factor_enroll,omitempty"`   // 必须先绑定验证器
+	RecoveryCodes     []string `json:"recovery_codes,omitempty"`      // 登录时完成绑定返回的恢复码，只返回一次
 }
 
 type BaseResponse struct {
@@ -122,3 +130,131 @@ type TeamMemberInfoResponse struct {
 	BaseResponse
 	Data TeamMember `json:"data"`
 }
+
+type ResDebugToken struct {
+	Header    string `json:"header"`     // 请求头名称
+	Token     string `json:"token"`      // 调试令牌
+	ExpiresAt int64  `json:"expires_at"` // 过期时间戳
+}
+
+// ResLogTableStats 日志表的行数与最近一次清理记录
+type ResLogTableStats struct {
+	Table      string       `json:"table"`                // 表名
+	Rows       int64        `json:"rows"`                 // 当前行数
+	OldestAt   string       `json:"oldest_at"`            // 最早一条记录的创建时间
+	MaxAgeDays int          `json:"max_age_days"`         // 配置的保留天数
+	MaxRows    int64        `json:"max_rows"`             // 配置的最多保留行数
+	LastPurge  *SysLogPurge `json:"last_purge,omitempty"` // 最近一次清理记录
+}
+
+// ResTwoFactorEnroll 绑定验证器所需的信息
+type ResTwoFactorEnroll struct {
+	Secret string `json:"secret"`  // base32 密钥，用于手动输入
+	URI    string `json:"uri"`     // otpauth URI
+	QRCode string `json:"qr_code"` // 二维码，data:image/png;base64,...
+}
+
+// ResTwoFactorStatus 当前用户的两步验证状态
+type ResTwoFactorStatus struct {
+	Enabled           bool  `json:"enabled"`             // 已启用
+	Required          bool  `json:"required"`            // 按权限要求必须启用
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"` // 剩余可用的恢复码
+}
+
+// ResRecoveryCodes 新生成的恢复码，只返回一次
+type ResRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ResOAuthProvider 可用的身份提供方
+type ResOAuthProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+}
+
+// ResOAuthAuthorize 跳转到身份提供方的授权地址
+type ResOAuthAuthorize struct {
+	Url string `json:"url"`
+}
+
+// ResAppCreate 创建应用的结果，API Key 与 SecKey 只返回这一次
+type ResAppCreate struct {
+	App
+	ApiKey string `json:"api_key"`
+	SecKey string `json:"sec_key"`
+}
+
+// ResAppKeyCreate 新建或轮换得到的 API Key，Key 只返回这一次
+type ResAppKeyCreate struct {
+	AppKey
+	Key string `json:"key"`
+}
+
+// ResAppSecret 重新生成的 SecKey，只返回这一次
+type ResAppSecret struct {
+	SecKey string `json:"sec_key"`
+}
+
+// ResAppToken 应用访问令牌（RFC 6749 5.1）
+type ResAppToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// ResAppTokenIntrospection 令牌内省结果（RFC 7662），无效令牌只返回 active=false
+type ResAppTokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// ResOAuthError OAuth2 错误响应（RFC 6749 5.2）
+type ResOAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// 登录锁定的对象类型
+const (
+	LoginLockUser = "user"
+	LoginLockIP   = "ip"
+)
+
+// ResLoginLock 当前生效的登录锁定
+type ResLoginLock struct {
+	Kind     string `json:"kind"`      // user/ip
+	Subject  string `json:"subject"`   // 用户名或IP
+	Failures int64  `json:"failures"`  // 锁定时的失败次数
+	Ip       string `json:"ip"`        // 触发锁定的请求IP
+	LockedAt string `json:"locked_at"` // 锁定时间
+	Until    string `json:"until"`     // 解除时间
+}
+
+// 请求时间线中的事件类型
+const (
+	TraceEventRequest = "request" // 请求日志 Log
+	TraceEventOp      = "op"      // 操作日志 SysOpLog
+	TraceEventLogin   = "login"   // 登录日志 SysLoginLog
+	TraceEventAudit   = "audit"   // 审计日志 SysAuditLog
+)
+
+// ResTraceEvent 时间线中的一条记录，Data 为对应的日志行
+type ResTraceEvent struct {
+	Type string      `json:"type"` // request/op/login/audit
+	Time string      `json:"time"` // 2006-01-02 15:04:05
+	Data interface{} `json:"data"`
+}
+
+// ResTraceView 同一请求ID下的请求、操作、登录与审计日志，按时间排序
+type ResTraceView struct {
+	TraceID  string           `json:"trace_id"`
+	Timeline []*ResTraceEvent `json:"timeline"`
+}
@@ -13,3 +13,6 @@ type Role struct {
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`     // UpdatedAt 记录了角色最后更新的时间
 	IsActive  bool      `gorm:"default:true" json:"is_active"`        // IsActive 标识角色是否是活跃的
 }
+
+// AuditEntity 纳入实体变更审计
+func (Role) AuditEntity() string { return "role" }
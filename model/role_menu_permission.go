@@ -11,3 +11,6 @@ type RoleMenuPermission struct {
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`     // CreatedAt 记录了角色菜单权限创建的时间
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`     // UpdatedAt 记录了角色菜单权限信息最后更新的时间
 }
+
+// AuditEntity 纳入实体变更审计
+func (RoleMenuPermission) AuditEntity() string { return "role_menu_permission" }
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const (
+	SmsStatusSuccess = 1
+	SmsStatusFail    = 2
+)
+
+// SmsLog 短信发送记录
+type SmsLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RequestId string    `gorm:"type:varchar(50);index" json:"request_id"`          // 请求ID
+	Phone     string    `gorm:"type:varchar(20);index" json:"phone" mask:"mobile"` // E.164 格式
+	Template  string    `gorm:"type:varchar(64)" json:"template"`                  // 模板名
+	Provider  string    `gorm:"type:varchar(32)" json:"provider"`                  // 服务商类型
+	Status    int       `gorm:"type:int" json:"status"`                            // 1:成功 2:失败
+	Error     string    `gorm:"type:varchar(500)" json:"error"`                    // 失败原因
+	Ip        string    `gorm:"type:varchar(64)" json:"ip"`                        // 请求IP
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// SysAuditLog 实体变更审计日志，由 pkg/audit 的 GORM 回调写入
+type SysAuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey;comment:'主键ID'"`                                      // 主键ID
+	Entity    string    `json:"entity" gorm:"type:varchar(50);index:idx_audit_entity;comment:'实体类型'"`     // 实体类型，如 user、permission
+	EntityId  string    `json:"entity_id" gorm:"type:varchar(100);index:idx_audit_entity;comment:'实体主键'"` // 实体主键，优先 uuid
+	Operation string    `json:"operation" gorm:"type:varchar(10);comment:'操作'"`                           // 操作 create/update/delete
+	Changes   string    `json:"changes" gorm:"type:text;comment:'变更字段'"`                                  // 变更前后的字段值（JSON）
+	UserUuid  string    `json:"user_uuid" gorm:"type:char(36);index;comment:'操作人UUID'"`                   // 操作人UUID
+	AppId     string    `json:"app_id" gorm:"type:varchar(50);comment:'调用方ID'"`                           // 调用方ID
+	RequestId string    `json:"request_id" gorm:"type:varchar(50);index;comment:'请求ID'"`                  // 请求ID（TraceID）
+	Ip        string    `json:"ip" gorm:"type:varchar(50);comment:'请求IP'"`                                // 请求IP
+	CreatedAt time.Time `json:"created_at" gorm:"index;comment:'创建时间'"`                                   // 创建时间
+}
+
+type SysAuditLogRes struct {
+	SysAuditLog
+	Username string `json:"username"` // 操作人
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+const (
+	// 日志清理状态
+	LogPurgeStatusSuccess = "success"
+	LogPurgeStatusFailed  = "failed"
+)
+
+// SysLogPurge 日志表清理记录，每次清理每张表一条
+type SysLogPurge struct {
+	ID          uint      `json:"id" gorm:"primaryKey;comment:'主键ID'"`                  // 主键ID
+	LogTable    string    `json:"log_table" gorm:"type:varchar(50);index;comment:'表名'"` // 表名
+	Deleted     int64     `json:"deleted" gorm:"comment:'删除行数'"`                        // 删除行数
+	Archived    int64     `json:"archived" gorm:"comment:'归档行数'"`                       // 归档行数
+	ArchiveFile string    `json:"archive_file" gorm:"type:varchar(255);comment:'归档文件'"` // 归档文件
+	Status      string    `json:"status" gorm:"type:varchar(20);comment:'状态'"`          // 状态 success | failed
+	Message     string    `json:"message" gorm:"type:text;comment:'错误信息'"`              // 错误信息
+	StartedAt   time.Time `json:"started_at" gorm:"comment:'开始时间'"`                     // 开始时间
+	FinishedAt  time.Time `json:"finished_at" gorm:"comment:'结束时间'"`                    // 结束时间
+}
@@ -4,6 +4,7 @@ const (
 	// 登录状态
 	LoginStatusSuccess = 1
 	LoginStatusFail    = 2
+	LoginStatusLocked  = 3 // 失败次数过多被锁定，或锁定期间尝试登录
 )
 
 // 系统登录日志
@@ -16,7 +17,7 @@ type SysLoginLog struct {
 
 	UserAgent string `json:"user_agent" gorm:"comment:'UserAgent'"`
 	// 登录状态
-	Status int `json:"status" gorm:"comment:'登录状态'"` // 登录状态 1:成功 2:失败
+	Status int `json:"status" gorm:"comment:'登录状态'"` // 登录状态 1:成功 2:失败 3:锁定
 	// 消息
 	Message string `json:"message" gorm:"comment:'消息'"` // 消息
 	// 浏览器
@@ -29,6 +30,8 @@ type SysLoginLog struct {
 
 	// 地址
 	Address string `json:"address" gorm:"comment:'地址'"` // 地址
+	// 请求ID
+	TraceID string `json:"trace_id" gorm:"type:varchar(50);index;comment:'请求ID'"` // 请求ID（TraceID）
 	// 创建时间
 	CreatedAt string `json:"created_at" gorm:"autoCreateTime;comment:'创建时间'"` // 创建时间
 }
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// RefreshToken 刷新令牌，只保存令牌的 SHA-256 摘要。
+// 每次刷新都会签发同一 FamilyId 下的新令牌并将旧令牌标记为已使用，已使用的令牌再次出现时整个 family 被吊销
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TokenHash string     `json:"-" gorm:"type:char(64);uniqueIndex"`      // 令牌摘要
+	FamilyId  string     `json:"family_id" gorm:"type:varchar(64);index"` // 同一次登录签发的令牌链
+	UserUuid  string     `json:"user_uuid" gorm:"type:char(36);index"`    // 用户UUID
+	Ip        string     `json:"ip" gorm:"type:varchar(50)"`              // 签发时的IP
+	UserAgent string     `json:"user_agent" gorm:"type:varchar(255)"`     // 签发时的 UserAgent
+	UsedAt    *time.Time `json:"used_at"`                                 // 使用（轮换）时间
+	Revoked   bool       `json:"revoked"`                                 // 是否已吊销
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`                 // 过期时间
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`        // 签发时间
+}
+
+// 令牌吊销类型
+const (
+	TokenRevocationJTI  = "jti"  // 吊销单个访问令牌
+	TokenRevocationUser = "user" // 吊销用户在某时间之前签发的所有令牌
+)
+
+// TokenRevocation 访问令牌吊销记录，过期后可删除
+type TokenRevocation struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Kind          string    `json:"kind" gorm:"type:varchar(10);uniqueIndex:idx_token_revocation"`    // jti | user
+	Subject       string    `json:"subject" gorm:"type:varchar(64);uniqueIndex:idx_token_revocation"` // jti 或用户UUID
+	RevokedBefore time.Time `json:"revoked_before"`                                                   // user 类型：早于该时间签发的令牌失效
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index"`                                          // 记录失效时间
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// UserTwoFactor 用户的 TOTP 两步验证，Secret 加密保存；Enabled 为 false 表示已生成密钥但尚未确认绑定
+type UserTwoFactor struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserUuid    string     `json:"user_uuid" gorm:"type:char(36);uniqueIndex"` // 用户UUID
+	Secret      string     `json:"-" gorm:"type:varchar(255)" audit:"-"`       // 加密后的 TOTP 密钥
+	Enabled     bool       `json:"enabled"`                                    // 是否已启用
+	LastCounter int64      `json:"-" audit:"-"`                                // 最近一次通过验证的时间步，防止验证码重放
+	EnabledAt   *time.Time `json:"enabled_at"`                                 // 启用时间
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// AuditEntity 纳入实体变更审计
+func (UserTwoFactor) AuditEntity() string { return "user_two_factor" }
+
+// UserRecoveryCode 两步验证恢复码，只保存摘要，使用一次后失效
+type UserRecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserUuid  string     `json:"user_uuid" gorm:"type:char(36);index"` // 用户UUID
+	CodeHash  string     `json:"-" gorm:"type:char(64);uniqueIndex"`   // 恢复码摘要
+	UsedAt    *time.Time `json:"used_at"`                              // 使用时间
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -1,19 +1,34 @@
 package model
 
+// 用户状态
+const (
+	UserStatusDisabled = 0 // 禁用
+	UserStatusEnabled  = 1 // 启用
+	UserStatusDeleted  = 2 // 删除
+)
+
 type User struct {
 	ID        int    `gorm:"primary_key" json:"id"`
-	Uuid      string `gorm:"type:char(36);unique" json:"uuid"`         // 用户唯一标识
-	Email     string `gorm:"type:varchar(100);unique" json:"email"`    // 邮箱
-	Username  string `gorm:"type:varchar(100);unique" json:"username"` // 用户名
-	Password  string `gorm:"type:varchar(100)" json:"password"`        // 密码
-	Phone     string `gorm:"type:varchar(20)" json:"phone"`            // 手机号
-	Avatar    string `gorm:"type:varchar(200)" json:"avatar"`          // 头像
-	Nickname  string `gorm:"type:varchar(50)" json:"nickname"`         // 昵称
-	Status    int    `gorm:"type:int" json:"status"`                   // 状态 0:禁用 1:启用 2:删除
-	Age       int    `gorm:"type:int" json:"age"`                      // 年龄
-	Sex       string `gorm:"type:varchar(10)" json:"sex"`              // 性别 0:未知 1:男 2:女
-	Signed    string `gorm:"type:varchar(255)" json:"signed"`          // 个性签名
-	CreatedAt string `gorm:"autoCreateTime" json:"created_at"`         // 创建时间
-	UpdatedAt string `gorm:"autoUpdateTime" json:"updated_at"`         // 更新时间
-	IsDeleted int    `gorm:"type:int" json:"is_deleted"`               // 是否删除 1:删除 0:未删除
+	Uuid      string `gorm:"type:char(36);unique" json:"uuid"`                   // 用户唯一标识
+	Email     string `gorm:"type:varchar(100);unique" json:"email" mask:"email"` // 邮箱
+	Username  string `gorm:"type:varchar(100);unique" json:"username"`           // 用户名
+	Password  string `gorm:"type:varchar(100)" json:"-" audit:"redact"`          // 密码哈希，不出现在任何响应中
+	Phone     string `gorm:"type:varchar(20)" json:"phone" mask:"mobile"`        // 手机号
+	Avatar    string `gorm:"type:varchar(200)" json:"avatar"`                    // 头像
+	Nickname  string `gorm:"type:varchar(50)" json:"nickname"`                   // 昵称
+	Status    int    `gorm:"type:int" json:"status"`                             // 状态 0:禁用 1:启用 2:删除
+	Age       int    `gorm:"type:int" json:"age"`                                // 年龄
+	Sex       string `gorm:"type:varchar(10)" json:"sex"`                        // 性别 0:未知 1:男 2:女
+	Signed    string `gorm:"type:varchar(255)" json:"signed"`                    // 个性签名
+	CreatedAt string `gorm:"autoCreateTime" json:"created_at"`                   // 创建时间
+	UpdatedAt string `gorm:"autoUpdateTime" json:"updated_at"`                   // 更新时间
+	IsDeleted int    `gorm:"type:int" json:"is_deleted"`                         // 是否删除 1:删除 0:未删除
+}
+
+// Active 未删除且状态为启用的用户才允许登录、刷新令牌等
+func (u *User) Active() bool {
+	return u.IsDeleted == 0 && u.Status == UserStatusEnabled
 }
+
+// AuditEntity 纳入实体变更审计
+func (User) AuditEntity() string { return "user" }
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// UserIdentity 用户关联的外部身份（OpenID Connect / OAuth2），同一提供方的 Subject 唯一
+type UserIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserUuid    string     `json:"user_uuid" gorm:"type:char(36);index"`                              // 用户UUID
+	Provider    string     `json:"provider" gorm:"type:varchar(50);uniqueIndex:idx_identity_subject"` // 提供方标识，对应 OAuthProviderConfig.Name
+	Subject     string     `json:"subject" gorm:"type:varchar(255);uniqueIndex:idx_identity_subject"` // 提供方中的用户唯一标识
+	Email       string     `json:"email" gorm:"type:varchar(100)" mask:"email"`                       // 提供方返回的邮箱
+	Name        string     `json:"name" gorm:"type:varchar(100)"`                                     // 提供方返回的名称
+	LastLoginAt *time.Time `json:"last_login_at" audit:"-"`                                           // 最近一次通过该身份登录的时间
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// AuditEntity 纳入实体变更审计
+func (UserIdentity) AuditEntity() string { return "user_identity" }
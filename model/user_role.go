@@ -10,3 +10,6 @@ type UserRole struct {
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`      // CreatedAt 记录了用户获得角色的时间
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`      // UpdatedAt 记录了用户角色信息最后更新的时间
 }
+
+// AuditEntity 纳入实体变更审计
+func (UserRole) AuditEntity() string { return "user_role" }
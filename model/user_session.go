@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// UserSession 用户登录会话。一次登录及其后的刷新属于同一个会话，Uuid 与刷新令牌的 FamilyId 相同；
+// 会话被吊销或过期后记录删除，属于该会话的访问令牌随即失效
+type UserSession struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Uuid       string    `json:"uuid" gorm:"type:varchar(64);uniqueIndex"` // 会话ID
+	UserUuid   string    `json:"user_uuid" gorm:"type:char(36);index"`     // 用户UUID
+	Ip         string    `json:"ip" gorm:"type:varchar(50)"`               // 登录IP
+	UserAgent  string    `json:"user_agent" gorm:"type:varchar(255)"`      // 登录时的 UserAgent
+	Browser    string    `json:"browser" gorm:"type:varchar(100)"`         // 浏览器
+	Os         string    `json:"os" gorm:"type:varchar(100)"`              // 操作系统
+	Device     string    `json:"device" gorm:"type:varchar(100)"`          // 设备
+	LastSeenAt time.Time `json:"last_seen_at"`                             // 最近活动时间
+	LastSeenIp string    `json:"last_seen_ip" gorm:"type:varchar(50)"`     // 最近活动IP
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index"`                  // 过期时间，随刷新令牌延长
+	CreatedAt  time.Time `json:"created_at"`                               // 登录时间
+	Current    bool      `json:"current" gorm:"-"`                         // 是否为发起请求的会话
+}
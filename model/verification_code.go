@@ -1,15 +1,67 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 验证码用途，验证码只能用于申请时的用途
+const (
+	VerificationPurposeRegister    = "register"     // 注册
+	VerificationPurposeReset       = "reset"        // 重置密码
+	VerificationPurposeLogin       = "login"        // 登录
+	VerificationPurposeChangeEmail = "change_email" // 修改邮箱
+)
+
+// VerificationPurposes 支持的验证码用途
+var VerificationPurposes = []string{
+	VerificationPurposeRegister,
+	VerificationPurposeReset,
+	VerificationPurposeLogin,
+	VerificationPurposeChangeEmail,
+}
+
+// 验证码状态
+const (
+	VerificationCodeUnused      = 0
+	VerificationCodeUsed        = 1
+	VerificationCodeInvalidated = 3 // 失败次数过多或已发送新的验证码
+)
 
 // 验证码
 type VerificationCode struct {
-	Id        uint      `gorm:"primary_key" json:"id"`            // ID 是验证码的主键
-	UUID      string    `gorm:"type:char(36);index" json:"uuid"`  // UUID 是验证码的唯一标识符
-	Code      string    `gorm:"type:varchar(6)" json:"code"`      // Code 是验证码的内容
-	Email     string    `gorm:"type:varchar(100)" json:"email"`   // Email 是验证码的接收者
-	Phone     string    `gorm:"type:varchar(11)" json:"phone"`    // Phone 是验证码的接收者
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"` // CreatedAt 记录了验证码创建的时间
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"` // UpdatedAt 记录了验证码信息最后更新的时间
-	Status    int       `gorm:"type:int(1)" json:"status"`        // Status 0:未使用 1:已使用 2:已过期
+	Id        uint      `gorm:"primary_key" json:"id"`                    // ID 是验证码的主键
+	UUID      string    `gorm:"type:char(36);index" json:"uuid"`          // UUID 是验证码的唯一标识符
+	Purpose   string    `gorm:"type:varchar(32);index" json:"purpose"`    // Purpose 是验证码的用途
+	CodeHash  string    `gorm:"type:varchar(64)" json:"-" audit:"redact"` // CodeHash 是验证码的 HMAC-SHA256 摘要
+	Email     string    `gorm:"type:varchar(100);index" json:"email"`     // Email 是验证码的接收者
+	Phone     string    `gorm:"type:varchar(20);index" json:"phone"`      // Phone 是验证码的接收者，E.164 格式
+	Ip        string    `gorm:"type:varchar(64);index" json:"ip"`         // Ip 是申请验证码的客户端 IP
+	Attempts  int       `gorm:"type:int;default:0" json:"attempts"`       // Attempts 是校验失败次数
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`                  // ExpiresAt 是验证码的过期时间
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`   // CreatedAt 记录了验证码创建的时间
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`         // UpdatedAt 记录了验证码信息最后更新的时间
+	Status    int       `gorm:"type:int(1)" json:"status"`                // Status 0:未使用 1:已使用 3:已作废
+}
+
+// VerificationSendCount 验证码发送次数，按接收方或 IP 在固定窗口内计数，窗口结束后重新计数
+type VerificationSendCount struct {
+	Id        uint      `gorm:"primary_key" json:"id"`
+	Subject   string    `gorm:"type:varchar(150);uniqueIndex" json:"subject"` // email:地址、phone:号码 或 ip:IP
+	Hits      int       `gorm:"type:int;default:0" json:"hits"`               // 窗口内的发送次数
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`                      // 窗口结束时间
+}
+
+// MigrateVerificationCodes 清理旧版本的验证码数据：删除明文保存验证码的 code 列，
+// 删除没有过期时间或没有摘要的记录，这些记录不会被清理任务删除也无法通过校验
+func MigrateVerificationCodes(db *gorm.DB) error {
+	m := db.Migrator()
+	if m.HasColumn(&VerificationCode{}, "code") {
+		if err := m.DropColumn(&VerificationCode{}, "code"); err != nil {
+			return err
+		}
+	}
+	return db.Where("expires_at IS NULL OR expires_at < ? OR code_hash IS NULL OR code_hash = ''", time.Unix(0, 0)).
+		Delete(&VerificationCode{}).Error
 }
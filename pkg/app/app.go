@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+
 	"github.com/luxingwen/sgin/pkg/config"
 	"github.com/luxingwen/sgin/pkg/db"
 	"github.com/luxingwen/sgin/pkg/logger"
@@ -8,6 +10,7 @@ import (
 
 	"net/http"
 	"reflect"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -27,6 +30,10 @@ type App struct {
 	Plugins []func(*App)
 	// Extras holds decoded custom configuration structures keyed by caller-provided name
 	Extras map[string]interface{}
+	// shutdownHooks 退出时执行的清理函数，见 OnShutdown
+	shutdownHooks []func(context.Context) error
+	// values 按 App 保存的资源，见 Value
+	values sync.Map
 }
 
 // RegisterPlugin 允许宿主或外部模块以回调方式注册路由/中间件等
@@ -148,7 +155,7 @@ func NewAppFromConfig(cfg *config.Config) *App {
 
 	if a.Config.MySQL.ShowSQL && a.DB != nil {
 		gormLogger := glogger.New(
-			a.Logger,
+			a.Logger.Named("gorm"),
 			glogger.Config{
 				LogLevel:                  glogger.Info,
 				IgnoreRecordNotFoundError: true,
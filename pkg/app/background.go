@@ -19,6 +19,8 @@ type BackgroundContext struct {
 	Config  *config.Config
 	TraceID string
 	Ctx     context.Context
+
+	app *App
 }
 
 // NewBackgroundContextFromApp 从现有 *App 构建 BackgroundContext（复用 App 的 DB/Logger/Redis/Config）。
@@ -34,6 +36,7 @@ func NewBackgroundContextFromApp(a *App) *BackgroundContext {
 		Config:  a.Config,
 		TraceID: trace,
 		Ctx:     context.Background(),
+		app:     a,
 	}
 }
 
@@ -2,15 +2,20 @@ package app
 
 import (
 	"context"
+	"time"
 
+	"github.com/luxingwen/sgin/pkg/audit"
 	"github.com/luxingwen/sgin/pkg/config"
 	"github.com/luxingwen/sgin/pkg/logger"
 	"github.com/luxingwen/sgin/pkg/redisop"
+	"github.com/luxingwen/sgin/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"gorm.io/gorm"
+	glogger "gorm.io/gorm/logger"
 )
 
 type Context struct {
@@ -21,8 +26,15 @@ type Context struct {
 	Config  *config.Config
 	TraceID string
 	Ctx     context.Context
+	// Debug 表示当前请求开启了单请求调试（日志级别为 debug 并记录 SQL 与完整请求/响应体）
+	Debug bool
+
+	app *App
 }
 
+// debugRequestKey 在 gin.Context 中缓存单请求调试状态
+const debugRequestKey = "sgin_debug_request"
+
 type HandlerFunc func(*Context)
 
 // AppContext 定义了应用层可用的最小上下文接口。
@@ -43,53 +55,124 @@ type HandlerFuncIface func(AppContext)
 // WrapIface 把以 AppContext 为参数的处理函数包装为 gin.HandlerFunc
 func (app *App) WrapIface(hf HandlerFuncIface) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		traceID := c.Request.Header.Get("X-Trace-ID")
+		hf(app.newContext(c))
+	}
+}
 
-		if traceID == "" {
-			traceID = uuid.New().String()
-		}
+func (app *App) Wrap(hf HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hf(app.newContext(c))
+	}
+}
+
+// newContext 为当前处理函数构造 Context
+func (app *App) newContext(c *gin.Context) *Context {
+	traceID := c.Request.Header.Get("X-Trace-ID")
+
+	if traceID == "" {
+		traceID = uuid.New().String()
+	}
 
-		c.Writer.Header().Set("X-Trace-ID", traceID)
+	// ensure trace id is visible to clients
+	c.Writer.Header().Set("X-Trace-ID", traceID)
+
+	cc := &Context{
+		Context: c,
+		DB:      app.DB,
+		Redis:   app.Redis,
+		Logger: app.Logger.With(
+			zap.String("traceID", traceID),
+		),
+		Config:  app.Config,
+		TraceID: traceID,
+		Ctx:     c.Request.Context(),
+		app:     app,
+	}
 
-		cc := &Context{
-			Context: c,
-			DB:      app.DB,
-			Redis:   app.Redis,
-			Logger: app.Logger.With(
-				zap.String("traceID", traceID),
-			),
-			Config:  app.Config,
+	// 实体变更审计的操作人信息。未使用请求的 context，避免客户端断开时中断数据库操作
+	if cc.DB != nil {
+		cc.DB = cc.DB.WithContext(audit.WithActor(context.Background(), audit.Actor{
+			UserID:  c.GetString("user_id"),
+			AppID:   c.GetString("app_id"),
 			TraceID: traceID,
-			Ctx:     c.Request.Context(),
+			IP:      c.ClientIP(),
+		}))
+	}
+
+	// 单请求调试：提升日志级别并打开 SQL 日志
+	if app.requestDebug(c) {
+		cc.Debug = true
+		cc.Logger = cc.Logger.ForceLevel(zapcore.DebugLevel).With(zap.Bool("debug_request", true))
+		if cc.DB != nil {
+			cc.DB = cc.DB.Session(&gorm.Session{
+				Logger: glogger.New(cc.Logger.Named("gorm"), glogger.Config{
+					LogLevel:                  glogger.Info,
+					IgnoreRecordNotFoundError: true,
+				}),
+			})
 		}
-		hf(cc)
 	}
+	return cc
 }
 
-func (app *App) Wrap(hf HandlerFunc) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		traceID := c.Request.Header.Get("X-Trace-ID")
+// requestDebug 判断当前请求是否开启单请求调试：
+// 携带有效的签名调试头，或已认证的 app_id/user_id 在调试名单中。
+// 同一请求中判断一次为真后缓存在 gin.Context 中，供后续处理函数复用。
+func (app *App) requestDebug(c *gin.Context) bool {
+	return requestDebug(c, app.Config, app.Logger.Levels())
+}
+
+func requestDebug(c *gin.Context, cfg *config.Config, levels *logger.Levels) bool {
+	if c.GetBool(debugRequestKey) {
+		return true
+	}
 
-		if traceID == "" {
-			traceID = uuid.New().String()
+	debug := false
+	if tok := c.GetHeader(logger.DebugTokenHeader); tok != "" && cfg != nil {
+		debug = logger.VerifyDebugToken(cfg.LogConfig.DebugSecret, tok, time.Now())
+	}
+	if !debug && levels != nil {
+		// 仅信任由认证中间件写入上下文的 app_id/user_id；RequestLogger 等在认证之前执行的处理函数
+		// 从签名有效的访问令牌中取 user_id
+		userID := c.GetString("user_id")
+		if userID == "" {
+			userID = tokenUserID(c)
 		}
+		debug = levels.IsDebugSubject(logger.DebugSubjectApp, c.GetString("app_id")) ||
+			levels.IsDebugSubject(logger.DebugSubjectUser, userID)
+	}
 
-		// ensure trace id is visible to clients
-		c.Writer.Header().Set("X-Trace-ID", traceID)
-
-		cc := &Context{
-			Context: c,
-			DB:      app.DB,
-			Redis:   app.Redis,
-			Logger: app.Logger.With(
-				zap.String("traceID", traceID),
-			),
-			Config:  app.Config,
-			TraceID: traceID,
-			Ctx:     c.Request.Context(),
+	if debug {
+		c.Set(debugRequestKey, true)
+	}
+	return debug
+}
+
+// tokenUserID 返回请求中访问令牌的 user_id，只校验签名与有效期，令牌无效时返回空
+func tokenUserID(c *gin.Context) string {
+	tok := c.GetHeader("X-Token")
+	if tok == "" {
+		const prefix = "Bearer "
+		if auth := c.GetHeader("Authorization"); len(auth) > len(prefix) && auth[:len(prefix)] == prefix {
+			tok = auth[len(prefix):]
 		}
-		hf(cc)
 	}
+	if tok == "" {
+		return ""
+	}
+	claims, err := utils.ParseTokenClaims(tok)
+	if err != nil {
+		return ""
+	}
+	return claims.UserID
+}
+
+// refreshDebug 认证中间件执行后重新判断单请求调试，名单中的 app_id 只有在认证后才能确定
+func (c *Context) refreshDebug() bool {
+	if !c.Debug && requestDebug(c.Context, c.Config, c.Logger.Levels()) {
+		c.Debug = true
+	}
+	return c.Debug
 }
 
 // AppContext 接口的实现 — 让当前的 Context 满足 AppContext
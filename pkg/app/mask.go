@@ -0,0 +1,39 @@
+package app
+
+import (
+	"github.com/luxingwen/sgin/pkg/ddm"
+)
+
+// MaskPolicy 决定当前请求的响应是否按 mask 标签脱敏，返回 false 时输出原始值
+type MaskPolicy func(*Context) bool
+
+// maskPolicyKey 在 gin.Context 中缓存本次请求的脱敏判断结果
+const maskPolicyKey = "sgin_mask_policy"
+
+var maskPolicy MaskPolicy
+
+// SetMaskPolicy 设置响应脱敏策略，未设置时总是脱敏。应在启动时设置，不支持运行中并发修改
+func SetMaskPolicy(p MaskPolicy) {
+	maskPolicy = p
+}
+
+// ShouldMask 返回当前请求的响应是否需要脱敏，同一请求内只调用一次策略
+func (c *Context) ShouldMask() bool {
+	if maskPolicy == nil {
+		return true
+	}
+	if v, ok := c.Get(maskPolicyKey); ok {
+		return v.(bool)
+	}
+	mask := maskPolicy(c)
+	c.Set(maskPolicyKey, mask)
+	return mask
+}
+
+// maskData 按策略对响应数据中带 mask 标签的字段脱敏；类型不含 mask 标签时不查询策略
+func (c *Context) maskData(data interface{}) interface{} {
+	if !ddm.NeedsMask(data) || !c.ShouldMask() {
+		return data
+	}
+	return ddm.Mask(data)
+}
@@ -26,9 +26,9 @@ func RequestLogger() HandlerFunc {
 		// 将 body 内容写回
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
-		// 限制请求体打印长度，复用 ResponseSize 作为最大打印字节
-		reqBody := string(bodyBytes)
-		if c.Config != nil && c.Config.LogConfig.ResponseSize > 0 && len(reqBody) > c.Config.LogConfig.ResponseSize {
+		// 先脱敏再截断；限制请求体打印长度，复用 ResponseSize 作为最大打印字节；单请求调试时记录完整请求体
+		reqBody := string(c.Redactor().Body(c.GetHeader("Content-Type"), bodyBytes))
+		if !c.Debug && c.Config != nil && c.Config.LogConfig.ResponseSize > 0 && len(reqBody) > c.Config.LogConfig.ResponseSize {
 			reqBody = reqBody[:c.Config.LogConfig.ResponseSize]
 		}
 
@@ -40,7 +40,13 @@ func RequestLogger() HandlerFunc {
 		}
 		c.Logger.WithOptions(zap.Fields(fields...)).Info("Request")
 
+		debug := c.Debug
 		c.Next()
+
+		// 认证后才确定在调试名单中的请求，补记完整请求体
+		if !debug && c.refreshDebug() {
+			c.Logger.Infof("Request body: %s", string(c.Redactor().Body(c.GetHeader("Content-Type"), bodyBytes)))
+		}
 	}
 }
 
@@ -66,14 +72,15 @@ func ResponseLogger() HandlerFunc {
 		c.Writer = recorder
 
 		c.Next()
+		c.refreshDebug()
 
 		c.Logger.With(zap.String("method", c.Request.Method), zap.String("path", c.Request.URL.Path), zap.String("ip", c.ClientIP()), zap.Int("status", c.Writer.Status())).Info("Response")
 
-		// 读取响应体
+		// 读取响应体，单请求调试时记录完整响应体
 
-		if c.Config.LogConfig.ResponseSize > 0 {
-			body := recorder.Body.Bytes()
-			if len(body) > c.Config.LogConfig.ResponseSize {
+		if c.Debug || c.Config.LogConfig.ResponseSize > 0 {
+			body := c.Redactor().Body(c.Writer.Header().Get("Content-Type"), recorder.Body.Bytes())
+			if !c.Debug && len(body) > c.Config.LogConfig.ResponseSize {
 				body = body[:c.Config.LogConfig.ResponseSize]
 			}
 			c.Logger.Infof("Response body: %s", string(body))
@@ -116,7 +123,7 @@ func Cors() HandlerFunc {
 		}
 		allowHeaders := c.Config.CORS.AllowHeaders
 		if len(allowHeaders) == 0 {
-			allowHeaders = []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-Trace-ID", "X-Token", "X-App-Id", "X-Nonce", "X-Timestamp", "X-Signature"}
+			allowHeaders = []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-Trace-ID", "X-Token", "X-App-Id", "X-Nonce", "X-Timestamp", "X-Signature", "X-Debug-Token"}
 		}
 		exposeHeaders := c.Config.CORS.ExposeHeaders
 		if len(exposeHeaders) == 0 {
@@ -160,11 +167,13 @@ func NoRouterHandler(use ...HandlerFunc) HandlerFunc {
 
 		remote, err := url.Parse(c.Config.NoRouterFoward) //将此替换为你的目标URL
 		if err != nil {
-			c.Logger.Error(err)
+			c.Logger.Named("proxy").Error(err)
 			c.JSONError(http.StatusInternalServerError, "500 Internal Server Error")
 			return
 		}
 
+		c.Logger.Named("proxy").Debugw("forward no route request", "path", c.Request.URL.Path, "method", c.Request.Method, "target", remote.Host)
+
 		proxy := httputil.NewSingleHostReverseProxy(remote)
 		// 定义我们自己的director
 		proxy.Director = func(req *http.Request) {
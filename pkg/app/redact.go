@@ -0,0 +1,22 @@
+package app
+
+import (
+	"sync"
+
+	"github.com/luxingwen/sgin/pkg/redact"
+)
+
+// redactors 按配置缓存 Redactor，避免每个请求重复编译规则
+var redactors sync.Map // *config.Config -> *redact.Redactor
+
+// Redactor 返回按 LogConfig.Redact 构造的请求/响应体脱敏器，供各日志中间件使用
+func (c *Context) Redactor() *redact.Redactor {
+	if c.Config == nil {
+		return redact.Default()
+	}
+	if r, ok := redactors.Load(c.Config); ok {
+		return r.(*redact.Redactor)
+	}
+	r, _ := redactors.LoadOrStore(c.Config, redact.New(c.Config.LogConfig.Redact))
+	return r.(*redact.Redactor)
+}
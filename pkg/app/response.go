@@ -21,7 +21,7 @@ func (ctx *Context) JSONSuccess(data interface{}) {
 		TraceID: ctx.TraceID,
 		Code:    http.StatusOK,
 		Message: "Success",
-		Data:    data,
+		Data:    ctx.maskData(data),
 	}
 	ctx.Set("code", http.StatusOK)
 	ctx.Set("message", "Success")
@@ -154,7 +154,7 @@ func (ctx *Context) ReturnWithStream_ObjTxt(data interface{}) {
 		TraceID: ctx.TraceID,
 		Code:    http.StatusOK,
 		Message: "Success",
-		Data:    data,
+		Data:    ctx.maskData(data),
 	}
 	dataByte, _ := json.Marshal(response)
 	respData := string(dataByte)
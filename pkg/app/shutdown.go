@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"sync"
+)
+
+var shutdownMu sync.Mutex
+
+// OnShutdown 注册退出时执行的清理函数（如写入缓冲中的日志），按注册的逆序执行
+func (a *App) OnShutdown(fn func(ctx context.Context) error) {
+	if a == nil || fn == nil {
+		return
+	}
+	shutdownMu.Lock()
+	a.shutdownHooks = append(a.shutdownHooks, fn)
+	shutdownMu.Unlock()
+}
+
+// Shutdown 依次执行 OnShutdown 注册的清理函数，返回第一个错误；每个函数只会执行一次
+func (a *App) Shutdown(ctx context.Context) error {
+	if a == nil {
+		return nil
+	}
+	shutdownMu.Lock()
+	hooks := a.shutdownHooks
+	a.shutdownHooks = nil
+	shutdownMu.Unlock()
+
+	var first error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			if a.Logger != nil {
+				a.Logger.Errorw("shutdown hook failed", "error", err.Error())
+			}
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// lazyValue 按 key 保存的值，只初始化一次
+type lazyValue struct {
+	once sync.Once
+	done uint32
+	v    interface{}
+}
+
+// Value 返回 App 上按 key 保存的值，首次调用时由 init 创建。
+// 用于日志管道、短信发送器、后台任务等按 App 初始化一次的资源，同一进程中的多个 App 各自持有一份；
+// key 建议使用包内未导出的类型，避免冲突
+func (a *App) Value(key interface{}, init func() interface{}) interface{} {
+	if a == nil {
+		return init()
+	}
+	e, _ := a.values.LoadOrStore(key, &lazyValue{})
+	lv := e.(*lazyValue)
+	lv.once.Do(func() {
+		lv.v = init()
+		atomic.StoreUint32(&lv.done, 1)
+	})
+	return lv.v
+}
+
+// Lookup 返回 App 上按 key 保存的值，尚未创建时返回 false
+func (a *App) Lookup(key interface{}) (interface{}, bool) {
+	if a == nil {
+		return nil, false
+	}
+	e, ok := a.values.Load(key)
+	if !ok {
+		return nil, false
+	}
+	lv := e.(*lazyValue)
+	if atomic.LoadUint32(&lv.done) == 0 {
+		return nil, false
+	}
+	return lv.v, true
+}
+
+// Once 对每个 App 与 key 只执行一次 fn，如启动后台任务
+func (a *App) Once(key interface{}, fn func()) {
+	a.Value(key, func() interface{} {
+		fn()
+		return nil
+	})
+}
+
+// AppOf 返回上下文所属的 App，不是由 App 创建的上下文返回 nil
+func AppOf(ctx AppContext) *App {
+	switch c := ctx.(type) {
+	case *Context:
+		return c.app
+	case *BackgroundContext:
+		return c.app
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package app
+
+import "testing"
+
+type testKey struct{}
+
+func TestValuePerApp(t *testing.T) {
+	a, b := &App{}, &App{}
+	calls := 0
+	newValue := func() interface{} {
+		calls++
+		return calls
+	}
+
+	if _, ok := a.Lookup(testKey{}); ok {
+		t.Fatal("value exists before init")
+	}
+	if v := a.Value(testKey{}, newValue); v != 1 {
+		t.Fatalf("a = %v", v)
+	}
+	if v := a.Value(testKey{}, newValue); v != 1 {
+		t.Fatalf("a reinitialized: %v", v)
+	}
+	// 每个 App 各自保存一份
+	if v := b.Value(testKey{}, newValue); v != 2 {
+		t.Fatalf("b = %v", v)
+	}
+	if v, ok := AppOf(&BackgroundContext{app: a}).Lookup(testKey{}); !ok || v != 1 {
+		t.Fatalf("lookup from context = %v, %v", v, ok)
+	}
+	if _, ok := AppOf(NewBackgroundContext(nil, nil, nil, nil)).Lookup(testKey{}); ok {
+		t.Fatal("context without app has value")
+	}
+
+	n := 0
+	a.Once(struct{ testKey }{}, func() { n++ })
+	a.Once(struct{ testKey }{}, func() { n++ })
+	b.Once(struct{ testKey }{}, func() { n++ })
+	if n != 2 {
+		t.Fatalf("once ran %d times, want 2", n)
+	}
+}
@@ -0,0 +1,403 @@
+// Package audit 基于 GORM 回调记录实体变更：实体类型、主键、操作以及变更前后的字段值。
+//
+// 模型实现 Auditable 接口后纳入审计；字段可用 `audit:"-"` 排除，`audit:"redact"` 只记录发生了变更而不记录值。
+// 操作人通过 WithActor 写入 context，并以 db.WithContext 传入。审计记录与变更在同一事务中写入，
+// 写入失败时变更随之回滚。
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 操作类型
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// TagName 字段的审计标签：- 不记录，redact 只记录变更不记录值
+const TagName = "audit"
+
+// Redacted 标记为 redact 的字段记录的值
+const Redacted = "******"
+
+// Auditable 实现该接口的模型在创建、更新、删除时记录审计日志，AuditEntity 返回实体类型名称
+type Auditable interface {
+	AuditEntity() string
+}
+
+// Actor 变更的操作人与请求信息
+type Actor struct {
+	UserID  string
+	AppID   string
+	TraceID string
+	IP      string
+}
+
+type actorKey struct{}
+
+// WithActor 返回携带操作人信息的 context
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFrom 返回 context 中的操作人信息
+func ActorFrom(ctx context.Context) Actor {
+	if ctx == nil {
+		return Actor{}
+	}
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
+
+// Change 单个字段变更前后的值，创建时 Old 为空，删除时 New 为空
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Entry 一条审计记录，对应一行数据的一次变更
+type Entry struct {
+	Entity    string
+	EntityID  string
+	Operation string
+	Changes   map[string]Change // 按列名
+	Actor     Actor
+	CreatedAt time.Time
+}
+
+// Store 保存审计记录，tx 与变更处于同一事务
+type Store func(tx *gorm.DB, entries []*Entry) error
+
+// Options 审计配置
+type Options struct {
+	Store   Store
+	MaxRows int // 单次更新/删除最多记录的行数，默认 1000
+}
+
+type auditor struct {
+	store   Store
+	maxRows int
+}
+
+const beforeKey = "audit:before"
+
+// Register 在 db 上注册审计回调
+func Register(db *gorm.DB, opts Options) error {
+	if opts.Store == nil {
+		return fmt.Errorf("audit: store is required")
+	}
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = 1000
+	}
+	a := &auditor{store: opts.Store, maxRows: opts.MaxRows}
+
+	// after 回调需在 gorm 默认事务提交之前执行，审计记录写入失败时变更才能随之回滚
+	const commit = "gorm:commit_or_rollback_transaction"
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Before(commit).Register("audit:after_create", a.afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("audit:before_update", a.before); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Before(commit).Register("audit:after_update", a.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("audit:before_delete", a.before); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Before(commit).Register("audit:after_delete", a.afterDelete)
+}
+
+// snapshot 一行数据的字段值，按列名
+type snapshot struct {
+	id     string      // 实体主键，记录在审计日志中
+	rowKey interface{} // 重新加载时使用的主键值
+	values map[string]interface{}
+}
+
+// before 在更新/删除前按相同条件加载受影响的行
+func (a *auditor) before(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || !auditable(stmt) {
+		return
+	}
+	p := planOf(stmt.Schema)
+	if p.key == nil {
+		return
+	}
+
+	q := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	conds := 0
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if w, ok := c.Expression.(clause.Where); ok && len(w.Exprs) > 0 {
+			q = q.Clauses(clause.Where{Exprs: w.Exprs})
+			conds++
+		}
+	}
+	// 以结构体主键更新/删除时（如 Save、Delete(&m)），主键条件在 gorm 回调中才加入，这里按相同规则补充
+	if stmt.ReflectValue.Kind() == reflect.Struct {
+		for _, f := range stmt.Schema.PrimaryFields {
+			if v, zero := f.ValueOf(stmt.Context, stmt.ReflectValue); !zero {
+				q = q.Where(clause.Eq{Column: clause.Column{Name: f.DBName}, Value: v})
+				conds++
+			}
+		}
+	}
+	// 无条件的全表更新/删除由 gorm 拒绝，不做快照
+	if conds == 0 {
+		return
+	}
+
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := q.Limit(a.maxRows).Find(rows.Interface()).Error; err != nil {
+		db.AddError(fmt.Errorf("audit: load rows before %s: %w", stmt.Table, err))
+		return
+	}
+	snaps := make([]snapshot, 0, rows.Elem().Len())
+	for i := 0; i < rows.Elem().Len(); i++ {
+		snaps = append(snaps, p.snapshot(stmt.Context, rows.Elem().Index(i)))
+	}
+	db.InstanceSet(beforeKey, snaps)
+}
+
+func (a *auditor) afterCreate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || !auditable(stmt) {
+		return
+	}
+	p := planOf(stmt.Schema)
+	entity := auditEntity(stmt)
+
+	var entries []*Entry
+	for _, rv := range structValues(stmt.ReflectValue) {
+		s := p.snapshot(stmt.Context, rv)
+		entries = append(entries, p.entry(entity, OpCreate, s.id, Diff(nil, s.values), stmt.Context))
+	}
+	a.save(db, entries)
+}
+
+func (a *auditor) afterUpdate(db *gorm.DB) {
+	stmt := db.Statement
+	before := beforeSnapshots(db)
+	if db.Error != nil || len(before) == 0 {
+		return
+	}
+	p := planOf(stmt.Schema)
+	entity := auditEntity(stmt)
+
+	keys := make([]interface{}, 0, len(before))
+	for _, s := range before {
+		keys = append(keys, s.rowKey)
+	}
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	err := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table).
+		Where(clause.IN{Column: clause.Column{Name: p.rowKey.DBName}, Values: keys}).
+		Find(rows.Interface()).Error
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: load rows after %s: %w", stmt.Table, err))
+		return
+	}
+	after := make(map[string]snapshot, rows.Elem().Len())
+	for i := 0; i < rows.Elem().Len(); i++ {
+		s := p.snapshot(stmt.Context, rows.Elem().Index(i))
+		after[fmt.Sprint(s.rowKey)] = s
+	}
+
+	var entries []*Entry
+	for _, b := range before {
+		changes := Diff(b.values, after[fmt.Sprint(b.rowKey)].values)
+		if len(changes) == 0 {
+			continue
+		}
+		entries = append(entries, p.entry(entity, OpUpdate, b.id, changes, stmt.Context))
+	}
+	a.save(db, entries)
+}
+
+func (a *auditor) afterDelete(db *gorm.DB) {
+	stmt := db.Statement
+	before := beforeSnapshots(db)
+	if db.Error != nil || len(before) == 0 {
+		return
+	}
+	p := planOf(stmt.Schema)
+	entity := auditEntity(stmt)
+
+	entries := make([]*Entry, 0, len(before))
+	for _, b := range before {
+		entries = append(entries, p.entry(entity, OpDelete, b.id, Diff(b.values, nil), stmt.Context))
+	}
+	a.save(db, entries)
+}
+
+func (a *auditor) save(db *gorm.DB, entries []*Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	if err := a.store(db.Session(&gorm.Session{NewDB: true}), entries); err != nil {
+		db.AddError(fmt.Errorf("audit: save entries: %w", err))
+	}
+}
+
+func beforeSnapshots(db *gorm.DB) []snapshot {
+	v, ok := db.InstanceGet(beforeKey)
+	if !ok {
+		return nil
+	}
+	snaps, _ := v.([]snapshot)
+	return snaps
+}
+
+// auditable 判断语句的模型是否纳入审计
+func auditable(stmt *gorm.Statement) bool {
+	if stmt.Schema == nil {
+		return false
+	}
+	_, ok := reflect.New(stmt.Schema.ModelType).Interface().(Auditable)
+	return ok
+}
+
+func auditEntity(stmt *gorm.Statement) string {
+	return reflect.New(stmt.Schema.ModelType).Interface().(Auditable).AuditEntity()
+}
+
+// Diff 比较两份快照，返回值不同的字段；old 为空表示创建，new 为空表示删除
+func Diff(old, new map[string]interface{}) map[string]Change {
+	changes := map[string]Change{}
+	for k, nv := range new {
+		ov, ok := old[k]
+		if ok && sameValue(ov, nv) {
+			continue
+		}
+		changes[k] = Change{Old: ov, New: nv}
+	}
+	for k, ov := range old {
+		if _, ok := new[k]; !ok {
+			changes[k] = Change{Old: ov}
+		}
+	}
+	return changes
+}
+
+// sameValue 以 JSON 形式比较，避免同一值在不同类型（如 time.Time 的时区）下被误判为变更
+func sameValue(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(ja) == string(jb)
+}
+
+// structValues 返回反射值中的所有结构体（单个结构体或切片中的元素）
+func structValues(rv reflect.Value) []reflect.Value {
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Struct:
+		return []reflect.Value{rv}
+	case reflect.Slice, reflect.Array:
+		out := make([]reflect.Value, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			if v := reflect.Indirect(rv.Index(i)); v.Kind() == reflect.Struct {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// plan 模型的审计字段
+type plan struct {
+	key    *schema.Field // 记录为 EntityID 的字段：优先 uuid，否则主键
+	rowKey *schema.Field // 更新后重新加载使用的字段：优先主键
+	fields []*schema.Field
+	redact map[string]bool
+}
+
+var plans sync.Map // *schema.Schema -> *plan
+
+func planOf(s *schema.Schema) *plan {
+	if p, ok := plans.Load(s); ok {
+		return p.(*plan)
+	}
+	p := &plan{redact: map[string]bool{}}
+	p.key = s.LookUpField("uuid")
+	p.rowKey = s.PrioritizedPrimaryField
+	if p.rowKey == nil && len(s.PrimaryFields) > 0 {
+		p.rowKey = s.PrimaryFields[0]
+	}
+	if p.key == nil {
+		p.key = p.rowKey
+	}
+	if p.rowKey == nil {
+		p.rowKey = p.key
+	}
+	for _, f := range s.Fields {
+		if f.DBName == "" || !f.Readable {
+			continue
+		}
+		switch f.Tag.Get(TagName) {
+		case "-":
+			continue
+		case "redact":
+			p.redact[f.DBName] = true
+		}
+		p.fields = append(p.fields, f)
+	}
+	actual, _ := plans.LoadOrStore(s, p)
+	return actual.(*plan)
+}
+
+func (p *plan) snapshot(ctx context.Context, rv reflect.Value) snapshot {
+	rv = reflect.Indirect(rv)
+	s := snapshot{values: make(map[string]interface{}, len(p.fields))}
+	for _, f := range p.fields {
+		v, _ := f.ValueOf(ctx, rv)
+		s.values[f.DBName] = v
+	}
+	if p.key != nil {
+		v, _ := p.key.ValueOf(ctx, rv)
+		s.id = fmt.Sprint(v)
+	}
+	if p.rowKey != nil {
+		s.rowKey, _ = p.rowKey.ValueOf(ctx, rv)
+	}
+	return s
+}
+
+// entry 构造审计记录，redact 字段的值替换为 Redacted
+func (p *plan) entry(entity, op, id string, changes map[string]Change, ctx context.Context) *Entry {
+	for k, c := range changes {
+		if !p.redact[k] {
+			continue
+		}
+		if c.Old != nil {
+			c.Old = Redacted
+		}
+		if c.New != nil {
+			c.New = Redacted
+		}
+		changes[k] = c
+	}
+	return &Entry{
+		Entity:    entity,
+		EntityID:  id,
+		Operation: op,
+		Changes:   changes,
+		Actor:     ActorFrom(ctx),
+		CreatedAt: time.Now(),
+	}
+}
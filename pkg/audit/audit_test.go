@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type account struct {
+	ID       uint   `gorm:"primaryKey"`
+	Uuid     string `gorm:"type:char(36)"`
+	Name     string
+	Password string `audit:"redact"`
+	Cache    string `audit:"-"`
+}
+
+func (account) AuditEntity() string { return "account" }
+
+func TestPlanAndDiff(t *testing.T) {
+	s, err := schema.Parse(&account{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := planOf(s)
+	if p.key == nil || p.key.DBName != "uuid" || p.rowKey.DBName != "id" {
+		t.Fatalf("unexpected keys: %+v %+v", p.key, p.rowKey)
+	}
+
+	old := p.snapshot(context.Background(), reflect.ValueOf(account{ID: 1, Uuid: "u1", Name: "a", Password: "x", Cache: "c1"}))
+	cur := p.snapshot(context.Background(), reflect.ValueOf(account{ID: 1, Uuid: "u1", Name: "b", Password: "y", Cache: "c2"}))
+	if old.id != "u1" {
+		t.Fatalf("id = %q", old.id)
+	}
+
+	e := p.entry("account", OpUpdate, old.id, Diff(old.values, cur.values), context.Background())
+	want := map[string]Change{
+		"name":     {Old: "a", New: "b"},
+		"password": {Old: Redacted, New: Redacted},
+	}
+	if !reflect.DeepEqual(e.Changes, want) {
+		t.Fatalf("changes = %#v", e.Changes)
+	}
+
+	created := Diff(nil, cur.values)
+	if len(created) != 4 || created["name"].Old != nil {
+		t.Fatalf("create diff = %#v", created)
+	}
+	deleted := Diff(old.values, nil)
+	if len(deleted) != 4 || deleted["name"].New != nil {
+		t.Fatalf("delete diff = %#v", deleted)
+	}
+}
+
+func TestActor(t *testing.T) {
+	ctx := WithActor(context.Background(), Actor{UserID: "u1", TraceID: "t1"})
+	if a := ActorFrom(ctx); a.UserID != "u1" || a.TraceID != "t1" {
+		t.Fatalf("actor = %+v", a)
+	}
+	if a := ActorFrom(context.Background()); a != (Actor{}) {
+		t.Fatalf("empty actor = %+v", a)
+	}
+}
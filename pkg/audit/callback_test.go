@@ -0,0 +1,210 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// auditRow 测试中保存审计记录的表
+type auditRow struct {
+	ID        uint `gorm:"primaryKey"`
+	Entity    string
+	EntityID  string
+	Operation string
+	Changes   string
+	UserID    string
+}
+
+// testStore 在调用方的事务中写入 auditRow，pools 记录每次写入使用的连接
+type testStore struct {
+	pools []gorm.ConnPool
+	err   error
+}
+
+func (s *testStore) save(tx *gorm.DB, entries []*Entry) error {
+	s.pools = append(s.pools, tx.Statement.ConnPool)
+	if s.err != nil {
+		return s.err
+	}
+	for _, e := range entries {
+		changes, _ := json.Marshal(e.Changes)
+		row := &auditRow{Entity: e.Entity, EntityID: e.EntityID, Operation: e.Operation, Changes: string(changes), UserID: e.Actor.UserID}
+		if err := tx.Create(row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestDB(t *testing.T) (*gorm.DB, *testStore) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&account{}, &auditRow{}); err != nil {
+		t.Fatal(err)
+	}
+	store := &testStore{}
+	if err := Register(db, Options{Store: store.save}); err != nil {
+		t.Fatal(err)
+	}
+	return db, store
+}
+
+// auditRows 返回全部审计记录，Changes 解析为按列名的变更
+func auditRows(t *testing.T, db *gorm.DB) ([]auditRow, []map[string]Change) {
+	var rows []auditRow
+	if err := db.Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	changes := make([]map[string]Change, len(rows))
+	for i, r := range rows {
+		if err := json.Unmarshal([]byte(r.Changes), &changes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rows, changes
+}
+
+func TestCallbacks(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := WithActor(context.Background(), Actor{UserID: "admin"})
+	db = db.WithContext(ctx)
+
+	acc := &account{Uuid: "u1", Name: "a", Password: "x", Cache: "c"}
+	if err := db.Create(acc).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 按条件更新
+	if err := db.Model(&account{}).Where("uuid = ?", "u1").Updates(map[string]interface{}{"name": "b", "cache": "c2"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 以结构体主键更新，值未变化时不记录
+	acc.Name, acc.Password = "b", "y"
+	if err := db.Save(acc).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(acc).Update("name", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(acc).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	rows, changes := auditRows(t, db)
+	if len(rows) != 4 {
+		t.Fatalf("got %d audit rows: %+v", len(rows), rows)
+	}
+	for i, op := range []string{OpCreate, OpUpdate, OpUpdate, OpDelete} {
+		if r := rows[i]; r.Operation != op || r.Entity != "account" || r.EntityID != "u1" || r.UserID != "admin" {
+			t.Fatalf("row %d = %+v, want %s", i, r, op)
+		}
+		if _, ok := changes[i]["cache"]; ok {
+			t.Fatalf("row %d records excluded field: %+v", i, changes[i])
+		}
+	}
+
+	if c := changes[0]; c["name"].New != "a" || c["name"].Old != nil || c["password"].New != Redacted {
+		t.Fatalf("create changes = %+v", c)
+	}
+	if c := changes[1]; len(c) != 1 || c["name"].Old != "a" || c["name"].New != "b" {
+		t.Fatalf("update changes = %+v", c)
+	}
+	if c := changes[2]; len(c) != 1 || c["password"].Old != Redacted || c["password"].New != Redacted {
+		t.Fatalf("save changes = %+v", c)
+	}
+	if c := changes[3]; c["name"].Old != "b" || c["name"].New != nil || c["password"].Old != Redacted {
+		t.Fatalf("delete changes = %+v", c)
+	}
+}
+
+func TestCallbacksUseCallerTransaction(t *testing.T) {
+	db, store := newTestDB(t)
+
+	var pool gorm.ConnPool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		pool = tx.Statement.ConnPool
+		if err := tx.Create(&account{Uuid: "u1", Name: "a"}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&account{}).Where("uuid = ?", "u1").Update("name", "b").Error; err != nil {
+			return err
+		}
+		// 事务内可以看到尚未提交的审计记录
+		var n int64
+		tx.Model(&auditRow{}).Count(&n)
+		if n != 2 {
+			t.Errorf("audit rows in transaction = %d, want 2", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.pools) != 2 {
+		t.Fatalf("store called %d times", len(store.pools))
+	}
+	for _, p := range store.pools {
+		if p != pool {
+			t.Fatal("audit entries not written in the caller's transaction")
+		}
+	}
+	if rows, _ := auditRows(t, db); len(rows) != 2 {
+		t.Fatalf("committed audit rows = %d, want 2", len(rows))
+	}
+}
+
+func TestCallbacksRollback(t *testing.T) {
+	db, store := newTestDB(t)
+
+	// 调用方回滚时审计记录一起回滚
+	errRollback := errors.New("rollback")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account{Uuid: "u1", Name: "a"}).Error; err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("transaction err = %v", err)
+	}
+	var n int64
+	db.Model(&account{}).Count(&n)
+	if rows, _ := auditRows(t, db); n != 0 || len(rows) != 0 {
+		t.Fatalf("after rollback: %d accounts, %d audit rows", n, len(rows))
+	}
+
+	// 审计记录写入失败时变更回滚
+	if err := db.Create(&account{Uuid: "u2", Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	store.err = errors.New("store failed")
+	if err := db.Model(&account{}).Where("uuid = ?", "u2").Update("name", "b").Error; !errors.Is(err, store.err) {
+		t.Fatalf("update err = %v, want store error", err)
+	}
+	if err := db.Where("uuid = ?", "u2").Delete(&account{}).Error; !errors.Is(err, store.err) {
+		t.Fatalf("delete err = %v, want store error", err)
+	}
+	if err := db.Create(&account{Uuid: "u3", Name: "a"}).Error; !errors.Is(err, store.err) {
+		t.Fatalf("create err = %v, want store error", err)
+	}
+	db.Model(&account{}).Where("uuid = ?", "u3").Count(&n)
+	if n != 0 {
+		t.Fatal("create not rolled back after failed audit")
+	}
+	var acc account
+	if err := db.Where("uuid = ?", "u2").First(&acc).Error; err != nil || acc.Name != "a" {
+		t.Fatalf("account after failed audit = %+v, %v", acc, err)
+	}
+	if rows, _ := auditRows(t, db); len(rows) != 1 || rows[0].Operation != OpCreate {
+		t.Fatalf("audit rows = %+v", rows)
+	}
+}
@@ -5,31 +5,51 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	ServerPort      string            // 服务端口
-	LogConfig       LogConfig         // 日志配置
-	MySQL           DBConfig          // mysql配置
-	Postgres        DBConfig          // postgres配置
-	DBType          string            // 数据库类型: mysql | postgres
-	TencentCloud    TencenCloudConfig // 腾讯云配置
-	PkgFileDir      string            // 包文件存放目录
-	UserInfoAddress string            // 用户信息地址
-	Upload          UploadConfig      // 上传配置
-	PasswdKey       string            // 密码加密key
-	MailConfig      MailConfig        // 邮件配置
-	RedisConfig     RedisConfig       // redis配置
-	NoRouterFoward  string            // 是否转发没有路由的请求
-	ForwardPrefix   []string          // 转发前缀
-	ForwardAddress  string            // 转发地址
-	ApiPrefix       string            // api前缀
-	AllowedOrigins  []string          // CORS 允许的来源（兼容旧配置）
-	CORS            CORSConfig        // CORS 详细配置
-	AppRateLimit    RateLimitConfig   // 应用级限流配置
+	ServerPort       string                 // 服务端口
+	LogConfig        LogConfig              // 日志配置
+	MySQL            DBConfig               // mysql配置
+	Postgres         DBConfig               // postgres配置
+	DBType           string                 // 数据库类型: mysql | postgres
+	TencentCloud     TencenCloudConfig      // 腾讯云配置
+	PkgFileDir       string                 // 包文件存放目录
+	UserInfoAddress  string                 // 用户信息地址
+	Upload           UploadConfig           // 上传配置
+	PasswdKey        string                 // 密码加密key
+	MailConfig       MailConfig             // 邮件配置
+	RedisConfig      RedisConfig            // redis配置
+	NoRouterFoward   string                 // 是否转发没有路由的请求
+	ForwardPrefix    []string               // 转发前缀
+	ForwardAddress   string                 // 转发地址
+	ApiPrefix        string                 // api前缀
+	AllowedOrigins   []string               // CORS 允许的来源（兼容旧配置）
+	CORS             CORSConfig             // CORS 详细配置
+	AppRateLimit     RateLimitConfig        // 应用级限流配置
+	DataMask         DataMaskConfig         // 响应数据脱敏配置
+	AsyncLog         AsyncLogConfig         // 请求日志/操作日志异步落库配置
+	LogRetention     LogRetentionConfig     // 日志表保留与清理配置
+	Audit            AuditConfig            // 实体变更审计配置
+	Auth             AuthConfig             // 登录令牌配置
+	JWT              JWTConfig              // 访问令牌签名配置
+	Password         PasswordConfig         // 密码哈希与强度策略
+	LoginGuard       LoginGuardConfig       // 登录失败限制配置
+	TwoFactor        TwoFactorConfig        // 两步验证配置
+	OAuth            OAuthConfig            // 外部身份提供方登录配置
+	AppKey           AppKeyConfig           // 应用 API Key 与 SecKey 配置
+	Signature        SignatureConfig        // 应用请求签名配置
+	Nonce            NonceConfig            // 防重放 nonce 配置
+	PasswordReset    PasswordResetConfig    // 找回密码配置
+	SMS              SMSConfig              // 短信发送配置
+	VerificationCode VerificationCodeConfig // 邮箱/短信验证码配置
+	// AdminPermissions 拥有其中任一权限（Permission.Name）的用户可以访问日志级别、日志查询、审计与登录锁定等管理接口，
+	// 未配置时这些接口拒绝所有请求
+	AdminPermissions []string
 }
 
 type UploadConfig struct {
@@ -50,6 +70,53 @@ type LogConfig struct {
 	SamplingInitial    int    // 采样初始条数/秒
 	SamplingThereafter int    // 之后每秒采样条数
 	StacktraceLevel    string // 输出堆栈的级别（error|warn|panic 等）
+	// 按模块（Logger.Named 的名称）设置日志级别，如 gorm: warn、proxy: debug
+	ModuleLevels map[string]string
+	// 单请求调试令牌（X-Debug-Token）的签名密钥，为空时不接受调试令牌
+	DebugSecret string
+	MaxBackups  int             // 保留的旧日志文件个数，默认 5
+	Sinks       []LogSinkConfig // 日志输出列表，为空时按 Filename/ShowConsole 输出
+	// 请求/响应体脱敏规则，与内置规则合并，Pattern 相同时覆盖内置规则
+	Redact []RedactRule
+}
+
+// RedactRule 日志脱敏规则
+type RedactRule struct {
+	// Pattern 不含 "." 时匹配任意层级的字段名，如 password、*token*；
+	// 含 "." 时为从根开始的 JSON 路径，每段可使用通配符，如 *.token、data.list.id_card（数组下标不计入路径）
+	Pattern string
+	// Strategy 掩码方式：full（默认，替换为 ******）| remove | hash | none（关闭同名内置规则）
+	// 或 pkg/ddm 注册的规则名称：mobile | e164 | bank_card | id_card | id_name | email | ip | address 等
+	Strategy string
+}
+
+// LogSinkConfig 单个日志输出的配置，Type 决定使用哪些字段
+type LogSinkConfig struct {
+	Type     string // 输出类型：console | file | syslog | http，或插件通过 logger.RegisterSink 注册的类型
+	Format   string // 编码格式 json | console，为空时使用 LogConfig.Format
+	MinLevel string // 输出的最低级别（含），为空不限制
+	MaxLevel string // 输出的最高级别（含），为空不限制；配合 MinLevel 可按级别拆分文件
+	// file
+	Filename   string // 文件路径
+	MaxSize    int    // 单个文件最大大小（MB）
+	MaxAge     int    // 最大保留天数
+	MaxBackups int    // 保留的旧文件个数
+	Compress   *bool  // 是否压缩旧文件，未配置时继承 LogConfig.Compress
+	// syslog（RFC 5424）
+	Network  string // udp | tcp | unix | unixgram
+	Address  string // 地址，如 127.0.0.1:514 或 /dev/log
+	AppName  string // APP-NAME 字段，默认 sgin
+	Facility int    // facility，默认 1（user-level）
+	// http（批量推送 JSON 数组）
+	URL           string            // 推送地址
+	Headers       map[string]string // 额外请求头，如 Authorization
+	BatchSize     int               // 单批条数，默认 100
+	BufferSize    int               // 内存缓冲条数上限，超出时丢弃，默认 10000
+	FlushInterval int               // 定时推送间隔（毫秒），默认 1000
+	MaxRetries    int               // 失败重试次数，默认 3
+	Timeout       int               // 单次请求超时（毫秒），默认 5000
+	// 插件自定义参数
+	Options map[string]interface{}
 }
 
 type DBConfig struct {
@@ -102,6 +169,219 @@ type RateLimitConfig struct {
 	B int // 桶容量
 }
 
+// AsyncLogConfig 请求日志（model.Log）与操作日志（model.SysOpLog）的异步批量写入配置
+type AsyncLogConfig struct {
+	Disable       bool   // 关闭异步写入，改为每个请求同步写库
+	BufferSize    int    // 缓冲区条数，默认 10000
+	BatchSize     int    // 单次批量写入条数，默认 200
+	FlushInterval int    // 定时写入间隔（毫秒），默认 1000
+	Overflow      string // 缓冲区满时的策略：drop（默认）| block | spill
+	BlockTimeout  int    // block 策略的最长等待时间（毫秒），默认 1000
+	SpillDir      string // spill 策略的文件目录，默认 logs
+}
+
+// LogRetentionConfig 日志表的保留策略，由后台任务定期清理
+type LogRetentionConfig struct {
+	Disable     bool           // 关闭后台清理任务
+	Interval    int            // 检查间隔（分钟），默认 60
+	BatchSize   int            // 单批删除行数，默认 1000，避免长时间锁表
+	BatchPause  int            // 批次之间的停顿（毫秒），默认 100
+	ArchiveDir  string         // 非空时删除前将行归档为 gzip 压缩的 JSONL 文件
+	Log         TableRetention // 请求日志 logs
+	SysOpLog    TableRetention // 操作日志 sys_op_logs
+	SysLoginLog TableRetention // 登录日志 sys_login_logs
+}
+
+// TableRetention 单表保留策略，均为 0 时不清理
+type TableRetention struct {
+	MaxAgeDays int   // 保留天数
+	MaxRows    int64 // 最多保留行数，超出时删除最早的行
+}
+
+// AuthConfig 访问令牌与刷新令牌配置
+type AuthConfig struct {
+	AccessTokenTTL  int    // 访问令牌有效期（分钟），默认 15
+	RefreshTokenTTL int    // 刷新令牌有效期（小时），默认 720（30 天）
+	TokenStore      string // 刷新令牌与吊销信息的存储：redis | db，默认配置了 Redis 时使用 redis，否则 db
+	AppTokenTTL     int    // 应用通过 client credentials 获取的访问令牌有效期（分钟），默认 30
+	MaxSessions     int    // 每个用户同时有效的登录会话数，超出时结束最久未活动的会话，0 表示不限制
+	// SessionAdminPermissions 拥有其中任一权限的用户可以查看与结束其他用户的会话，未配置时不允许
+	SessionAdminPermissions []string
+}
+
+// JWTConfig 访问令牌签名配置
+type JWTConfig struct {
+	Algorithm string         // 签名算法：HS256 | RS256 | ES256 | EdDSA，默认 HS256
+	Secret    string         // HS256 密钥，未配置 Keys 时必须配置，不与 PasswdKey 共用
+	Keys      []JWTKeyConfig // 签名密钥，按 kid 区分；轮换时保留旧密钥用于验证
+	ActiveKid string         // 签名使用的密钥 kid，默认 Keys 中第一个配置了私钥的
+	Issuer    string         // iss 声明，配置后签发时写入并在验证时校验
+	Audience  string         // aud 声明，配置后签发时写入并在验证时校验
+}
+
+// JWTKeyConfig 单个签名密钥，PEM 格式
+type JWTKeyConfig struct {
+	Kid            string // 密钥ID，为空时由公钥计算
+	Algorithm      string // 签名算法，默认 JWT.Algorithm
+	PrivateKeyFile string // 私钥文件，只用于验证的旧密钥可不配置
+	PublicKeyFile  string // 公钥文件，配置了私钥时可省略
+	Secret         string // HS256 密钥
+}
+
+// PasswordConfig 密码哈希参数与强度策略，修改哈希参数后旧哈希在用户下次登录成功时重新生成
+type PasswordConfig struct {
+	Algorithm     string   // argon2id | bcrypt，默认 argon2id
+	Argon2Time    uint32   // argon2id 迭代次数，默认 3
+	Argon2Memory  uint32   // argon2id 内存（KiB），默认 65536
+	Argon2Threads uint8    // argon2id 并行度，默认 2
+	BcryptCost    int      // bcrypt cost，默认 12
+	MinLength     int      // 最小长度，默认 8
+	MaxLength     int      // 最大长度，默认 128
+	MinClasses    int      // 至少包含的字符类别数（小写、大写、数字、符号），默认 2
+	Blocklist     []string // 额外禁止使用的密码
+}
+
+// LoginGuardConfig 登录失败计数、递增延迟与临时锁定，依赖 Redis，未配置 Redis 时不生效
+type LoginGuardConfig struct {
+	Disable         bool // 关闭登录失败限制
+	MaxUserFailures int  // 同一用户名在窗口内失败多少次后锁定，默认 5
+	MaxIPFailures   int  // 同一 IP 在窗口内失败多少次后锁定，默认 20
+	Window          int  // 失败计数窗口（分钟），默认 15
+	LockDuration    int  // 锁定时长（分钟），默认 15
+	DelayBase       int  // 每次失败后同一用户名需等待的基础时长（毫秒），之后每次翻倍，默认 1000
+	MaxDelay        int  // 等待时长上限（毫秒），默认 30000
+}
+
+// TwoFactorConfig TOTP 两步验证配置
+type TwoFactorConfig struct {
+	Issuer              string   // 验证器应用中显示的发行方，默认 sgin
+	EncryptionKey       string   // 加密保存 TOTP 密钥的密钥，必须单独配置，未配置时不能绑定与校验验证器；修改后已绑定的验证器需重新绑定
+	Skew                int      // 允许前后偏移的时间步数，默认 1，小于 0 表示不允许偏移
+	ChallengeTTL        int      // 密码验证后完成两步验证的时限（分钟），默认 5
+	RecoveryCodes       int      // 恢复码数量，默认 10
+	RequiredPermissions []string // 拥有其中任一权限（Permission.Name）的用户必须启用两步验证
+	ResetPermissions    []string // 拥有其中任一权限的用户可以重置其他用户的两步验证，未配置时不允许重置
+}
+
+// PasswordResetConfig 通过邮件找回密码，重置链接只能使用一次；频率限制使用 Redis，未配置时在本机内存计数
+type PasswordResetConfig struct {
+	LinkURL       string // 前端重置密码页面地址，令牌以 token 查询参数追加；未配置时不提供找回密码
+	TokenTTL      int    // 重置链接有效期（分钟），默认 30
+	Window        int    // 频率限制窗口（分钟），默认 60
+	MaxPerAddress int    // 同一邮箱在窗口内最多请求次数，默认 3
+	MaxPerIP      int    // 同一 IP 在窗口内最多请求次数，默认 20
+	Subject       string // 重置邮件标题，默认 重置密码
+	NotifySubject string // 密码已重置的通知邮件标题，默认 密码已重置
+}
+
+// SMSConfig 短信发送配置，Provider 为空时不发送短信
+type SMSConfig struct {
+	Provider      string                 // http | file | log（只写应用日志，不记录参数值），或插件通过 sms.RegisterProvider 注册的类型
+	DefaultRegion string                 // 不带国际区号的号码使用的区号，默认 86
+	SignName      string                 // 短信签名，加在内容前，如 【sgin】
+	Templates     map[string]SMSTemplate // 模板，键为模板名；内置 verification_code
+	// http（通用网关，以 JSON POST 短信）
+	URL     string            // 网关地址
+	Headers map[string]string // 额外请求头，如 Authorization
+	Timeout int               // 超时（秒），默认 10
+	// file（开发用，追加 JSON 行）
+	File string // 文件路径，file 类型必须配置
+}
+
+// SMSTemplate 短信模板
+type SMSTemplate struct {
+	ID      string // 服务商侧的模板ID，随短信传给网关
+	Content string // 内容，text/template 语法，如 您的验证码为 {{.code}}
+}
+
+// VerificationCodeConfig 邮箱/短信验证码配置，验证码只保存摘要
+type VerificationCodeConfig struct {
+	Store             string // redis | db，为空时有 Redis 用 Redis，否则用数据库
+	HashKey           string // 计算验证码摘要的 HMAC 密钥，必须单独配置，未配置时不发送验证码
+	TTL               int    // 有效期（分钟），默认 5
+	ResendInterval    int    // 同一用途与接收方两次发送的最小间隔（秒），默认 60
+	MaxAttempts       int    // 校验失败多少次后验证码作废，默认 5
+	Window            int    // 发送次数限制窗口（分钟），默认 60
+	MaxPerDestination int    // 同一邮箱或手机号在窗口内最多发送次数，默认 10
+	MaxPerIP          int    // 同一 IP 在窗口内最多发送次数，默认 30
+	CleanupInterval   int    // 清理过期验证码的间隔（分钟），默认 60，小于 0 时不清理；Redis 存储自动过期
+	Retention         int    // 过期多少小时后删除，默认 24
+}
+
+// AppKeyConfig 应用 API Key 与 SecKey 配置；API Key 只保存摘要，SecKey 加密保存
+type AppKeyConfig struct {
+	Prefix        string // 生成的 API Key 前缀，便于识别与密钥扫描，默认 sgk
+	EncryptionKey string // 加密保存 SecKey 的密钥，必须单独配置，未配置时不能创建应用与校验签名；修改后需重新生成 SecKey
+	CacheTTL      int    // API Key 校验结果在本机的缓存时长（秒），默认 60，小于 0 表示不缓存
+	RotationGrace int    // 轮换时旧密钥默认的宽限期（分钟），默认 1440
+	MaxActiveKeys int    // 每个应用同时有效的 API Key 数量上限，默认 5
+}
+
+// SignatureConfig 应用请求签名配置，签名规则见 pkg/sign
+type SignatureConfig struct {
+	Window int // v2 签名的 X-Timestamp 与服务器时间允许的偏差（秒），前后对称，默认 300；nonce 在 2 倍窗口内不能重复
+	// Groups 按路由组覆盖签名要求，键为 middleware.GroupSignature 的组名（小写），如应用接口组 app
+	Groups map[string]SignatureGroupConfig
+}
+
+// SignatureGroupConfig 路由组的签名要求，未配置的组必须签名
+type SignatureGroupConfig struct {
+	Optional bool     // 为 true 时没有 X-Signature 的请求放行
+	Headers  []string // 除 x-app-id、x-nonce、x-timestamp 外必须参与签名的请求头，如 content-type
+}
+
+// NonceConfig 防重放 nonce 的存储与 NonceHandler 的时间窗口，请求签名的窗口见 SignatureConfig
+type NonceConfig struct {
+	Store      string // redis | memory，默认配置了 Redis 时使用 redis，否则 memory（只适用于单实例部署）
+	Window     int    // X-Timestamp 早于服务器时间的最大秒数，默认 60
+	FutureSkew int    // X-Timestamp 晚于服务器时间的最大秒数，默认 5
+	MemorySize int    // memory 存储最多保存的 nonce 数，超出时淘汰最早的，默认 100000
+}
+
+// OAuthConfig 外部身份提供方（OpenID Connect / OAuth2）登录配置
+type OAuthConfig struct {
+	Providers []OAuthProviderConfig // 身份提供方
+	StateTTL  int                   // 跳转到提供方后完成登录的时限（分钟），默认 10
+	StateKey  string                // 加密登录状态的密钥，必须单独配置，未配置时不能发起外部身份登录
+}
+
+// OAuthProviderConfig 单个身份提供方；OIDC 提供方只需配置 Issuer，端点通过发现文档获取
+type OAuthProviderConfig struct {
+	Name           string   // 标识，用于 /oauth/:provider 路径与外部身份关联，配置后不要修改
+	DisplayName    string   // 登录页显示的名称
+	Type           string   // oidc | oauth2，默认 oidc
+	Issuer         string   // OIDC Issuer
+	ClientID       string   // 客户端ID
+	ClientSecret   string   // 客户端密钥，可通过环境变量 OAUTH_<NAME>_CLIENT_SECRET 注入
+	AuthURL        string   // 授权端点，OIDC 提供方可省略
+	TokenURL       string   // 令牌端点，OIDC 提供方可省略
+	UserInfoURL    string   // 用户信息端点，OAuth2 提供方必须配置
+	JWKSURL        string   // ID Token 验证公钥，OIDC 提供方可省略
+	RedirectURL    string   // 回调地址，需与提供方登记的一致
+	Scopes         []string // 默认 openid profile email
+	AuthMethod     string   // client_secret_post | client_secret_basic，默认 client_secret_post
+	SubjectClaim   string   // 用户唯一标识字段，默认 sub（OAuth2 提供方没有 sub 时取 id）
+	EmailClaim     string   // 邮箱字段，默认 email
+	NameClaim      string   // 昵称字段，默认 name
+	UsernameClaim  string   // 用户名字段，默认 preferred_username
+	AutoCreate     bool     // 未关联的外部身份首次登录时自动创建用户
+	DefaultRoles   []string // 自动创建的用户分配的角色（Role.Name）
+	LinkByEmail    bool     // 提供方确认邮箱已验证且与现有用户邮箱相同时自动关联
+	AllowedDomains []string // 只允许这些邮箱域名的用户登录，为空不限制
+}
+
+// AuditConfig 实体变更审计配置，纳入审计的模型见 model 中实现 AuditEntity 的类型
+type AuditConfig struct {
+	Disable bool // 关闭审计
+	MaxRows int  // 单次更新/删除最多记录的行数，默认 1000
+}
+
+// DataMaskConfig 响应中带 mask 标签字段的脱敏配置
+type DataMaskConfig struct {
+	Disable           bool     // 关闭响应脱敏
+	UnmaskPermissions []string // 拥有其中任一权限（Permission.Name）的用户查看原始数据
+}
+
 var (
 	config *Config
 	v      *viper.Viper
@@ -191,6 +471,41 @@ func loadConfigFile() {
 	if config.Upload.Dir == "" {
 		config.Upload.Dir = os.Getenv("UPLOAD_DIR")
 	}
+	if config.JWT.Secret == "" {
+		config.JWT.Secret = os.Getenv("JWT_SECRET")
+	}
+	if config.TwoFactor.EncryptionKey == "" {
+		config.TwoFactor.EncryptionKey = os.Getenv("TWO_FACTOR_ENCRYPTION_KEY")
+	}
+	if config.AppKey.EncryptionKey == "" {
+		config.AppKey.EncryptionKey = os.Getenv("APP_KEY_ENCRYPTION_KEY")
+	}
+	if config.OAuth.StateKey == "" {
+		config.OAuth.StateKey = os.Getenv("OAUTH_STATE_KEY")
+	}
+	if config.VerificationCode.HashKey == "" {
+		config.VerificationCode.HashKey = os.Getenv("VERIFICATION_CODE_HASH_KEY")
+	}
+	for i := range config.OAuth.Providers {
+		p := &config.OAuth.Providers[i]
+		if p.ClientSecret == "" && p.Name != "" {
+			p.ClientSecret = os.Getenv("OAUTH_" + strings.ToUpper(strings.ReplaceAll(p.Name, "-", "_")) + "_CLIENT_SECRET")
+		}
+	}
+	if config.LogConfig.DebugSecret == "" {
+		config.LogConfig.DebugSecret = os.Getenv("LOG_DEBUG_SECRET")
+	}
+	if config.LogConfig.MaxBackups == 0 {
+		if n, err := strconv.Atoi(os.Getenv("LOG_MAX_BACKUPS")); err == nil {
+			config.LogConfig.MaxBackups = n
+		}
+	}
+	// LOG_MODULE_LEVELS=gorm=warn,proxy=debug
+	if len(config.LogConfig.ModuleLevels) == 0 {
+		if v := os.Getenv("LOG_MODULE_LEVELS"); v != "" {
+			config.LogConfig.ModuleLevels = splitKeyValues(v)
+		}
+	}
 
 	// 兼容从环境变量注入的逗号分隔形式的 AllowedOrigins（旧字段）
 	if len(config.AllowedOrigins) == 1 && strings.Contains(config.AllowedOrigins[0], ",") {
@@ -260,6 +575,8 @@ func bindEnvs() {
 	viper.BindEnv("LogConfig.SamplingInitial", "LOG_SAMPLING_INITIAL")
 	viper.BindEnv("LogConfig.SamplingThereafter", "LOG_SAMPLING_THEREAFTER")
 	viper.BindEnv("LogConfig.StacktraceLevel", "LOG_STACKTRACE_LEVEL")
+	viper.BindEnv("LogConfig.DebugSecret", "LOG_DEBUG_SECRET")
+	viper.BindEnv("LogConfig.MaxBackups", "LOG_MAX_BACKUPS")
 	viper.BindEnv("MySQL.Host", "MYSQL_HOST")
 	viper.BindEnv("MySQL.Port", "MYSQL_PORT")
 	viper.BindEnv("Postgres.Host", "POSTGRES_HOST")
@@ -273,6 +590,12 @@ func bindEnvs() {
 	viper.BindEnv("Redis.Database", "REDIS_DB")
 	viper.BindEnv("AllowedOrigins", "ALLOWED_ORIGINS")
 	viper.BindEnv("PasswdKey", "PASSWD_KEY")
+	viper.BindEnv("JWT.Secret", "JWT_SECRET")
+	viper.BindEnv("JWT.Algorithm", "JWT_ALGORITHM")
+	viper.BindEnv("TwoFactor.EncryptionKey", "TWO_FACTOR_ENCRYPTION_KEY")
+	viper.BindEnv("AppKey.EncryptionKey", "APP_KEY_ENCRYPTION_KEY")
+	viper.BindEnv("VerificationCode.HashKey", "VERIFICATION_CODE_HASH_KEY")
+	viper.BindEnv("OAuth.StateKey", "OAUTH_STATE_KEY")
 	viper.BindEnv("Upload.Dir", "UPLOAD_DIR")
 	// CORS 详细配置
 	viper.BindEnv("CORS.AllowedOrigins", "CORS_ALLOWED_ORIGINS")
@@ -301,6 +624,22 @@ func splitAndTrim(s string) []string {
 	return out
 }
 
+// splitKeyValues 解析 "k1=v1,k2=v2" 形式的字符串
+func splitKeyValues(s string) map[string]string {
+	out := make(map[string]string)
+	for _, item := range splitAndTrim(s) {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k != "" && v != "" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
 // 基础配置校验（最小化约束，避免误伤现有用法）
 func (c *Config) Validate() error {
 	if c.ServerPort == "" {
@@ -316,7 +655,18 @@ func (c *Config) Validate() error {
 	if c.AppRateLimit.B <= 0 {
 		c.AppRateLimit.B = 200
 	}
-	// 允许不配置 DB/Redis；JWT 密钥若未配置则在 utils 里回退旧值
+	// 访问令牌密钥必须单独配置，未配置时拒绝启动
+	if c.JWT.Secret == "" && len(c.JWT.Keys) == 0 {
+		return errors.New("JWT.Secret or JWT.Keys is required, please set JWT_SECRET")
+	}
+	seen := map[string]bool{}
+	for _, p := range c.OAuth.Providers {
+		if p.Name == "" || seen[p.Name] {
+			return fmt.Errorf("OAuth.Providers: name is empty or duplicated: %q", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	// 允许不配置 DB/Redis
 	return nil
 }
 
@@ -1,62 +1,53 @@
 package ddm
 
 import (
-	"fmt"
-	"strings"
+	"encoding/json"
 )
 
 func (m Mobile) MarshalJSON() ([]byte, error) {
-	if len(m) != 11 {
-		return []byte(`"` + m + `"`), nil
-	}
-
-	v := fmt.Sprintf("%s****%s", m[:3], m[len(m)-4:])
-	return []byte(`"` + v + `"`), nil
+	return json.Marshal(MaskMobile(string(m)))
 }
 
 func (bc BankCard) MarshalJSON() ([]byte, error) {
-	if len(bc) > 19 || len(bc) < 16 {
-		return []byte(`"` + bc + `"`), nil
-	}
-
-	v := fmt.Sprintf("%s******%s", bc[:6], bc[len(bc)-4:])
-	return []byte(`"` + v + `"`), nil
+	return json.Marshal(MaskBankCard(string(bc)))
 }
 
 func (card IDCard) MarshalJSON() ([]byte, error) {
-	if len(card) != 18 {
-		return []byte(`"` + card + `"`), nil
-	}
-
-	v := fmt.Sprintf("%s******%s", card[:1], card[len(card)-1:])
-	return []byte(`"` + v + `"`), nil
+	return json.Marshal(MaskIDCard(string(card)))
 }
 
 func (name IDName) MarshalJSON() ([]byte, error) {
-	if len(name) < 1 {
-		return []byte(`""`), nil
-	}
-
-	nameRune := []rune(name)
-	v := fmt.Sprintf("*%s", string(nameRune[1:]))
-	return []byte(`"` + v + `"`), nil
+	return json.Marshal(MaskIDName(string(name)))
 }
 
 func (pw PassWord) MarshalJSON() ([]byte, error) {
-	v := "******"
-	return []byte(`"` + v + `"`), nil
+	return json.Marshal(MaskPassword(string(pw)))
 }
 
 func (e Email) MarshalJSON() ([]byte, error) {
-	if !strings.Contains(string(e), "@") {
-		return []byte(`"` + e + `"`), nil
-	}
+	return json.Marshal(MaskEmail(string(e)))
+}
+
+func (m E164) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MaskE164(string(m)))
+}
 
-	split := strings.Split(string(e), "@")
-	if len(split[0]) < 1 || len(split[1]) < 1 {
-		return []byte(`"` + e + `"`), nil
-	}
+func (ip IP) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MaskIP(string(ip)))
+}
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MaskAddress(string(a)))
+}
+
+func (p Passport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MaskPassport(string(p)))
+}
+
+func (p Plate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MaskPlate(string(p)))
+}
 
-	v := fmt.Sprintf("%s***%s", split[0][:1], split[0][len(split[0])-1:])
-	return []byte(`"` + v + "@" + split[1] + `"`), nil
+func (iban IBAN) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MaskIBAN(string(iban)))
 }
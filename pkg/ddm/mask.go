@@ -0,0 +1,167 @@
+package ddm
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// 内置掩码规则名称，可用于 mask 标签、MaskString 以及日志脱敏配置
+const (
+	FormatMobile   = "mobile"
+	FormatBankCard = "bank_card"
+	FormatIDCard   = "id_card"
+	FormatIDName   = "id_name"
+	FormatPassword = "password"
+	FormatEmail    = "email"
+	FormatE164     = "e164"
+	FormatIP       = "ip"
+	FormatAddress  = "address"
+	FormatPassport = "passport"
+	FormatPlate    = "plate"
+	FormatIBAN     = "iban"
+)
+
+var (
+	e164Rule     = Rule{KeepPrefix: 3, KeepSuffix: 4, Pattern: regexp.MustCompile(`^\+[1-9]\d{6,14}$`)}
+	phoneRule    = Rule{KeepPrefix: 3, KeepSuffix: 4}
+	bankCardRule = Rule{KeepPrefix: 6, KeepSuffix: 4, FixedLen: 6, Pattern: regexp.MustCompile(`^\d{12,19}$`)}
+	idCardRule   = Rule{KeepPrefix: 1, KeepSuffix: 1, FixedLen: 6}
+	addressRule  = Rule{KeepPrefix: 6, FixedLen: 4}
+	passportRule = Rule{KeepPrefix: 1, KeepSuffix: 2, Pattern: regexp.MustCompile(`^[A-Za-z0-9]{5,20}$`)}
+	plateRule    = Rule{KeepPrefix: 2, KeepSuffix: 2}
+	ibanRule     = Rule{KeepPrefix: 4, KeepSuffix: 4, Pattern: regexp.MustCompile(`^[A-Z]{2}\d{2}[A-Z0-9]{10,30}$`)}
+)
+
+func init() {
+	Register(FormatMobile, MaskMobile)
+	Register(FormatBankCard, MaskBankCard)
+	Register(FormatIDCard, MaskIDCard)
+	Register(FormatIDName, MaskIDName)
+	Register(FormatPassword, MaskPassword)
+	Register(FormatEmail, MaskEmail)
+	Register(FormatE164, MaskE164)
+	Register(FormatIP, MaskIP)
+	Register(FormatAddress, MaskAddress)
+	Register(FormatPassport, MaskPassport)
+	Register(FormatPlate, MaskPlate)
+	Register(FormatIBAN, MaskIBAN)
+}
+
+// MaskMobile 手机号：11 位国内号码 132****7986，+ 开头按 E.164 处理，其他保留前 3 后 4
+func MaskMobile(m string) string {
+	if len(m) == 11 && isDigits(m) {
+		return m[:3] + "****" + m[7:]
+	}
+	if strings.HasPrefix(m, "+") {
+		return MaskE164(m)
+	}
+	return phoneRule.Mask(m)
+}
+
+// MaskE164 国际电话号码 +86*******7986，忽略空格与连字符，不符合 E.164 时整体掩码
+func MaskE164(m string) string {
+	if m == "" {
+		return m
+	}
+	return e164Rule.Mask(stripSeparators(m))
+}
+
+// MaskBankCard 银行卡号 622888******5676，12~19 位数字，忽略空格
+func MaskBankCard(bc string) string {
+	if bc == "" {
+		return bc
+	}
+	return bankCardRule.Mask(stripSeparators(bc))
+}
+
+// MaskIDCard 身份证号 1******7，支持 15/18 位，其他长度保留首尾各 1 位
+func MaskIDCard(card string) string {
+	return idCardRule.Mask(card)
+}
+
+// MaskIDName 姓名 *鸿章，隐藏第一个字符
+func MaskIDName(name string) string {
+	rs := []rune(name)
+	if len(rs) == 0 {
+		return ""
+	}
+	return "*" + string(rs[1:])
+}
+
+// MaskPassword 密码 ******
+func MaskPassword(string) string {
+	return DefaultMask
+}
+
+// MaskEmail 邮箱 l***w@gmail.com，仅对 @ 之前的部分掩码
+func MaskEmail(e string) string {
+	i := strings.LastIndex(e, "@")
+	if i < 0 {
+		return e
+	}
+	local, domain := []rune(e[:i]), e[i+1:]
+	if len(local) < 1 || len(domain) < 1 {
+		return e
+	}
+	return string(local[:1]) + "***" + string(local[len(local)-1:]) + "@" + domain
+}
+
+// MaskIP IP 地址：IPv4 保留前两段 192.168.*.*，IPv6 保留前 4 组（/64 前缀）；无法解析时整体掩码
+func MaskIP(s string) string {
+	if s == "" {
+		return s
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return DefaultMask
+	}
+	if v4 := ip.To4(); v4 != nil && !strings.Contains(s, ":") {
+		return fmt.Sprintf("%d.%d.*.*", v4[0], v4[1])
+	}
+	ip16 := ip.To16()
+	return fmt.Sprintf("%x:%x:%x:%x:*:*:*:*",
+		uint16(ip16[0])<<8|uint16(ip16[1]),
+		uint16(ip16[2])<<8|uint16(ip16[3]),
+		uint16(ip16[4])<<8|uint16(ip16[5]),
+		uint16(ip16[6])<<8|uint16(ip16[7]),
+	)
+}
+
+// MaskAddress 地址 北京市海淀区****，保留前 6 个字符
+func MaskAddress(addr string) string {
+	return addressRule.Mask(addr)
+}
+
+// MaskPassport 护照号 E******78，保留首 1 尾 2
+func MaskPassport(p string) string {
+	return passportRule.Mask(p)
+}
+
+// MaskPlate 车牌号 京A***45，保留前 2 后 2
+func MaskPlate(p string) string {
+	return plateRule.Mask(p)
+}
+
+// MaskIBAN 国际银行账号 DE89**************3000，保留国家代码与校验位及末 4 位，忽略空格
+func MaskIBAN(iban string) string {
+	if iban == "" {
+		return iban
+	}
+	return ibanRule.Mask(strings.ToUpper(stripSeparators(iban)))
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// stripSeparators 去掉号码中常见的空格与连字符
+func stripSeparators(s string) string {
+	return strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(s)
+}
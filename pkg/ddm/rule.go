@@ -0,0 +1,108 @@
+package ddm
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMask 无法按规则保留部分字符时输出的整体掩码
+const DefaultMask = "******"
+
+// Rule 通用的保留首尾、中间掩码规则，按 Unicode 字符计数
+type Rule struct {
+	KeepPrefix int            // 保留开头的字符数
+	KeepSuffix int            // 保留结尾的字符数
+	MaskChar   rune           // 掩码字符，默认 '*'
+	FixedLen   int            // 掩码部分固定输出的字符数，0 表示与被掩码部分等长（避免泄露长度时使用）
+	Pattern    *regexp.Regexp // 非空时值必须匹配，否则整体替换为 DefaultMask
+}
+
+// Mask 按规则掩码。字符数不足以保留首尾时依次减少保留的字符，保证至少有一个字符被掩码
+func (r Rule) Mask(s string) string {
+	if s == "" {
+		return s
+	}
+	if r.Pattern != nil && !r.Pattern.MatchString(s) {
+		return DefaultMask
+	}
+	maskChar := r.MaskChar
+	if maskChar == 0 {
+		maskChar = '*'
+	}
+
+	rs := []rune(s)
+	prefix, suffix := max0(r.KeepPrefix), max0(r.KeepSuffix)
+	for prefix+suffix >= len(rs) && prefix+suffix > 0 {
+		if prefix >= suffix {
+			prefix--
+		} else {
+			suffix--
+		}
+	}
+	n := len(rs) - prefix - suffix
+	if r.FixedLen > 0 {
+		n = r.FixedLen
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + n)
+	b.WriteString(string(rs[:prefix]))
+	b.WriteString(strings.Repeat(string(maskChar), n))
+	b.WriteString(string(rs[len(rs)-suffix:]))
+	return b.String()
+}
+
+func max0(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func(string) string{}
+)
+
+// Register 注册命名的掩码函数，同名覆盖。注册后可用于 mask 标签、MaskString 以及日志脱敏的 Strategy
+func Register(name string, fn func(string) string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(name)] = fn
+}
+
+// RegisterRule 以 Rule 注册命名的掩码规则
+func RegisterRule(name string, r Rule) {
+	Register(name, r.Mask)
+}
+
+// Lookup 返回命名的掩码函数
+func Lookup(name string) (func(string) string, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	fn, ok := registry[strings.ToLower(name)]
+	return fn, ok
+}
+
+// Names 返回已注册的掩码规则名称
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MaskString 按规则名称对字符串掩码，规则不存在时返回 false
+func MaskString(name, s string) (string, bool) {
+	fn, ok := Lookup(name)
+	if !ok {
+		return s, false
+	}
+	return fn(s), true
+}
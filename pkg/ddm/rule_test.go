@@ -0,0 +1,87 @@
+package ddm
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinRules(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{FormatMobile, "13288887986", "132****7986"},
+		{FormatMobile, "+86 132 8888 7986", "+86*******7986"},
+		{FormatMobile, "0755-1234", "075**1234"},
+		{FormatE164, "+14155552671", "+14*****2671"},
+		{FormatE164, "not a phone", DefaultMask},
+		{FormatIDCard, "125252525252525252", "1******2"},
+		{FormatIDCard, "125252525252525", "1******5"},
+		{FormatBankCard, "6222 0200 1234 5678", "622202******5678"},
+		{FormatBankCard, "12345", DefaultMask},
+		{FormatIDName, "李鸿章", "*鸿章"},
+		{FormatEmail, "张三@例子.中国", "张***三@例子.中国"},
+		{FormatIP, "192.168.1.23", "192.168.*.*"},
+		{FormatIP, "2001:db8:85a3::8a2e:370:7334", "2001:db8:85a3:0:*:*:*:*"},
+		{FormatIP, "host", DefaultMask},
+		{FormatAddress, "北京市海淀区中关村大街1号", "北京市海淀区****"},
+		{FormatPassport, "E12345678", "E******78"},
+		{FormatPlate, "京A12345", "京A***45"},
+		{FormatIBAN, "de89 3704 0044 0532 0130 00", "DE89**************3000"},
+		{FormatPassword, "secret", DefaultMask},
+	}
+	for _, c := range cases {
+		got, ok := MaskString(c.name, c.in)
+		if !ok || got != c.want {
+			t.Errorf("%s(%q) = %q, want %q", c.name, c.in, got, c.want)
+		}
+	}
+}
+
+func TestRule(t *testing.T) {
+	r := Rule{KeepPrefix: 2, KeepSuffix: 2, MaskChar: '#'}
+	if got := r.Mask("名字很长的用户"); got != "名字###用户" {
+		t.Fatalf("got %q", got)
+	}
+	// 字符数不足时减少保留字符，保证至少掩码一个字符
+	if got := r.Mask("abc"); got != "a#c" {
+		t.Fatalf("got %q", got)
+	}
+	if got := r.Mask("a"); got != "#" {
+		t.Fatalf("got %q", got)
+	}
+	r.Pattern = regexp.MustCompile(`^\d+$`)
+	if got := r.Mask("abcdef"); got != DefaultMask {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	RegisterRule("order_no", Rule{KeepSuffix: 4, FixedLen: 3})
+	Register("upper", strings.ToUpper)
+	// 注册表是包级变量，测试结束后移除，避免影响其他测试
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, "order_no")
+		delete(registry, "upper")
+	})
+
+	if got, _ := MaskString("order_no", "SO20240101000123"); got != "***0123" {
+		t.Fatalf("got %q", got)
+	}
+	if _, ok := MaskString("missing", "x"); ok {
+		t.Fatal("expected missing rule")
+	}
+
+	type order struct {
+		No   string `json:"no" mask:"order_no"`
+		Code string `json:"code" mask:"upper"`
+		IP   IP     `json:"ip"`
+	}
+	b, _ := json.Marshal(Mask(order{No: "SO20240101000123", Code: "abc", IP: "10.0.0.1"}))
+	if string(b) != `{"no":"***0123","code":"ABC","ip":"10.0.*.*"}` {
+		t.Fatalf("got %s", b)
+	}
+}
@@ -0,0 +1,228 @@
+package ddm
+
+import (
+	"reflect"
+	"sync"
+)
+
+// TagName 结构体字段的掩码标签，如 `mask:"mobile"`，值为掩码格式名称
+const TagName = "mask"
+
+// fieldPlan 描述结构体中需要处理的字段：format 非空时对字符串掩码，否则递归处理
+type fieldPlan struct {
+	index  int
+	format string
+}
+
+type typePlan struct {
+	fields []fieldPlan
+}
+
+// 按类型缓存反射分析结果
+var (
+	needs  sync.Map // reflect.Type -> bool，interface 视为需要处理
+	tagged sync.Map // reflect.Type -> bool，不经过 interface 即可到达 mask 标签
+	plans  sync.Map // reflect.Type -> *typePlan
+)
+
+// Mask 返回 v 的副本，其中带 mask 标签的字符串字段已按格式掩码，v 本身不会被修改。
+// 支持嵌套的结构体、指针、切片、数组、map 与 interface{}；不含 mask 标签的类型直接返回 v。
+// 未导出字段、不存在的格式会被忽略
+func Mask(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if !NeedsMask(v) {
+		return v
+	}
+	return maskValue(reflect.ValueOf(v)).Interface()
+}
+
+// NeedsMask 判断 v 是否含有 mask 标签的字段；调用方可先判断再决定是否查询脱敏策略。
+// 类型本身的判断按类型缓存，interface 字段（如 ListResult.List、gin.H 的值）按其中实际保存的值判断
+func NeedsMask(v interface{}) bool {
+	return v != nil && valueNeedsMask(reflect.ValueOf(v))
+}
+
+// valueNeedsMask 类型不经过 interface 即含有 mask 标签时直接返回 true，否则只检查 interface 中保存的值
+func valueNeedsMask(v reflect.Value) bool {
+	t := v.Type()
+	if !needsMask(t) {
+		return false
+	}
+	if hasMaskTag(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil() && valueNeedsMask(v.Elem())
+	case reflect.Struct:
+		for _, fp := range planOf(t).fields {
+			if valueNeedsMask(v.Field(fp.index)) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if valueNeedsMask(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if valueNeedsMask(iter.Value()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func maskValue(v reflect.Value) reflect.Value {
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || !needsMask(t.Elem()) {
+			return v
+		}
+		out := reflect.New(t.Elem())
+		out.Elem().Set(maskValue(v.Elem()))
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(t).Elem()
+		out.Set(maskValue(v.Elem()))
+		return out
+	case reflect.Struct:
+		if !needsMask(t) {
+			return v
+		}
+		out := reflect.New(t).Elem()
+		out.Set(v)
+		for _, fp := range planOf(t).fields {
+			f := out.Field(fp.index)
+			if fp.format != "" {
+				if s, ok := MaskString(fp.format, f.String()); ok {
+					f.SetString(s)
+				}
+				continue
+			}
+			f.Set(maskValue(f))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() || !needsMask(t.Elem()) {
+			return v
+		}
+		out := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(maskValue(v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		if !needsMask(t.Elem()) {
+			return v
+		}
+		out := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(maskValue(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() || !needsMask(t.Elem()) {
+			return v
+		}
+		out := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), maskValue(iter.Value()))
+		}
+		return out
+	}
+	return v
+}
+
+// needsMask 判断类型（含嵌套类型）是否存在需要掩码的字段
+func needsMask(t reflect.Type) bool {
+	if n, ok := needs.Load(t); ok {
+		return n.(bool)
+	}
+	n := reachesMask(t, true, make(map[reflect.Type]bool))
+	needs.Store(t, n)
+	return n
+}
+
+// hasMaskTag 判断类型不经过 interface 是否存在需要掩码的字段
+func hasMaskTag(t reflect.Type) bool {
+	if n, ok := tagged.Load(t); ok {
+		return n.(bool)
+	}
+	n := reachesMask(t, false, make(map[reflect.Type]bool))
+	tagged.Store(t, n)
+	return n
+}
+
+// planOf 返回结构体类型中需要处理的字段
+func planOf(t reflect.Type) *typePlan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*typePlan)
+	}
+	p := &typePlan{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if format := maskTag(f); format != "" {
+			p.fields = append(p.fields, fieldPlan{index: i, format: format})
+			continue
+		}
+		if needsMask(f.Type) {
+			p.fields = append(p.fields, fieldPlan{index: i})
+		}
+	}
+	actual, _ := plans.LoadOrStore(t, p)
+	return actual.(*typePlan)
+}
+
+// maskTag 返回字符串字段的掩码格式，不需要掩码时返回空
+func maskTag(f reflect.StructField) string {
+	format := f.Tag.Get(TagName)
+	if format == "-" || f.Type.Kind() != reflect.String {
+		return ""
+	}
+	return format
+}
+
+// reachesMask 判断从 t 可达的类型中是否存在带 mask 标签的字段；interface 的动态类型未知，按 iface 返回
+func reachesMask(t reflect.Type, iface bool, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return iface
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return reachesMask(t.Elem(), iface, visited)
+	case reflect.Map:
+		return reachesMask(t.Elem(), iface, visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if maskTag(f) != "" {
+				return true
+			}
+			if reachesMask(f.Type, iface, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package ddm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type maskedUser struct {
+	Name     string `json:"name"`
+	Phone    string `json:"phone" mask:"mobile"`
+	Email    string `json:"email" mask:"email"`
+	Password string `json:"password" mask:"password"`
+	Friends  []*maskedUser
+	secret   string
+}
+
+type page struct {
+	List  interface{}
+	Extra map[string]maskedUser
+}
+
+func TestMask(t *testing.T) {
+	u := &maskedUser{
+		Name:     "li",
+		Phone:    "13288887986",
+		Email:    "xinliangnote@163.com",
+		Password: "hash",
+		Friends:  []*maskedUser{{Phone: "13300001111"}},
+		secret:   "keep",
+	}
+	out := Mask(page{List: []*maskedUser{u}, Extra: map[string]maskedUser{"a": {Email: "a@b.c"}}}).(page)
+
+	got := out.List.([]*maskedUser)[0]
+	if got.Phone != "132****7986" || got.Email != "x***e@163.com" || got.Password != "******" || got.Name != "li" {
+		t.Fatalf("unexpected masked user %+v", got)
+	}
+	if got.Friends[0].Phone != "133****1111" || got.secret != "keep" {
+		t.Fatalf("unexpected nested value %+v", got)
+	}
+	if out.Extra["a"].Email != "a***a@b.c" {
+		t.Fatalf("unexpected map value %+v", out.Extra)
+	}
+	// 原值不被修改
+	if u.Phone != "13288887986" || u.Friends[0].Phone != "13300001111" {
+		t.Fatalf("source modified %+v", u)
+	}
+}
+
+func TestMaskUntagged(t *testing.T) {
+	type plain struct{ A string }
+	in := &plain{A: "x"}
+	if Mask(in).(*plain) != in {
+		t.Fatal("expected untagged value to be returned as is")
+	}
+	b, _ := json.Marshal(Mask(map[string]interface{}{"u": maskedUser{Phone: "13288887986"}}))
+	if string(b) != `{"u":{"name":"","phone":"132****7986","email":"","password":"******","Friends":null}}` {
+		t.Fatalf("unexpected json %s", b)
+	}
+}
+
+func TestNeedsMask(t *testing.T) {
+	type plain struct{ A string }
+	if NeedsMask(nil) || NeedsMask(&plain{}) || NeedsMask([]plain{}) || NeedsMask("x") {
+		t.Fatal("untagged types should not need masking")
+	}
+	if !NeedsMask(&maskedUser{}) || !NeedsMask([]*maskedUser{}) {
+		t.Fatal("tagged types need masking")
+	}
+
+	// interface 按实际保存的值判断
+	type list struct {
+		List interface{} `json:"list"`
+	}
+	if NeedsMask(map[string]interface{}{}) || NeedsMask(map[string]interface{}{"list": []plain{{A: "x"}}}) ||
+		NeedsMask(&list{List: []plain{{A: "x"}}}) || NeedsMask(&list{}) {
+		t.Fatal("interface holding untagged values should not need masking")
+	}
+	if !NeedsMask(map[string]interface{}{"u": &maskedUser{}}) || !NeedsMask(&list{List: []maskedUser{{}}}) ||
+		!NeedsMask(&list{List: []interface{}{plain{}, &maskedUser{}}}) {
+		t.Fatal("interface holding tagged values needs masking")
+	}
+}
@@ -10,8 +10,6 @@ type BankCard string
 type IDCard string
 
 // IDName 姓名 *鸿章
-// TODO:参考 https://blog.thinkeridea.com/201910/go/efficient_string_truncation.html
-// Deprecated:有更好的性能选择
 type IDName string
 
 // PassWord 密码 ******
@@ -19,3 +17,21 @@ type PassWord string
 
 // Email 邮箱 l***w@gmail.com
 type Email string
+
+// E164 国际电话号码 +86*******7986
+type E164 string
+
+// IP IP 地址 192.168.*.*
+type IP string
+
+// Address 地址 北京市海淀区****
+type Address string
+
+// Passport 护照号 E******78
+type Passport string
+
+// Plate 车牌号 京A***45
+type Plate string
+
+// IBAN 国际银行账号 DE89**************3000
+type IBAN string
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugTokenHeader 携带单请求调试令牌的请求头
+const DebugTokenHeader = "X-Debug-Token"
+
+// SignDebugToken 生成单请求调试令牌，格式为 "<过期时间戳>.<签名>"
+func SignDebugToken(secret string, expiresAt time.Time) string {
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	return exp + "." + debugTokenSign(secret, exp)
+}
+
+// VerifyDebugToken 校验单请求调试令牌的签名与有效期，secret 为空时总是返回 false
+func VerifyDebugToken(secret, token string, now time.Time) bool {
+	if secret == "" || token == "" {
+		return false
+	}
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() > expUnix {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(debugTokenSign(secret, exp)))
+}
+
+func debugTokenSign(secret, exp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("sgin-debug:" + exp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
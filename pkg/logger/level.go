@@ -0,0 +1,326 @@
+package logger
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels 维护全局日志级别与按名称（Logger.Named）的日志级别，支持运行时修改
+type Levels struct {
+	global zap.AtomicLevel
+
+	mu      sync.RWMutex
+	named   map[string]zapcore.Level
+	reverts map[string]*levelRevert
+	// 单请求调试名单，key 为 kind:id，value 为过期时间（零值表示不过期）
+	debugSubjects map[string]time.Time
+}
+
+// levelRevert 记录带 TTL 的临时级别，到期后恢复为 prev
+type levelRevert struct {
+	timer     *time.Timer
+	prev      zapcore.Level
+	hadPrev   bool
+	expiresAt time.Time
+}
+
+// LevelInfo 描述某个名称当前生效的日志级别
+type LevelInfo struct {
+	Name      string     `json:"name"`                 // 名称，空字符串表示全局级别
+	Level     string     `json:"level"`                // 当前级别
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 临时级别的自动恢复时间
+}
+
+// NewLevels 创建级别管理器，named 的 key 为 Logger 名称（如 gorm、proxy）
+func NewLevels(global zapcore.Level, named map[string]zapcore.Level) *Levels {
+	l := &Levels{
+		global:        zap.NewAtomicLevelAt(global),
+		named:         make(map[string]zapcore.Level),
+		reverts:       make(map[string]*levelRevert),
+		debugSubjects: make(map[string]time.Time),
+	}
+	for name, lv := range named {
+		l.named[normalizeName(name)] = lv
+	}
+	return l
+}
+
+// ParseLevel 解析级别字符串（debug|info|warn|error|dpanic|panic|fatal）
+func ParseLevel(s string) (zapcore.Level, error) {
+	var lv zapcore.Level
+	if err := lv.Set(strings.TrimSpace(s)); err != nil {
+		return lv, err
+	}
+	return lv, nil
+}
+
+// Global 返回底层的 zap.AtomicLevel，可直接挂载为 zap 自带的 HTTP handler
+func (l *Levels) Global() zap.AtomicLevel {
+	return l.global
+}
+
+// Level 返回 name 对应的生效级别：精确匹配优先，其次按 "." 逐级匹配父名称，最后回退到全局级别
+func (l *Levels) Level(name string) zapcore.Level {
+	if name == "" {
+		return l.global.Level()
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.named) == 0 {
+		return l.global.Level()
+	}
+	for n := strings.ToLower(name); n != ""; {
+		if lv, ok := l.named[n]; ok {
+			return lv
+		}
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	return l.global.Level()
+}
+
+// minLevel 返回所有已配置级别中的最低级别，用于 Core.Enabled 的快速判断
+func (l *Levels) minLevel() zapcore.Level {
+	min := l.global.Level()
+	l.mu.RLock()
+	for _, lv := range l.named {
+		if lv < min {
+			min = lv
+		}
+	}
+	l.mu.RUnlock()
+	return min
+}
+
+// SetLevel 设置 name 的日志级别（name 为空表示全局）。ttl > 0 时到期自动恢复为修改前的级别
+func (l *Levels) SetLevel(name string, lv zapcore.Level, ttl time.Duration) {
+	name = normalizeName(name)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 已存在的临时级别：保留最初的恢复目标，只重置定时器
+	prev, hadPrev := l.currentLocked(name)
+	if r, ok := l.reverts[name]; ok {
+		r.timer.Stop()
+		prev, hadPrev = r.prev, r.hadPrev
+		delete(l.reverts, name)
+	}
+
+	l.setLocked(name, lv)
+
+	if ttl > 0 {
+		r := &levelRevert{prev: prev, hadPrev: hadPrev, expiresAt: time.Now().Add(ttl)}
+		r.timer = time.AfterFunc(ttl, func() { l.revert(name, r) })
+		l.reverts[name] = r
+	}
+}
+
+// ResetLevel 删除 name 的单独级别配置，使其回退到全局级别；name 为空时无效
+func (l *Levels) ResetLevel(name string) error {
+	name = normalizeName(name)
+	if name == "" {
+		return errors.New("global level can not be reset, use SetLevel instead")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if r, ok := l.reverts[name]; ok {
+		r.timer.Stop()
+		delete(l.reverts, name)
+	}
+	delete(l.named, name)
+	return nil
+}
+
+// List 返回全局及所有按名称配置的日志级别
+func (l *Levels) List() []LevelInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	out := make([]LevelInfo, 0, len(l.named)+1)
+	out = append(out, l.infoLocked("", l.global.Level()))
+	names := make([]string, 0, len(l.named))
+	for name := range l.named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out = append(out, l.infoLocked(name, l.named[name]))
+	}
+	return out
+}
+
+func (l *Levels) infoLocked(name string, lv zapcore.Level) LevelInfo {
+	info := LevelInfo{Name: name, Level: lv.String()}
+	if r, ok := l.reverts[name]; ok {
+		t := r.expiresAt
+		info.ExpiresAt = &t
+	}
+	return info
+}
+
+func (l *Levels) currentLocked(name string) (zapcore.Level, bool) {
+	if name == "" {
+		return l.global.Level(), true
+	}
+	lv, ok := l.named[name]
+	return lv, ok
+}
+
+func (l *Levels) setLocked(name string, lv zapcore.Level) {
+	if name == "" {
+		l.global.SetLevel(lv)
+		return
+	}
+	l.named[name] = lv
+}
+
+func (l *Levels) revert(name string, r *levelRevert) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 期间已被再次修改或重置
+	if l.reverts[name] != r {
+		return
+	}
+	delete(l.reverts, name)
+	if r.hadPrev {
+		l.setLocked(name, r.prev)
+	} else {
+		delete(l.named, name)
+	}
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// 单请求调试名单的类型
+const (
+	DebugSubjectApp  = "app"
+	DebugSubjectUser = "user"
+)
+
+// DebugSubject 描述单请求调试名单中的一项
+type DebugSubject struct {
+	Kind      string     `json:"kind"`                 // app | user
+	Id        string     `json:"id"`                   // app_id 或 user_id
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 过期时间，为空表示不过期
+}
+
+// AddDebugSubject 将 app_id 或 user_id 加入单请求调试名单，命中的请求以 debug 级别记录日志
+func (l *Levels) AddDebugSubject(kind, id string, ttl time.Duration) error {
+	if kind != DebugSubjectApp && kind != DebugSubjectUser {
+		return errors.New("kind must be app or user")
+	}
+	if id == "" {
+		return errors.New("id is empty")
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	l.mu.Lock()
+	l.debugSubjects[kind+":"+id] = expiresAt
+	l.mu.Unlock()
+	return nil
+}
+
+// RemoveDebugSubject 将 app_id 或 user_id 移出单请求调试名单
+func (l *Levels) RemoveDebugSubject(kind, id string) {
+	l.mu.Lock()
+	delete(l.debugSubjects, kind+":"+id)
+	l.mu.Unlock()
+}
+
+// IsDebugSubject 判断 app_id 或 user_id 是否在单请求调试名单中
+func (l *Levels) IsDebugSubject(kind, id string) bool {
+	if id == "" {
+		return false
+	}
+	l.mu.RLock()
+	if len(l.debugSubjects) == 0 {
+		l.mu.RUnlock()
+		return false
+	}
+	expiresAt, ok := l.debugSubjects[kind+":"+id]
+	l.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	if !expiresAt.IsZero() && time.Now().After(expiresAt) {
+		l.RemoveDebugSubject(kind, id)
+		return false
+	}
+	return true
+}
+
+// DebugSubjects 返回当前有效的单请求调试名单
+func (l *Levels) DebugSubjects() []DebugSubject {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]DebugSubject, 0, len(l.debugSubjects))
+	for key, expiresAt := range l.debugSubjects {
+		if !expiresAt.IsZero() && now.After(expiresAt) {
+			delete(l.debugSubjects, key)
+			continue
+		}
+		kind, id, _ := strings.Cut(key, ":")
+		item := DebugSubject{Kind: kind, Id: id}
+		if !expiresAt.IsZero() {
+			t := expiresAt
+			item.ExpiresAt = &t
+		}
+		out = append(out, item)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Id < out[j].Id
+	})
+	return out
+}
+
+// levelCore 根据 Levels 动态过滤日志条目，包装在所有输出 Core 的最外层
+type levelCore struct {
+	zapcore.Core
+	levels *Levels
+	// force 为 true 时，不低于 floor 的条目总是输出（用于单请求调试）
+	force bool
+	floor zapcore.Level
+}
+
+func newLevelCore(core zapcore.Core, levels *Levels) zapcore.Core {
+	return &levelCore{Core: core, levels: levels}
+}
+
+func (c *levelCore) Enabled(lv zapcore.Level) bool {
+	if c.force && lv >= c.floor {
+		return true
+	}
+	return lv >= c.levels.minLevel()
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels, force: c.force, floor: c.floor}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.force && ent.Level >= c.floor {
+		return c.Core.Check(ent, ce)
+	}
+	if ent.Level < c.levels.Level(ent.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newObservedLogger(global zapcore.Level, named map[string]zapcore.Level) (*Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	levels := NewLevels(global, named)
+	return &Logger{zap.New(newLevelCore(core, levels)).Sugar(), levels, nil}, logs
+}
+
+func TestNamedLevels(t *testing.T) {
+	l, logs := newObservedLogger(zapcore.InfoLevel, map[string]zapcore.Level{
+		"gorm":  zapcore.WarnLevel,
+		"proxy": zapcore.DebugLevel,
+	})
+
+	l.Debug("root debug")
+	l.Named("gorm").Info("gorm info")
+	l.Named("gorm").Named("sql").Info("gorm sql info")
+	l.Named("gorm").Warn("gorm warn")
+	l.Named("proxy").Debug("proxy debug")
+	l.With("k", "v").Named("proxy").Debug("proxy debug with fields")
+
+	var got []string
+	for _, e := range logs.All() {
+		got = append(got, e.Message)
+	}
+	want := []string{"gorm warn", "proxy debug", "proxy debug with fields"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSetLevelWithTTL(t *testing.T) {
+	l, logs := newObservedLogger(zapcore.InfoLevel, nil)
+	levels := l.Levels()
+
+	levels.SetLevel("", zapcore.DebugLevel, 50*time.Millisecond)
+	l.Debug("enabled")
+	if logs.Len() != 1 {
+		t.Fatalf("expected debug entry after raising level, got %d", logs.Len())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if lv := levels.Level(""); lv != zapcore.InfoLevel {
+		t.Fatalf("expected level reverted to info, got %s", lv)
+	}
+	l.Debug("disabled")
+	if logs.Len() != 1 {
+		t.Fatalf("expected debug entry dropped after revert, got %d", logs.Len())
+	}
+
+	levels.SetLevel("proxy", zapcore.ErrorLevel, 50*time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+	if len(levels.List()) != 1 {
+		t.Fatalf("expected temporary module level removed, got %+v", levels.List())
+	}
+}
+
+func TestForceLevel(t *testing.T) {
+	l, logs := newObservedLogger(zapcore.WarnLevel, map[string]zapcore.Level{"gorm": zapcore.ErrorLevel})
+
+	l.Debug("dropped")
+	dbg := l.ForceLevel(zapcore.DebugLevel).With("debug_request", true)
+	dbg.Debug("forced")
+	dbg.Named("gorm").Info("forced sql")
+	l.Named("gorm").Info("dropped sql")
+
+	if logs.Len() != 2 {
+		t.Fatalf("expected 2 forced entries, got %d: %+v", logs.Len(), logs.All())
+	}
+}
+
+func TestDebugToken(t *testing.T) {
+	now := time.Now()
+	tok := SignDebugToken("secret", now.Add(time.Minute))
+	if !VerifyDebugToken("secret", tok, now) {
+		t.Fatal("expected token to be valid")
+	}
+	if VerifyDebugToken("other", tok, now) {
+		t.Fatal("expected token signed with another secret to be invalid")
+	}
+	if VerifyDebugToken("secret", tok, now.Add(2*time.Minute)) {
+		t.Fatal("expected expired token to be invalid")
+	}
+	if VerifyDebugToken("", tok, now) {
+		t.Fatal("expected empty secret to disable tokens")
+	}
+}
+
+func TestDebugSubjects(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel, nil)
+	if err := levels.AddDebugSubject("team", "x", 0); err == nil {
+		t.Fatal("expected invalid kind to be rejected")
+	}
+	levels.AddDebugSubject(DebugSubjectUser, "u1", 0)
+	levels.AddDebugSubject(DebugSubjectApp, "a1", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if !levels.IsDebugSubject(DebugSubjectUser, "u1") {
+		t.Fatal("expected u1 to be a debug subject")
+	}
+	if levels.IsDebugSubject(DebugSubjectApp, "a1") {
+		t.Fatal("expected a1 to be expired")
+	}
+	if n := len(levels.DebugSubjects()); n != 1 {
+		t.Fatalf("expected 1 debug subject, got %d", n)
+	}
+}
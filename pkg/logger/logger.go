@@ -1,22 +1,24 @@
 package logger
 
 import (
-	"os"
+	"io"
 	"time"
 
 	"github.com/luxingwen/sgin/pkg/config"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 type Logger struct {
 	*zap.SugaredLogger
+	levels *Levels
+	// closers 持有后台协程的输出（如 syslog），由 Close 停止
+	closers []io.Closer
 }
 
 func (l *Logger) With(args ...interface{}) *Logger {
-	return &Logger{l.SugaredLogger.With(args...)}
+	return &Logger{l.SugaredLogger.With(args...), l.levels, l.closers}
 }
 
 // WithOptions wraps zap.WithOptions and preserves *Logger type
@@ -25,15 +27,40 @@ func (l *Logger) WithOptions(opts ...zap.Option) *Logger {
 		return l
 	}
 	base := l.SugaredLogger.Desugar().WithOptions(opts...)
-	return &Logger{base.Sugar()}
+	return &Logger{base.Sugar(), l.levels, l.closers}
 }
 
-// Named returns a new named logger
+// Named returns a new named logger. Its level can be configured separately
+// via LogConfig.ModuleLevels or Levels.SetLevel.
 func (l *Logger) Named(name string) *Logger {
 	if l == nil || l.SugaredLogger == nil {
 		return l
 	}
-	return &Logger{l.SugaredLogger.Named(name)}
+	return &Logger{l.SugaredLogger.Named(name), l.levels, l.closers}
+}
+
+// Levels returns the runtime level registry shared by this logger and all
+// loggers derived from it. It is nil for loggers not created by NewLogger.
+func (l *Logger) Levels() *Levels {
+	if l == nil {
+		return nil
+	}
+	return l.levels
+}
+
+// ForceLevel returns a logger that always emits entries at or above lv,
+// regardless of the global and per-module levels. It is used to turn on
+// debug logging for a single request.
+func (l *Logger) ForceLevel(lv zapcore.Level) *Logger {
+	if l == nil || l.SugaredLogger == nil {
+		return l
+	}
+	return l.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		if lc, ok := c.(*levelCore); ok {
+			return &levelCore{Core: lc.Core, levels: lc.levels, force: true, floor: lv}
+		}
+		return c
+	}))
 }
 
 // Desugar exposes the underlying zap.Logger
@@ -100,26 +127,29 @@ func (l *Logger) Fatalw(msg string, keysAndValues ...interface{}) {
 }
 
 func NewLogger(cfg config.LogConfig) *Logger {
-	encoder := getEncoder(cfg.Format)
-
 	var logLevel zapcore.Level
 	if err := logLevel.Set(cfg.Level); err != nil {
 		logLevel = zap.InfoLevel
 	}
 
-	cores := []zapcore.Core{}
-
-	// 文件输出（当配置了文件名时）
-	if cfg.Filename != "" {
-		fileWS := getLogWriter(cfg)
-		cores = append(cores, zapcore.NewCore(encoder, fileWS, logLevel))
+	// 按模块名称配置的级别，如 gorm: warn
+	moduleLevels := make(map[string]zapcore.Level, len(cfg.ModuleLevels))
+	for name, s := range cfg.ModuleLevels {
+		lv, err := ParseLevel(s)
+		if err != nil {
+			continue
+		}
+		moduleLevels[name] = lv
 	}
-
-	// 控制台输出（可与文件并存）
-	if cfg.ShowConsole || cfg.Filename == "" {
-		consoleEncoder := zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
-		consoleWS := zapcore.Lock(os.Stdout)
-		cores = append(cores, zapcore.NewCore(consoleEncoder, consoleWS, logLevel))
+	levels := NewLevels(logLevel, moduleLevels)
+
+	// 各输出 Core 只按自身的 MinLevel/MaxLevel 过滤，动态级别统一由最外层的 levelCore 判断
+	cores := buildSinks(cfg)
+	var closers []io.Closer
+	for _, c := range cores {
+		if cl, ok := c.(io.Closer); ok {
+			closers = append(closers, cl)
+		}
 	}
 
 	var core zapcore.Core
@@ -142,6 +172,8 @@ func NewLogger(cfg config.LogConfig) *Logger {
 		core = zapcore.NewSamplerWithOptions(core, time.Second, initial, thereafter)
 	}
 
+	core = newLevelCore(core, levels)
+
 	// 选择堆栈等级
 	var opts []zap.Option
 	opts = append(opts, zap.AddCaller(), zap.AddCallerSkip(1))
@@ -150,7 +182,7 @@ func NewLogger(cfg config.LogConfig) *Logger {
 	}
 
 	logger := zap.New(core, opts...)
-	return &Logger{logger.Sugar()}
+	return &Logger{logger.Sugar(), levels, closers}
 }
 
 func getEncoder(format string) zapcore.Encoder {
@@ -168,19 +200,6 @@ func getEncoder(format string) zapcore.Encoder {
 	}
 }
 
-func getLogWriter(config config.LogConfig) zapcore.WriteSyncer {
-	lumberJackLogger := &lumberjack.Logger{
-		Filename:   config.Filename,
-		MaxSize:    config.MaxSize, // megabytes
-		MaxBackups: 5,
-		MaxAge:     config.MaxAge,   //days
-		Compress:   config.Compress, // disabled by default
-		LocalTime:  true,
-	}
-
-	return zapcore.AddSync(lumberJackLogger)
-}
-
 // Printf 实现了 gorm.io/gorm/logger.Writer 接口的方法
 func (l *Logger) Printf(format string, args ...interface{}) {
 	l.Infof(format, args...)
@@ -208,3 +227,17 @@ func (l *Logger) Sync() error {
 	}
 	return l.SugaredLogger.Sync()
 }
+
+// Close 刷新缓冲区并停止各输出的后台协程，之后写入这些输出的日志被丢弃；在进程退出前调用
+func (l *Logger) Close() error {
+	if l == nil || l.SugaredLogger == nil {
+		return nil
+	}
+	err := l.Sync()
+	for _, c := range l.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
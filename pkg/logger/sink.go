@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/luxingwen/sgin/pkg/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 内置的日志输出类型
+const (
+	SinkConsole = "console"
+	SinkFile    = "file"
+	SinkSyslog  = "syslog"
+	SinkHTTP    = "http"
+)
+
+// SinkFactory 根据配置创建一个日志输出 Core。
+// enabler 已按 MinLevel/MaxLevel 构造，工厂只需把它交给返回的 Core；
+// 动态日志级别由外层统一处理，工厂无需关心。
+type SinkFactory func(cfg config.LogSinkConfig, enabler zapcore.LevelEnabler) (zapcore.Core, error)
+
+var (
+	sinkMu        sync.RWMutex
+	sinkFactories = map[string]SinkFactory{
+		SinkConsole: newConsoleSink,
+		SinkFile:    newFileSink,
+		SinkSyslog:  newSyslogSink,
+		SinkHTTP:    newHTTPSink,
+	}
+)
+
+// RegisterSink 注册自定义日志输出类型，插件需在 NewLogger 之前调用；同名类型会被覆盖
+func RegisterSink(typ string, factory SinkFactory) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sinkFactories[strings.ToLower(typ)] = factory
+}
+
+// NewEncoder 返回 sgin 默认的日志编码器，format 为 json 或 console，供自定义输出使用
+func NewEncoder(format string) zapcore.Encoder {
+	return getEncoder(format)
+}
+
+// NewSinkCore 按配置创建单个日志输出
+func NewSinkCore(cfg config.LogSinkConfig) (zapcore.Core, error) {
+	sinkMu.RLock()
+	factory, ok := sinkFactories[strings.ToLower(cfg.Type)]
+	sinkMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown log sink type %q", cfg.Type)
+	}
+	enabler, err := newRangeEnabler(cfg.MinLevel, cfg.MaxLevel)
+	if err != nil {
+		return nil, err
+	}
+	return factory(cfg, enabler)
+}
+
+// buildSinks 根据 LogConfig 创建所有输出。未配置 Sinks 时沿用 Filename/ShowConsole 的行为
+func buildSinks(cfg config.LogConfig) []zapcore.Core {
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		if cfg.Filename != "" {
+			sinks = append(sinks, config.LogSinkConfig{
+				Type:     SinkFile,
+				Filename: cfg.Filename,
+			})
+		}
+		if cfg.ShowConsole || cfg.Filename == "" {
+			sinks = append(sinks, config.LogSinkConfig{Type: SinkConsole, Format: SinkConsole})
+		}
+	}
+
+	cores := make([]zapcore.Core, 0, len(sinks))
+	for _, s := range sinks {
+		if s.Format == "" {
+			s.Format = cfg.Format
+		}
+		// 文件参数未单独配置时继承 LogConfig
+		if s.MaxSize == 0 {
+			s.MaxSize = cfg.MaxSize
+		}
+		if s.MaxAge == 0 {
+			s.MaxAge = cfg.MaxAge
+		}
+		if s.MaxBackups == 0 {
+			s.MaxBackups = cfg.MaxBackups
+		}
+		if s.Compress == nil {
+			compress := cfg.Compress
+			s.Compress = &compress
+		}
+
+		core, err := NewSinkCore(s)
+		if err != nil {
+			// 日志系统尚未就绪，只能输出到标准错误
+			fmt.Fprintf(os.Stderr, "logger: skip sink %q: %v\n", s.Type, err)
+			continue
+		}
+		cores = append(cores, core)
+	}
+
+	if len(cores) == 0 {
+		core, _ := newConsoleSink(config.LogSinkConfig{Format: SinkConsole}, zapcore.DebugLevel)
+		cores = append(cores, core)
+	}
+	return cores
+}
+
+// rangeEnabler 只放行 [min, max] 区间内的级别
+type rangeEnabler struct {
+	min, max zapcore.Level
+}
+
+func (r rangeEnabler) Enabled(lv zapcore.Level) bool {
+	return lv >= r.min && lv <= r.max
+}
+
+func newRangeEnabler(min, max string) (zapcore.LevelEnabler, error) {
+	r := rangeEnabler{min: zapcore.DebugLevel, max: zapcore.FatalLevel}
+	if min != "" {
+		lv, err := ParseLevel(min)
+		if err != nil {
+			return nil, err
+		}
+		r.min = lv
+	}
+	if max != "" {
+		lv, err := ParseLevel(max)
+		if err != nil {
+			return nil, err
+		}
+		r.max = lv
+	}
+	if r.min > r.max {
+		return nil, fmt.Errorf("MinLevel %s is above MaxLevel %s", r.min, r.max)
+	}
+	return r, nil
+}
+
+func newConsoleSink(cfg config.LogSinkConfig, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	encoder := getEncoder(cfg.Format)
+	if cfg.Format == SinkConsole {
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	}
+	return zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), enabler), nil
+}
+
+func newFileSink(cfg config.LogSinkConfig, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	if cfg.Filename == "" {
+		return nil, fmt.Errorf("file sink requires Filename")
+	}
+	maxBackups := cfg.MaxBackups
+	if maxBackups <= 0 {
+		maxBackups = 5
+	}
+	ws := zapcore.AddSync(&lumberjack.Logger{
+		Filename:   cfg.Filename,
+		MaxSize:    cfg.MaxSize, // megabytes
+		MaxBackups: maxBackups,
+		MaxAge:     cfg.MaxAge, //days
+		Compress:   cfg.Compress != nil && *cfg.Compress,
+		LocalTime:  true,
+	})
+	return zapcore.NewCore(getEncoder(cfg.Format), ws, enabler), nil
+}
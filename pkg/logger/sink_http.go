@@ -0,0 +1,223 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luxingwen/sgin/pkg/config"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// httpCore 将日志编码为 JSON 后放入 httpShipper 的缓冲区，由其批量推送
+type httpCore struct {
+	zapcore.LevelEnabler
+	enc     zapcore.Encoder
+	shipper *httpShipper
+}
+
+func newHTTPSink(cfg config.LogSinkConfig, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("http sink requires URL")
+	}
+	return &httpCore{
+		LevelEnabler: enabler,
+		// 推送内容为 JSON 数组，忽略 Format 配置
+		enc:     getEncoder("json"),
+		shipper: newHTTPShipper(cfg),
+	}, nil
+}
+
+func (c *httpCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *httpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *httpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.shipper.enqueue(bytes.TrimRight(buf.Bytes(), "\n"))
+	buf.Free()
+	return nil
+}
+
+// Sync 同步推送缓冲区中的全部日志
+func (c *httpCore) Sync() error {
+	return c.shipper.flush()
+}
+
+// httpShipper 按条数或时间间隔批量推送日志，缓冲区满时丢弃新日志并计数
+type httpShipper struct {
+	url        string
+	headers    map[string]string
+	client     *http.Client
+	batchSize  int
+	bufferSize int
+	maxRetries int
+	backoff    time.Duration
+
+	mu      sync.Mutex
+	buf     [][]byte
+	sendMu  sync.Mutex // 保证批次按顺序发送
+	notify  chan struct{}
+	dropped uint64
+}
+
+func newHTTPShipper(cfg config.LogSinkConfig) *httpShipper {
+	s := &httpShipper{
+		url:        cfg.URL,
+		headers:    cfg.Headers,
+		client:     &http.Client{Timeout: millisOr(cfg.Timeout, 5000)},
+		batchSize:  intOr(cfg.BatchSize, 100),
+		bufferSize: intOr(cfg.BufferSize, 10000),
+		maxRetries: cfg.MaxRetries,
+		backoff:    200 * time.Millisecond,
+		notify:     make(chan struct{}, 1),
+	}
+	if s.maxRetries == 0 {
+		s.maxRetries = 3
+	}
+	if s.maxRetries < 0 {
+		s.maxRetries = 0
+	}
+	go s.loop(millisOr(cfg.FlushInterval, 1000))
+	return s
+}
+
+func (s *httpShipper) enqueue(entry []byte) {
+	s.mu.Lock()
+	if len(s.buf) >= s.bufferSize {
+		s.mu.Unlock()
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	// buf 由编码器复用，需拷贝
+	s.buf = append(s.buf, append([]byte(nil), entry...))
+	full := len(s.buf) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *httpShipper) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.notify:
+		}
+		s.flush()
+	}
+}
+
+// flush 推送缓冲区中的全部日志，返回最后一次失败的错误
+func (s *httpShipper) flush() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	var lastErr error
+	for {
+		s.mu.Lock()
+		n := len(s.buf)
+		if n > s.batchSize {
+			n = s.batchSize
+		}
+		batch := s.buf[:n:n]
+		s.buf = s.buf[n:]
+		s.mu.Unlock()
+
+		if n == 0 {
+			return lastErr
+		}
+		if err := s.send(batch); err != nil {
+			atomic.AddUint64(&s.dropped, uint64(n))
+			lastErr = err
+		}
+	}
+}
+
+// send 以 JSON 数组推送一批日志，网络错误与 5xx/429 按指数退避重试
+func (s *httpShipper) send(batch [][]byte) error {
+	body := make([]byte, 0, 2+len(batch)*256)
+	body = append(body, '[')
+	body = append(body, bytes.Join(batch, []byte{','})...)
+	body = append(body, ']')
+
+	var err error
+	backoff := s.backoff
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		if retry, err = s.post(body); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *httpShipper) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("log shipper: %s returned %d", s.url, resp.StatusCode)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// Dropped 返回因缓冲区已满或推送失败而丢弃的日志条数
+func (s *httpShipper) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func intOr(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+func millisOr(v, def int) time.Duration {
+	return time.Duration(intOr(v, def)) * time.Millisecond
+}
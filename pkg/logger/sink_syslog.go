@@ -0,0 +1,310 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luxingwen/sgin/pkg/config"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogCore 以 RFC 5424 格式发送日志，MSG 部分为编码器输出（默认 JSON）
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc      zapcore.Encoder
+	w        *syslogWriter
+	facility int
+	hostname string
+	appName  string
+	procID   string
+}
+
+func newSyslogSink(cfg config.LogSinkConfig, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	network := strings.ToLower(cfg.Network)
+	if network == "" {
+		network = "udp"
+	}
+	switch network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", cfg.Network)
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("syslog sink requires Address")
+	}
+
+	facility := cfg.Facility
+	if facility <= 0 || facility > 23 {
+		facility = 1 // user-level messages
+	}
+	appName := cfg.AppName
+	if appName == "" {
+		appName = "sgin"
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogCore{
+		LevelEnabler: enabler,
+		enc:          getEncoder(cfg.Format),
+		w:            newSyslogWriter(network, cfg.Address),
+		facility:     facility,
+		hostname:     hostname,
+		appName:      appName,
+		procID:       strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := strings.TrimRight(buf.String(), "\n")
+	buf.Free()
+
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	line := fmt.Sprintf("<%d>1 %s %s %s %s %s - %s",
+		c.facility*8+syslogSeverity(ent.Level),
+		ent.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		c.hostname,
+		c.appName,
+		c.procID,
+		syslogMsgID(ent.LoggerName),
+		msg,
+	)
+	return c.w.write(line)
+}
+
+// Sync 等待缓冲区中的日志发送完成，最长等待 syslogSyncTimeout
+func (c *syslogCore) Sync() error {
+	return c.w.sync()
+}
+
+// Close 发送剩余日志后停止后台协程，之后的日志被丢弃
+func (c *syslogCore) Close() error {
+	return c.w.Close()
+}
+
+// syslogSeverity 将 zap 级别映射为 RFC 5424 severity
+func syslogSeverity(lv zapcore.Level) int {
+	switch lv {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2
+	case zapcore.FatalLevel:
+		return 1
+	default:
+		return 5
+	}
+}
+
+// syslogMsgID 使用 Logger 名称作为 MSGID，需为不含空格的可打印 ASCII 且不超过 32 字符
+func syslogMsgID(name string) string {
+	if name == "" {
+		return "-"
+	}
+	b := make([]byte, 0, len(name))
+	for i := 0; i < len(name) && len(b) < 32; i++ {
+		if ch := name[i]; ch > 32 && ch < 127 {
+			b = append(b, ch)
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+const (
+	syslogBufferSize   = 1000
+	syslogDialTimeout  = 3 * time.Second
+	syslogWriteTimeout = 3 * time.Second
+	syslogSyncTimeout  = 5 * time.Second
+	syslogMinBackoff   = time.Second
+	syslogMaxBackoff   = 30 * time.Second
+)
+
+var errSyslogSyncTimeout = errors.New("syslog: sync timed out")
+
+// syslogMsg 为缓冲区中的一条日志；flushed 不为空时表示 Sync 的标记，处理到它时之前的日志都已发送
+type syslogMsg struct {
+	data    []byte
+	flushed chan struct{}
+}
+
+// syslogWriter 将日志放入有界缓冲区，由后台协程维护连接并发送，写日志的协程不会因 syslog 不可用而阻塞。
+// 连接断开时按指数退避重连，期间缓冲区满后丢弃新日志并计数
+type syslogWriter struct {
+	network     string
+	address     string
+	syncTimeout time.Duration
+
+	ch        chan syslogMsg
+	once      sync.Once
+	closeOnce sync.Once
+	closed    uint32
+	quit      chan struct{} // Close 后关闭，通知后台协程退出
+	stopped   chan struct{} // 后台协程退出后关闭
+	dropped   uint64
+}
+
+func newSyslogWriter(network, address string) *syslogWriter {
+	return &syslogWriter{
+		network:     network,
+		address:     address,
+		syncTimeout: syslogSyncTimeout,
+		ch:          make(chan syslogMsg, syslogBufferSize),
+		quit:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+}
+
+func (w *syslogWriter) write(line string) error {
+	if atomic.LoadUint32(&w.closed) == 1 {
+		atomic.AddUint64(&w.dropped, 1)
+		return nil
+	}
+	w.once.Do(func() { go w.run() })
+
+	data := []byte(line)
+	// 流式传输使用 RFC 6587 octet-counting 分帧
+	if w.network == "tcp" || w.network == "unix" {
+		data = append([]byte(strconv.Itoa(len(data))+" "), data...)
+	}
+	select {
+	case w.ch <- syslogMsg{data: data}:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	return nil
+}
+
+// sync 在缓冲区末尾放入标记并等待后台协程处理到它，超过 syncTimeout 返回错误
+func (w *syslogWriter) sync() error {
+	if atomic.LoadUint32(&w.closed) == 1 {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *syslogWriter) flush() error {
+	w.once.Do(func() { go w.run() })
+
+	timer := time.NewTimer(w.syncTimeout)
+	defer timer.Stop()
+	flushed := make(chan struct{})
+	select {
+	case w.ch <- syslogMsg{flushed: flushed}:
+	case <-timer.C:
+		return errSyslogSyncTimeout
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-timer.C:
+		return errSyslogSyncTimeout
+	}
+}
+
+// Close 等待缓冲区中的日志发送完成（最长 syncTimeout）后停止后台协程，可重复调用
+func (w *syslogWriter) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		atomic.StoreUint32(&w.closed, 1)
+		err = w.flush()
+		close(w.quit)
+	})
+	return err
+}
+
+// Dropped 返回因缓冲区满被丢弃的日志条数
+func (w *syslogWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *syslogWriter) run() {
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+		close(w.stopped)
+	}()
+
+	backoff := syslogMinBackoff
+	for {
+		var msg syslogMsg
+		select {
+		case msg = <-w.ch:
+		case <-w.quit:
+			return
+		}
+		if msg.flushed != nil {
+			close(msg.flushed)
+			continue
+		}
+
+		// 每条日志最多在两个连接上尝试，仍失败时丢弃，避免单条日志反复重连
+		for attempt := 0; attempt < 2; attempt++ {
+			for conn == nil {
+				c, err := net.DialTimeout(w.network, w.address, syslogDialTimeout)
+				if err == nil {
+					conn, backoff = c, syslogMinBackoff
+					break
+				}
+				select {
+				case <-time.After(backoff):
+				case <-w.quit:
+					atomic.AddUint64(&w.dropped, 1)
+					return
+				}
+				if backoff *= 2; backoff > syslogMaxBackoff {
+					backoff = syslogMaxBackoff
+				}
+			}
+			conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+			if _, err := conn.Write(msg.data); err == nil {
+				break
+			}
+			conn.Close()
+			conn = nil
+			if attempt == 1 {
+				atomic.AddUint64(&w.dropped, 1)
+			}
+		}
+	}
+}
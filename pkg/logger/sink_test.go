@@ -0,0 +1,270 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luxingwen/sgin/pkg/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestHTTPSinkBatchAndRetry(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []string
+		calls    int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次请求返回 503，验证重试
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch []map[string]interface{}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Errorf("invalid batch %s: %v", body, err)
+		}
+		mu.Lock()
+		for _, e := range batch {
+			messages = append(messages, e["msg"].(string))
+		}
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	l := NewLogger(config.LogConfig{
+		Level: "info",
+		Sinks: []config.LogSinkConfig{{
+			Type:          SinkHTTP,
+			URL:           srv.URL,
+			BatchSize:     2,
+			FlushInterval: 60000,
+		}},
+	})
+	l.Info("a")
+	l.With("k", "v").Warn("b")
+	l.Debug("dropped by level")
+	l.Error("c")
+	if err := l.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(messages, ",") != "a,b,c" {
+		t.Fatalf("got %v", messages)
+	}
+}
+
+func TestHTTPShipperBoundedBuffer(t *testing.T) {
+	s := &httpShipper{batchSize: 10, bufferSize: 2, notify: make(chan struct{}, 1)}
+	for i := 0; i < 5; i++ {
+		s.enqueue([]byte(`{}`))
+	}
+	if s.Dropped() != 3 || len(s.buf) != 2 {
+		t.Fatalf("expected 3 dropped and 2 buffered, got %d and %d", s.Dropped(), len(s.buf))
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("udp not available:", err)
+	}
+	defer pc.Close()
+
+	core, err := NewSinkCore(config.LogSinkConfig{
+		Type:     SinkSyslog,
+		Network:  "udp",
+		Address:  pc.LocalAddr().String(),
+		AppName:  "test",
+		Facility: 16,
+		MinLevel: "warn",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &Logger{zap.New(core).Sugar().Named("proxy"), nil, nil}
+	l.Info("ignored")
+	l.Error("boom")
+
+	buf := make([]byte, 4096)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(buf[:n])
+	// local0(16)*8 + err(3) = 131
+	if !strings.HasPrefix(line, "<131>1 ") || !strings.Contains(line, " test ") ||
+		!strings.Contains(line, " proxy - {") || !strings.Contains(line, `"msg":"boom"`) {
+		t.Fatalf("unexpected syslog line %q", line)
+	}
+}
+
+func TestPerLevelFiles(t *testing.T) {
+	dir := t.TempDir()
+	appLog := filepath.Join(dir, "app.log")
+	errLog := filepath.Join(dir, "error.log")
+
+	l := NewLogger(config.LogConfig{
+		Level: "debug",
+		Sinks: []config.LogSinkConfig{
+			{Type: SinkFile, Filename: appLog, MaxLevel: "warn"},
+			{Type: SinkFile, Filename: errLog, MinLevel: "error"},
+		},
+	})
+	l.Info("info line")
+	l.Error("error line")
+	l.Sync()
+
+	app, _ := os.ReadFile(appLog)
+	errs, _ := os.ReadFile(errLog)
+	if !strings.Contains(string(app), "info line") || strings.Contains(string(app), "error line") {
+		t.Fatalf("unexpected app.log: %s", app)
+	}
+	if !strings.Contains(string(errs), "error line") || strings.Contains(string(errs), "info line") {
+		t.Fatalf("unexpected error.log: %s", errs)
+	}
+}
+
+func TestRegisterSink(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	RegisterSink("memory", func(cfg config.LogSinkConfig, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+		return &enablerCore{obs, enabler}, nil
+	})
+
+	l := NewLogger(config.LogConfig{
+		Level: "info",
+		Sinks: []config.LogSinkConfig{{Type: "memory", MinLevel: "warn"}, {Type: "unknown"}},
+	})
+	l.Info("ignored")
+	l.Warn("kept")
+	if logs.Len() != 1 || logs.All()[0].Message != "kept" {
+		t.Fatalf("got %+v", logs.All())
+	}
+}
+
+// enablerCore 以指定的 LevelEnabler 替换内部 Core 的级别判断
+type enablerCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c *enablerCore) Enabled(lv zapcore.Level) bool { return c.enabler.Enabled(lv) }
+
+func (c *enablerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.enabler.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func TestSyslogWriterDoesNotBlock(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("tcp not available:", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// syslog 不可用时写日志立即返回，超出缓冲区的日志被丢弃
+	w := newSyslogWriter("tcp", addr)
+	start := time.Now()
+	for i := 0; i < syslogBufferSize*2; i++ {
+		w.write("line")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("write blocked for %v", d)
+	}
+	if w.Dropped() == 0 {
+		t.Fatal("expected dropped lines")
+	}
+}
+
+func TestSyslogWriterSyncAndClose(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("udp not available:", err)
+	}
+	defer pc.Close()
+
+	// Sync 返回时缓冲区中的日志都已发送
+	w := newSyslogWriter("udp", pc.LocalAddr().String())
+	for i := 0; i < 3; i++ {
+		w.write("line")
+	}
+	if err := w.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(w.ch); n != 0 {
+		t.Fatalf("%d lines left after sync", n)
+	}
+	buf := make([]byte, 1024)
+	for i := 0; i < 3; i++ {
+		pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, _, err := pc.ReadFrom(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close 后后台协程退出，之后的日志被丢弃
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-w.stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("writer goroutine still running")
+	}
+	w.write("after close")
+	if w.Dropped() != 1 {
+		t.Fatalf("dropped = %d, want 1", w.Dropped())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyslogWriterSyncTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("tcp not available:", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// syslog 不可用时 Sync 与 Close 最多等待 syncTimeout
+	w := newSyslogWriter("tcp", addr)
+	w.syncTimeout = 100 * time.Millisecond
+	w.write("line")
+	start := time.Now()
+	if err := w.sync(); err != errSyslogSyncTimeout {
+		t.Fatalf("sync = %v, want timeout", err)
+	}
+	if err := w.Close(); err != errSyslogSyncTimeout {
+		t.Fatalf("close = %v, want timeout", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("sync blocked for %v", d)
+	}
+	select {
+	case <-w.stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("writer goroutine still running")
+	}
+}
@@ -0,0 +1,270 @@
+// Package logpipe 提供异步批量写入管道，用于请求日志、操作日志等不要求实时落库的数据。
+//
+// 数据先进入有界缓冲区，由后台协程按条数或时间间隔批量写入；缓冲区满时按溢出策略
+// 丢弃、阻塞或写入本地 JSONL 文件；Close 时写入剩余数据。
+package logpipe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luxingwen/sgin/pkg/logger"
+)
+
+// 缓冲区满时的处理策略
+const (
+	OverflowDrop  = "drop"  // 丢弃新数据（默认）
+	OverflowBlock = "block" // 阻塞写入方，最长 BlockTimeout，超时后丢弃
+	OverflowSpill = "spill" // 追加到本地 JSONL 文件，之后可人工导入
+)
+
+// Config 管道配置，零值字段使用默认值
+type Config struct {
+	BufferSize    int           // 缓冲区条数，默认 10000
+	BatchSize     int           // 单次批量写入条数，默认 200
+	FlushInterval time.Duration // 定时写入间隔，默认 1s
+	Overflow      string        // drop | block | spill
+	BlockTimeout  time.Duration // block 策略的最长等待时间，默认 1s
+	SpillFile     string        // spill 策略的文件路径；写入失败的批次在该策略下也会写入此文件
+}
+
+// Stats 管道运行统计
+type Stats struct {
+	Name    string `json:"name"`
+	Pending int    `json:"pending"` // 缓冲区中等待写入的条数
+	Queued  uint64 `json:"queued"`  // 累计进入缓冲区的条数
+	Written uint64 `json:"written"` // 累计写入成功的条数
+	Dropped uint64 `json:"dropped"` // 累计丢弃的条数（缓冲区满或写入失败）
+	Spilled uint64 `json:"spilled"` // 累计写入溢出文件的条数
+	Failed  uint64 `json:"failed"`  // 累计写入失败的批次数
+}
+
+// Pipeline 异步批量写入管道
+type Pipeline[T any] struct {
+	name   string
+	cfg    Config
+	write  func([]T) error
+	logger *logger.Logger
+
+	ch      chan T
+	stop    chan struct{}
+	done    chan struct{}
+	closed  int32
+	spillMu sync.Mutex
+
+	queued, written, dropped, spilled, failed uint64
+}
+
+// New 创建并启动管道，write 负责批量写入（如 gorm 的 CreateInBatches），调用返回后不得再持有 batch
+func New[T any](name string, cfg Config, write func([]T) error, log *logger.Logger) *Pipeline[T] {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 200
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = time.Second
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = OverflowDrop
+	}
+	if cfg.Overflow == OverflowSpill && cfg.SpillFile == "" {
+		cfg.SpillFile = name + ".spill.jsonl"
+	}
+
+	p := &Pipeline[T]{
+		name:   name,
+		cfg:    cfg,
+		write:  write,
+		logger: log,
+		ch:     make(chan T, cfg.BufferSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Push 放入一条数据，不等待写入；返回 false 表示数据未进入缓冲区（已丢弃或写入溢出文件）
+func (p *Pipeline[T]) Push(item T) bool {
+	if atomic.LoadInt32(&p.closed) == 1 {
+		// 关闭后直接同步写入，避免丢失关闭过程中产生的数据
+		p.flush([]T{item})
+		return false
+	}
+
+	select {
+	case p.ch <- item:
+		atomic.AddUint64(&p.queued, 1)
+		return true
+	default:
+	}
+
+	switch p.cfg.Overflow {
+	case OverflowBlock:
+		timer := time.NewTimer(p.cfg.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case p.ch <- item:
+			atomic.AddUint64(&p.queued, 1)
+			return true
+		case <-timer.C:
+		case <-p.stop:
+		}
+	case OverflowSpill:
+		p.spill([]T{item})
+		return false
+	}
+	atomic.AddUint64(&p.dropped, 1)
+	return false
+}
+
+// Close 停止接收并写入缓冲区中的剩余数据，ctx 到期时返回 ctx.Err()
+func (p *Pipeline[T]) Close(ctx context.Context) error {
+	if atomic.CompareAndSwapInt32(&p.closed, 0, 1) {
+		close(p.stop)
+	}
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// 关闭瞬间仍可能有数据进入缓冲区
+	var rest []T
+	for {
+		select {
+		case item := <-p.ch:
+			rest = append(rest, item)
+		default:
+			if len(rest) > 0 {
+				p.flush(rest)
+			}
+			return nil
+		}
+	}
+}
+
+// Stats 返回运行统计
+func (p *Pipeline[T]) Stats() Stats {
+	return Stats{
+		Name:    p.name,
+		Pending: len(p.ch),
+		Queued:  atomic.LoadUint64(&p.queued),
+		Written: atomic.LoadUint64(&p.written),
+		Dropped: atomic.LoadUint64(&p.dropped),
+		Spilled: atomic.LoadUint64(&p.spilled),
+		Failed:  atomic.LoadUint64(&p.failed),
+	}
+}
+
+func (p *Pipeline[T]) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, p.cfg.BatchSize)
+	for {
+		select {
+		case item := <-p.ch:
+			batch = append(batch, item)
+			if len(batch) >= p.cfg.BatchSize {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-p.stop:
+			// 写入剩余数据
+			for {
+				select {
+				case item := <-p.ch:
+					batch = append(batch, item)
+					if len(batch) >= p.cfg.BatchSize {
+						p.flush(batch)
+						batch = batch[:0]
+					}
+				default:
+					if len(batch) > 0 {
+						p.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *Pipeline[T]) flush(batch []T) {
+	err := p.safeWrite(batch)
+	if err == nil {
+		atomic.AddUint64(&p.written, uint64(len(batch)))
+		return
+	}
+
+	atomic.AddUint64(&p.failed, 1)
+	if p.logger != nil {
+		p.logger.Errorw("logpipe write batch failed", "pipeline", p.name, "size", len(batch), "error", err.Error())
+	}
+	if p.cfg.Overflow == OverflowSpill {
+		p.spill(batch)
+		return
+	}
+	atomic.AddUint64(&p.dropped, uint64(len(batch)))
+}
+
+// safeWrite 调用 write 并将 panic 转换为错误，避免后台协程退出
+func (p *Pipeline[T]) safeWrite(batch []T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("logpipe: write panic")
+		}
+	}()
+	return p.write(batch)
+}
+
+func (p *Pipeline[T]) spill(items []T) {
+	p.spillMu.Lock()
+	defer p.spillMu.Unlock()
+
+	err := func() error {
+		if dir := filepath.Dir(p.cfg.SpillFile); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+		f, err := os.OpenFile(p.cfg.SpillFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		enc := json.NewEncoder(f)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		atomic.AddUint64(&p.dropped, uint64(len(items)))
+		if p.logger != nil {
+			p.logger.Errorw("logpipe spill failed", "pipeline", p.name, "file", p.cfg.SpillFile, "error", err.Error())
+		}
+		return
+	}
+	atomic.AddUint64(&p.spilled, uint64(len(items)))
+}
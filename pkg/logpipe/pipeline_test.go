@@ -0,0 +1,121 @@
+package logpipe
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sink struct {
+	mu      sync.Mutex
+	batches [][]int
+	block   chan struct{}
+	err     error
+}
+
+func (s *sink) write(items []int) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, append([]int(nil), items...))
+	return nil
+}
+
+func (s *sink) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, b := range s.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestBatchAndClose(t *testing.T) {
+	s := &sink{}
+	p := New("test", Config{BatchSize: 3, FlushInterval: time.Hour}, s.write, nil)
+	for i := 0; i < 7; i++ {
+		p.Push(i)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s.total() != 7 {
+		t.Fatalf("expected 7 written, got %d", s.total())
+	}
+	for _, b := range s.batches {
+		if len(b) > 3 {
+			t.Fatalf("batch too large: %v", b)
+		}
+	}
+	if st := p.Stats(); st.Written != 7 || st.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestFlushInterval(t *testing.T) {
+	s := &sink{}
+	p := New("test", Config{BatchSize: 100, FlushInterval: 20 * time.Millisecond}, s.write, nil)
+	defer p.Close(context.Background())
+	p.Push(1)
+	time.Sleep(100 * time.Millisecond)
+	if s.total() != 1 {
+		t.Fatalf("expected timed flush, got %d", s.total())
+	}
+}
+
+func TestOverflowDrop(t *testing.T) {
+	s := &sink{block: make(chan struct{})}
+	p := New("test", Config{BufferSize: 2, BatchSize: 1}, s.write, nil)
+	// 第一条被后台协程取走并阻塞在 write 中，之后两条占满缓冲区
+	p.Push(0)
+	time.Sleep(20 * time.Millisecond)
+	p.Push(1)
+	p.Push(2)
+	if p.Push(3) {
+		t.Fatal("expected push to fail when buffer is full")
+	}
+	if st := p.Stats(); st.Dropped != 1 {
+		t.Fatalf("expected 1 dropped, got %+v", st)
+	}
+	close(s.block)
+	p.Close(context.Background())
+	if s.total() != 3 {
+		t.Fatalf("expected 3 written, got %d", s.total())
+	}
+}
+
+func TestOverflowSpill(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "spill", "log.jsonl")
+	s := &sink{err: errors.New("db down")}
+	p := New("test", Config{BufferSize: 1, BatchSize: 10, Overflow: OverflowSpill, SpillFile: file}, s.write, nil)
+	p.Push(1)
+	p.Push(2) // 缓冲区已满，直接写入文件
+	p.Close(context.Background())
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		lines++
+	}
+	if lines != 2 {
+		t.Fatalf("expected 2 spilled lines, got %d", lines)
+	}
+	if st := p.Stats(); st.Spilled != 2 || st.Failed != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,73 @@
+// Package nonce 提供防重放 nonce 的本机内存存储
+package nonce
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore 本机内存中的 LRU，只适用于单实例部署；
+// 超出容量时淘汰最早的记录，被淘汰的 nonce 在时间窗口内可能被重放，容量应大于窗口内的请求量
+type MemoryStore struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+type entry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryStore 创建最多保存 size 个 nonce 的存储
+func NewMemoryStore(size int) *MemoryStore {
+	if size <= 0 {
+		size = 1
+	}
+	return &MemoryStore{size: size, ll: list.New(), items: map[string]*list.Element{}, now: time.Now}
+}
+
+// Len 当前保存的 nonce 数量（含尚未清理的过期记录）
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+// Claim 原子地记录 scope 下的 nonce：ttl 内首次出现返回 true，已使用过返回 false
+func (s *MemoryStore) Claim(ctx context.Context, scope, nonce string, ttl time.Duration) (bool, error) {
+	key := scope + ":" + nonce
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 链表按写入时间排列，尾部过期的记录顺带清理
+	for e := s.ll.Back(); e != nil; e = s.ll.Back() {
+		n := e.Value.(*entry)
+		if now.Before(n.expires) {
+			break
+		}
+		s.ll.Remove(e)
+		delete(s.items, n.key)
+	}
+
+	if e, ok := s.items[key]; ok {
+		if now.Before(e.Value.(*entry).expires) {
+			return false, nil
+		}
+		s.ll.Remove(e)
+		delete(s.items, key)
+	}
+	s.items[key] = s.ll.PushFront(&entry{key: key, expires: now.Add(ttl)})
+	for s.ll.Len() > s.size {
+		e := s.ll.Back()
+		s.ll.Remove(e)
+		delete(s.items, e.Value.(*entry).key)
+	}
+	return true, nil
+}
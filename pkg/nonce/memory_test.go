@@ -0,0 +1,76 @@
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreClaim(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryStore(10)
+	s.now = func() time.Time { return now }
+
+	if ok, _ := s.Claim(ctx, "app1", "n1", time.Minute); !ok {
+		t.Fatal("first claim should succeed")
+	}
+	if ok, _ := s.Claim(ctx, "app1", "n1", time.Minute); ok {
+		t.Fatal("duplicate claim should fail")
+	}
+	if ok, _ := s.Claim(ctx, "app2", "n1", time.Minute); !ok {
+		t.Fatal("same nonce in another scope should succeed")
+	}
+
+	// 过期后可以再次使用，过期的记录被清理
+	now = now.Add(time.Minute)
+	if ok, _ := s.Claim(ctx, "app1", "n1", time.Minute); !ok {
+		t.Fatal("claim after expiry should succeed")
+	}
+	if n := s.Len(); n != 1 {
+		t.Fatalf("expired entries not cleaned, len = %d", n)
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(3)
+	for i := 0; i < 4; i++ {
+		if ok, _ := s.Claim(ctx, "app", fmt.Sprint(i), time.Hour); !ok {
+			t.Fatalf("claim %d failed", i)
+		}
+	}
+	if n := s.Len(); n != 3 {
+		t.Fatalf("len = %d, want 3", n)
+	}
+	// 最早的记录被淘汰，较新的仍然拒绝重复使用
+	if ok, _ := s.Claim(ctx, "app", "0", time.Hour); !ok {
+		t.Fatal("evicted nonce should be claimable")
+	}
+	if ok, _ := s.Claim(ctx, "app", "3", time.Hour); ok {
+		t.Fatal("recent nonce should still be rejected")
+	}
+}
+
+func TestMemoryStoreConcurrentClaim(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(100)
+	var wins int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := s.Claim(ctx, "app", "same", time.Minute); ok {
+				atomic.AddInt32(&wins, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Fatalf("%d concurrent claims succeeded, want 1", wins)
+	}
+}
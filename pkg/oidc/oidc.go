@@ -0,0 +1,383 @@
+// Package oidc 实现 OpenID Connect 与 OAuth2 授权码登录的客户端部分：
+// 发现文档、PKCE、授权码换取令牌、ID Token 验证与用户信息获取。
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 提供方类型
+const (
+	TypeOIDC   = "oidc"
+	TypeOAuth2 = "oauth2"
+)
+
+// 客户端认证方式
+const (
+	AuthMethodPost  = "client_secret_post"
+	AuthMethodBasic = "client_secret_basic"
+)
+
+const maxBodySize = 1 << 20
+
+var (
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+	ErrMissingSubject = errors.New("oidc: missing subject")
+)
+
+// Config 提供方配置；OIDC 提供方配置 Issuer 后其余端点可通过发现文档获取，显式配置的端点优先
+type Config struct {
+	Type         string // oidc | oauth2，默认 oidc
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	JWKSURL      string
+	RedirectURL  string
+	Scopes       []string // 默认 oidc 为 openid profile email
+	AuthMethod   string   // client_secret_post | client_secret_basic，默认 client_secret_post
+
+	// 用户信息中的字段名，默认 sub（OAuth2 提供方没有 sub 时取 id）、email、name、preferred_username
+	SubjectClaim  string
+	EmailClaim    string
+	NameClaim     string
+	UsernameClaim string
+}
+
+// Provider 一个已完成发现的身份提供方
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	keys   *keySet
+}
+
+// discovery OpenID Provider 发现文档中用到的字段
+type discovery struct {
+	Issuer      string `json:"issuer"`
+	AuthURL     string `json:"authorization_endpoint"`
+	TokenURL    string `json:"token_endpoint"`
+	UserInfoURL string `json:"userinfo_endpoint"`
+	JWKSURL     string `json:"jwks_uri"`
+}
+
+// New 创建提供方，OIDC 提供方会请求 Issuer 的发现文档；client 为 nil 时使用 10 秒超时的默认客户端
+func New(ctx context.Context, cfg Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.Type == "" {
+		cfg.Type = TypeOIDC
+	}
+	if cfg.AuthMethod == "" {
+		cfg.AuthMethod = AuthMethodPost
+	}
+	if cfg.ClientID == "" {
+		return nil, errors.New("oidc: client id is required")
+	}
+
+	switch cfg.Type {
+	case TypeOIDC:
+		if cfg.Issuer == "" {
+			return nil, errors.New("oidc: issuer is required")
+		}
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = []string{"openid", "profile", "email"}
+		}
+		if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.JWKSURL == "" {
+			d := &discovery{}
+			wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+			if err := getJSON(ctx, client, wellKnown, "", d); err != nil {
+				return nil, fmt.Errorf("oidc: discovery: %w", err)
+			}
+			// 发现文档中的 issuer 必须与配置一致，防止被替换为其他提供方
+			if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(cfg.Issuer, "/") {
+				return nil, fmt.Errorf("oidc: issuer mismatch: configured %q, discovered %q", cfg.Issuer, d.Issuer)
+			}
+			cfg.Issuer = d.Issuer
+			cfg.AuthURL = firstNonEmpty(cfg.AuthURL, d.AuthURL)
+			cfg.TokenURL = firstNonEmpty(cfg.TokenURL, d.TokenURL)
+			cfg.UserInfoURL = firstNonEmpty(cfg.UserInfoURL, d.UserInfoURL)
+			cfg.JWKSURL = firstNonEmpty(cfg.JWKSURL, d.JWKSURL)
+		}
+		if cfg.JWKSURL == "" {
+			return nil, errors.New("oidc: jwks uri is required")
+		}
+	case TypeOAuth2:
+		if cfg.UserInfoURL == "" {
+			return nil, errors.New("oidc: userinfo url is required for oauth2 provider")
+		}
+	default:
+		return nil, fmt.Errorf("oidc: unknown provider type %q", cfg.Type)
+	}
+	if cfg.AuthURL == "" || cfg.TokenURL == "" {
+		return nil, errors.New("oidc: authorization and token endpoints are required")
+	}
+
+	p := &Provider{cfg: cfg, client: client}
+	if cfg.JWKSURL != "" {
+		p.keys = newKeySet(client, cfg.JWKSURL)
+	}
+	return p, nil
+}
+
+// Config 返回补全端点后的配置
+func (p *Provider) Config() Config {
+	return p.cfg
+}
+
+// NewPKCE 生成 PKCE code_verifier 与 S256 code_challenge
+func NewPKCE() (verifier, challenge string) {
+	verifier = RandomString(32)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// RandomString 返回 n 字节随机数的 base64url 编码，用于 state、nonce
+func RandomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// AuthCodeURL 返回授权地址；nonce 只对 OIDC 提供方生效
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	if p.cfg.RedirectURL != "" {
+		q.Set("redirect_uri", p.cfg.RedirectURL)
+	}
+	if len(p.cfg.Scopes) > 0 {
+		q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	}
+	q.Set("state", state)
+	if nonce != "" && p.cfg.Type == TypeOIDC {
+		q.Set("nonce", nonce)
+	}
+	if codeChallenge != "" {
+		q.Set("code_challenge", codeChallenge)
+		q.Set("code_challenge_method", "S256")
+	}
+	sep := "?"
+	if strings.Contains(p.cfg.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.cfg.AuthURL + sep + q.Encode()
+}
+
+// Token 令牌端点的响应
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+	Scope        string `json:"scope"`
+}
+
+// TokenError 令牌端点返回的错误
+type TokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+	StatusCode  int    `json:"-"`
+}
+
+func (e *TokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oidc: token endpoint: %s: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("oidc: token endpoint: %s (status %d)", e.Code, e.StatusCode)
+}
+
+// Exchange 用授权码换取令牌，codeVerifier 为授权时生成的 PKCE verifier
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	if p.cfg.RedirectURL != "" {
+		form.Set("redirect_uri", p.cfg.RedirectURL)
+	}
+	if codeVerifier != "" {
+		form.Set("code_verifier", codeVerifier)
+	}
+	if p.cfg.AuthMethod != AuthMethodBasic {
+		form.Set("client_id", p.cfg.ClientID)
+		if p.cfg.ClientSecret != "" {
+			form.Set("client_secret", p.cfg.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.AuthMethod == AuthMethodBasic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token endpoint: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		te := &TokenError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(body, te) != nil || te.Code == "" {
+			te.Code = http.StatusText(resp.StatusCode)
+		}
+		return nil, te
+	}
+	tok := &Token{}
+	if err := json.Unmarshal(body, tok); err != nil {
+		return nil, fmt.Errorf("oidc: token endpoint: %w", err)
+	}
+	// 部分 OAuth2 提供方出错时仍返回 200
+	if tok.AccessToken == "" {
+		te := &TokenError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(body, te)
+		if te.Code == "" {
+			te.Code = "missing access_token"
+		}
+		return nil, te
+	}
+	return tok, nil
+}
+
+// UserInfo 请求用户信息端点
+func (p *Provider) UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	if p.cfg.UserInfoURL == "" {
+		return nil, errors.New("oidc: userinfo endpoint is not configured")
+	}
+	claims := map[string]interface{}{}
+	if err := getJSON(ctx, p.client, p.cfg.UserInfoURL, accessToken, &claims); err != nil {
+		return nil, fmt.Errorf("oidc: userinfo: %w", err)
+	}
+	return claims, nil
+}
+
+// Identity 外部身份
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+	Claims        map[string]interface{} // ID Token 与用户信息合并后的全部字段
+}
+
+// Identity 从令牌中取出外部身份：OIDC 提供方验证 ID Token（包括 nonce），配置了用户信息端点时补充缺少的字段；
+// OAuth2 提供方只使用用户信息端点
+func (p *Provider) Identity(ctx context.Context, tok *Token, nonce string) (*Identity, error) {
+	claims := map[string]interface{}{}
+	if p.cfg.Type == TypeOIDC {
+		if tok.IDToken == "" {
+			return nil, fmt.Errorf("%w: missing id_token", ErrInvalidIDToken)
+		}
+		c, err := p.VerifyIDToken(ctx, tok.IDToken, nonce)
+		if err != nil {
+			return nil, err
+		}
+		claims = c
+	}
+
+	if p.cfg.UserInfoURL != "" && (p.cfg.Type == TypeOAuth2 || claimString(claims, firstNonEmpty(p.cfg.EmailClaim, "email")) == "") {
+		info, err := p.UserInfo(ctx, tok.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		// 用户信息中的 sub 必须与 ID Token 一致
+		if p.cfg.Type == TypeOIDC && claimString(info, "sub") != claimString(claims, "sub") {
+			return nil, errors.New("oidc: userinfo subject does not match id token")
+		}
+		for k, v := range info {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	id := &Identity{Claims: claims}
+	if p.cfg.SubjectClaim != "" {
+		id.Subject = claimString(claims, p.cfg.SubjectClaim)
+	} else {
+		id.Subject = firstNonEmpty(claimString(claims, "sub"), claimString(claims, "id"))
+	}
+	if id.Subject == "" {
+		return nil, ErrMissingSubject
+	}
+	id.Email = claimString(claims, firstNonEmpty(p.cfg.EmailClaim, "email"))
+	id.Name = claimString(claims, firstNonEmpty(p.cfg.NameClaim, "name"))
+	id.Username = claimString(claims, firstNonEmpty(p.cfg.UsernameClaim, "preferred_username"))
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		id.EmailVerified = v
+	case string:
+		id.EmailVerified = v == "true"
+	}
+	return id, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, u, bearer string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, u)
+	}
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize))
+	dec.UseNumber()
+	return dec.Decode(out)
+}
+
+// claimString 取字符串字段，数字（如 GitHub 的用户 id）按原样转为字符串
+func claimString(claims map[string]interface{}, key string) string {
+	switch v := claims[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%f", v), "0"), ".")
+	}
+	return ""
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,234 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// stubProvider 本地模拟的 OIDC 提供方：发现文档、JWKS、令牌端点（校验 PKCE）与用户信息端点
+type stubProvider struct {
+	srv       *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string // 授权时提交的 code_challenge
+	nonce     string
+	claims    jwt.MapClaims // 覆盖 ID Token 中的声明
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.srv.URL,
+			"authorization_endpoint": s.srv.URL + "/authorize",
+			"token_endpoint":         s.srv.URL + "/token",
+			"userinfo_endpoint":      s.srv.URL + "/userinfo",
+			"jwks_uri":               s.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != s.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "at-123",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     s.idToken(t),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at-123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// 数字 id 与 GitHub 等 OAuth2 提供方一致
+		w.Write([]byte(`{"sub":"user-1","id":12345678901,"login":"alice","email":"alice@example.com"}`))
+	})
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *stubProvider) idToken(t *testing.T) string {
+	claims := jwt.MapClaims{
+		"iss":            s.srv.URL,
+		"aud":            "client-1",
+		"sub":            "user-1",
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          s.nonce,
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+	}
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "k1"
+	raw, err := tok.SignedString(s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// authorize 模拟浏览器跳转到授权地址，记录 PKCE challenge 与 nonce
+func (s *stubProvider) authorize(t *testing.T, authURL string) {
+	u, err := url.Parse(authURL)
+	if err != nil || !strings.HasPrefix(authURL, s.srv.URL+"/authorize?") {
+		t.Fatalf("unexpected auth url %s", authURL)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "client-1" || q.Get("response_type") != "code" {
+		t.Fatalf("unexpected auth url %s", authURL)
+	}
+	s.challenge = q.Get("code_challenge")
+	s.nonce = q.Get("nonce")
+}
+
+func TestOIDCFlow(t *testing.T) {
+	stub := newStubProvider(t)
+	ctx := context.Background()
+	p, err := New(ctx, Config{Issuer: stub.srv.URL, ClientID: "client-1", ClientSecret: "secret", RedirectURL: "https://app.example.com/cb"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, challenge := NewPKCE()
+	nonce := RandomString(16)
+	stub.authorize(t, p.AuthCodeURL("state-1", nonce, challenge))
+
+	if _, err := p.Exchange(ctx, "good-code", "wrong-verifier"); err == nil {
+		t.Fatal("exchange with wrong pkce verifier succeeded")
+	} else if te := (*TokenError)(nil); !errors.As(err, &te) || te.Code != "invalid_grant" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tok, err := p.Exchange(ctx, "good-code", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := p.Identity(ctx, tok, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "user-1" || id.Email != "alice@example.com" || !id.EmailVerified || id.Name != "Alice" {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+
+	if _, err := p.Identity(ctx, tok, "other-nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("nonce mismatch accepted: %v", err)
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	stub := newStubProvider(t)
+	ctx := context.Background()
+	p, err := New(ctx, Config{Issuer: stub.srv.URL, ClientID: "client-1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]jwt.MapClaims{
+		"audience": {"aud": "other-client"},
+		"issuer":   {"iss": "https://evil.example.com"},
+		"expired":  {"exp": time.Now().Add(-time.Minute).Unix()},
+		"azp":      {"aud": []string{"client-1", "other"}, "azp": "other"},
+	}
+	for name, c := range cases {
+		stub.claims = c
+		if _, err := p.VerifyIDToken(ctx, stub.idToken(t), ""); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+
+	// 使用其他密钥签名
+	stub.claims = nil
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	stub.key, other = other, stub.key
+	forged := stub.idToken(t)
+	stub.key = other
+	if _, err := p.VerifyIDToken(ctx, forged, ""); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("forged signature: got %v", err)
+	}
+	// HMAC 签名（以公钥作为密钥的算法混淆）不被接受
+	hs, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": stub.srv.URL, "aud": "client-1", "sub": "x", "exp": time.Now().Add(time.Minute).Unix()}).SignedString([]byte("k"))
+	if _, err := p.VerifyIDToken(ctx, hs, ""); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("hs256: got %v", err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	stub := newStubProvider(t)
+	if _, err := New(context.Background(), Config{Issuer: stub.srv.URL + "/other", ClientID: "client-1",
+		AuthURL: stub.srv.URL + "/authorize"}, nil); err == nil {
+		t.Fatal("discovery with mismatched issuer succeeded")
+	}
+}
+
+func TestOAuth2UserInfo(t *testing.T) {
+	stub := newStubProvider(t)
+	ctx := context.Background()
+	p, err := New(ctx, Config{
+		Type:          TypeOAuth2,
+		ClientID:      "client-1",
+		ClientSecret:  "secret",
+		AuthURL:       stub.srv.URL + "/authorize",
+		TokenURL:      stub.srv.URL + "/token",
+		UserInfoURL:   stub.srv.URL + "/userinfo",
+		Scopes:        []string{"read:user"},
+		AuthMethod:    AuthMethodBasic,
+		SubjectClaim:  "id",
+		UsernameClaim: "login",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, challenge := NewPKCE()
+	authURL := p.AuthCodeURL("state-1", "ignored", challenge)
+	stub.authorize(t, authURL)
+	if stub.nonce != "" {
+		t.Fatal("nonce sent to oauth2 provider")
+	}
+	tok, err := p.Exchange(ctx, "good-code", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := p.Identity(ctx, tok, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "12345678901" || id.Username != "alice" || id.Email != "alice@example.com" || id.EmailVerified {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+}
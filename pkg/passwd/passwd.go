@@ -0,0 +1,143 @@
+// Package passwd 提供密码哈希、验证与强度策略。
+//
+// 新哈希使用 argon2id（默认）或 bcrypt，参数与盐编码在哈希字符串中：
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+//	$2a$12$...
+//
+// 验证同时识别旧版本的 HMAC-SHA256 格式（以 PasswdKey 为密钥），
+// 旧格式或参数低于当前配置的哈希在验证成功后应重新哈希。
+package passwd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 哈希算法
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+// Params 哈希参数，零值字段使用默认值
+type Params struct {
+	Algorithm  string // argon2id | bcrypt，默认 argon2id
+	Time       uint32 // argon2id 迭代次数，默认 3
+	Memory     uint32 // argon2id 内存（KiB），默认 65536
+	Threads    uint8  // argon2id 并行度，默认 2
+	BcryptCost int    // bcrypt cost，默认 12
+}
+
+const (
+	saltLen = 16
+	keyLen  = 32
+)
+
+func (p Params) withDefaults() Params {
+	if p.Algorithm == "" {
+		p.Algorithm = Argon2id
+	}
+	if p.Time == 0 {
+		p.Time = 3
+	}
+	if p.Memory == 0 {
+		p.Memory = 64 * 1024
+	}
+	if p.Threads == 0 {
+		p.Threads = 2
+	}
+	if p.BcryptCost == 0 {
+		p.BcryptCost = 12
+	}
+	return p
+}
+
+// Hash 按参数哈希密码，返回自描述的哈希字符串
+func Hash(password string, p Params) (string, error) {
+	p = p.withDefaults()
+	switch p.Algorithm {
+	case Argon2id:
+		salt := make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, keyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Time, p.Threads,
+			b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+	case Bcrypt:
+		b, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		return string(b), err
+	}
+	return "", fmt.Errorf("passwd: unsupported algorithm %q", p.Algorithm)
+}
+
+var b64 = base64.RawStdEncoding
+
+// Verify 验证密码；legacyKey 用于验证旧版本的 HMAC 哈希。
+// rehash 为 true 表示验证成功但哈希为旧格式或参数与 p 不一致，应使用 Hash 重新生成
+func Verify(password, encoded string, p Params, legacyKey string) (ok, rehash bool) {
+	p = p.withDefaults()
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		hp, salt, key, err := decodeArgon2(encoded)
+		if err != nil {
+			return false, false
+		}
+		got := argon2.IDKey([]byte(password), salt, hp.Time, hp.Memory, hp.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, false
+		}
+		return true, p.Algorithm != Argon2id || hp.Time != p.Time || hp.Memory != p.Memory || hp.Threads != p.Threads
+	case strings.HasPrefix(encoded, "$2"):
+		if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) != nil {
+			return false, false
+		}
+		cost, _ := bcrypt.Cost([]byte(encoded))
+		return true, p.Algorithm != Bcrypt || cost != p.BcryptCost
+	case encoded != "" && legacyKey != "":
+		h := hmac.New(sha256.New, []byte(legacyKey))
+		h.Write([]byte(password))
+		expected := base64.StdEncoding.EncodeToString(h.Sum(nil))
+		if !hmac.Equal([]byte(encoded), []byte(expected)) {
+			return false, false
+		}
+		return true, true
+	}
+	return false, false
+}
+
+func decodeArgon2(encoded string) (p Params, salt, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errors.New("passwd: invalid argon2id hash")
+	}
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errors.New("passwd: unsupported argon2 version")
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, err
+	}
+	if p.Time == 0 || p.Threads == 0 {
+		return p, nil, nil, errors.New("passwd: invalid argon2id params")
+	}
+	if salt, err = b64.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, err
+	}
+	if key, err = b64.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, errors.New("passwd: invalid argon2id key")
+	}
+	p.Algorithm = Argon2id
+	return p, salt, key, nil
+}
@@ -0,0 +1,91 @@
+package passwd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 测试使用较低的参数以加快速度
+var fast = Params{Time: 1, Memory: 1024, Threads: 1, BcryptCost: 4}
+
+func TestHashVerify(t *testing.T) {
+	for _, alg := range []string{Argon2id, Bcrypt} {
+		p := fast
+		p.Algorithm = alg
+		h, err := Hash("S3cret-pass", p)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if ok, rehash := Verify("S3cret-pass", h, p, ""); !ok || rehash {
+			t.Fatalf("%s: ok=%v rehash=%v", alg, ok, rehash)
+		}
+		if ok, _ := Verify("wrong", h, p, ""); ok {
+			t.Fatalf("%s: wrong password verified", alg)
+		}
+	}
+
+	// 盐随机，同一密码两次哈希不同
+	a, _ := Hash("S3cret-pass", fast)
+	b, _ := Hash("S3cret-pass", fast)
+	if a == b || !strings.HasPrefix(a, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected hashes %q %q", a, b)
+	}
+}
+
+func TestRehash(t *testing.T) {
+	h, _ := Hash("S3cret-pass", fast)
+	stronger := fast
+	stronger.Time = 2
+	if ok, rehash := Verify("S3cret-pass", h, stronger, ""); !ok || !rehash {
+		t.Fatalf("param change should require rehash: ok=%v rehash=%v", ok, rehash)
+	}
+	toBcrypt := fast
+	toBcrypt.Algorithm = Bcrypt
+	if ok, rehash := Verify("S3cret-pass", h, toBcrypt, ""); !ok || !rehash {
+		t.Fatalf("algorithm change should require rehash: ok=%v rehash=%v", ok, rehash)
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("123456"))
+	legacy := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	if ok, rehash := Verify("123456", legacy, fast, "key"); !ok || !rehash {
+		t.Fatalf("legacy hash: ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, _ := Verify("123456", legacy, fast, "other"); ok {
+		t.Fatal("legacy hash verified with wrong key")
+	}
+	if ok, _ := Verify("", "", fast, "key"); ok {
+		t.Fatal("empty hash must not verify")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	var p Policy
+	cases := map[string]bool{
+		"short1":           false,
+		"alllowercase":     false,
+		"Tiger-lily9":      true,
+		"password1":        false, // 常见密码
+		"alice-2024x":      false, // 包含用户名
+		"correct horse 42": true,
+	}
+	for pw, want := range cases {
+		err := p.Check(pw, "alice", "bob@example.com")
+		if (err == nil) != want {
+			t.Errorf("%q: got %v, want ok=%v", pw, err, want)
+		}
+		if err != nil && !errors.Is(err, ErrWeakPassword) {
+			t.Errorf("%q: error should wrap ErrWeakPassword", pw)
+		}
+	}
+	if err := (Policy{Blocklist: []string{"Company2024"}}).Check("company2024"); err == nil {
+		t.Error("blocklisted password accepted")
+	}
+}
@@ -0,0 +1,90 @@
+package passwd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrWeakPassword 密码不满足策略，具体原因见 PolicyError
+var ErrWeakPassword = errors.New("weak password")
+
+// PolicyError 密码不满足策略的原因
+type PolicyError struct {
+	Reason string
+}
+
+func (e *PolicyError) Error() string { return e.Reason }
+
+func (e *PolicyError) Unwrap() error { return ErrWeakPassword }
+
+// Policy 密码强度策略，零值字段使用默认值
+type Policy struct {
+	MinLength  int      // 最小长度，默认 8
+	MaxLength  int      // 最大长度，默认 128
+	MinClasses int      // 至少包含的字符类别数（小写、大写、数字、符号），默认 2
+	Blocklist  []string // 额外禁止使用的密码，不区分大小写
+}
+
+// commonPasswords 常见弱密码
+var commonPasswords = []string{
+	"123456", "12345678", "123456789", "1234567890", "111111", "000000", "123123",
+	"password", "password1", "passw0rd", "qwerty", "qwerty123", "abc123", "abcd1234",
+	"admin", "admin123", "root", "letmein", "welcome", "iloveyou", "1q2w3e4r", "a123456",
+}
+
+// Check 检查密码是否满足策略；userInputs 为用户名、邮箱等，密码不能与之相同或包含它们
+func (p Policy) Check(password string, userInputs ...string) error {
+	if p.MinLength <= 0 {
+		p.MinLength = 8
+	}
+	if p.MaxLength <= 0 {
+		p.MaxLength = 128
+	}
+	if p.MinClasses <= 0 {
+		p.MinClasses = 2
+	}
+
+	n := len([]rune(password))
+	if n < p.MinLength {
+		return &PolicyError{Reason: fmt.Sprintf("密码长度不能少于 %d 位", p.MinLength)}
+	}
+	if n > p.MaxLength {
+		return &PolicyError{Reason: fmt.Sprintf("密码长度不能超过 %d 位", p.MaxLength)}
+	}
+
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	if lower+upper+digit+symbol < p.MinClasses {
+		return &PolicyError{Reason: fmt.Sprintf("密码需包含小写字母、大写字母、数字、符号中的至少 %d 类", p.MinClasses)}
+	}
+
+	lp := strings.ToLower(password)
+	for _, list := range [][]string{commonPasswords, p.Blocklist} {
+		for _, w := range list {
+			if lp == strings.ToLower(w) {
+				return &PolicyError{Reason: "密码过于常见"}
+			}
+		}
+	}
+	for _, in := range userInputs {
+		// 邮箱只比较 @ 之前的部分
+		in, _, _ = strings.Cut(strings.ToLower(in), "@")
+		if len(in) >= 3 && strings.Contains(lp, in) {
+			return &PolicyError{Reason: "密码不能包含用户名或邮箱"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package redact
+
+import (
+	"net/http"
+
+	"github.com/luxingwen/sgin/pkg/logger"
+)
+
+// SensitiveHeaders 记录或展示请求头时整体替换的头
+var SensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Token",
+	"X-Signature",
+	logger.DebugTokenHeader,
+}
+
+const maskedHeader = "*****"
+
+// Header 返回 h 的副本，SensitiveHeaders 中的头替换为 *****
+func Header(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range SensitiveHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, maskedHeader)
+		}
+	}
+	return out
+}
@@ -0,0 +1,308 @@
+// Package redact 对请求/响应体中的敏感字段脱敏，用于日志记录。
+//
+// 规则按字段名或 JSON 路径匹配，支持 JSON 与 application/x-www-form-urlencoded 两种格式；
+// 无法完整解析的内容（如被截断的 JSON）按字段名做尽力而为的替换。
+package redact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/luxingwen/sgin/pkg/config"
+	"github.com/luxingwen/sgin/pkg/ddm"
+)
+
+// 掩码方式，除此之外还可以使用 pkg/ddm 的格式名称（mobile、email 等）
+const (
+	StrategyFull   = "full"   // 替换为 ******
+	StrategyRemove = "remove" // 删除字段
+	StrategyHash   = "hash"   // 替换为 sha256 摘要前缀，便于关联同一值
+	StrategyNone   = "none"   // 不脱敏，用于豁免内置规则
+)
+
+const masked = "******"
+
+// DefaultRules 内置脱敏规则
+var DefaultRules = []config.RedactRule{
+	{Pattern: "*password*", Strategy: StrategyFull},
+	{Pattern: "*passwd*", Strategy: StrategyFull},
+	{Pattern: "*token*", Strategy: StrategyFull},
+	{Pattern: "*secret*", Strategy: StrategyFull},
+	{Pattern: "sec_key", Strategy: StrategyFull},
+	{Pattern: "seckey", Strategy: StrategyFull},
+	{Pattern: "authorization", Strategy: StrategyFull},
+	{Pattern: "id_card", Strategy: ddm.FormatIDCard},
+	{Pattern: "idcard", Strategy: ddm.FormatIDCard},
+	{Pattern: "bank_card", Strategy: ddm.FormatBankCard},
+	{Pattern: "phone", Strategy: ddm.FormatMobile},
+	{Pattern: "mobile", Strategy: ddm.FormatMobile},
+	{Pattern: "email", Strategy: ddm.FormatEmail},
+}
+
+type rule struct {
+	segs     []string // 小写的路径段
+	anywhere bool     // 单段规则匹配任意层级
+	strategy string
+}
+
+// Redactor 按规则脱敏，创建后只读，可并发使用
+type Redactor struct {
+	rules []rule
+	// loose 匹配 "key": value 形式，用于无法解析的 JSON
+	loose *regexp.Regexp
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRedactor *Redactor
+)
+
+// Default 返回只包含内置规则的 Redactor
+func Default() *Redactor {
+	defaultOnce.Do(func() { defaultRedactor = New(nil) })
+	return defaultRedactor
+}
+
+// New 创建 Redactor。rules 优先于内置规则匹配，Pattern 与内置规则相同时替换该内置规则；
+// 非法的 Pattern 会被忽略
+func New(rules []config.RedactRule) *Redactor {
+	r := &Redactor{}
+	seen := make(map[string]bool)
+	var looseKeys []string
+	for _, list := range [][]config.RedactRule{rules, DefaultRules} {
+		for _, cr := range list {
+			pattern := strings.ToLower(strings.TrimSpace(cr.Pattern))
+			if pattern == "" || seen[pattern] {
+				continue
+			}
+			seen[pattern] = true
+
+			segs := strings.Split(pattern, ".")
+			if !validSegments(segs) {
+				continue
+			}
+			strategy := strings.ToLower(strings.TrimSpace(cr.Strategy))
+			if strategy == "" {
+				strategy = StrategyFull
+			}
+			r.rules = append(r.rules, rule{segs: segs, anywhere: len(segs) == 1, strategy: strategy})
+			if strategy != StrategyNone {
+				looseKeys = append(looseKeys, globToRegexp(segs[len(segs)-1]))
+			}
+		}
+	}
+	if len(looseKeys) > 0 {
+		r.loose = regexp.MustCompile(`(?i)"(` + strings.Join(looseKeys, "|") + `)"(\s*:\s*)("(?:[^"\\]|\\.)*"?|[-+.\w]+)`)
+	}
+	return r
+}
+
+// Body 按 Content-Type 对请求/响应体脱敏。contentType 为空时根据内容猜测格式，其他类型原样返回
+func (r *Redactor) Body(contentType string, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	ct := strings.ToLower(contentType)
+	switch {
+	case strings.Contains(ct, "json"):
+		return r.JSON(body)
+	case strings.Contains(ct, "application/x-www-form-urlencoded"):
+		return []byte(r.Form(string(body)))
+	case ct == "":
+		trimmed := bytes.TrimSpace(body)
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			return r.JSON(body)
+		}
+		if bytes.IndexByte(body, '=') > 0 {
+			return []byte(r.Form(string(body)))
+		}
+	}
+	return body
+}
+
+// JSON 对 JSON 内容脱敏，解析失败时按字段名做尽力而为的替换
+func (r *Redactor) JSON(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return r.looseJSON(body)
+	}
+	v = r.walk(v, nil)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return r.looseJSON(body)
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+// Form 对 application/x-www-form-urlencoded 内容脱敏，保持参数顺序；
+// user[password] 形式的参数名按路径 user.password 匹配
+func (r *Redactor) Form(body string) string {
+	if body == "" {
+		return body
+	}
+	pairs := strings.Split(body, "&")
+	out := pairs[:0]
+	for _, pair := range pairs {
+		rawKey, rawVal, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		ru := r.match(formPath(key))
+		if ru == nil || ru.strategy == StrategyNone {
+			out = append(out, pair)
+			continue
+		}
+		if ru.strategy == StrategyRemove {
+			continue
+		}
+		val, err := url.QueryUnescape(rawVal)
+		if err != nil {
+			val = rawVal
+		}
+		out = append(out, rawKey+"="+url.QueryEscape(ru.maskString(val)))
+	}
+	return strings.Join(out, "&")
+}
+
+func (r *Redactor) walk(v interface{}, parent []string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			p := make([]string, len(parent)+1)
+			copy(p, parent)
+			p[len(parent)] = k
+
+			ru := r.match(p)
+			switch {
+			case ru == nil || ru.strategy == StrategyNone:
+				t[k] = r.walk(child, p)
+			case ru.strategy == StrategyRemove:
+				delete(t, k)
+			default:
+				t[k] = ru.mask(child)
+			}
+		}
+	case []interface{}:
+		// 数组下标不计入路径
+		for i := range t {
+			t[i] = r.walk(t[i], parent)
+		}
+	}
+	return v
+}
+
+// match 返回第一个匹配 p 的规则
+func (r *Redactor) match(p []string) *rule {
+	if len(p) == 0 {
+		return nil
+	}
+	for i := range r.rules {
+		ru := &r.rules[i]
+		if ru.anywhere {
+			if segMatch(ru.segs[0], p[len(p)-1]) {
+				return ru
+			}
+			continue
+		}
+		if len(ru.segs) != len(p) {
+			continue
+		}
+		ok := true
+		for j, seg := range ru.segs {
+			if !segMatch(seg, p[j]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return ru
+		}
+	}
+	return nil
+}
+
+func (r *Redactor) looseJSON(body []byte) []byte {
+	if r.loose == nil {
+		return body
+	}
+	return r.loose.ReplaceAll(body, []byte(`"$1"$2"`+masked+`"`))
+}
+
+func (ru *rule) mask(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return ru.maskString(t)
+	case json.Number:
+		return ru.maskString(t.String())
+	case nil, bool:
+		return t
+	default:
+		// 对象与数组整体替换
+		return masked
+	}
+}
+
+func (ru *rule) maskString(s string) string {
+	if s == "" {
+		return s
+	}
+	switch ru.strategy {
+	case StrategyFull, StrategyRemove:
+		return masked
+	case StrategyHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	// ddm 格式不适用（如长度不符）时原样返回，此时整体替换以免泄露
+	if v, ok := ddm.MaskString(ru.strategy, s); ok && v != s {
+		return v
+	}
+	return masked
+}
+
+func segMatch(pattern, name string) bool {
+	ok, _ := path.Match(pattern, strings.ToLower(name))
+	return ok
+}
+
+func validSegments(segs []string) bool {
+	for _, seg := range segs {
+		if seg == "" {
+			return false
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// formPath 将 a[b][c] 形式的参数名拆分为路径
+func formPath(key string) []string {
+	if !strings.Contains(key, "[") {
+		return []string{key}
+	}
+	f := func(r rune) bool { return r == '[' || r == ']' }
+	return strings.FieldsFunc(key, f)
+}
+
+// globToRegexp 将路径段的通配符转换为正则，用于匹配 JSON 字段名
+func globToRegexp(seg string) string {
+	s := regexp.QuoteMeta(seg)
+	s = strings.ReplaceAll(s, `\*`, `[^"]*`)
+	s = strings.ReplaceAll(s, `\?`, `[^"]`)
+	return s
+}
@@ -0,0 +1,111 @@
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/luxingwen/sgin/pkg/config"
+)
+
+func TestJSON(t *testing.T) {
+	r := New([]config.RedactRule{
+		{Pattern: "*.token", Strategy: StrategyHash},
+		{Pattern: "data.list.nickname", Strategy: "id_name"},
+		{Pattern: "trace", Strategy: StrategyRemove},
+		{Pattern: "token_type", Strategy: StrategyNone},
+	})
+	in := `{"username":"admin","password":"123456","trace":"x","token_type":"bearer",
+		"data":{"token":"abc","list":[{"nickname":"李鸿章","phone":"13288887986","id_card":"125252525252525252"}]},
+		"extra":{"secret":{"a":1},"amount":12.50}}`
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(r.JSON([]byte(in)), &got); err != nil {
+		t.Fatal(err)
+	}
+	data := got["data"].(map[string]interface{})
+	item := data["list"].([]interface{})[0].(map[string]interface{})
+	extra := got["extra"].(map[string]interface{})
+
+	checks := map[string]interface{}{
+		"username":   got["username"],
+		"password":   got["password"],
+		"token_type": got["token_type"],
+		"nickname":   item["nickname"],
+		"phone":      item["phone"],
+		"id_card":    item["id_card"],
+		"secret":     extra["secret"],
+		"amount":     extra["amount"],
+	}
+	want := map[string]interface{}{
+		"username":   "admin",
+		"password":   "******",
+		"token_type": "bearer",
+		"nickname":   "*鸿章",
+		"phone":      "132****7986",
+		"id_card":    "1******2",
+		"secret":     "******",
+		"amount":     12.5,
+	}
+	for k, v := range want {
+		if checks[k] != v {
+			t.Errorf("%s: got %v, want %v", k, checks[k], v)
+		}
+	}
+	if _, ok := got["trace"]; ok {
+		t.Error("expected trace to be removed")
+	}
+	if tok, _ := data["token"].(string); !strings.HasPrefix(tok, "sha256:") {
+		t.Errorf("expected hashed token, got %v", data["token"])
+	}
+}
+
+func TestTruncatedJSON(t *testing.T) {
+	out := string(Default().JSON([]byte(`{"user":"a","Password": "p\"w","token":123,"list":[{"email":"a@b.c`)))
+	if strings.Contains(out, `p\"w`) || strings.Contains(out, "123") || strings.Contains(out, "a@b.c") {
+		t.Fatalf("sensitive values leaked: %s", out)
+	}
+	if !strings.Contains(out, `"user":"a"`) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestForm(t *testing.T) {
+	out := Default().Form("username=admin&password=p%40ss&user%5Bmobile%5D=13288887986&x=1")
+	want := "username=admin&password=%2A%2A%2A%2A%2A%2A&user%5Bmobile%5D=132%2A%2A%2A%2A7986&x=1"
+	if out != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+}
+
+func TestBody(t *testing.T) {
+	r := Default()
+	if got := string(r.Body("application/json; charset=utf-8", []byte(`{"password":"x"}`))); got != `{"password":"******"}` {
+		t.Fatalf("json body: %s", got)
+	}
+	if got := string(r.Body("", []byte(`password=x`))); got != `password=%2A%2A%2A%2A%2A%2A` {
+		t.Fatalf("guessed form body: %s", got)
+	}
+	if got := string(r.Body("text/plain", []byte(`password=x`))); got != `password=x` {
+		t.Fatalf("plain body should be untouched: %s", got)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Set("X-Debug-Token", "tok")
+	h.Set("Content-Type", "application/json")
+
+	out := Header(h)
+	if out.Get("Authorization") != "*****" || out.Get("X-Debug-Token") != "*****" {
+		t.Fatalf("sensitive headers not masked: %v", out)
+	}
+	if out.Get("Content-Type") != "application/json" {
+		t.Fatalf("content-type changed: %v", out)
+	}
+	if h.Get("Authorization") != "Bearer abc" {
+		t.Fatal("source header modified")
+	}
+}
@@ -3,6 +3,7 @@ package redisop
 import (
 	"context"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -52,6 +53,30 @@ func (c *RedisClient) HGet(ctx context.Context, key, field string) (string, erro
 	return c.standaloneClient.HGet(ctx, key, field).Result()
 }
 
+// hash get all
+func (c *RedisClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	if c.isCluster {
+		return c.clusterClient.HGetAll(ctx, key).Result()
+	}
+	return c.standaloneClient.HGetAll(ctx, key).Result()
+}
+
+// hash delete
+func (c *RedisClient) HDel(ctx context.Context, key string, fields ...string) error {
+	if c.isCluster {
+		return c.clusterClient.HDel(ctx, key, fields...).Err()
+	}
+	return c.standaloneClient.HDel(ctx, key, fields...).Err()
+}
+
+// hash increment
+func (c *RedisClient) HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error) {
+	if c.isCluster {
+		return c.clusterClient.HIncrBy(ctx, key, field, incr).Result()
+	}
+	return c.standaloneClient.HIncrBy(ctx, key, field, incr).Result()
+}
+
 func (c *RedisClient) Set(ctx context.Context, key, value string, expiration time.Duration) error {
 	if c.isCluster {
 		return c.clusterClient.Set(ctx, key, value, expiration).Err()
@@ -105,6 +130,59 @@ func (c *RedisClient) Incr(ctx context.Context, key string) (int64, error) {
 	return c.standaloneClient.Incr(ctx, key).Result()
 }
 
+// incrExpireScript 自增并在 key 没有过期时间时设置过期时间，同一脚本内执行，不会留下永不过期的计数
+var incrExpireScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n`)
+
+// IncrWithExpire atomically increments key and sets its expiration when the key has none,
+// used for fixed-window counters.
+func (c *RedisClient) IncrWithExpire(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	ms := expiration.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+	if c.isCluster {
+		return incrExpireScript.Run(ctx, c.clusterClient, []string{key}, ms).Int64()
+	}
+	return incrExpireScript.Run(ctx, c.standaloneClient, []string{key}, ms).Int64()
+}
+
+// hCompareAndSetScript 字段 ARGV[1] 的当前值等于 ARGV[2] 时才写入 ARGV[3]，字段不存在时不写入
+var hCompareAndSetScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+	return 1
+end
+return 0`)
+
+// HCompareAndSet sets field to value only if its current value equals old,
+// it never creates the field. Reports whether the field was updated.
+func (c *RedisClient) HCompareAndSet(ctx context.Context, key, field, old, value string) (bool, error) {
+	var (
+		n   int64
+		err error
+	)
+	if c.isCluster {
+		n, err = hCompareAndSetScript.Run(ctx, c.clusterClient, []string{key}, field, old, value).Int64()
+	} else {
+		n, err = hCompareAndSetScript.Run(ctx, c.standaloneClient, []string{key}, field, old, value).Int64()
+	}
+	return n == 1, err
+}
+
+// RunScript runs a Lua script on the standalone or cluster client. In cluster mode
+// all keys must hash to the same slot.
+func (c *RedisClient) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) *redis.Cmd {
+	if c.isCluster {
+		return script.Run(ctx, c.clusterClient, keys, args...)
+	}
+	return script.Run(ctx, c.standaloneClient, keys, args...)
+}
+
 // Expire sets a timeout on key.
 func (c *RedisClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
 	if c.isCluster {
@@ -113,6 +191,14 @@ func (c *RedisClient) Expire(ctx context.Context, key string, expiration time.Du
 	return c.standaloneClient.Expire(ctx, key, expiration).Err()
 }
 
+// SetNX sets key to value only if key does not exist and reports whether it was set.
+func (c *RedisClient) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	if c.isCluster {
+		return c.clusterClient.SetNX(ctx, key, value, expiration).Result()
+	}
+	return c.standaloneClient.SetNX(ctx, key, value, expiration).Result()
+}
+
 // Exists reports whether key exists.
 func (c *RedisClient) Exists(ctx context.Context, key string) (bool, error) {
 	var n int64
@@ -125,6 +211,43 @@ func (c *RedisClient) Exists(ctx context.Context, key string) (bool, error) {
 	return n > 0, err
 }
 
+// TTL returns the remaining time to live of key; negative values follow redis semantics.
+func (c *RedisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.isCluster {
+		return c.clusterClient.TTL(ctx, key).Result()
+	}
+	return c.standaloneClient.TTL(ctx, key).Result()
+}
+
+// Scan returns all keys matching pattern, iterating every master node in cluster mode.
+func (c *RedisClient) Scan(ctx context.Context, match string) ([]string, error) {
+	scan := func(ctx context.Context, client *redis.Client) ([]string, error) {
+		var keys []string
+		iter := client.Scan(ctx, 0, match, 100).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		return keys, iter.Err()
+	}
+	if !c.isCluster {
+		return scan(ctx, c.standaloneClient)
+	}
+
+	var mu sync.Mutex
+	var keys []string
+	err := c.clusterClient.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		k, err := scan(ctx, client)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		keys = append(keys, k...)
+		mu.Unlock()
+		return nil
+	})
+	return keys, err
+}
+
 func (c *RedisClient) Close() error {
 	if c.isCluster {
 		return c.clusterClient.Close()
@@ -0,0 +1,232 @@
+// Package sign 实现应用请求签名。服务端中间件与 Go 客户端共用同一套规范化规则：
+//
+//	v1：HMAC-SHA256(SecKey, 请求体)，只为兼容旧版本客户端保留
+//	v2：HMAC-SHA256(SecKey, 规范请求)，规范请求依次为算法、方法、路径、排序后的查询参数、
+//	    参与签名的请求头（固定包含 x-app-id、x-nonce、x-timestamp）、请求头名称列表与请求体 SHA-256
+package sign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 签名相关的请求头
+const (
+	HeaderAppID         = "X-App-Id"
+	HeaderTimestamp     = "X-Timestamp"
+	HeaderNonce         = "X-Nonce"
+	HeaderSignature     = "X-Signature"
+	HeaderVersion       = "X-Signature-Version"
+	HeaderSignedHeaders = "X-Signed-Headers"
+)
+
+// V2 签名版本，X-Signature-Version 必须为该值
+const V2 = "v2"
+
+// AlgorithmV2 v2 规范请求的第一行
+const AlgorithmV2 = "SGIN-HMAC-SHA256-V2"
+
+var (
+	ErrMissingHeader     = errors.New("missing signature header")
+	ErrUnsupported       = errors.New("unsupported signature version")
+	ErrSignatureMismatch = errors.New("signature mismatch")
+)
+
+// mandatoryHeaders v2 必须参与签名的请求头
+var mandatoryHeaders = []string{"x-app-id", "x-nonce", "x-timestamp"}
+
+// DefaultHeaders 客户端默认额外签名的请求头
+var DefaultHeaders = []string{"host", "content-type"}
+
+// BodyHash 返回请求体 SHA-256 的十六进制摘要
+func BodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Compute 返回 HMAC-SHA256(secret, data) 的十六进制值
+func Compute(secret string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Equal 以常量时间比较两个签名
+func Equal(a, b string) bool {
+	return hmac.Equal([]byte(a), []byte(b))
+}
+
+// NormalizeHeaders 将请求头名称转为小写、去重排序，并补充必须签名的请求头
+func NormalizeHeaders(names []string) []string {
+	seen := map[string]bool{}
+	list := []string{}
+	for _, n := range append(append([]string{}, mandatoryHeaders...), names...) {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n != "" && !seen[n] {
+			seen[n] = true
+			list = append(list, n)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// ParseSignedHeaders 解析 X-Signed-Headers（分号分隔）
+func ParseSignedHeaders(v string) []string {
+	return NormalizeHeaders(strings.Split(v, ";"))
+}
+
+func headerValue(r *http.Request, name string) string {
+	if name == "host" {
+		if r.Host != "" {
+			return r.Host
+		}
+		return r.URL.Host
+	}
+	values := r.Header.Values(name)
+	for i := range values {
+		values[i] = strings.Join(strings.Fields(values[i]), " ")
+	}
+	return strings.Join(values, ",")
+}
+
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// canonicalQuery 参数名与值均按字典序排序后编码
+func canonicalQuery(rawQuery string) string {
+	values, _ := url.ParseQuery(rawQuery)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, k := range keys {
+		vs := append([]string{}, values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// Canonical 返回 v2 规范请求，signedHeaders 须经过 NormalizeHeaders
+func Canonical(r *http.Request, signedHeaders []string, bodyHash string) string {
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	var b strings.Builder
+	b.WriteString(AlgorithmV2 + "\n")
+	b.WriteString(strings.ToUpper(r.Method) + "\n")
+	b.WriteString(path + "\n")
+	b.WriteString(canonicalQuery(r.URL.RawQuery) + "\n")
+	for _, h := range signedHeaders {
+		b.WriteString(h + ":" + headerValue(r, h) + "\n")
+	}
+	b.WriteString(strings.Join(signedHeaders, ";") + "\n")
+	b.WriteString(bodyHash)
+	return b.String()
+}
+
+// Verify 校验 v2 签名；时间戳窗口与 nonce 是否重复由调用方检查
+func Verify(r *http.Request, body []byte, secret string) error {
+	sig := r.Header.Get(HeaderSignature)
+	if sig == "" || r.Header.Get(HeaderAppID) == "" || r.Header.Get(HeaderNonce) == "" || r.Header.Get(HeaderTimestamp) == "" {
+		return ErrMissingHeader
+	}
+	if v := r.Header.Get(HeaderVersion); v != V2 {
+		return ErrUnsupported
+	}
+	headers := ParseSignedHeaders(r.Header.Get(HeaderSignedHeaders))
+	if !Equal(Compute(secret, []byte(Canonical(r, headers, BodyHash(body)))), sig) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// NewNonce 返回 16 字节随机数的十六进制字符串
+func NewNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Signer 客户端签名器，使用应用UUID与 SecKey 为请求添加 v2 签名
+type Signer struct {
+	AppID   string
+	Secret  string
+	Headers []string         // 额外参与签名的请求头，为空时使用 DefaultHeaders
+	Now     func() time.Time // 测试时替换，默认 time.Now
+}
+
+// Sign 为请求设置 X-App-Id、X-Timestamp、X-Nonce 与签名头；会读取并恢复请求体
+func (s *Signer) Sign(r *http.Request) error {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		b, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		body = b
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	extra := s.Headers
+	if len(extra) == 0 {
+		extra = DefaultHeaders
+	}
+	headers := NormalizeHeaders(extra)
+
+	r.Header.Set(HeaderAppID, s.AppID)
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(now().Unix(), 10))
+	r.Header.Set(HeaderNonce, NewNonce())
+	r.Header.Set(HeaderVersion, V2)
+	r.Header.Set(HeaderSignedHeaders, strings.Join(headers, ";"))
+	r.Header.Set(HeaderSignature, Compute(s.Secret, []byte(Canonical(r, headers, BodyHash(body)))))
+	return nil
+}
+
+// Transport 为每个请求签名的 http.RoundTripper
+type Transport struct {
+	Signer *Signer
+	Base   http.RoundTripper // 默认 http.DefaultTransport
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	if err := t.Signer.Sign(r); err != nil {
+		return nil, err
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
+
+// NewClient 返回自动签名的 http.Client
+func NewClient(appID, secret string) *http.Client {
+	return &http.Client{Transport: &Transport{Signer: &Signer{AppID: appID, Secret: secret}}}
+}
@@ -0,0 +1,96 @@
+package sign
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signedRequest(t *testing.T, target, body string) *http.Request {
+	t.Helper()
+	r, _ := http.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	s := &Signer{AppID: "app-1", Secret: "secret", Now: func() time.Time { return time.Unix(1700000000, 0) }}
+	if err := s.Sign(r); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(r.Body)
+	if string(b) != body {
+		t.Fatalf("body not restored: %q", b)
+	}
+	return r
+}
+
+// serverRequest 模拟服务端收到的请求
+func serverRequest(r *http.Request, body string) *http.Request {
+	s := httptest.NewRequest(r.Method, r.URL.RequestURI(), strings.NewReader(body))
+	s.Host = r.Host
+	s.Header = r.Header.Clone()
+	return s
+}
+
+func TestSignVerify(t *testing.T) {
+	body := `{"a":1}`
+	r := signedRequest(t, "http://api.example.com/api/v1/order?b=2&a=1&a=0", body)
+	if r.Header.Get(HeaderTimestamp) != "1700000000" || r.Header.Get(HeaderVersion) != V2 {
+		t.Fatalf("unexpected headers: %v", r.Header)
+	}
+	if err := Verify(serverRequest(r, body), []byte(body), "secret"); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := Verify(serverRequest(r, body), []byte(body), "other"); err != ErrSignatureMismatch {
+		t.Fatalf("wrong secret: %v", err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	body := `{"a":1}`
+	r := signedRequest(t, "http://api.example.com/api/v1/order?a=1", body)
+
+	cases := map[string]func(s *http.Request) []byte{
+		"body":   func(s *http.Request) []byte { return []byte(`{"a":2}`) },
+		"path":   func(s *http.Request) []byte { s.URL.Path = "/api/v1/refund"; return []byte(body) },
+		"query":  func(s *http.Request) []byte { s.URL.RawQuery = "a=2"; return []byte(body) },
+		"method": func(s *http.Request) []byte { s.Method = http.MethodPut; return []byte(body) },
+		"nonce":  func(s *http.Request) []byte { s.Header.Set(HeaderNonce, "x"); return []byte(body) },
+		"time":   func(s *http.Request) []byte { s.Header.Set(HeaderTimestamp, "1700000001"); return []byte(body) },
+		"header": func(s *http.Request) []byte { s.Header.Set("Content-Type", "text/plain"); return []byte(body) },
+		"host":   func(s *http.Request) []byte { s.Host = "evil.example.com"; return []byte(body) },
+	}
+	for name, tamper := range cases {
+		s := serverRequest(r, body)
+		b := tamper(s)
+		if err := Verify(s, b, "secret"); err != ErrSignatureMismatch {
+			t.Errorf("%s: got %v, want mismatch", name, err)
+		}
+	}
+}
+
+func TestCanonicalQueryOrder(t *testing.T) {
+	if got := canonicalQuery("b=2&a=x+y&a=1"); got != "a=1&a=x%20y&b=2" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(r, body, "secret"); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := NewClient("app-1", "secret").Post(srv.URL+"/x?q=1", "application/json", bytes.NewReader([]byte(`{}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+}
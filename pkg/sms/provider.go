@@ -0,0 +1,124 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/luxingwen/sgin/pkg/config"
+)
+
+// httpProvider 通用 HTTP 短信网关：以 JSON POST Message，2xx 视为发送成功
+type httpProvider struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPProvider(cfg config.SMSConfig) (Provider, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("http sms provider requires URL")
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &httpProvider{
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (p *httpProvider) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms gateway returned %d: %s", resp.StatusCode, bytes.TrimSpace(b))
+	}
+	return nil
+}
+
+// fileProvider 不真正发送，将短信以 JSON 行追加到文件；内容含验证码明文，只用于开发与测试
+type fileProvider struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newFileProvider(cfg config.SMSConfig) (Provider, error) {
+	if cfg.File == "" {
+		return nil, fmt.Errorf("file sms provider requires File")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterProvider(f), nil
+}
+
+// LogFunc 结构化日志函数，如 logger.Infow
+type LogFunc func(msg string, keysAndValues ...interface{})
+
+// logProvider 不真正发送，只通过应用日志记录号码、模板与参数名；参数值与内容可能含验证码，不写入日志
+type logProvider struct {
+	logf LogFunc
+}
+
+// NewLogProvider 通过 logf 记录短信的 Provider
+func NewLogProvider(logf LogFunc) Provider {
+	return &logProvider{logf: logf}
+}
+
+func (p *logProvider) Send(ctx context.Context, msg *Message) error {
+	params := make(map[string]string, len(msg.Params))
+	for k := range msg.Params {
+		params[k] = "******"
+	}
+	p.logf("sms sent", "phone", msg.Phone, "template", msg.Template, "template_id", msg.TemplateID, "params", params)
+	return nil
+}
+
+// NewWriterProvider 将短信以 JSON 行写入 w 的 Provider
+func NewWriterProvider(w io.Writer) Provider {
+	return &fileProvider{w: w}
+}
+
+func (p *fileProvider) Send(ctx context.Context, msg *Message) error {
+	b, err := json.Marshal(struct {
+		Time string `json:"time"`
+		*Message
+	}{time.Now().Format(time.RFC3339), msg})
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,201 @@
+// Package sms 发送短信。
+//
+// Provider 对接具体的短信服务：内置通用 HTTP 网关（http）、追加到本地文件的开发用实现（file）
+// 与只记录日志的 NewLogProvider（log 类型由调用方传入日志函数创建），其他服务商可通过 RegisterProvider 注册。Sender 负责号码校验与规范化（E.164）、按模板渲染内容后交给 Provider 发送。
+package sms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/luxingwen/sgin/pkg/config"
+)
+
+// TemplateVerificationCode 验证码短信的模板名，参数为 code 与 minutes
+const TemplateVerificationCode = "verification_code"
+
+var (
+	ErrInvalidPhone    = errors.New("invalid phone number")
+	ErrUnknownTemplate = errors.New("unknown sms template")
+	ErrNotConfigured   = errors.New("sms provider is not configured")
+)
+
+// defaultTemplates 内置模板，可被配置中同名的模板覆盖
+var defaultTemplates = map[string]config.SMSTemplate{
+	TemplateVerificationCode: {Content: "您的验证码为 {{.code}}，{{.minutes}} 分钟内有效，请勿泄露给他人。"},
+}
+
+// Message 一条待发送的短信
+type Message struct {
+	Phone      string            `json:"phone"`                 // E.164 格式，如 +8613800138000
+	Template   string            `json:"template"`              // 模板名
+	TemplateID string            `json:"template_id,omitempty"` // 服务商侧的模板ID
+	Params     map[string]string `json:"params,omitempty"`      // 模板参数
+	Content    string            `json:"content"`               // 按模板渲染后的内容，已加签名
+}
+
+// Provider 短信服务商
+type Provider interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// ProviderFactory 按配置创建 Provider
+type ProviderFactory func(cfg config.SMSConfig) (Provider, error)
+
+var (
+	providerMu        sync.RWMutex
+	providerFactories = map[string]ProviderFactory{
+		"http": newHTTPProvider,
+		"file": newFileProvider,
+	}
+)
+
+// RegisterProvider 注册自定义短信服务商，插件需在 New 之前调用；同名类型会被覆盖
+func RegisterProvider(typ string, factory ProviderFactory) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	providerFactories[strings.ToLower(typ)] = factory
+}
+
+// Sender 校验号码、渲染模板并通过 Provider 发送
+type Sender struct {
+	provider  Provider
+	typ       string
+	region    string
+	signName  string
+	templates map[string]config.SMSTemplate
+}
+
+// New 按配置创建 Sender，Provider 为空时返回 ErrNotConfigured
+func New(cfg config.SMSConfig) (*Sender, error) {
+	typ := strings.ToLower(cfg.Provider)
+	if typ == "" {
+		return nil, ErrNotConfigured
+	}
+	providerMu.RLock()
+	factory, ok := providerFactories[typ]
+	providerMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sms provider %q", cfg.Provider)
+	}
+	p, err := factory(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewSender(p, cfg), nil
+}
+
+// NewSender 使用指定的 Provider 创建 Sender，模板、签名与默认区号取自 cfg
+func NewSender(p Provider, cfg config.SMSConfig) *Sender {
+	s := &Sender{
+		provider:  p,
+		typ:       strings.ToLower(cfg.Provider),
+		region:    cfg.DefaultRegion,
+		signName:  cfg.SignName,
+		templates: map[string]config.SMSTemplate{},
+	}
+	for name, t := range defaultTemplates {
+		s.templates[name] = t
+	}
+	for name, t := range cfg.Templates {
+		s.templates[name] = t
+	}
+	return s
+}
+
+// Provider 返回配置的服务商类型
+func (s *Sender) Provider() string {
+	return s.typ
+}
+
+// Normalize 按 Sender 的默认区号规范化号码
+func (s *Sender) Normalize(phone string) (string, error) {
+	return NormalizePhone(phone, s.region)
+}
+
+// Send 向 phone 发送模板短信，params 为模板参数
+func (s *Sender) Send(ctx context.Context, phone, name string, params map[string]string) error {
+	phone, err := s.Normalize(phone)
+	if err != nil {
+		return err
+	}
+	t, ok := s.templates[name]
+	if !ok {
+		return ErrUnknownTemplate
+	}
+	content, err := render(t.Content, params)
+	if err != nil {
+		return err
+	}
+	if s.signName != "" {
+		content = "【" + s.signName + "】" + content
+	}
+	return s.provider.Send(ctx, &Message{
+		Phone:      phone,
+		Template:   name,
+		TemplateID: t.ID,
+		Params:     params,
+		Content:    content,
+	})
+}
+
+func render(text string, params map[string]string) (string, error) {
+	t, err := template.New("sms").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// NormalizePhone 将号码规范化为 E.164（+ 国家区号 + 号码）。
+// 去掉空格、横线、括号，00 开头视为国际前缀；不带 + 的号码按 region（默认 86）补全区号，并去掉国内长途前缀 0。
+// 中国大陆号码须为 1 开头的 11 位手机号
+func NormalizePhone(phone, region string) (string, error) {
+	if region == "" {
+		region = "86"
+	}
+	region = strings.TrimPrefix(region, "+")
+
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+	p := b.String()
+	switch {
+	case strings.HasPrefix(p, "+"):
+		p = p[1:]
+	case strings.HasPrefix(p, "00"):
+		p = p[2:]
+	default:
+		p = region + strings.TrimLeft(p, "0")
+	}
+
+	// E.164 最长 15 位数字
+	if len(p) < 8 || len(p) > 15 || p[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	if strings.HasPrefix(p, "86") {
+		n := p[2:]
+		if len(n) != 11 || n[0] != '1' || n[1] < '3' {
+			return "", ErrInvalidPhone
+		}
+	}
+	return "+" + p, nil
+}
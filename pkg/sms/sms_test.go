@@ -0,0 +1,114 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/luxingwen/sgin/pkg/config"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	cases := map[string]string{
+		"13800138000":        "+8613800138000",
+		"138-0013-8000":      "+8613800138000",
+		"+86 138 0013 8000":  "+8613800138000",
+		"008613800138000":    "+8613800138000",
+		"+1 (415) 555-2671":  "+14155552671",
+		"+44 020 7946 0958x": "",
+		"12345":              "",
+		"+8612800138000":     "",
+		"2800138000":         "",
+		"+8613800138000000":  "",
+	}
+	for in, want := range cases {
+		got, err := NormalizePhone(in, "")
+		if want == "" {
+			if err != ErrInvalidPhone {
+				t.Errorf("%q: got %q, want invalid", in, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("%q: got %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if got, err := NormalizePhone("4155552671", "+1"); err != nil || got != "+14155552671" {
+		t.Errorf("region: got %q, %v", got, err)
+	}
+}
+
+func TestSenderTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSender(NewWriterProvider(&buf), config.SMSConfig{
+		SignName:  "sgin",
+		Templates: map[string]config.SMSTemplate{"notice": {ID: "T1", Content: "hello {{.name}}"}},
+	})
+	if err := s.Send(context.Background(), "13800138000", TemplateVerificationCode, map[string]string{"code": "123456", "minutes": "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "【sgin】您的验证码为 123456，5 分钟内有效") {
+		t.Fatalf("unexpected content: %s", buf.String())
+	}
+	if err := s.Send(context.Background(), "13800138000", "notice", nil); err == nil {
+		t.Fatal("missing template param should fail")
+	}
+	if err := s.Send(context.Background(), "13800138000", "unknown", nil); err != ErrUnknownTemplate {
+		t.Fatalf("unknown template: %v", err)
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var got Message
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	s, err := New(config.SMSConfig{
+		Provider:  "http",
+		URL:       srv.URL,
+		Headers:   map[string]string{"Authorization": "Bearer k"},
+		Templates: map[string]config.SMSTemplate{TemplateVerificationCode: {ID: "SMS_1", Content: "code {{.code}}"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(context.Background(), "13800138000", TemplateVerificationCode, map[string]string{"code": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Phone != "+8613800138000" || got.TemplateID != "SMS_1" || got.Content != "code 1" {
+		t.Fatalf("unexpected message: %+v", got)
+	}
+
+	status = http.StatusBadGateway
+	if err := s.Send(context.Background(), "13800138000", TemplateVerificationCode, map[string]string{"code": "1"}); err == nil {
+		t.Fatal("gateway error should fail")
+	}
+}
+
+func TestLogProviderMasksParams(t *testing.T) {
+	var logged []interface{}
+	s := NewSender(NewLogProvider(func(msg string, kv ...interface{}) { logged = append(logged, msg, kv) }), config.SMSConfig{Provider: "log"})
+	if err := s.Send(context.Background(), "13800138000", TemplateVerificationCode, map[string]string{"code": "987654", "minutes": "5"}); err != nil {
+		t.Fatal(err)
+	}
+	out := fmt.Sprint(logged...)
+	if strings.Contains(out, "987654") || !strings.Contains(out, "+8613800138000") || !strings.Contains(out, "code") {
+		t.Fatalf("unexpected log: %s", out)
+	}
+	if _, err := New(config.SMSConfig{Provider: "file"}); err == nil {
+		t.Fatal("file provider without File should fail")
+	}
+}
@@ -0,0 +1,355 @@
+// Package token 负责访问令牌（JWT）的签名与验证。
+//
+// 支持 HS256、RS256、ES256 与 EdDSA，密钥按 kid 区分：签名使用当前密钥并在头部写入 kid，
+// 验证时按 kid 选择密钥，轮换后保留的旧密钥仍可验证未过期的令牌。非对称密钥的公钥通过
+// JWKS 对外发布，下游服务无需共享密钥即可验证令牌。
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/luxingwen/sgin/pkg/config"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey      = errors.New("token: unknown key id")
+	ErrInvalidIssuer   = errors.New("token: invalid issuer")
+	ErrInvalidAudience = errors.New("token: invalid audience")
+	ErrInvalidToken    = errors.New("token: invalid token")
+)
+
+// Key 一个签名密钥
+type Key struct {
+	Kid    string
+	Method jwt.SigningMethod
+	sign   interface{} // 私钥或 HMAC 密钥，nil 表示只用于验证
+	verify interface{} // 公钥或 HMAC 密钥
+}
+
+// CanSign 是否可以用于签名
+func (k *Key) CanSign() bool {
+	return k.sign != nil
+}
+
+// Manager 持有全部密钥，负责签名与验证
+type Manager struct {
+	active   *Key
+	keys     map[string]*Key
+	order    []string
+	issuer   string
+	audience string
+}
+
+// New 按配置创建 Manager；未配置 Keys 时使用 HS256 与 cfg.Secret，两者都未配置时返回 ErrSecretRequired
+func New(cfg config.JWTConfig) (*Manager, error) {
+	defAlg := cfg.Algorithm
+	if defAlg == "" {
+		defAlg = AlgHS256
+	}
+
+	keyCfgs := cfg.Keys
+	if len(keyCfgs) == 0 {
+		if !strings.EqualFold(defAlg, AlgHS256) {
+			return nil, fmt.Errorf("token: JWT.Keys is required for %s", defAlg)
+		}
+		if cfg.Secret == "" {
+			return nil, ErrSecretRequired
+		}
+		// 未配置 kid 的 HS256 密钥不写入 kid 头部，与旧版本签发的令牌兼容
+		keyCfgs = []config.JWTKeyConfig{{Algorithm: AlgHS256, Secret: cfg.Secret}}
+	}
+
+	m := &Manager{keys: make(map[string]*Key), issuer: cfg.Issuer, audience: cfg.Audience}
+	for i, kc := range keyCfgs {
+		if kc.Algorithm == "" {
+			kc.Algorithm = defAlg
+		}
+		k, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("token: key %d: %w", i, err)
+		}
+		if _, ok := m.keys[k.Kid]; ok {
+			return nil, fmt.Errorf("token: duplicate kid %q", k.Kid)
+		}
+		m.keys[k.Kid] = k
+		m.order = append(m.order, k.Kid)
+	}
+
+	if cfg.ActiveKid != "" {
+		m.active = m.keys[cfg.ActiveKid]
+		if m.active == nil {
+			return nil, fmt.Errorf("token: active kid %q not found", cfg.ActiveKid)
+		}
+		if !m.active.CanSign() {
+			return nil, fmt.Errorf("token: active kid %q has no private key", cfg.ActiveKid)
+		}
+	} else {
+		for _, kid := range m.order {
+			if m.keys[kid].CanSign() {
+				m.active = m.keys[kid]
+				break
+			}
+		}
+		if m.active == nil {
+			return nil, errors.New("token: no signing key configured")
+		}
+	}
+	return m, nil
+}
+
+// ActiveKey 返回签名使用的密钥
+func (m *Manager) ActiveKey() *Key {
+	return m.active
+}
+
+// Sign 使用当前密钥签名，配置了 Issuer/Audience 时写入 iss/aud
+func (m *Manager) Sign(claims jwt.MapClaims) (string, error) {
+	if m.issuer != "" {
+		claims["iss"] = m.issuer
+	}
+	if m.audience != "" {
+		claims["aud"] = m.audience
+	}
+	t := jwt.NewWithClaims(m.active.Method, claims)
+	if m.active.Kid != "" {
+		t.Header["kid"] = m.active.Kid
+	}
+	return t.SignedString(m.active.sign)
+}
+
+// Parse 按 kid 选择密钥验证签名与有效期，令牌算法必须与密钥一致；配置了 Issuer/Audience 时同时校验
+func (m *Manager) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k := m.keys[kid]
+		if k == nil {
+			return nil, ErrUnknownKey
+		}
+		// 算法由密钥决定，防止 none 算法与算法混淆攻击
+		if t.Method.Alg() != k.Method.Alg() {
+			return nil, errors.New("token: unexpected signing method")
+		}
+		return k.verify, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if m.issuer != "" && !claims.VerifyIssuer(m.issuer, true) {
+		return nil, ErrInvalidIssuer
+	}
+	if m.audience != "" && !claims.VerifyAudience(m.audience, true) {
+		return nil, ErrInvalidAudience
+	}
+	return claims, nil
+}
+
+func loadKey(kc config.JWTKeyConfig) (*Key, error) {
+	method := jwt.GetSigningMethod(normalizeAlg(kc.Algorithm))
+	if method == nil {
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+	k := &Key{Kid: kc.Kid, Method: method}
+
+	if method.Alg() == AlgHS256 {
+		if kc.Secret == "" {
+			return nil, errors.New("HS256 secret is empty")
+		}
+		k.sign, k.verify = []byte(kc.Secret), []byte(kc.Secret)
+		return k, nil
+	}
+
+	var pub crypto.PublicKey
+	if kc.PrivateKeyFile != "" {
+		priv, err := readPrivateKey(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		k.sign = priv
+		pub = priv.Public()
+	}
+	if kc.PublicKeyFile != "" {
+		p, err := readPublicKey(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if eq, ok := pub.(interface{ Equal(crypto.PublicKey) bool }); ok && !eq.Equal(p) {
+			return nil, errors.New("public key does not match private key")
+		}
+		pub = p
+	}
+	if pub == nil {
+		return nil, errors.New("PrivateKeyFile or PublicKeyFile is required")
+	}
+	if err := checkKeyType(method.Alg(), pub); err != nil {
+		return nil, err
+	}
+	k.verify = pub
+
+	if k.Kid == "" {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(der)
+		k.Kid = base64.RawURLEncoding.EncodeToString(sum[:12])
+	}
+	return k, nil
+}
+
+func normalizeAlg(alg string) string {
+	switch strings.ToUpper(alg) {
+	case "HS256":
+		return AlgHS256
+	case "RS256":
+		return AlgRS256
+	case "ES256":
+		return AlgES256
+	case "EDDSA", "ED25519":
+		return AlgEdDSA
+	}
+	return ""
+}
+
+func checkKeyType(alg string, pub crypto.PublicKey) error {
+	switch alg {
+	case AlgRS256:
+		p, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 requires an RSA key")
+		}
+		if p.N.BitLen() < 2048 {
+			return errors.New("RSA key must be at least 2048 bits")
+		}
+	case AlgES256:
+		p, ok := pub.(*ecdsa.PublicKey)
+		if !ok || p.Curve != elliptic.P256() {
+			return errors.New("ES256 requires a P-256 ECDSA key")
+		}
+	case AlgEdDSA:
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			return errors.New("EdDSA requires an Ed25519 key")
+		}
+	}
+	return nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	return block, nil
+}
+
+// readPrivateKey 支持 PKCS#8、PKCS#1（RSA）与 SEC 1（EC）格式
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if s, ok := k.(crypto.Signer); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("%s: unsupported private key type", path)
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	if k, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported private key format", path)
+}
+
+// readPublicKey 支持 PKIX、PKCS#1（RSA）公钥与 X.509 证书
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if k, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	if k, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported public key format", path)
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultMgr    *Manager
+	defaultCfg    *config.Config
+	defaultPinned bool
+)
+
+// Default 返回按全局配置创建的 Manager，配置对象变化时重新创建
+func Default() (*Manager, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	cfg := config.GetConfig()
+	if defaultMgr != nil && (defaultPinned || defaultCfg == cfg) {
+		return defaultMgr, nil
+	}
+
+	m, err := NewFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defaultMgr, defaultCfg = m, cfg
+	return m, nil
+}
+
+// ErrSecretRequired 未配置 JWT.Secret 或 JWT.Keys
+var ErrSecretRequired = errors.New("JWT.Secret or JWT.Keys is required")
+
+// NewFromConfig 按 cfg.JWT 创建 Manager，令牌密钥必须单独配置，不与 PasswdKey 共用
+func NewFromConfig(cfg *config.Config) (*Manager, error) {
+	if cfg == nil {
+		return nil, ErrSecretRequired
+	}
+	return New(cfg.JWT)
+}
+
+// SetDefault 替换 Default 返回的 Manager，传入 nil 时恢复按配置创建
+func SetDefault(m *Manager) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultMgr, defaultCfg, defaultPinned = m, nil, m != nil
+}
@@ -0,0 +1,186 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luxingwen/sgin/pkg/config"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func writeKey(t *testing.T, dir, name string, priv interface{}) (privFile, pubFile string) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privFile = filepath.Join(dir, name+".pem")
+	if err := os.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pubDer, err := x509.MarshalPKIXPublicKey(priv.(crypto.Signer).Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubFile = filepath.Join(dir, name+".pub.pem")
+	if err := os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return privFile, pubFile
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": "u1", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestAsymmetricAlgorithms(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	cases := []struct {
+		alg string
+		key interface{}
+		kty string
+	}{
+		{AlgRS256, rsaKey, "RSA"},
+		{AlgES256, ecKey, "EC"},
+		{AlgEdDSA, edKey, "OKP"},
+	}
+	for _, c := range cases {
+		priv, _ := writeKey(t, dir, c.alg, c.key)
+		m, err := New(config.JWTConfig{Algorithm: c.alg, Keys: []config.JWTKeyConfig{{Kid: c.alg, PrivateKeyFile: priv}}})
+		if err != nil {
+			t.Fatalf("%s: %v", c.alg, err)
+		}
+		s, err := m.Sign(testClaims())
+		if err != nil {
+			t.Fatalf("%s: sign: %v", c.alg, err)
+		}
+		claims, err := m.Parse(s)
+		if err != nil || claims["user_id"] != "u1" {
+			t.Fatalf("%s: parse: %v %v", c.alg, claims, err)
+		}
+		set := m.JWKS()
+		if len(set.Keys) != 1 || set.Keys[0].Kty != c.kty || set.Keys[0].Kid != c.alg || set.Keys[0].Alg != c.alg {
+			t.Fatalf("%s: unexpected jwks %+v", c.alg, set.Keys)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	oldPriv, oldPub := writeKey(t, dir, "old", oldKey)
+	newPriv, _ := writeKey(t, dir, "new", newKey)
+
+	before, err := New(config.JWTConfig{Algorithm: AlgES256, Keys: []config.JWTKeyConfig{{Kid: "k1", PrivateKeyFile: oldPriv}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, _ := before.Sign(testClaims())
+
+	// 轮换：新密钥签名，旧密钥只保留公钥用于验证
+	after, err := New(config.JWTConfig{
+		Algorithm: AlgES256,
+		ActiveKid: "k2",
+		Keys: []config.JWTKeyConfig{
+			{Kid: "k1", PublicKeyFile: oldPub},
+			{Kid: "k2", PrivateKeyFile: newPriv},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := after.Parse(oldToken); err != nil {
+		t.Fatalf("old token should still verify: %v", err)
+	}
+	newToken, _ := after.Sign(testClaims())
+	tok, _, _ := new(jwt.Parser).ParseUnverified(newToken, jwt.MapClaims{})
+	if tok.Header["kid"] != "k2" {
+		t.Fatalf("expected kid k2, got %v", tok.Header["kid"])
+	}
+	if len(after.JWKS().Keys) != 2 {
+		t.Fatalf("expected both keys in jwks")
+	}
+	if _, err := before.Parse(newToken); err == nil {
+		t.Fatal("token signed by unknown kid should fail")
+	}
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	priv, pub := writeKey(t, dir, "rsa", rsaKey)
+	m, err := New(config.JWTConfig{Algorithm: AlgRS256, Keys: []config.JWTKeyConfig{{Kid: "r", PrivateKeyFile: priv}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 以公钥 PEM 作为 HMAC 密钥伪造令牌
+	pubPEM, _ := os.ReadFile(pub)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	forged.Header["kid"] = "r"
+	s, _ := forged.SignedString(pubPEM)
+	if _, err := m.Parse(s); err == nil {
+		t.Fatal("HS256 token must not verify against an RSA key")
+	}
+}
+
+func TestHS256IssuerAudience(t *testing.T) {
+	m, err := New(config.JWTConfig{Secret: "fallback", Issuer: "sgin", Audience: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := m.Sign(testClaims())
+	claims, err := m.Parse(s)
+	if err != nil || claims["iss"] != "sgin" || claims["aud"] != "api" {
+		t.Fatalf("parse: %v %v", claims, err)
+	}
+	if len(m.JWKS().Keys) != 0 {
+		t.Fatal("hmac keys must not be published")
+	}
+
+	// 旧版本签发的令牌：同一密钥、无 kid、无 iss/aud
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("fallback"))
+	plain, _ := New(config.JWTConfig{Secret: "fallback"})
+	if _, err := plain.Parse(legacy); err != nil {
+		t.Fatalf("legacy token should verify: %v", err)
+	}
+	if _, err := m.Parse(legacy); err != ErrInvalidIssuer {
+		t.Fatalf("expected ErrInvalidIssuer, got %v", err)
+	}
+
+	other, _ := New(config.JWTConfig{Secret: "s", Issuer: "sgin", Audience: "other"})
+	s, _ = other.Sign(testClaims())
+	if _, err := (&Manager{keys: other.keys, active: other.active, issuer: "sgin", audience: "api"}).Parse(s); err != ErrInvalidAudience {
+		t.Fatalf("expected ErrInvalidAudience, got %v", err)
+	}
+}
+
+func TestNewFromConfigRequireSecret(t *testing.T) {
+	// 不再回退到 PasswdKey
+	cfg := &config.Config{PasswdKey: "passwd"}
+	if _, err := NewFromConfig(cfg); err != ErrSecretRequired {
+		t.Fatalf("got %v, want ErrSecretRequired", err)
+	}
+	if _, err := NewFromConfig(nil); err != ErrSecretRequired {
+		t.Fatalf("nil config: got %v, want ErrSecretRequired", err)
+	}
+	cfg.JWT.Secret = "jwt-secret"
+	if _, err := NewFromConfig(cfg); err != nil {
+		t.Fatalf("secret configured: %v", err)
+	}
+}
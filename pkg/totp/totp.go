@@ -0,0 +1,139 @@
+// Package totp 实现 RFC 6238 基于时间的一次性密码（HMAC-SHA1），
+// 用于两步验证；生成的 otpauth URI 可被常见的验证器应用识别。
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Options 验证参数，零值字段使用默认值
+type Options struct {
+	Digits int // 位数，默认 6
+	Period int // 时间步长（秒），默认 30
+	Skew   int // 允许前后偏移的时间步数，默认 1，小于 0 表示不允许偏移
+}
+
+func (o Options) withDefaults() Options {
+	if o.Digits <= 0 {
+		o.Digits = 6
+	}
+	if o.Period <= 0 {
+		o.Period = 30
+	}
+	if o.Skew < 0 {
+		o.Skew = 0
+	} else if o.Skew == 0 {
+		o.Skew = 1
+	}
+	return o
+}
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ErrInvalidSecret 密钥不是合法的 base32 字符串
+var ErrInvalidSecret = errors.New("totp: invalid secret")
+
+// GenerateSecret 生成 160 位随机密钥，base32 编码（无填充）
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := b32.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// Counter 返回 t 所在的时间步
+func (o Options) Counter(t time.Time) int64 {
+	o = o.withDefaults()
+	return t.Unix() / int64(o.Period)
+}
+
+// Code 返回 t 时刻的验证码
+func (o Options) Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	o = o.withDefaults()
+	return hotp(key, o.Counter(t), o.Digits), nil
+}
+
+// Validate 校验验证码，允许前后 Skew 个时间步；成功时返回匹配的时间步，
+// 调用方应保存该值并拒绝不大于它的时间步，防止同一验证码被重放
+func (o Options) Validate(secret, code string, t time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	o = o.withDefaults()
+	code = strings.TrimSpace(code)
+	if len(code) != o.Digits {
+		return 0, false
+	}
+	now := o.Counter(t)
+	for i := -o.Skew; i <= o.Skew; i++ {
+		c := now + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, c, o.Digits)), []byte(code)) == 1 {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// hotp RFC 4226 HOTP
+func hotp(key []byte, counter int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, v%mod)
+}
+
+// URI 返回验证器应用使用的 otpauth URI
+func (o Options) URI(issuer, account, secret string) string {
+	o = o.withDefaults()
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(o.Digits))
+	q.Set("period", fmt.Sprint(o.Period))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// QRCodePNG 将 URI 编码为二维码 PNG
+func QRCodePNG(uri string, size int) ([]byte, error) {
+	if size <= 0 {
+		size = 256
+	}
+	return qrcode.Encode(uri, qrcode.Medium, size)
+}
@@ -0,0 +1,74 @@
+package totp
+
+import (
+	"bytes"
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量，密钥为 "12345678901234567890"
+func TestRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	o := Options{Digits: 8}
+	cases := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for ts, want := range cases {
+		got, err := o.Code(secret, time.Unix(ts, 0))
+		if err != nil || got != want {
+			t.Errorf("t=%d: got %s %v, want %s", ts, got, err, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var o Options
+	now := time.Unix(1700000000, 0)
+	code, _ := o.Code(secret, now)
+
+	counter, ok := o.Validate(secret, code, now)
+	if !ok || counter != o.Counter(now) {
+		t.Fatalf("current code rejected")
+	}
+	// 允许前后一个时间步
+	if _, ok := o.Validate(secret, code, now.Add(30*time.Second)); !ok {
+		t.Fatal("code from previous step rejected")
+	}
+	if _, ok := o.Validate(secret, code, now.Add(90*time.Second)); ok {
+		t.Fatal("code outside skew accepted")
+	}
+	if _, ok := o.Validate(secret, "000000x", now); ok {
+		t.Fatal("malformed code accepted")
+	}
+	if _, ok := o.Validate("not base32!", code, now); ok {
+		t.Fatal("invalid secret accepted")
+	}
+}
+
+func TestURIAndQRCode(t *testing.T) {
+	var o Options
+	uri := o.URI("sgin", "alice@example.com", "JBSWY3DPEHPK3PXP")
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+	if !strings.HasPrefix(u.Path, "/sgin:alice@example.com") || u.Query().Get("secret") != "JBSWY3DPEHPK3PXP" || u.Query().Get("issuer") != "sgin" {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+	png, err := QRCodePNG(uri, 128)
+	if err != nil || !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Fatalf("qr png: %v", err)
+	}
+}
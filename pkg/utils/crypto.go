@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const encryptedPrefix = "v1:"
+
+// ErrDecrypt 密文格式错误或密钥不匹配
+var ErrDecrypt = errors.New("decrypt failed")
+
+func aead(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("encryption key is empty")
+	}
+	k := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(k[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptString 使用 AES-256-GCM 加密，密钥为 key 的 SHA-256，返回 "v1:" + base64(nonce|密文)
+func EncryptString(key, plaintext string) (string, error) {
+	gcm, err := aead(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// DecryptString 解密 EncryptString 的结果
+func DecryptString(key, ciphertext string) (string, error) {
+	gcm, err := aead(key)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(ciphertext, encryptedPrefix) {
+		return "", ErrDecrypt
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, encryptedPrefix))
+	if err != nil || len(b) < gcm.NonceSize() {
+		return "", ErrDecrypt
+	}
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
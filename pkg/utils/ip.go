@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+var ErrInvalidIP = errors.New("invalid ip or cidr")
+
+// NormalizeIPList 校验 IP 与 CIDR 并规范化（CIDR 取网络地址）后以逗号连接，忽略空项
+func NormalizeIPList(items []string) (string, error) {
+	list := []string{}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			_, n, err := net.ParseCIDR(item)
+			if err != nil {
+				return "", ErrInvalidIP
+			}
+			item = n.String()
+		} else if ip := net.ParseIP(item); ip != nil {
+			item = ip.String()
+		} else {
+			return "", ErrInvalidIP
+		}
+		list = append(list, item)
+	}
+	return strings.Join(list, ","), nil
+}
+
+// IPAllowed clientIP 是否在逗号分隔的 IP 或 CIDR 列表 allowed 中，allowed 为空时不限制
+func IPAllowed(allowed, clientIP string) bool {
+	if allowed == "" {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, item := range strings.Split(allowed, ",") {
+		item = strings.TrimSpace(item)
+		if strings.Contains(item, "/") {
+			if _, n, err := net.ParseCIDR(item); err == nil && n.Contains(ip) {
+				return true
+			}
+		} else if x := net.ParseIP(item); x != nil && x.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
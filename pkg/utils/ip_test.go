@@ -0,0 +1,45 @@
+package utils
+
+import "testing"
+
+func TestNormalizeIPList(t *testing.T) {
+	got, err := NormalizeIPList([]string{" 10.0.0.1 ", "", "192.168.1.77/24", "::1", "2001:db8::1/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "10.0.0.1,192.168.1.0/24,::1,2001:db8::/32"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got, err := NormalizeIPList(nil); err != nil || got != "" {
+		t.Fatalf("empty: got %q, %v", got, err)
+	}
+	for _, bad := range []string{"10.0.0.256", "10.0.0.0/33", "example.com", "10.0.0.1/"} {
+		if _, err := NormalizeIPList([]string{"10.0.0.1", bad}); err != ErrInvalidIP {
+			t.Errorf("%q: got %v, want ErrInvalidIP", bad, err)
+		}
+	}
+}
+
+func TestIPAllowed(t *testing.T) {
+	cases := []struct {
+		allowed, ip string
+		want        bool
+	}{
+		{"", "1.2.3.4", true},
+		{"", "not-an-ip", true},
+		{"10.0.0.1", "10.0.0.1", true},
+		{"10.0.0.1", "10.0.0.2", false},
+		{"10.0.0.1,192.168.1.0/24", "192.168.1.200", true},
+		{"192.168.1.0/24", "192.168.2.1", false},
+		{"10.0.0.1", "not-an-ip", false},
+		{"::1", "0:0:0:0:0:0:0:1", true},
+		{"2001:db8::/32", "2001:db8:ffff::1", true},
+		{"10.0.0.0/8", "::ffff:10.1.2.3", true},
+		{"bogus,10.0.0.1", "10.0.0.1", true},
+	}
+	for _, c := range cases {
+		if got := IPAllowed(c.allowed, c.ip); got != c.want {
+			t.Errorf("IPAllowed(%q, %q) = %v, want %v", c.allowed, c.ip, got, c.want)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 )
 
 // HashPasswordWithSalt 使用盐（salt）来哈希密码
+//
+// Deprecated: 没有独立盐与工作因子，新代码使用 pkg/passwd；旧哈希由 passwd.Verify 识别并在登录时迁移
 func HashPasswordWithSalt(password, salt string) string {
 	h := hmac.New(sha256.New, []byte(salt))
 	h.Write([]byte(password))
@@ -15,6 +17,8 @@ func HashPasswordWithSalt(password, salt string) string {
 }
 
 // CheckPasswordHashWithSalt 验证密码与其哈希值是否匹配
+//
+// Deprecated: 使用 passwd.Verify
 func CheckPasswordHashWithSalt(password, hashed, salt string) bool {
 	expectedHash := HashPasswordWithSalt(password, salt)
 	return hmac.Equal([]byte(hashed), []byte(expectedHash))
@@ -9,65 +9,209 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/luxingwen/sgin/pkg/config"
+	"github.com/luxingwen/sgin/pkg/token"
 
 	jwt "github.com/golang-jwt/jwt/v4"
 )
 
-// 从配置加载 JWT 秘钥，若未配置则回退到 PasswdKey
-func jwtSecret() []byte {
-	cfg := config.GetConfig()
-	if cfg != nil && cfg.PasswdKey != "" {
-		return []byte(cfg.PasswdKey)
-	}
-	// 最小化改动：保持兼容旧行为
-	return []byte("your-secret-key")
+// TokenClaims 访问令牌中的声明
+type TokenClaims struct {
+	UserID    string
+	SessionID string    // 登录会话ID，旧令牌为空
+	JTI       string    // 令牌ID，用于吊销，旧令牌为空
+	IssuedAt  time.Time // 签发时间，旧令牌为零值
+	ExpiresAt time.Time
+}
+
+// GenerateAccessToken 生成带 jti 与 iat 的访问令牌
+func GenerateAccessToken(userID string, ttl time.Duration) (string, *TokenClaims, error) {
+	return GenerateSessionToken(userID, "", ttl)
 }
 
-// GenerateToken 生成 JWT token
-func GenerateToken(userID string) (string, error) {
-	// 定义 JWT 的有效期限
-	expirationTime := time.Now().Add(24 * time.Hour) // 设置为 24 小时有效期，可根据需求调整
+// GenerateSessionToken 生成属于登录会话 sessionID 的访问令牌，sessionID 写入 sid 声明
+func GenerateSessionToken(userID, sessionID string, ttl time.Duration) (string, *TokenClaims, error) {
+	now := time.Now()
+	tc := &TokenClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		JTI:       RandomToken(16),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
 
 	// 创建 token 的声明部分
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     expirationTime.Unix(),
+		"jti":     tc.JTI,
+		"iat":     now.Unix(),
+		"exp":     tc.ExpiresAt.Unix(),
+	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
 	}
 
-	// 使用 HS256 算法进行签名
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// 按 JWT 配置的当前密钥签名
+	m, err := token.Default()
+	if err != nil {
+		return "", nil, err
+	}
+	tokenString, err := m.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, tc, nil
+}
+
+// ParseTokenClaims 解析访问令牌并返回声明
+func ParseTokenClaims(tokenString string) (*TokenClaims, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := claimUserID(claims)
+	if err != nil {
+		return nil, err
+	}
+	tc := &TokenClaims{UserID: userID}
+	tc.JTI, _ = claims["jti"].(string)
+	tc.SessionID, _ = claims["sid"].(string)
+	if iat, ok := claims["iat"].(float64); ok {
+		tc.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		tc.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	return tc, nil
+}
 
-	// 使用密钥对 token 进行签名，生成字符串格式的 token
-	tokenString, err := token.SignedString(jwtSecret()) // 使用与验证时相同的密钥
+// GenerateChallengeToken 签发用途为 purpose 的短期令牌，如两步验证中密码已验证的凭据。
+// 令牌不含 user_id，不能作为访问令牌使用
+func GenerateChallengeToken(subject, purpose string, ttl time.Duration) (string, error) {
+	m, err := token.Default()
 	if err != nil {
 		return "", err
 	}
+	now := time.Now()
+	return m.Sign(jwt.MapClaims{
+		"sub":     subject,
+		"purpose": purpose,
+		"jti":     RandomToken(16),
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	})
+}
 
-	return tokenString, nil
+// ParseChallengeToken 校验 GenerateChallengeToken 签发的令牌并返回 subject
+func ParseChallengeToken(tokenString, purpose string) (string, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+	if p, _ := claims["purpose"].(string); p != purpose || purpose == "" {
+		return "", errors.New("invalid token purpose")
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return "", errors.New("invalid token subject")
+	}
+	return sub, nil
 }
 
-// ParseToken 解析 JWT token
-func ParseToken(tokenString string) (jwt.MapClaims, error) {
-	// 解析 token 并校验签名算法与有效性
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// 仅接受 HMAC 系列算法，防止 none 算法攻击
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return jwtSecret(), nil
+// AppTokenUse 应用访问令牌（OAuth2 client credentials）的 token_use 声明
+const AppTokenUse = "app"
+
+// AppTokenClaims 应用访问令牌中的声明
+type AppTokenClaims struct {
+	ClientID  string // 应用UUID
+	Scopes    []string
+	JTI       string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// GenerateAppToken 为应用签发访问令牌。令牌不含 user_id，不能作为用户访问令牌使用
+func GenerateAppToken(clientID string, scopes []string, ttl time.Duration) (string, *AppTokenClaims, error) {
+	m, err := token.Default()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	tc := &AppTokenClaims{
+		ClientID:  clientID,
+		Scopes:    scopes,
+		JTI:       RandomToken(16),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	tok, err := m.Sign(jwt.MapClaims{
+		"sub":       clientID,
+		"client_id": clientID,
+		"scope":     strings.Join(scopes, " "),
+		"token_use": AppTokenUse,
+		"jti":       tc.JTI,
+		"iat":       now.Unix(),
+		"exp":       tc.ExpiresAt.Unix(),
 	})
+	if err != nil {
+		return "", nil, err
+	}
+	return tok, tc, nil
+}
+
+// ParseAppTokenClaims 校验 GenerateAppToken 签发的令牌并返回声明
+func ParseAppTokenClaims(tokenString string) (*AppTokenClaims, error) {
+	claims, err := ParseToken(tokenString)
 	if err != nil {
 		return nil, err
 	}
+	if use, _ := claims["token_use"].(string); use != AppTokenUse {
+		return nil, errors.New("not an app access token")
+	}
+	tc := &AppTokenClaims{}
+	tc.ClientID, _ = claims["client_id"].(string)
+	if tc.ClientID == "" {
+		return nil, errors.New("invalid client_id in token")
+	}
+	tc.JTI, _ = claims["jti"].(string)
+	if scope, _ := claims["scope"].(string); scope != "" {
+		tc.Scopes = strings.Fields(scope)
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		tc.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		tc.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	return tc, nil
+}
+
+// RandomToken 返回 n 字节加密安全随机数的十六进制字符串
+func RandomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
 
-	// 获取 token 中的声明部分并确保 token.Valid
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+// SHA256Hex 返回 s 的 SHA-256 十六进制摘要
+func SHA256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseToken 解析 JWT token
+func ParseToken(tokenString string) (jwt.MapClaims, error) {
+	// 按 kid 选择密钥校验签名、算法、有效期以及配置的 iss/aud
+	m, err := token.Default()
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("invalid token")
+	return m.Parse(tokenString)
 }
 
 // 解析token返回user_id
@@ -76,7 +220,10 @@ func ParseTokenGetUserID(tokenString string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	return claimUserID(claims)
+}
 
+func claimUserID(claims jwt.MapClaims) (string, error) {
 	switch v := claims["user_id"].(type) {
 	case string:
 		return v, nil
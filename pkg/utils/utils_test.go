@@ -1,13 +1,103 @@
 package utils
 
 import (
+	"os"
 	"testing"
+	"time"
+
+	"github.com/luxingwen/sgin/pkg/config"
+	"github.com/luxingwen/sgin/pkg/token"
 )
 
-func TestGenerateToken(t *testing.T) {
-	token, err := GenerateToken("123")
+func TestMain(m *testing.M) {
+	// 令牌密钥必须配置，测试使用固定的 HS256 密钥
+	mgr, err := token.New(config.JWTConfig{Secret: "test-secret"})
+	if err != nil {
+		panic(err)
+	}
+	token.SetDefault(mgr)
+	os.Exit(m.Run())
+}
+
+func TestAccessTokenClaims(t *testing.T) {
+	token, tc, err := GenerateAccessToken("u1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseTokenClaims(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != "u1" || got.JTI != tc.JTI || got.JTI == "" || got.IssuedAt.Unix() != tc.IssuedAt.Unix() {
+		t.Fatalf("claims = %+v, want %+v", got, tc)
+	}
+
+	if got.SessionID != "" {
+		t.Fatalf("unexpected session id %q", got.SessionID)
+	}
+	sessToken, _, err := GenerateSessionToken("u1", "s1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ParseTokenClaims(sessToken); err != nil || got.SessionID != "s1" {
+		t.Fatalf("session claims = %+v, err = %v", got, err)
+	}
+
+	expired, _, _ := GenerateAccessToken("u1", -time.Minute)
+	if _, err := ParseTokenClaims(expired); err == nil {
+		t.Fatal("expired token accepted")
+	}
+}
+
+func TestChallengeToken(t *testing.T) {
+	tok, err := GenerateChallengeToken("u1", "2fa", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub, err := ParseChallengeToken(tok, "2fa"); err != nil || sub != "u1" {
+		t.Fatalf("sub = %q, err = %v", sub, err)
+	}
+	if _, err := ParseChallengeToken(tok, "reset"); err == nil {
+		t.Fatal("token accepted for another purpose")
+	}
+	// 不能当作访问令牌使用
+	if _, err := ParseTokenClaims(tok); err == nil {
+		t.Fatal("challenge token accepted as access token")
+	}
+	access, _, _ := GenerateSessionToken("u1", "s1", time.Minute)
+	if _, err := ParseChallengeToken(access, "2fa"); err == nil {
+		t.Fatal("access token accepted as challenge token")
+	}
+}
+
+func TestAppToken(t *testing.T) {
+	tok, tc, err := GenerateAppToken("app-1", []string{"order", "user"}, time.Minute)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	got, err := ParseAppTokenClaims(tok)
+	if err != nil || got.ClientID != "app-1" || got.JTI != tc.JTI || len(got.Scopes) != 2 || got.Scopes[1] != "user" {
+		t.Fatalf("claims = %+v, err = %v", got, err)
+	}
+	// 应用令牌与用户令牌不能互用
+	if _, err := ParseTokenClaims(tok); err == nil {
+		t.Fatal("app token accepted as user access token")
+	}
+	access, _, _ := GenerateSessionToken("u1", "s1", time.Minute)
+	if _, err := ParseAppTokenClaims(access); err == nil {
+		t.Fatal("user access token accepted as app token")
+	}
+}
+
+func TestEncryptString(t *testing.T) {
+	c, err := EncryptString("k1", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := DecryptString("k1", c); err != nil || p != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("decrypt = %q, %v", p, err)
+	}
+	if _, err := DecryptString("k2", c); err != ErrDecrypt {
+		t.Fatalf("wrong key: %v", err)
 	}
-	t.Log(token)
 }
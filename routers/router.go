@@ -13,6 +13,20 @@ import (
 )
 
 func InitRouter(ctx *app.App) {
+	// 响应中带 mask 标签的字段按权限脱敏
+	app.SetMaskPolicy(service.DataMaskPolicy)
+	// 请求日志与操作日志异步落库
+	service.InitLogPipeline(ctx)
+	// 日志表定期清理
+	service.StartLogRetention(ctx)
+	// 过期验证码定期清理
+	service.StartVerificationCodeCleanup(ctx)
+	// 实体变更审计
+	service.InitAudit(ctx)
+	// 旧版本明文保存的 API Key 迁移为摘要
+	service.MigrateLegacyApiKeys(ctx)
+	// 访问令牌签名密钥
+	service.InitTokenSigner(ctx)
 	// Register all routers as a plugin so they are stored in App.Plugins and
 	// can be replayed into a host engine via RegisterIntoGinEngine.
 	ctx.RegisterPlugin(func(a *app.App) {
@@ -33,6 +47,15 @@ func InitRouter(ctx *app.App) {
 		InitPermissionUserRouter(a)
 		InitMenuAPIRouter(a)
 		InitTeamMemberRouter(a)
+		InitLogLevelRouter(a)
+		InitSysLogRouter(a)
+		InitSysAuditLogRouter(a)
+		InitJWKSRouter(a)
+		InitTwoFactorRouter(a)
+		InitOAuthRouter(a)
+		InitAppTokenRouter(a)
+		InitSessionRouter(a)
+		InitPasswordRouter(a)
 	})
 }
 
@@ -41,6 +64,13 @@ func InitRouter(ctx *app.App) {
 // host can later replay these callbacks into its own engine to avoid
 // double-registration on sgin's internal router.
 func InitRouterStored(ctx *app.App) {
+	app.SetMaskPolicy(service.DataMaskPolicy)
+	service.InitLogPipeline(ctx)
+	service.StartLogRetention(ctx)
+	service.StartVerificationCodeCleanup(ctx)
+	service.InitAudit(ctx)
+	service.MigrateLegacyApiKeys(ctx)
+	service.InitTokenSigner(ctx)
 	ctx.StorePlugin(func(a *app.App) {
 		InitSwaggerRouter(a)
 		InitUserRouter(a)
@@ -59,6 +89,15 @@ func InitRouterStored(ctx *app.App) {
 		InitPermissionUserRouter(a)
 		InitMenuAPIRouter(a)
 		InitTeamMemberRouter(a)
+		InitLogLevelRouter(a)
+		InitSysLogRouter(a)
+		InitSysAuditLogRouter(a)
+		InitJWKSRouter(a)
+		InitTwoFactorRouter(a)
+		InitOAuthRouter(a)
+		InitAppTokenRouter(a)
+		InitSessionRouter(a)
+		InitPasswordRouter(a)
 	})
 }
 
@@ -75,6 +114,7 @@ func InitUserRouter(ctx *app.App) {
 		v1.POST("/user/info", userController.GetUserByUUID)
 		v1.POST("/user/list", userController.GetUserList)
 		v1.POST("/user/update", userController.UpdateUser)
+		v1.POST("/user/status", userController.UpdateUserStatus)
 		v1.POST("/user/delete", userController.DeleteUser)
 		v1.GET("/user/myinfo", userController.GetMyInfo)
 		v1.POST("/user/avatar", userController.UpdateAvatar)
@@ -122,12 +162,18 @@ func InitAppRouter(ctx *app.App) {
 	v1.Use(appLimiter.HandleRateLimit())
 	{
 		appController := &controller.AppController{
-			AppService: &service.AppService{},
+			AppService:    &service.AppService{},
+			AppKeyService: &service.AppKeyService{},
 		}
 		v1.POST("/app/list", appController.GetAppList)
 		v1.POST("/app/create", appController.CreateApp)
 		v1.POST("/app/update", appController.UpdateApp)
 		v1.POST("/app/delete", appController.DeleteApp)
+		v1.POST("/app/key/list", appController.GetAppKeys)
+		v1.POST("/app/key/create", appController.CreateAppKey)
+		v1.POST("/app/key/rotate", appController.RotateAppKey)
+		v1.POST("/app/key/revoke", appController.RevokeAppKey)
+		v1.POST("/app/secret/rotate", appController.RotateAppSecret)
 	}
 }
 
@@ -135,7 +181,8 @@ func InitVerificationCodeRouter(ctx *app.App) {
 	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
 	{
 		verificationCodeController := &controller.VerificationCodeController{
-			VerificationCodeService: &service.VerificationCodeService{},
+			VerificationCodeService: service.NewVerificationCodeService(),
+			SmsService:              service.NewSmsService(),
 		}
 		v1.POST("/verification_code/create", verificationCodeController.CreateVerificationCode)
 	}
@@ -147,22 +194,138 @@ func InitRegisterRouter(ctx *app.App) {
 	{
 		registerController := &controller.RegisterController{
 			UserService:             &service.UserService{},
-			VerificationCodeService: &service.VerificationCodeService{},
+			VerificationCodeService: service.NewVerificationCodeService(),
 		}
 		v1.POST("/register", registerController.Register)
 	}
 }
 
+func InitPasswordRouter(ctx *app.App) {
+	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	{
+		passwordController := &controller.PasswordController{
+			PasswordResetService: service.NewPasswordResetService(),
+		}
+		v1.POST("/password/forgot", passwordController.Forgot)
+		v1.POST("/password/reset", passwordController.Reset)
+	}
+}
+
 func InitLoginRouter(ctx *app.App) {
 	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
 	{
 		loginController := &controller.LoginController{
-			UserService: &service.UserService{},
+			UserService:        &service.UserService{},
+			SysLoginLogService: &service.SysLoginLogService{},
+			AuthService:        service.NewAuthService(),
+			LoginGuardService:  service.NewLoginGuardService(),
+			TwoFactorService:   service.NewTwoFactorService(),
+			OAuthService:       service.NewOAuthService(),
 		}
 		v1.POST("/login", loginController.Login)
+		v1.POST("/login/2fa", loginController.LoginTwoFactor)
+		v1.POST("/login/2fa/enroll", loginController.LoginTwoFactorEnroll)
+		v1.POST("/refresh", loginController.Refresh)
+		v1.GET("/oauth/providers", loginController.GetOAuthProviders)
+		v1.GET("/oauth/:provider/authorize", loginController.OAuthAuthorize)
+		v1.GET("/oauth/:provider/callback", loginController.OAuthCallback)
+		v1.POST("/oauth/:provider/callback", loginController.OAuthCallback)
+
+		auth := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+		auth.Use(middleware.LoginCheck())
+		auth.POST("/logout", loginController.Logout)
 	}
 }
 
+func InitSessionRouter(ctx *app.App) {
+	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	v1.Use(middleware.LoginCheck())
+	v1.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	{
+		sessionController := &controller.SessionController{
+			SessionService: service.NewSessionService(),
+		}
+		v1.POST("/session/list", sessionController.GetSessions)
+		v1.POST("/session/revoke", sessionController.Revoke)
+	}
+
+	// 管理其他用户的会话，需要 Auth.SessionAdminPermissions 中的权限
+	admin := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	admin.Use(middleware.LoginCheck())
+	admin.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	admin.Use(middleware.RequirePermission(ctx.Config.Auth.SessionAdminPermissions))
+	{
+		sessionController := &controller.SessionController{
+			SessionService: service.NewSessionService(),
+		}
+		admin.POST("/session/user_list", sessionController.GetUserSessions)
+		admin.POST("/session/force_logout", sessionController.ForceLogout)
+	}
+}
+
+func InitTwoFactorRouter(ctx *app.App) {
+	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	v1.Use(middleware.LoginCheck())
+	v1.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	{
+		twoFactorController := &controller.TwoFactorController{
+			TwoFactorService: service.NewTwoFactorService(),
+			UserService:      service.NewUserService(),
+		}
+		v1.POST("/2fa/status", twoFactorController.GetStatus)
+		v1.POST("/2fa/enroll", twoFactorController.Enroll)
+		v1.POST("/2fa/confirm", twoFactorController.Confirm)
+		v1.POST("/2fa/disable", twoFactorController.Disable)
+		v1.POST("/2fa/recovery_codes", twoFactorController.RegenerateRecoveryCodes)
+	}
+
+	// 管理员重置其他用户的两步验证，需要 TwoFactor.ResetPermissions 中的权限
+	admin := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	admin.Use(middleware.LoginCheck())
+	admin.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	admin.Use(middleware.RequirePermission(ctx.Config.TwoFactor.ResetPermissions))
+	{
+		twoFactorController := &controller.TwoFactorController{
+			TwoFactorService: service.NewTwoFactorService(),
+			UserService:      service.NewUserService(),
+		}
+		admin.POST("/2fa/reset", twoFactorController.Reset)
+	}
+}
+
+func InitOAuthRouter(ctx *app.App) {
+	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	v1.Use(middleware.LoginCheck())
+	v1.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	{
+		oauthController := &controller.OAuthController{
+			OAuthService: service.NewOAuthService(),
+		}
+		v1.POST("/oauth/identities", oauthController.GetIdentities)
+		v1.POST("/oauth/link", oauthController.Link)
+		v1.POST("/oauth/unlink", oauthController.Unlink)
+	}
+}
+
+// 应用的 OAuth2 令牌端点（client credentials），使用客户端凭据认证
+func InitAppTokenRouter(ctx *app.App) {
+	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	{
+		appTokenController := &controller.AppTokenController{
+			AppTokenService: service.NewAppTokenService(),
+		}
+		v1.POST("/oauth/token", appTokenController.Token)
+		v1.POST("/oauth/introspect", appTokenController.Introspect)
+		v1.POST("/oauth/revoke", appTokenController.Revoke)
+	}
+}
+
+// JWKS 发布访问令牌的验证公钥，无需登录
+func InitJWKSRouter(ctx *app.App) {
+	jwksController := &controller.JWKSController{AuthService: service.NewAuthService()}
+	ctx.GET("/.well-known/jwks.json", jwksController.GetJWKS)
+}
+
 func InitServerRouter(ctx *app.App) {
 	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
 	v1.Use(middleware.LoginCheck())
@@ -239,6 +402,21 @@ func InitSysOpLogRouter(ctx *app.App) {
 		v1.POST("/sysoplog/delete", sysOpLogController.DeleteSysOpLog)
 		v1.POST("/sysoplog/info", sysOpLogController.GetSysOpLogInfo)
 		v1.POST("/sysoplog/list", sysOpLogController.GetSysOpLogList)
+		v1.POST("/sysoplog/export", sysOpLogController.ExportSysOpLog)
+	}
+}
+
+// 实体变更审计的路由，审计记录含变更前后的快照，需要 AdminPermissions 中的权限
+func InitSysAuditLogRouter(ctx *app.App) {
+	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	v1.Use(middleware.LoginCheck())
+	v1.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	v1.Use(middleware.RequirePermission(ctx.Config.AdminPermissions))
+	{
+		sysAuditLogController := &controller.SysAuditLogController{
+			SysAuditLogService: service.NewSysAuditLogService(),
+		}
+		v1.POST("/sys_audit_log/list", sysAuditLogController.GetSysAuditLogList)
 	}
 }
 
@@ -248,12 +426,27 @@ func InitSysLoginLogRouter(ctx *app.App) {
 	v1.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
 	{
 		sysLoginLogController := &controller.SysLoginLogController{
-			LoginLogService: &service.SysLoginLogService{},
+			LoginLogService:   &service.SysLoginLogService{},
+			LoginGuardService: service.NewLoginGuardService(),
 		}
 
 		v1.POST("/sys_login_log/info", sysLoginLogController.GetLoginLog)
 		v1.POST("/sys_login_log/list", sysLoginLogController.GetLoginLogList)
 	}
+
+	// 查看与解除登录锁定，需要 AdminPermissions 中的权限
+	admin := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	admin.Use(middleware.LoginCheck())
+	admin.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	admin.Use(middleware.RequirePermission(ctx.Config.AdminPermissions))
+	{
+		sysLoginLogController := &controller.SysLoginLogController{
+			LoginLogService:   &service.SysLoginLogService{},
+			LoginGuardService: service.NewLoginGuardService(),
+		}
+		admin.POST("/login_lock/list", sysLoginLogController.GetLoginLockList)
+		admin.POST("/login_lock/clear", sysLoginLogController.ClearLoginLock)
+	}
 }
 
 func InitPermissionRouter(ctx *app.App) {
@@ -323,6 +516,40 @@ func InitMenuAPIRouter(ctx *app.App) {
 	}
 }
 
+// 日志级别的路由，需要 AdminPermissions 中的权限
+func InitLogLevelRouter(ctx *app.App) {
+	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	v1.Use(middleware.LoginCheck())
+	v1.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	v1.Use(middleware.RequirePermission(ctx.Config.AdminPermissions))
+	{
+		logLevelController := &controller.LogLevelController{}
+		v1.POST("/sys_log_level/list", logLevelController.GetLogLevels)
+		v1.POST("/sys_log_level/update", logLevelController.UpdateLogLevel)
+		v1.POST("/sys_log_level/reset", logLevelController.ResetLogLevel)
+		v1.POST("/sys_log_level/debug_subject/list", logLevelController.GetDebugSubjects)
+		v1.POST("/sys_log_level/debug_subject/add", logLevelController.AddDebugSubject)
+		v1.POST("/sys_log_level/debug_subject/remove", logLevelController.RemoveDebugSubject)
+		v1.POST("/sys_log_level/debug_token", logLevelController.CreateDebugToken)
+	}
+}
+
+// 日志查询与清理的路由，需要 AdminPermissions 中的权限
+func InitSysLogRouter(ctx *app.App) {
+	v1 := ctx.Group(ctx.Config.ApiPrefix + "/v1")
+	v1.Use(middleware.LoginCheck())
+	v1.Use(middleware.SysOpLogMiddleware(&service.SysOpLogService{}))
+	v1.Use(middleware.RequirePermission(ctx.Config.AdminPermissions))
+	{
+		sysLogController := &controller.SysLogController{}
+		v1.POST("/sys_log/pipeline_stats", sysLogController.GetPipelineStats)
+		v1.POST("/sys_log/retention_stats", sysLogController.GetRetentionStats)
+		v1.POST("/sys_log/purge", sysLogController.PurgeLogs)
+		v1.POST("/sys_log/request/list", sysLogController.GetRequestLogList)
+		v1.POST("/sys_log/trace", sysLogController.GetTrace)
+	}
+}
+
 func InitSwaggerRouter(ctx *app.App) {
 	// ctx.GET("/swagger/doc.json", func(c *app.Context) {
 	// 	c.Header("Cache-Control", "public, max-age=3600")
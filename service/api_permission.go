@@ -78,7 +78,10 @@ func (s *AppPermissionService) GetAppAPIPermissions(ctx *app.Context, appUUID st
 // 根据name ，path，method获取api 权限信息
 func (s *AppPermissionService) GetAPIPermissionByNamePathMethod(ctx *app.Context, appUUID, path, method string) (*model.AppPermission, error) {
 	appPermission := &model.AppPermission{}
-	err := ctx.DB.Where("app_uuid = ? and path = ? and method = ?", appUUID, path, method).First(appPermission).Error
+	err := ctx.DB.Table("app_permissions").Select("app_permissions.*").
+		Joins("join apis on apis.uuid = app_permissions.api_uuid").
+		Where("app_permissions.app_uuid = ? and apis.path = ? and apis.method = ?", appUUID, path, method).
+		First(appPermission).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.New("api permissions  not found")
@@ -88,3 +91,20 @@ func (s *AppPermissionService) GetAPIPermissionByNamePathMethod(ctx *app.Context
 	}
 	return appPermission, nil
 }
+
+// GetAppAPIByPathMethod 返回应用有权限调用的接口，paths 可同时传入请求路径与路由模板
+func (s *AppPermissionService) GetAppAPIByPathMethod(ctx *app.Context, appUUID, method string, paths ...string) (*model.API, error) {
+	api := &model.API{}
+	err := ctx.DB.Table("apis").Select("apis.*").
+		Joins("join app_permissions on apis.uuid = app_permissions.api_uuid").
+		Where("app_permissions.app_uuid = ? and apis.path in ? and apis.method = ?", appUUID, paths, method).
+		First(api).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New("api permissions  not found")
+		}
+		ctx.Logger.Error("Failed to get app api by path method", err)
+		return nil, errors.New("failed to get app api by path method")
+	}
+	return api, nil
+}
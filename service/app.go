@@ -2,10 +2,13 @@ package service
 
 import (
 	"errors"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/utils"
 
 	"gorm.io/gorm"
 )
@@ -17,16 +20,112 @@ func NewAppService() *AppService {
 	return &AppService{}
 }
 
-func (s *AppService) CreateApp(ctx *app.Context, app *model.App) error {
+// appSecretEncPrefix 加密保存的 SecKey 的前缀，没有前缀的是旧版本明文保存的值
+const appSecretEncPrefix = "enc:"
+
+// appSecretKey 加密 SecKey 的密钥，必须单独配置，不与 PasswdKey 共用
+func appSecretKey(ctx app.AppContext) (string, error) {
+	cfg := ctx.GetConfig()
+	if cfg == nil || cfg.AppKey.EncryptionKey == "" {
+		return "", errors.New("AppKey.EncryptionKey is not configured")
+	}
+	return cfg.AppKey.EncryptionKey, nil
+}
+
+// newSecret 生成 SecKey，返回明文与加密后的保存值
+func (s *AppService) newSecret(ctx *app.Context) (string, string, error) {
+	key, err := appSecretKey(ctx)
+	if err != nil {
+		ctx.Logger.Error("Failed to get app secret encryption key", err)
+		return "", "", errors.New("app secret encryption is not configured")
+	}
+	secret := "sgs_" + utils.RandomToken(32)
+	encrypted, err := utils.EncryptString(key, secret)
+	if err != nil {
+		ctx.Logger.Error("Failed to encrypt app secret", err)
+		return "", "", errors.New("failed to encrypt app secret")
+	}
+	return secret, appSecretEncPrefix + encrypted, nil
+}
+
+// Secret 返回应用 SecKey 的明文；旧版本明文保存的值在此时加密保存
+func (s *AppService) Secret(ctx *app.Context, a *model.App) (string, error) {
+	if a.SecKey == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(a.SecKey, appSecretEncPrefix) {
+		if key, err := appSecretKey(ctx); err == nil {
+			if encrypted, err := utils.EncryptString(key, a.SecKey); err == nil {
+				err = ctx.DB.Model(&model.App{}).Where("id = ?", a.Id).Update("sec_key", appSecretEncPrefix+encrypted).Error
+				if err != nil {
+					ctx.Logger.Warnw("Failed to encrypt legacy app secret", "app_id", a.UUID, "error", err)
+				}
+			}
+		}
+		return a.SecKey, nil
+	}
+	key, err := appSecretKey(ctx)
+	if err != nil {
+		ctx.Logger.Error("Failed to get app secret encryption key", err)
+		return "", errors.New("app secret encryption is not configured")
+	}
+	secret, err := utils.DecryptString(key, strings.TrimPrefix(a.SecKey, appSecretEncPrefix))
+	if err != nil {
+		ctx.Logger.Error("Failed to decrypt app secret", err)
+		return "", errors.New("failed to decrypt app secret")
+	}
+	return secret, nil
+}
+
+// CreateApp 创建应用，由服务端生成UUID、SecKey 与第一个 API Key，明文只在返回值中出现一次
+func (s *AppService) CreateApp(ctx *app.Context, app *model.App) (*model.ResAppCreate, error) {
+	app.Id = 0
+	app.UUID = uuid.New().String()
+	app.ApiKey = ""
+	if app.Status == 0 {
+		app.Status = 1
+	}
 	app.CreatedAt = time.Now()
 	app.UpdatedAt = app.CreatedAt
 
-	err := ctx.DB.Create(app).Error
+	secret, stored, err := s.newSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	app.SecKey = stored
+	key, raw := newAppKey(ctx, app.UUID, "default", "", "", nil)
+
+	err = ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(app).Error; err != nil {
+			return err
+		}
+		return tx.Create(key).Error
+	})
 	if err != nil {
 		ctx.Logger.Error("Failed to create app", err)
-		return errors.New("failed to create app")
+		return nil, errors.New("failed to create app")
 	}
-	return nil
+	return &model.ResAppCreate{App: *app, ApiKey: raw, SecKey: secret}, nil
+}
+
+// RotateSecret 重新生成 SecKey，旧 SecKey 立即失效，已签发的访问令牌全部吊销
+func (s *AppService) RotateSecret(ctx *app.Context, uuid string) (*model.ResAppSecret, error) {
+	a, err := s.GetAppByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	secret, stored, err := s.newSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.DB.Model(a).Update("sec_key", stored).Error; err != nil {
+		ctx.Logger.Error("Failed to update app secret", err)
+		return nil, errors.New("failed to update app secret")
+	}
+	if err := NewAppTokenService().RevokeAppTokens(ctx, uuid); err != nil {
+		return nil, err
+	}
+	return &model.ResAppSecret{SecKey: secret}, nil
 }
 
 func (s *AppService) GetAppByUUID(ctx *app.Context, uuid string) (*model.App, error) {
@@ -42,27 +141,32 @@ func (s *AppService) GetAppByUUID(ctx *app.Context, uuid string) (*model.App, er
 	return app, nil
 }
 
+// GetAppByApiKey 校验 API Key 并返回所属应用，见 AppKeyService.Verify
 func (s *AppService) GetAppByApiKey(ctx *app.Context, apikey string) (*model.App, error) {
-	app := &model.App{}
-	err := ctx.DB.Where("api_key = ?", apikey).First(app).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, errors.New("app not found")
-		}
-		ctx.Logger.Error("Failed to get app by Apikey", err, "apikey:", apikey)
-		return nil, errors.New("failed to get app by apikey")
-	}
-	return app, nil
+	a, _, err := NewAppKeyService().Verify(ctx, apikey, ctx.ClientIP())
+	return a, err
 }
 
 func (s *AppService) UpdateApp(ctx *app.Context, app *model.App) error {
+	old, err := s.GetAppByUUID(ctx, app.UUID)
+	if err != nil {
+		return err
+	}
+
+	// 密钥通过单独的接口生成与轮换，这里只更新基础信息
 	app.UpdatedAt = time.Now()
-	err := ctx.DB.Save(app).Error
+	err = ctx.DB.Model(old).Select("name", "user_uuid", "status", "updated_at").Updates(app).Error
 	if err != nil {
 		ctx.Logger.Error("Failed to update app", err)
 		return errors.New("failed to update app")
 	}
+	app.Id, app.CreatedAt = old.Id, old.CreatedAt
+	invalidateAppKeys(app.UUID)
 
+	// 停用后，已签发的访问令牌全部失效
+	if app.Status != 1 {
+		return NewAppTokenService().RevokeAppTokens(ctx, app.UUID)
+	}
 	return nil
 }
 
@@ -73,7 +177,10 @@ func (s *AppService) DeleteApp(ctx *app.Context, uuid string) error {
 		return errors.New("failed to delete app")
 	}
 
-	return nil
+	if err := NewAppKeyService().revokeAppKeys(ctx, uuid); err != nil {
+		return err
+	}
+	return NewAppTokenService().RevokeAppTokens(ctx, uuid)
 }
 
 // 查询app列表
@@ -90,8 +197,8 @@ func (s *AppService) GetAppList(ctx *app.Context, params *model.ReqAppQueryParam
 		query = query.Where("name LIKE ?", "%"+params.Name+"%")
 	}
 
-	if params.ApiKey != "" {
-		query = query.Where("api_key LIKE ?", "%"+params.ApiKey+"%")
+	if params.KeyPrefix != "" {
+		query = query.Where("uuid IN (?)", ctx.DB.Model(&model.AppKey{}).Select("app_uuid").Where("prefix = ?", params.KeyPrefix))
 	}
 
 	if params.Status != 0 {
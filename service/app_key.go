@@ -0,0 +1,385 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAppKey         = errors.New("invalid api key")
+	ErrAppKeyIPNotAllowed    = errors.New("api key is not allowed from this ip")
+	ErrAppKeyNotFound        = errors.New("api key not found or no longer active")
+	ErrAppKeyLimitExceeded   = errors.New("too many active api keys")
+	ErrAppKeyInvalidArgument = errors.New("invalid allowed ips or expiry")
+)
+
+// AppKeyService 应用 API Key 的生成、轮换、吊销与校验。
+// 密钥格式为 <prefix>_<8位标识>_<随机串>，数据库只保存 SHA-256 摘要
+type AppKeyService struct {
+}
+
+func NewAppKeyService() *AppKeyService {
+	return &AppKeyService{}
+}
+
+func appKeyPrefix(ctx app.AppContext) string {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.AppKey.Prefix != "" {
+		return cfg.AppKey.Prefix
+	}
+	return "sgk"
+}
+
+func appKeyCacheTTL(ctx app.AppContext) time.Duration {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.AppKey.CacheTTL != 0 {
+		return time.Duration(cfg.AppKey.CacheTTL) * time.Second
+	}
+	return time.Minute
+}
+
+func appKeyRotationGrace(ctx app.AppContext) time.Duration {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.AppKey.RotationGrace > 0 {
+		return time.Duration(cfg.AppKey.RotationGrace) * time.Minute
+	}
+	return 24 * time.Hour
+}
+
+func appKeyMaxActive(ctx app.AppContext) int {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.AppKey.MaxActiveKeys > 0 {
+		return cfg.AppKey.MaxActiveKeys
+	}
+	return 5
+}
+
+// newAppKey 生成密钥，返回待保存的记录与只展示一次的明文
+func newAppKey(ctx app.AppContext, appUUID, name, scopes, allowedIPs string, expiresAt *time.Time) (*model.AppKey, string) {
+	prefix := appKeyPrefix(ctx) + "_" + utils.RandomToken(4)
+	raw := prefix + "_" + utils.RandomToken(24)
+	return &model.AppKey{
+		Uuid:       uuid.New().String(),
+		AppUuid:    appUUID,
+		Name:       name,
+		Prefix:     prefix,
+		KeyHash:    utils.SHA256Hex(raw),
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  expiresAt,
+	}, raw
+}
+
+// normalizeScopes 去重排序后以空格连接
+func normalizeScopes(scopes []string) string {
+	seen := map[string]bool{}
+	list := []string{}
+	for _, sc := range scopes {
+		for _, f := range strings.Fields(sc) {
+			if !seen[f] {
+				seen[f] = true
+				list = append(list, f)
+			}
+		}
+	}
+	sort.Strings(list)
+	return strings.Join(list, " ")
+}
+
+// normalizeAllowedIPs 校验 IP 与 CIDR 后以逗号连接
+func normalizeAllowedIPs(ips []string) (string, error) {
+	list, err := utils.NormalizeIPList(ips)
+	if err != nil {
+		return "", ErrAppKeyInvalidArgument
+	}
+	return list, nil
+}
+
+// appKeyCacheEntry 校验通过的密钥与应用，按密钥摘要缓存在本机
+type appKeyCacheEntry struct {
+	key     model.AppKey
+	app     model.App
+	expires time.Time
+}
+
+// appKeyCacheMax 缓存条数上限，超出时整体清空
+const appKeyCacheMax = 10000
+
+var appKeyCache = struct {
+	sync.Mutex
+	m map[string]*appKeyCacheEntry
+}{m: map[string]*appKeyCacheEntry{}}
+
+// invalidateAppKeys 清除应用在本机缓存的密钥，其他实例的缓存在 CacheTTL 后过期
+func invalidateAppKeys(appUUID string) {
+	appKeyCache.Lock()
+	defer appKeyCache.Unlock()
+	for h, e := range appKeyCache.m {
+		if e.app.UUID == appUUID {
+			delete(appKeyCache.m, h)
+		}
+	}
+}
+
+// Verify 校验 API Key，返回所属应用与密钥；只有有效的密钥与启用的应用可以通过。
+// 校验结果按摘要在本机缓存 CacheTTL，本机上的吊销、轮换与应用变更会立即清除缓存
+func (s *AppKeyService) Verify(ctx *app.Context, raw, clientIP string) (*model.App, *model.AppKey, error) {
+	if raw == "" {
+		return nil, nil, ErrInvalidAppKey
+	}
+	hash := utils.SHA256Hex(raw)
+	now := time.Now()
+
+	appKeyCache.Lock()
+	e := appKeyCache.m[hash]
+	if e != nil && now.After(e.expires) {
+		delete(appKeyCache.m, hash)
+		e = nil
+	}
+	appKeyCache.Unlock()
+
+	if e == nil {
+		key, a, err := s.load(ctx, hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		e = &appKeyCacheEntry{key: *key, app: *a}
+		if ttl := appKeyCacheTTL(ctx); ttl > 0 {
+			e.expires = now.Add(ttl)
+			appKeyCache.Lock()
+			if len(appKeyCache.m) >= appKeyCacheMax {
+				appKeyCache.m = map[string]*appKeyCacheEntry{}
+			}
+			appKeyCache.m[hash] = e
+			appKeyCache.Unlock()
+		}
+	}
+
+	appKeyCache.Lock()
+	key, a := e.key, e.app
+	// 最近使用时间每个密钥每分钟最多写一次数据库
+	touch := key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= time.Minute
+	if touch {
+		e.key.LastUsedAt, e.key.LastUsedIP = &now, clientIP
+	}
+	appKeyCache.Unlock()
+
+	if !key.Active(now) || a.Status != 1 {
+		return nil, nil, ErrInvalidAppKey
+	}
+	if !utils.IPAllowed(key.AllowedIPs, clientIP) {
+		return nil, nil, ErrAppKeyIPNotAllowed
+	}
+	if touch {
+		err := ctx.DB.Model(&model.AppKey{}).Where("id = ?", key.ID).
+			UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": clientIP}).Error
+		if err != nil {
+			ctx.Logger.Warnw("Failed to update api key last used time", "prefix", key.Prefix, "error", err)
+		}
+	}
+	return &a, &key, nil
+}
+
+// load 按摘要查找密钥，旧版本明文保存的密钥在启动时由 MigrateLegacyApiKeys 迁移
+func (s *AppKeyService) load(ctx *app.Context, hash string) (*model.AppKey, *model.App, error) {
+	key := &model.AppKey{}
+	err := ctx.DB.Where("key_hash = ?", hash).First(key).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, ErrInvalidAppKey
+	}
+	if err != nil {
+		ctx.Logger.Error("Failed to get api key", err)
+		return nil, nil, errors.New("failed to get api key")
+	}
+	a, err := NewAppService().GetAppByUUID(ctx, key.AppUuid)
+	if err != nil {
+		if err.Error() == "app not found" {
+			return nil, nil, ErrInvalidAppKey
+		}
+		return nil, nil, err
+	}
+	return key, a, nil
+}
+
+// MigrateLegacyApiKeys 启动时将旧版本明文保存在 apps.api_key 中的密钥转为摘要保存到 app_keys 并清空该列，
+// 已迁移的密钥只清空该列
+func MigrateLegacyApiKeys(a *app.App) {
+	if a == nil || a.DB == nil {
+		return
+	}
+	var apps []*model.App
+	if err := a.DB.Where("api_key IS NOT NULL AND api_key <> ''").Find(&apps).Error; err != nil {
+		a.Logger.Error("Failed to get apps with legacy api keys", err)
+		return
+	}
+	for _, m := range apps {
+		if err := migrateLegacyApiKey(a.DB, m); err != nil {
+			a.Logger.Errorw("Failed to migrate legacy api key", "app_id", m.UUID, "error", err)
+			continue
+		}
+		a.Logger.Infow("legacy api key migrated", "app_id", m.UUID)
+	}
+}
+
+func migrateLegacyApiKey(db *gorm.DB, a *model.App) error {
+	hash := utils.SHA256Hex(a.ApiKey)
+	prefix := a.ApiKey
+	if len(prefix) > 8 {
+		prefix = prefix[:8]
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&model.AppKey{}).Where("key_hash = ?", hash).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			key := &model.AppKey{
+				Uuid:    uuid.New().String(),
+				AppUuid: a.UUID,
+				Name:    "legacy",
+				Prefix:  prefix,
+				KeyHash: hash,
+			}
+			if err := tx.Create(key).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&model.App{}).Where("id = ?", a.Id).Update("api_key", "").Error
+	})
+}
+
+// ListAppKeys 返回应用的全部密钥（不含明文与摘要）
+func (s *AppKeyService) ListAppKeys(ctx *app.Context, appUUID string) ([]*model.AppKey, error) {
+	var keys []*model.AppKey
+	err := ctx.DB.Where("app_uuid = ?", appUUID).Order("id desc").Find(&keys).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to list api keys", err)
+		return nil, errors.New("failed to list api keys")
+	}
+	return keys, nil
+}
+
+func (s *AppKeyService) countActive(ctx *app.Context, appUUID string) (int64, error) {
+	var n int64
+	err := ctx.DB.Model(&model.AppKey{}).
+		Where("app_uuid = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", appUUID, time.Now()).
+		Count(&n).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to count api keys", err)
+		return 0, errors.New("failed to count api keys")
+	}
+	return n, nil
+}
+
+// CreateAppKey 为应用新建密钥，同时有效的密钥数受 MaxActiveKeys 限制
+func (s *AppKeyService) CreateAppKey(ctx *app.Context, param *model.ReqAppKeyCreate) (*model.ResAppKeyCreate, error) {
+	if _, err := NewAppService().GetAppByUUID(ctx, param.AppUuid); err != nil {
+		return nil, err
+	}
+	ips, err := normalizeAllowedIPs(param.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	if param.ExpiresAt != nil && !param.ExpiresAt.After(time.Now()) {
+		return nil, ErrAppKeyInvalidArgument
+	}
+	n, err := s.countActive(ctx, param.AppUuid)
+	if err != nil {
+		return nil, err
+	}
+	if n >= int64(appKeyMaxActive(ctx)) {
+		return nil, ErrAppKeyLimitExceeded
+	}
+
+	key, raw := newAppKey(ctx, param.AppUuid, param.Name, normalizeScopes(param.Scopes), ips, param.ExpiresAt)
+	if err := ctx.DB.Create(key).Error; err != nil {
+		ctx.Logger.Error("Failed to create api key", err)
+		return nil, errors.New("failed to create api key")
+	}
+	return &model.ResAppKeyCreate{AppKey: *key, Key: raw}, nil
+}
+
+// RotateAppKey 生成新密钥并沿用旧密钥的名称、scope 与 IP 限制，旧密钥在宽限期结束后失效
+func (s *AppKeyService) RotateAppKey(ctx *app.Context, param *model.ReqAppKeyRotate) (*model.ResAppKeyCreate, error) {
+	old := &model.AppKey{}
+	err := ctx.DB.Where("uuid = ?", param.Uuid).First(old).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrAppKeyNotFound
+	}
+	if err != nil {
+		ctx.Logger.Error("Failed to get api key", err)
+		return nil, errors.New("failed to get api key")
+	}
+	now := time.Now()
+	if !old.Active(now) {
+		return nil, ErrAppKeyNotFound
+	}
+
+	grace := appKeyRotationGrace(ctx)
+	if param.GraceMinutes != nil {
+		if *param.GraceMinutes < 0 {
+			return nil, ErrAppKeyInvalidArgument
+		}
+		grace = time.Duration(*param.GraceMinutes) * time.Minute
+	}
+	updates := map[string]interface{}{}
+	if grace == 0 {
+		updates["revoked_at"] = now
+	} else if end := now.Add(grace); old.ExpiresAt == nil || end.Before(*old.ExpiresAt) {
+		updates["expires_at"] = end
+	}
+
+	key, raw := newAppKey(ctx, old.AppUuid, old.Name, old.Scopes, old.AllowedIPs, nil)
+	err = ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(old).Updates(updates).Error
+	})
+	if err != nil {
+		ctx.Logger.Error("Failed to rotate api key", err)
+		return nil, errors.New("failed to rotate api key")
+	}
+	invalidateAppKeys(old.AppUuid)
+	return &model.ResAppKeyCreate{AppKey: *key, Key: raw}, nil
+}
+
+// RevokeAppKey 立即吊销密钥
+func (s *AppKeyService) RevokeAppKey(ctx *app.Context, keyUUID string) error {
+	key := &model.AppKey{}
+	err := ctx.DB.Where("uuid = ? AND revoked_at IS NULL", keyUUID).First(key).Error
+	if err == gorm.ErrRecordNotFound {
+		return ErrAppKeyNotFound
+	}
+	if err != nil {
+		ctx.Logger.Error("Failed to get api key", err)
+		return errors.New("failed to get api key")
+	}
+	if err := ctx.DB.Model(key).Update("revoked_at", time.Now()).Error; err != nil {
+		ctx.Logger.Error("Failed to revoke api key", err)
+		return errors.New("failed to revoke api key")
+	}
+	invalidateAppKeys(key.AppUuid)
+	return nil
+}
+
+// revokeAppKeys 吊销应用的全部密钥，用于删除应用
+func (s *AppKeyService) revokeAppKeys(ctx *app.Context, appUUID string) error {
+	err := ctx.DB.Model(&model.AppKey{}).Where("app_uuid = ? AND revoked_at IS NULL", appUUID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to revoke api keys", err)
+		return errors.New("failed to revoke api keys")
+	}
+	invalidateAppKeys(appUUID)
+	return nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/utils"
+)
+
+var (
+	ErrInvalidClient = errors.New("invalid client")
+	ErrInvalidScope  = errors.New("invalid scope")
+)
+
+// DefaultAPIScope 未设置模块的接口对应的 scope
+const DefaultAPIScope = "default"
+
+// AppTokenService 应用的 OAuth2 授权服务（client credentials）：签发、校验、内省与吊销访问令牌。
+// scope 对应接口所属的模块（API.Module），应用能申请的 scope 是其 AppPermission 中接口的模块
+type AppTokenService struct {
+}
+
+func NewAppTokenService() *AppTokenService {
+	return &AppTokenService{}
+}
+
+func appTokenTTL(ctx app.AppContext) time.Duration {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.Auth.AppTokenTTL > 0 {
+		return time.Duration(cfg.Auth.AppTokenTTL) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// APIScope 返回访问接口需要的 scope
+func APIScope(api *model.API) string {
+	if api.Module == "" {
+		return DefaultAPIScope
+	}
+	return api.Module
+}
+
+// ScopeAllowed scopes 中是否包含 scope
+func ScopeAllowed(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate 使用应用UUID（client_id）与 SecKey（client_secret）认证应用，只有启用的应用可以认证
+func (s *AppTokenService) Authenticate(ctx *app.Context, clientID, clientSecret string) (*model.App, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, ErrInvalidClient
+	}
+	a, err := NewAppService().GetAppByUUID(ctx, clientID)
+	if err != nil {
+		if err.Error() == "app not found" {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+	if a.Status != 1 {
+		return nil, ErrInvalidClient
+	}
+	secret, err := NewAppService().Secret(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(clientSecret)) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return a, nil
+}
+
+// GrantedScopes 返回应用可以申请的 scope
+func (s *AppTokenService) GrantedScopes(ctx *app.Context, appUUID string) ([]string, error) {
+	apis, err := NewAppPermissionService().GetAppAPIPermissions(ctx, appUUID)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, api := range apis {
+		if sc := APIScope(api); !seen[sc] {
+			seen[sc] = true
+			scopes = append(scopes, sc)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes, nil
+}
+
+// Issue 签发访问令牌；scope 为空时授予应用的全部 scope，否则必须是其子集
+func (s *AppTokenService) Issue(ctx *app.Context, a *model.App, scope string) (*model.ResAppToken, error) {
+	granted, err := s.GrantedScopes(ctx, a.UUID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := granted
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		seen := map[string]bool{}
+		scopes = scopes[:0:0]
+		for _, sc := range requested {
+			if !ScopeAllowed(granted, sc) {
+				return nil, ErrInvalidScope
+			}
+			if !seen[sc] {
+				seen[sc] = true
+				scopes = append(scopes, sc)
+			}
+		}
+		sort.Strings(scopes)
+	}
+
+	ttl := appTokenTTL(ctx)
+	tok, _, err := utils.GenerateAppToken(a.UUID, scopes, ttl)
+	if err != nil {
+		ctx.Logger.Error("Failed to generate app token", err)
+		return nil, errors.New("failed to generate app token")
+	}
+	return &model.ResAppToken{
+		AccessToken: tok,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl / time.Second),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// ParseToken 校验应用访问令牌：签名、有效期、是否已吊销，以及应用是否仍处于启用状态
+func (s *AppTokenService) ParseToken(ctx *app.Context, tokenString string) (*utils.AppTokenClaims, *model.App, error) {
+	claims, err := utils.ParseAppTokenClaims(tokenString)
+	if err != nil {
+		return nil, nil, err
+	}
+	if store := NewTokenStore(ctx); store != nil {
+		revoked, err := store.IsAccessTokenRevoked(ctx.Ctx, claims.JTI)
+		if err != nil {
+			ctx.Logger.Error("Failed to check app token revocation", err)
+			return nil, nil, errors.New("failed to check app token revocation")
+		}
+		if revoked {
+			return nil, nil, ErrTokenRevoked
+		}
+		before, err := store.RevokedBefore(ctx.Ctx, claims.ClientID)
+		if err != nil {
+			ctx.Logger.Error("Failed to get app token revocation", err)
+			return nil, nil, errors.New("failed to get app token revocation")
+		}
+		if !before.IsZero() && !claims.IssuedAt.After(before) {
+			return nil, nil, ErrTokenRevoked
+		}
+	}
+	a, err := NewAppService().GetAppByUUID(ctx, claims.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.Status != 1 {
+		return nil, nil, ErrInvalidClient
+	}
+	return claims, a, nil
+}
+
+// Introspect 返回令牌状态（RFC 7662）；应用只能查询自己的令牌，其他令牌一律视为无效
+func (s *AppTokenService) Introspect(ctx *app.Context, caller *model.App, tokenString string) *model.ResAppTokenIntrospection {
+	claims, _, err := s.ParseToken(ctx, tokenString)
+	if err != nil || claims.ClientID != caller.UUID {
+		return &model.ResAppTokenIntrospection{Active: false}
+	}
+	return &model.ResAppTokenIntrospection{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes, " "),
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Sub:       claims.ClientID,
+		Jti:       claims.JTI,
+	}
+}
+
+// Revoke 吊销应用自己的访问令牌（RFC 7009）；无效或不属于该应用的令牌直接忽略
+func (s *AppTokenService) Revoke(ctx *app.Context, caller *model.App, tokenString string) error {
+	claims, err := utils.ParseAppTokenClaims(tokenString)
+	if err != nil || claims.JTI == "" {
+		return nil
+	}
+	if claims.ClientID != caller.UUID {
+		ctx.Logger.Warnw("app tried to revoke token of another app", "app_id", caller.UUID, "token_app_id", claims.ClientID)
+		return nil
+	}
+	store := NewTokenStore(ctx)
+	if store == nil {
+		return errors.New("token store is not available")
+	}
+	if err := store.RevokeAccessToken(ctx.Ctx, claims.JTI, claims.ExpiresAt); err != nil {
+		ctx.Logger.Error("Failed to revoke app token", err)
+		return errors.New("failed to revoke app token")
+	}
+	return nil
+}
+
+// RevokeAppTokens 使应用此前签发的访问令牌全部失效，用于更换密钥、停用或删除应用；
+// 与用户共用按主体吊销的记录，应用UUID与用户UUID不会冲突
+func (s *AppTokenService) RevokeAppTokens(ctx *app.Context, appUUID string) error {
+	store := NewTokenStore(ctx)
+	if store == nil {
+		return nil
+	}
+	now := time.Now().Truncate(time.Second)
+	if err := store.RevokeUserTokens(ctx.Ctx, appUUID, now, now.Add(appTokenTTL(ctx))); err != nil {
+		ctx.Logger.Error("Failed to revoke app tokens", err)
+		return errors.New("failed to revoke app tokens")
+	}
+	return nil
+}
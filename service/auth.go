@@ -0,0 +1,259 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/token"
+	"github.com/luxingwen/sgin/pkg/utils"
+)
+
+// LoginCheck 写入 gin.Context 的访问令牌信息
+const (
+	CtxTokenJTI = "token_jti"
+	CtxTokenExp = "token_exp"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrTokenRevoked        = errors.New("token revoked")
+)
+
+// AuthService 签发访问令牌与刷新令牌，处理刷新、登出与吊销
+type AuthService struct {
+}
+
+func NewAuthService() *AuthService {
+	return &AuthService{}
+}
+
+// InitTokenSigner 按 App 配置加载 JWT 签名密钥，未配置或无法加载时终止启动
+func InitTokenSigner(a *app.App) {
+	if a == nil {
+		return
+	}
+	m, err := token.NewFromConfig(a.Config)
+	if err != nil {
+		a.Logger.Fatalw("Failed to load JWT signing keys", "error", err)
+	}
+	token.SetDefault(m)
+	a.Logger.Infow("jwt signing key loaded", "alg", m.ActiveKey().Method.Alg(), "kid", m.ActiveKey().Kid)
+}
+
+// JWKS 返回用于验证访问令牌的公钥集合
+func (s *AuthService) JWKS() (*token.JWKSet, error) {
+	m, err := token.Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.JWKS(), nil
+}
+
+func accessTokenTTL(ctx app.AppContext) time.Duration {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.Auth.AccessTokenTTL > 0 {
+		return time.Duration(cfg.Auth.AccessTokenTTL) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+func refreshTokenTTL(ctx app.AppContext) time.Duration {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.Auth.RefreshTokenTTL > 0 {
+		return time.Duration(cfg.Auth.RefreshTokenTTL) * time.Hour
+	}
+	return 30 * 24 * time.Hour
+}
+
+// IssueTokens 为用户签发访问令牌与刷新令牌，familyID 为空时开始新的令牌链（新登录）
+// 令牌链即登录会话，familyID 同时作为会话ID写入访问令牌
+func (s *AuthService) IssueTokens(ctx *app.Context, userUuid, familyID string) (*model.ResUserLogin, error) {
+	store := NewTokenStore(ctx)
+	if store == nil {
+		// 没有可用的存储时只签发访问令牌
+		token, claims, err := utils.GenerateAccessToken(userUuid, accessTokenTTL(ctx))
+		if err != nil {
+			ctx.Logger.Error("Failed to generate access token", err)
+			return nil, errors.New("failed to generate access token")
+		}
+		return &model.ResUserLogin{Token: token, ExpiresAt: claims.ExpiresAt.Unix()}, nil
+	}
+
+	newLogin := familyID == ""
+	if newLogin {
+		familyID = utils.RandomToken(16)
+	}
+	token, claims, err := utils.GenerateSessionToken(userUuid, familyID, accessTokenTTL(ctx))
+	if err != nil {
+		ctx.Logger.Error("Failed to generate access token", err)
+		return nil, errors.New("failed to generate access token")
+	}
+	res := &model.ResUserLogin{Token: token, ExpiresAt: claims.ExpiresAt.Unix()}
+
+	refresh := utils.RandomToken(32)
+	rt := &model.RefreshToken{
+		FamilyId:  familyID,
+		UserUuid:  userUuid,
+		Ip:        ctx.ClientIP(),
+		UserAgent: ctx.GetHeader("User-Agent"),
+		ExpiresAt: time.Now().Add(refreshTokenTTL(ctx)),
+	}
+	if len(rt.UserAgent) > 255 {
+		rt.UserAgent = rt.UserAgent[:255]
+	}
+	if err := store.SaveRefreshToken(ctx.Ctx, utils.SHA256Hex(refresh), rt); err != nil {
+		ctx.Logger.Error("Failed to save refresh token", err)
+		return nil, errors.New("failed to save refresh token")
+	}
+	res.RefreshToken = refresh
+	res.RefreshExpiresAt = rt.ExpiresAt.Unix()
+
+	if newLogin {
+		if err := NewSessionService().Create(ctx, userUuid, familyID, rt.ExpiresAt); err != nil {
+			return nil, err
+		}
+	} else {
+		NewSessionService().Extend(ctx, userUuid, familyID, rt.ExpiresAt)
+	}
+	return res, nil
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌与刷新令牌，旧刷新令牌随即失效；
+// 已使用过的刷新令牌再次出现视为泄露，吊销整个令牌链
+func (s *AuthService) Refresh(ctx *app.Context, refreshToken string) (*model.ResUserLogin, error) {
+	store := NewTokenStore(ctx)
+	if store == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rt, used, err := store.UseRefreshToken(ctx.Ctx, utils.SHA256Hex(refreshToken))
+	if err != nil {
+		ctx.Logger.Error("Failed to use refresh token", err)
+		return nil, errors.New("failed to use refresh token")
+	}
+	if rt == nil || rt.Revoked || time.Now().After(rt.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if used {
+		ctx.Logger.Warnw("refresh token reused, revoking token family",
+			"user_uuid", rt.UserUuid, "family_id", rt.FamilyId, "client_ip", ctx.ClientIP())
+		if err := store.RevokeFamily(ctx.Ctx, rt.FamilyId, time.Now().Add(refreshTokenTTL(ctx))); err != nil {
+			ctx.Logger.Error("Failed to revoke token family", err)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	before, err := store.RevokedBefore(ctx.Ctx, rt.UserUuid)
+	if err != nil {
+		ctx.Logger.Error("Failed to get user token revocation", err)
+		return nil, errors.New("failed to get user token revocation")
+	}
+	if !before.IsZero() && !rt.CreatedAt.After(before) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := NewUserService().GetUserByUUID(ctx, rt.UserUuid)
+	if err != nil || !user.Active() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.IssueTokens(ctx, rt.UserUuid, rt.FamilyId)
+}
+
+// Logout 吊销当前访问令牌；提供刷新令牌时吊销其令牌链，all 为 true 时吊销用户的所有令牌
+func (s *AuthService) Logout(ctx *app.Context, userUuid string, param *model.ReqLogout) error {
+	store := NewTokenStore(ctx)
+	if store == nil {
+		return nil
+	}
+
+	// 结束当前会话
+	if sid := ctx.GetString(CtxSessionID); sid != "" {
+		if err := NewSessionService().Revoke(ctx, userUuid, sid); err != nil && err != ErrSessionNotFound {
+			return err
+		}
+	}
+
+	if jti := ctx.GetString(CtxTokenJTI); jti != "" {
+		until := ctx.GetTime(CtxTokenExp)
+		if err := store.RevokeAccessToken(ctx.Ctx, jti, until); err != nil {
+			ctx.Logger.Error("Failed to revoke access token", err)
+			return errors.New("failed to revoke access token")
+		}
+	}
+
+	if param.RefreshToken != "" {
+		rt, err := store.GetRefreshToken(ctx.Ctx, utils.SHA256Hex(param.RefreshToken))
+		if err != nil {
+			ctx.Logger.Error("Failed to get refresh token", err)
+			return errors.New("failed to get refresh token")
+		}
+		// 只允许吊销自己的令牌
+		if rt != nil && rt.UserUuid == userUuid {
+			if err := store.RevokeFamily(ctx.Ctx, rt.FamilyId, rt.ExpiresAt); err != nil {
+				ctx.Logger.Error("Failed to revoke token family", err)
+				return errors.New("failed to revoke token family")
+			}
+			if err := NewSessionService().Revoke(ctx, userUuid, rt.FamilyId); err != nil && err != ErrSessionNotFound {
+				return err
+			}
+		}
+	}
+
+	if param.All {
+		return s.RevokeUserTokens(ctx, userUuid)
+	}
+	return nil
+}
+
+// RevokeUserTokens 使用户当前所有的会话、访问令牌与刷新令牌失效，用于登出所有设备、禁用或删除用户
+func (s *AuthService) RevokeUserTokens(ctx app.AppContext, userUuid string) error {
+	if err := deleteUserSessions(ctx, userUuid); err != nil {
+		return err
+	}
+	store := NewTokenStore(ctx)
+	if store == nil {
+		return nil
+	}
+	now := time.Now().Truncate(time.Second)
+	until := now.Add(refreshTokenTTL(ctx))
+	if ttl := accessTokenTTL(ctx); ttl > refreshTokenTTL(ctx) {
+		until = now.Add(ttl)
+	}
+	if err := store.RevokeUserTokens(ctx.GetCtx(), userUuid, now, until); err != nil {
+		ctx.GetLogger().Error("Failed to revoke user tokens", err)
+		return errors.New("failed to revoke user tokens")
+	}
+	return nil
+}
+
+// CheckAccessToken 检查访问令牌是否已被吊销：jti 在吊销列表中，签发时间不晚于用户的令牌失效时间点，或所属会话已结束
+func (s *AuthService) CheckAccessToken(ctx *app.Context, claims *utils.TokenClaims) error {
+	store := NewTokenStore(ctx)
+	if store == nil {
+		return nil
+	}
+	if claims.JTI != "" {
+		revoked, err := store.IsAccessTokenRevoked(ctx.Ctx, claims.JTI)
+		if err != nil {
+			ctx.Logger.Error("Failed to check access token revocation", err)
+			return errors.New("failed to check access token revocation")
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+	before, err := store.RevokedBefore(ctx.Ctx, claims.UserID)
+	if err != nil {
+		ctx.Logger.Error("Failed to get user token revocation", err)
+		return errors.New("failed to get user token revocation")
+	}
+	if !before.IsZero() && !claims.IssuedAt.After(before) {
+		return ErrTokenRevoked
+	}
+	// 有存储时签发的令牌都属于登录会话，不带会话ID的旧令牌不再接受；会话被结束后，属于该会话的访问令牌失效
+	if claims.SessionID == "" {
+		return ErrTokenRevoked
+	}
+	return NewSessionService().Check(ctx, claims.UserID, claims.SessionID)
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"github.com/luxingwen/sgin/pkg/app"
+)
+
+// DataMaskPolicy 响应脱敏策略：DataMask.Disable 时不脱敏；
+// 已登录且拥有 DataMask.UnmaskPermissions 中任一权限的用户查看原始数据；查询失败时按脱敏处理
+func DataMaskPolicy(ctx *app.Context) bool {
+	if ctx.Config == nil {
+		return true
+	}
+	cfg := ctx.Config.DataMask
+	if cfg.Disable {
+		return false
+	}
+	userId := ctx.GetString("user_id")
+	if userId == "" || len(cfg.UnmaskPermissions) == 0 {
+		return true
+	}
+	ok, err := NewUserPermissionService().HasAnyPermission(ctx, userId, cfg.UnmaskPermissions)
+	if err != nil {
+		return true
+	}
+	return !ok
+}
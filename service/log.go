@@ -1,11 +1,15 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
+	"net/http"
+	"sort"
 	"time"
 
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/redact"
 )
 
 type LogService struct {
@@ -39,3 +43,138 @@ func (s *LogService) UpdateLog(ctx *app.Context, log *model.Log) error {
 
 	return nil
 }
+
+// 异步创建日志，启用异步日志管道时只放入缓冲区，否则同步写入
+func (s *LogService) CreateLogAsync(ctx *app.Context, log *model.Log) error {
+	p := logPipelinesOf(ctx)
+	if p == nil {
+		return s.CreateLog(ctx, log)
+	}
+	log.CreatedAt = time.Now()
+	log.UpdatedAt = log.CreatedAt
+	p.log.Push(log)
+	return nil
+}
+
+// GetLogList 按请求ID、用户、调用方、路径、状态与时间范围查询请求日志，请求头与请求/响应体脱敏后返回
+func (s *LogService) GetLogList(ctx *app.Context, params *model.ReqLogQueryParam) (*model.PagedResponse, error) {
+	var (
+		logs  []*model.Log
+		total int64
+	)
+
+	db := ctx.DB.Model(&model.Log{})
+
+	if params.TraceID != "" {
+		db = db.Where("trace_id = ?", params.TraceID)
+	}
+	if params.UserUuid != "" {
+		db = db.Where("user_uuid = ?", params.UserUuid)
+	}
+	if params.AppId != "" {
+		db = db.Where("app_id = ?", params.AppId)
+	}
+	if params.Path != "" {
+		db = db.Where("path LIKE ?", "%"+params.Path+"%")
+	}
+	if params.Method != "" {
+		db = db.Where("method = ?", params.Method)
+	}
+	if params.Status != 0 {
+		db = db.Where("status = ?", params.Status)
+	}
+	if params.StartTime != "" {
+		db = db.Where("created_at >= ?", params.StartTime)
+	}
+	if params.EndTime != "" {
+		db = db.Where("created_at <= ?", params.EndTime)
+	}
+
+	err := db.Count(&total).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to get log count", err)
+		return nil, errors.New("failed to get log count")
+	}
+
+	err = db.Order("id DESC").Offset(params.GetOffset()).Limit(params.PageSize).Find(&logs).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to get log list", err)
+		return nil, errors.New("failed to get log list")
+	}
+	for _, log := range logs {
+		s.maskLog(ctx, log)
+	}
+
+	return &model.PagedResponse{
+		Total:    total,
+		Data:     logs,
+		Current:  params.Current,
+		PageSize: params.PageSize,
+	}, nil
+}
+
+// maskLog 对请求头与请求/响应体脱敏。写入时已经脱敏，这里按当前规则再处理一次，覆盖规则调整前写入的数据
+func (s *LogService) maskLog(ctx *app.Context, log *model.Log) {
+	if log.Header != "" {
+		header := http.Header{}
+		if err := json.Unmarshal([]byte(log.Header), &header); err == nil {
+			if b, err := json.Marshal(redact.Header(header)); err == nil {
+				log.Header = string(b)
+			}
+		}
+	}
+	r := ctx.Redactor()
+	log.ReqBody = string(r.Body("", []byte(log.ReqBody)))
+	log.RespBody = string(r.Body("", []byte(log.RespBody)))
+}
+
+// GetTrace 返回同一请求ID下的请求日志、操作日志、登录日志与审计日志，按时间排序
+func (s *LogService) GetTrace(ctx *app.Context, traceID string) (*model.ResTraceView, error) {
+	var (
+		logs      []*model.Log
+		opLogs    []*model.SysOpLog
+		loginLogs []*model.SysLoginLog
+		auditLogs []*model.SysAuditLog
+	)
+	view := &model.ResTraceView{TraceID: traceID, Timeline: make([]*model.ResTraceEvent, 0)}
+
+	if err := ctx.DB.Where("trace_id = ?", traceID).Order("id").Find(&logs).Error; err != nil {
+		ctx.Logger.Error("Failed to get logs by trace ID", err)
+		return nil, errors.New("failed to get logs by trace ID")
+	}
+	for _, log := range logs {
+		s.maskLog(ctx, log)
+		view.Timeline = append(view.Timeline, &model.ResTraceEvent{Type: model.TraceEventRequest, Time: log.CreatedAt.Format(logTimeLayout), Data: log})
+	}
+
+	if err := ctx.DB.Where("request_id = ?", traceID).Order("id").Find(&opLogs).Error; err != nil {
+		ctx.Logger.Error("Failed to get operation logs by trace ID", err)
+		return nil, errors.New("failed to get operation logs by trace ID")
+	}
+	for _, log := range opLogs {
+		log.Params = string(ctx.Redactor().Body("", []byte(log.Params)))
+		view.Timeline = append(view.Timeline, &model.ResTraceEvent{Type: model.TraceEventOp, Time: log.CreatedAt, Data: log})
+	}
+
+	if err := ctx.DB.Where("trace_id = ?", traceID).Order("id").Find(&loginLogs).Error; err != nil {
+		ctx.Logger.Error("Failed to get login logs by trace ID", err)
+		return nil, errors.New("failed to get login logs by trace ID")
+	}
+	for _, log := range loginLogs {
+		view.Timeline = append(view.Timeline, &model.ResTraceEvent{Type: model.TraceEventLogin, Time: log.CreatedAt, Data: log})
+	}
+
+	if err := ctx.DB.Where("request_id = ?", traceID).Order("id").Find(&auditLogs).Error; err != nil {
+		ctx.Logger.Error("Failed to get audit logs by trace ID", err)
+		return nil, errors.New("failed to get audit logs by trace ID")
+	}
+	for _, log := range auditLogs {
+		view.Timeline = append(view.Timeline, &model.ResTraceEvent{Type: model.TraceEventAudit, Time: log.CreatedAt.Format(logTimeLayout), Data: log})
+	}
+
+	// 各表时间精度为秒，同一秒内保持上面的先后顺序
+	sort.SliceStable(view.Timeline, func(i, j int) bool {
+		return view.Timeline[i].Time < view.Timeline[j].Time
+	})
+	return view, nil
+}
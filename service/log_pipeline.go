@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/logpipe"
+)
+
+// logPipelines 请求日志与操作日志的异步写入管道，按 App 保存；未初始化时各 Async 方法退化为同步写入
+type logPipelines struct {
+	log *logpipe.Pipeline[*model.Log]
+	op  *logpipe.Pipeline[*model.SysOpLog]
+}
+
+type logPipelinesKey struct{}
+
+// logPipelinesOf 返回 ctx 所属 App 的异步日志管道，未启用时返回 nil
+func logPipelinesOf(ctx app.AppContext) *logPipelines {
+	v, _ := app.AppOf(ctx).Lookup(logPipelinesKey{})
+	p, _ := v.(*logPipelines)
+	return p
+}
+
+// InitLogPipeline 按 AsyncLog 配置创建 App 的异步日志管道，并在 App 退出时写入剩余数据
+func InitLogPipeline(a *app.App) {
+	if a == nil || a.DB == nil || a.Config == nil || a.Config.AsyncLog.Disable {
+		return
+	}
+	a.Value(logPipelinesKey{}, func() interface{} {
+		cfg := a.Config.AsyncLog
+		spillDir := cfg.SpillDir
+		if spillDir == "" {
+			spillDir = "logs"
+		}
+		pcfg := logpipe.Config{
+			BufferSize:    cfg.BufferSize,
+			BatchSize:     cfg.BatchSize,
+			FlushInterval: time.Duration(cfg.FlushInterval) * time.Millisecond,
+			Overflow:      cfg.Overflow,
+			BlockTimeout:  time.Duration(cfg.BlockTimeout) * time.Millisecond,
+		}
+		log := a.Logger.Named("logpipe")
+		db := a.DB
+
+		p := &logPipelines{}
+		pcfg.SpillFile = filepath.Join(spillDir, "request_log.spill.jsonl")
+		p.log = logpipe.New("request_log", pcfg, func(items []*model.Log) error {
+			return db.CreateInBatches(items, len(items)).Error
+		}, log)
+
+		pcfg.SpillFile = filepath.Join(spillDir, "sys_op_log.spill.jsonl")
+		p.op = logpipe.New("sys_op_log", pcfg, func(items []*model.SysOpLog) error {
+			return db.CreateInBatches(items, len(items)).Error
+		}, log)
+
+		a.OnShutdown(func(ctx context.Context) error {
+			err := p.log.Close(ctx)
+			if opErr := p.op.Close(ctx); err == nil {
+				err = opErr
+			}
+			return err
+		})
+		return p
+	})
+}
+
+// LogPipelineStats 返回 ctx 所属 App 的异步日志管道运行统计，未启用时返回空列表
+func LogPipelineStats(ctx app.AppContext) []logpipe.Stats {
+	stats := []logpipe.Stats{}
+	if p := logPipelinesOf(ctx); p != nil {
+		stats = append(stats, p.log.Stats(), p.op.Stats())
+	}
+	return stats
+}
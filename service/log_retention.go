@@ -0,0 +1,346 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+// LogRetentionService 按保留策略清理日志表，可选在删除前归档
+type LogRetentionService struct {
+}
+
+func NewLogRetentionService() *LogRetentionService {
+	return &LogRetentionService{}
+}
+
+// retentionTable 描述一张可清理的日志表
+type retentionTable struct {
+	name   string
+	model  func() interface{}
+	policy func(cfg config.LogRetentionConfig) config.TableRetention
+	// cutoff 返回与 created_at 列比较的值：logs 为时间，其余表为 "2006-01-02 15:04:05" 字符串
+	cutoff func(t time.Time) interface{}
+	load   func(db *gorm.DB, ids []uint) ([]interface{}, error)
+	oldest func(db *gorm.DB) (string, error)
+}
+
+const logTimeLayout = "2006-01-02 15:04:05"
+
+var retentionTables = []retentionTable{
+	{
+		name:   "logs",
+		model:  func() interface{} { return &model.Log{} },
+		policy: func(cfg config.LogRetentionConfig) config.TableRetention { return cfg.Log },
+		cutoff: func(t time.Time) interface{} { return t },
+		load:   loadLogRows[model.Log],
+		oldest: func(db *gorm.DB) (string, error) {
+			var row model.Log
+			if err := db.Order("id").First(&row).Error; err != nil {
+				return "", err
+			}
+			return row.CreatedAt.Format(logTimeLayout), nil
+		},
+	},
+	{
+		name:   "sys_op_logs",
+		model:  func() interface{} { return &model.SysOpLog{} },
+		policy: func(cfg config.LogRetentionConfig) config.TableRetention { return cfg.SysOpLog },
+		cutoff: func(t time.Time) interface{} { return t.Format(logTimeLayout) },
+		load:   loadLogRows[model.SysOpLog],
+		oldest: func(db *gorm.DB) (string, error) {
+			var row model.SysOpLog
+			if err := db.Order("id").First(&row).Error; err != nil {
+				return "", err
+			}
+			return row.CreatedAt, nil
+		},
+	},
+	{
+		name:   "sys_login_logs",
+		model:  func() interface{} { return &model.SysLoginLog{} },
+		policy: func(cfg config.LogRetentionConfig) config.TableRetention { return cfg.SysLoginLog },
+		cutoff: func(t time.Time) interface{} { return t.Format(logTimeLayout) },
+		load:   loadLogRows[model.SysLoginLog],
+		oldest: func(db *gorm.DB) (string, error) {
+			var row model.SysLoginLog
+			if err := db.Order("id").First(&row).Error; err != nil {
+				return "", err
+			}
+			return row.CreatedAt, nil
+		},
+	},
+}
+
+func loadLogRows[T any](db *gorm.DB, ids []uint) ([]interface{}, error) {
+	var rows []T
+	if err := db.Where("id IN ?", ids).Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, len(rows))
+	for i := range rows {
+		out[i] = rows[i]
+	}
+	return out, nil
+}
+
+// purgeMu 保证同一时间只有一次清理在执行
+var purgeMu sync.Mutex
+
+// ErrLogPurgeRunning 已有清理任务在执行
+var ErrLogPurgeRunning = errors.New("log purge is already running")
+
+// Purge 按 LogRetention 配置清理所有日志表，每张表写入一条 SysLogPurge 记录
+func (s *LogRetentionService) Purge(ctx app.AppContext) ([]*model.SysLogPurge, error) {
+	if !purgeMu.TryLock() {
+		return nil, ErrLogPurgeRunning
+	}
+	defer purgeMu.Unlock()
+
+	cfg := ctx.GetConfig().LogRetention
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.BatchPause <= 0 {
+		cfg.BatchPause = 100
+	}
+
+	records := []*model.SysLogPurge{}
+	for _, t := range retentionTables {
+		policy := t.policy(cfg)
+		if policy.MaxAgeDays <= 0 && policy.MaxRows <= 0 {
+			continue
+		}
+		rec := s.purgeTable(ctx, t, policy, cfg)
+		if err := ctx.GetDB().Create(rec).Error; err != nil {
+			ctx.GetLogger().Error("Failed to create log purge record", err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func (s *LogRetentionService) purgeTable(ctx app.AppContext, t retentionTable, policy config.TableRetention, cfg config.LogRetentionConfig) *model.SysLogPurge {
+	now := time.Now()
+	rec := &model.SysLogPurge{LogTable: t.name, StartedAt: now}
+	db := ctx.GetDB()
+
+	var arch *logArchiver
+
+	// deleteWhere 按 id 分批删除满足条件的行，每批删除前先归档
+	deleteWhere := func(where string, args ...interface{}) error {
+		for {
+			if err := ctx.GetCtx().Err(); err != nil {
+				return err
+			}
+			var ids []uint
+			if err := db.Model(t.model()).Where(where, args...).Order("id").Limit(cfg.BatchSize).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+
+			if cfg.ArchiveDir != "" {
+				rows, err := t.load(db, ids)
+				if err != nil {
+					return err
+				}
+				if arch == nil {
+					if arch, err = newLogArchiver(cfg.ArchiveDir, t.name, now); err != nil {
+						return err
+					}
+					rec.ArchiveFile = arch.path
+				}
+				// 归档失败时不删除
+				if err := arch.Write(rows); err != nil {
+					return err
+				}
+				rec.Archived += int64(len(rows))
+			}
+
+			res := db.Where("id IN ?", ids).Delete(t.model())
+			if res.Error != nil {
+				return res.Error
+			}
+			rec.Deleted += res.RowsAffected
+
+			if len(ids) < cfg.BatchSize {
+				return nil
+			}
+			time.Sleep(time.Duration(cfg.BatchPause) * time.Millisecond)
+		}
+	}
+
+	err := func() error {
+		if policy.MaxAgeDays > 0 {
+			cutoff := t.cutoff(now.AddDate(0, 0, -policy.MaxAgeDays))
+			if err := deleteWhere("created_at < ?", cutoff); err != nil {
+				return err
+			}
+		}
+		if policy.MaxRows > 0 {
+			// 保留 id 最大的 MaxRows 行
+			var boundary []uint
+			if err := db.Model(t.model()).Order("id DESC").Offset(int(policy.MaxRows)).Limit(1).Pluck("id", &boundary).Error; err != nil {
+				return err
+			}
+			if len(boundary) > 0 {
+				if err := deleteWhere("id <= ?", boundary[0]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}()
+
+	// 归档文件关闭（写入 gzip 尾部）失败时文件不完整，本次清理记为失败
+	if arch != nil {
+		if closeErr := arch.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close archive %s: %w", arch.path, closeErr)
+		}
+	}
+
+	rec.FinishedAt = time.Now()
+	rec.Status = model.LogPurgeStatusSuccess
+	if err != nil {
+		ctx.GetLogger().Errorw("Failed to purge log table", "table", t.name, "deleted", rec.Deleted, "error", err.Error())
+		rec.Status = model.LogPurgeStatusFailed
+		rec.Message = err.Error()
+	}
+	return rec
+}
+
+// GetTableStats 返回各日志表的行数、最早记录时间、保留策略与最近一次清理记录
+func (s *LogRetentionService) GetTableStats(ctx app.AppContext) ([]*model.ResLogTableStats, error) {
+	db := ctx.GetDB()
+	cfg := ctx.GetConfig().LogRetention
+
+	out := make([]*model.ResLogTableStats, 0, len(retentionTables))
+	for _, t := range retentionTables {
+		policy := t.policy(cfg)
+		item := &model.ResLogTableStats{Table: t.name, MaxAgeDays: policy.MaxAgeDays, MaxRows: policy.MaxRows}
+
+		if err := db.Model(t.model()).Count(&item.Rows).Error; err != nil {
+			ctx.GetLogger().Error("Failed to count log table", err)
+			return nil, errors.New("failed to count log table")
+		}
+		if item.Rows > 0 {
+			oldest, err := t.oldest(db)
+			if err != nil && err != gorm.ErrRecordNotFound {
+				ctx.GetLogger().Error("Failed to get oldest log", err)
+				return nil, errors.New("failed to get oldest log")
+			}
+			item.OldestAt = oldest
+		}
+
+		last := &model.SysLogPurge{}
+		err := db.Where("log_table = ?", t.name).Order("id DESC").First(last).Error
+		if err == nil {
+			item.LastPurge = last
+		} else if err != gorm.ErrRecordNotFound {
+			ctx.GetLogger().Error("Failed to get last log purge", err)
+			return nil, errors.New("failed to get last log purge")
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+type logRetentionKey struct{}
+
+// StartLogRetention 按 LogRetention.Interval 定期清理日志表，App 退出时停止
+func StartLogRetention(a *app.App) {
+	if a == nil || a.DB == nil || a.Config == nil || a.Config.LogRetention.Disable {
+		return
+	}
+	a.Once(logRetentionKey{}, func() {
+		interval := time.Duration(a.Config.LogRetention.Interval) * time.Minute
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		bg := app.NewBackgroundContextFromApp(a)
+		bg.Logger = a.Logger.Named("retention")
+		runCtx, cancel := context.WithCancel(context.Background())
+		bg.Ctx = runCtx
+
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					records, err := NewLogRetentionService().Purge(bg)
+					if err != nil {
+						bg.Logger.Warnw("log purge skipped", "error", err.Error())
+						continue
+					}
+					for _, rec := range records {
+						bg.Logger.Infow("log table purged", "table", rec.LogTable, "deleted", rec.Deleted, "archived", rec.Archived, "status", rec.Status)
+					}
+				case <-runCtx.Done():
+					return
+				}
+			}
+		}()
+
+		a.OnShutdown(func(ctx context.Context) error {
+			cancel()
+			return nil
+		})
+	})
+}
+
+// logArchiver 将删除前的行写入 gzip 压缩的 JSONL 文件
+type logArchiver struct {
+	path string
+	f    *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+}
+
+func newLogArchiver(dir, table string, now time.Time) (*logArchiver, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.jsonl.gz", table, now.Format("20060102T150405")))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(f)
+	return &logArchiver{path: path, f: f, gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+// Write 写入一批行并刷新到磁盘，返回后才可以删除这些行
+func (a *logArchiver) Write(rows []interface{}) error {
+	for _, row := range rows {
+		if err := a.enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	if err := a.gz.Flush(); err != nil {
+		return err
+	}
+	return a.f.Sync()
+}
+
+func (a *logArchiver) Close() error {
+	err := a.gz.Close()
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
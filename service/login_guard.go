@@ -0,0 +1,237 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/config"
+)
+
+const (
+	redisLoginFailKey  = "sgin:login:fail:"  // 失败计数，后接 kind:subject
+	redisLoginDelayKey = "sgin:login:delay:" // 下次允许尝试的时间（Unix 毫秒），后接 user:subject
+	redisLoginLockKey  = "sgin:login:lock:"  // 锁定信息（ResLoginLock JSON），后接 kind:subject
+)
+
+// LoginGuardService 按用户名与 IP 统计登录失败次数，失败后递增等待时间，超过阈值临时锁定。
+// 计数与锁定对不存在的用户名同样生效，响应不会暴露用户是否存在
+type LoginGuardService struct {
+}
+
+func NewLoginGuardService() *LoginGuardService {
+	return &LoginGuardService{}
+}
+
+// LoginBlock 拒绝本次登录的原因
+type LoginBlock struct {
+	Locked     bool          // true 为锁定，false 为未到递增延迟后的允许时间
+	RetryAfter time.Duration // 距可再次尝试的时长
+}
+
+func loginGuardConfig(ctx app.AppContext) (config.LoginGuardConfig, bool) {
+	cfg := ctx.GetConfig()
+	if cfg == nil || cfg.LoginGuard.Disable || ctx.GetRedis() == nil {
+		return config.LoginGuardConfig{}, false
+	}
+	gc := cfg.LoginGuard
+	if gc.MaxUserFailures <= 0 {
+		gc.MaxUserFailures = 5
+	}
+	if gc.MaxIPFailures <= 0 {
+		gc.MaxIPFailures = 20
+	}
+	if gc.Window <= 0 {
+		gc.Window = 15
+	}
+	if gc.LockDuration <= 0 {
+		gc.LockDuration = 15
+	}
+	if gc.DelayBase <= 0 {
+		gc.DelayBase = 1000
+	}
+	if gc.MaxDelay <= 0 {
+		gc.MaxDelay = 30000
+	}
+	return gc, true
+}
+
+func loginGuardSubject(kind, subject string) string {
+	if kind == model.LoginLockUser {
+		subject = strings.ToLower(strings.TrimSpace(subject))
+	}
+	return kind + ":" + subject
+}
+
+// Check 检查用户名与当前 IP 是否被锁定、是否需要等待；允许登录时返回 nil。
+// Redis 不可用时只记录日志并放行，避免缓存故障导致所有用户无法登录
+func (s *LoginGuardService) Check(ctx *app.Context, username string) *LoginBlock {
+	if _, ok := loginGuardConfig(ctx); !ok {
+		return nil
+	}
+	for _, key := range []string{
+		redisLoginLockKey + loginGuardSubject(model.LoginLockUser, username),
+		redisLoginLockKey + loginGuardSubject(model.LoginLockIP, ctx.ClientIP()),
+	} {
+		ttl, err := ctx.Redis.TTL(ctx.Ctx, key)
+		if err != nil {
+			ctx.Logger.Error("Failed to check login lock", err)
+			return nil
+		}
+		if ttl > 0 {
+			return &LoginBlock{Locked: true, RetryAfter: ttl}
+		}
+	}
+
+	v, err := ctx.Redis.Get(ctx.Ctx, redisLoginDelayKey+loginGuardSubject(model.LoginLockUser, username))
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		ctx.Logger.Error("Failed to check login delay", err)
+		return nil
+	}
+	next, _ := strconv.ParseInt(v, 10, 64)
+	if wait := time.Until(time.UnixMilli(next)); wait > 0 {
+		return &LoginBlock{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次登录失败，返回本次新产生的锁定
+func (s *LoginGuardService) RecordFailure(ctx *app.Context, username string) []*model.ResLoginLock {
+	gc, ok := loginGuardConfig(ctx)
+	if !ok {
+		return nil
+	}
+	window := time.Duration(gc.Window) * time.Minute
+	now := time.Now()
+
+	var locks []*model.ResLoginLock
+	userFailures, err := s.incrFailures(ctx, loginGuardSubject(model.LoginLockUser, username), window)
+	if err != nil {
+		ctx.Logger.Error("Failed to record login failure", err)
+		return nil
+	}
+	ipFailures, err := s.incrFailures(ctx, loginGuardSubject(model.LoginLockIP, ctx.ClientIP()), window)
+	if err != nil {
+		ctx.Logger.Error("Failed to record login failure", err)
+		return nil
+	}
+
+	if userFailures >= int64(gc.MaxUserFailures) {
+		if l := s.lock(ctx, gc, model.LoginLockUser, username, userFailures, now); l != nil {
+			locks = append(locks, l)
+		}
+	} else {
+		// 第 n 次失败后等待 DelayBase * 2^(n-1)，不超过 MaxDelay
+		delay := time.Duration(gc.DelayBase) * time.Millisecond << uint(userFailures-1)
+		if max := time.Duration(gc.MaxDelay) * time.Millisecond; delay > max || delay <= 0 {
+			delay = max
+		}
+		next := strconv.FormatInt(now.Add(delay).UnixMilli(), 10)
+		if err := ctx.Redis.Set(ctx.Ctx, redisLoginDelayKey+loginGuardSubject(model.LoginLockUser, username), next, delay); err != nil {
+			ctx.Logger.Error("Failed to set login delay", err)
+		}
+	}
+	if ipFailures >= int64(gc.MaxIPFailures) {
+		if l := s.lock(ctx, gc, model.LoginLockIP, ctx.ClientIP(), ipFailures, now); l != nil {
+			locks = append(locks, l)
+		}
+	}
+	return locks
+}
+
+// RecordSuccess 登录成功后清除用户名的失败计数与等待时间；IP 计数保留到窗口结束，
+// 避免持有一个有效账号即可重置对其他账号的尝试次数
+func (s *LoginGuardService) RecordSuccess(ctx *app.Context, username string) {
+	if _, ok := loginGuardConfig(ctx); !ok {
+		return
+	}
+	subject := loginGuardSubject(model.LoginLockUser, username)
+	for _, key := range []string{redisLoginFailKey + subject, redisLoginDelayKey + subject} {
+		if err := ctx.Redis.Del(ctx.Ctx, key); err != nil {
+			ctx.Logger.Error("Failed to clear login failures", err)
+		}
+	}
+}
+
+func (s *LoginGuardService) incrFailures(ctx *app.Context, subject string, window time.Duration) (int64, error) {
+	// 自增与设置过期时间原子执行，避免留下永不过期的计数导致永久锁定
+	return ctx.Redis.IncrWithExpire(ctx.Ctx, redisLoginFailKey+subject, window)
+}
+
+func (s *LoginGuardService) lock(ctx *app.Context, gc config.LoginGuardConfig, kind, subject string, failures int64, now time.Time) *model.ResLoginLock {
+	d := time.Duration(gc.LockDuration) * time.Minute
+	l := &model.ResLoginLock{
+		Kind:     kind,
+		Subject:  subject,
+		Failures: failures,
+		Ip:       ctx.ClientIP(),
+		LockedAt: now.Format(logTimeLayout),
+		Until:    now.Add(d).Format(logTimeLayout),
+	}
+	b, _ := json.Marshal(l)
+	key := loginGuardSubject(kind, subject)
+	if err := ctx.Redis.Set(ctx.Ctx, redisLoginLockKey+key, string(b), d); err != nil {
+		ctx.Logger.Error("Failed to set login lock", err)
+		return nil
+	}
+	// 锁定后重新计数
+	if err := ctx.Redis.Del(ctx.Ctx, redisLoginFailKey+key); err != nil {
+		ctx.Logger.Error("Failed to reset login failures", err)
+	}
+	ctx.Logger.Warnw("login locked", "kind", kind, "subject", subject, "failures", failures, "client_ip", ctx.ClientIP())
+	return l
+}
+
+// ListLocks 返回当前生效的锁定，按锁定时间倒序
+func (s *LoginGuardService) ListLocks(ctx *app.Context) ([]*model.ResLoginLock, error) {
+	locks := []*model.ResLoginLock{}
+	if _, ok := loginGuardConfig(ctx); !ok {
+		return locks, nil
+	}
+	keys, err := ctx.Redis.Scan(ctx.Ctx, redisLoginLockKey+"*")
+	if err != nil {
+		ctx.Logger.Error("Failed to scan login locks", err)
+		return nil, errors.New("failed to scan login locks")
+	}
+	for _, key := range keys {
+		v, err := ctx.Redis.Get(ctx.Ctx, key)
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			ctx.Logger.Error("Failed to get login lock", err)
+			return nil, errors.New("failed to get login lock")
+		}
+		l := &model.ResLoginLock{}
+		if err := json.Unmarshal([]byte(v), l); err != nil {
+			continue
+		}
+		locks = append(locks, l)
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].LockedAt > locks[j].LockedAt })
+	return locks, nil
+}
+
+// ClearLock 解除锁定并清除对应的失败计数与等待时间
+func (s *LoginGuardService) ClearLock(ctx *app.Context, kind, subject string) error {
+	if _, ok := loginGuardConfig(ctx); !ok {
+		return nil
+	}
+	key := loginGuardSubject(kind, subject)
+	for _, k := range []string{redisLoginLockKey + key, redisLoginFailKey + key, redisLoginDelayKey + key} {
+		if err := ctx.Redis.Del(ctx.Ctx, k); err != nil {
+			ctx.Logger.Error("Failed to clear login lock", err)
+			return errors.New("failed to clear login lock")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/nonce"
+	"github.com/luxingwen/sgin/pkg/redisop"
+)
+
+// NonceStore 记录已使用的 nonce，用于防重放
+type NonceStore interface {
+	// Claim 原子地记录 scope 下的 nonce：ttl 内首次出现返回 true，已使用过返回 false
+	Claim(ctx context.Context, scope, nonce string, ttl time.Duration) (bool, error)
+}
+
+// NewNonceStore 按 Nonce.Store 配置返回存储；未配置时有 Redis 用 Redis，否则用本机内存；
+// 配置为 redis 但 Redis 不可用时返回 nil。内存存储按 App 保存，不是由 App 创建的上下文无法共享，同样返回 nil
+func NewNonceStore(ctx app.AppContext) NonceStore {
+	cfg := ctx.GetConfig()
+	typ, size := "", 0
+	if cfg != nil {
+		typ, size = cfg.Nonce.Store, cfg.Nonce.MemorySize
+	}
+	switch {
+	case typ != "memory" && ctx.GetRedis() != nil:
+		return &redisNonceStore{rc: ctx.GetRedis()}
+	case typ != "redis" && app.AppOf(ctx) != nil:
+		return app.AppOf(ctx).Value(memoryNoncesKey{}, func() interface{} {
+			if size <= 0 {
+				size = 100000
+			}
+			return nonce.NewMemoryStore(size)
+		}).(*nonce.MemoryStore)
+	}
+	return nil
+}
+
+// redisNonceStore 基于 Redis SET NX EX，多实例共享
+type redisNonceStore struct {
+	rc *redisop.RedisClient
+}
+
+func (s *redisNonceStore) Claim(ctx context.Context, scope, nonce string, ttl time.Duration) (bool, error) {
+	return s.rc.SetNX(ctx, "nonce:"+scope+":"+nonce, "1", ttl)
+}
+
+type memoryNoncesKey struct{}
@@ -0,0 +1,466 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/config"
+	"github.com/luxingwen/sgin/pkg/oidc"
+	"github.com/luxingwen/sgin/pkg/passwd"
+	"github.com/luxingwen/sgin/pkg/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOAuthUnknownProvider  = errors.New("unknown identity provider")
+	ErrOAuthInvalidState     = errors.New("invalid or expired login state")
+	ErrOAuthVerifyFailed     = errors.New("failed to verify external identity")
+	ErrOAuthNotLinked        = errors.New("external identity is not linked to any user")
+	ErrOAuthAlreadyLinked    = errors.New("external identity is already linked to another user")
+	ErrOAuthProviderLinked   = errors.New("another account of this provider is already linked")
+	ErrOAuthDomainNotAllowed = errors.New("email domain is not allowed")
+	ErrOAuthEmailRequired    = errors.New("identity provider did not return an email")
+	ErrOAuthEmailInUse       = errors.New("email is already used by another user")
+)
+
+// OAuthService 外部身份提供方登录：授权跳转、回调处理、外部身份关联与用户自动创建
+type OAuthService struct {
+}
+
+func NewOAuthService() *OAuthService {
+	return &OAuthService{}
+}
+
+// oauthState 加密后作为 state 参数往返于提供方，服务端不需要保存登录过程的状态
+type oauthState struct {
+	Provider string `json:"p"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`           // PKCE code_verifier
+	Binding  string `json:"b"`           // 浏览器 Cookie 中绑定值的摘要，防止把他人的回调地址发给用户完成登录
+	UserUuid string `json:"u,omitempty"` // 不为空时为已登录用户关联外部身份
+	Expires  int64  `json:"e"`
+}
+
+// oauthProviders 按配置缓存完成发现的提供方，配置重新加载后重建
+var oauthProviders struct {
+	sync.Mutex
+	cfg *config.Config
+	m   map[string]*oidc.Provider
+}
+
+// oauthStateKey 加密登录状态的密钥，必须单独配置，不与 PasswdKey 共用
+func oauthStateKey(ctx app.AppContext) (string, error) {
+	cfg := ctx.GetConfig()
+	if cfg == nil || cfg.OAuth.StateKey == "" {
+		return "", errors.New("OAuth.StateKey is not configured")
+	}
+	return cfg.OAuth.StateKey, nil
+}
+
+func oauthStateTTL(ctx app.AppContext) time.Duration {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.OAuth.StateTTL > 0 {
+		return time.Duration(cfg.OAuth.StateTTL) * time.Minute
+	}
+	return 10 * time.Minute
+}
+
+func oauthProviderConfig(ctx app.AppContext, name string) *config.OAuthProviderConfig {
+	cfg := ctx.GetConfig()
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.OAuth.Providers {
+		if cfg.OAuth.Providers[i].Name == name {
+			return &cfg.OAuth.Providers[i]
+		}
+	}
+	return nil
+}
+
+// provider 返回完成发现的提供方；发现失败不缓存，下次请求时重试
+func (s *OAuthService) provider(ctx *app.Context, name string) (*oidc.Provider, *config.OAuthProviderConfig, error) {
+	pc := oauthProviderConfig(ctx, name)
+	if pc == nil {
+		return nil, nil, ErrOAuthUnknownProvider
+	}
+
+	oauthProviders.Lock()
+	if oauthProviders.cfg != ctx.Config {
+		oauthProviders.cfg = ctx.Config
+		oauthProviders.m = map[string]*oidc.Provider{}
+	}
+	p := oauthProviders.m[name]
+	oauthProviders.Unlock()
+	if p != nil {
+		return p, pc, nil
+	}
+
+	p, err := oidc.New(ctx.Ctx, oidc.Config{
+		Type:          pc.Type,
+		Issuer:        pc.Issuer,
+		ClientID:      pc.ClientID,
+		ClientSecret:  pc.ClientSecret,
+		AuthURL:       pc.AuthURL,
+		TokenURL:      pc.TokenURL,
+		UserInfoURL:   pc.UserInfoURL,
+		JWKSURL:       pc.JWKSURL,
+		RedirectURL:   pc.RedirectURL,
+		Scopes:        pc.Scopes,
+		AuthMethod:    pc.AuthMethod,
+		SubjectClaim:  pc.SubjectClaim,
+		EmailClaim:    pc.EmailClaim,
+		NameClaim:     pc.NameClaim,
+		UsernameClaim: pc.UsernameClaim,
+	}, nil)
+	if err != nil {
+		ctx.Logger.Error("Failed to init identity provider", err)
+		return nil, nil, fmt.Errorf("failed to init identity provider %s", name)
+	}
+
+	oauthProviders.Lock()
+	if oauthProviders.cfg == ctx.Config {
+		oauthProviders.m[name] = p
+	}
+	oauthProviders.Unlock()
+	return p, pc, nil
+}
+
+// Providers 返回已配置的身份提供方
+func (s *OAuthService) Providers(ctx *app.Context) []*model.ResOAuthProvider {
+	list := []*model.ResOAuthProvider{}
+	if ctx.Config == nil {
+		return list
+	}
+	for _, pc := range ctx.Config.OAuth.Providers {
+		typ := pc.Type
+		if typ == "" {
+			typ = oidc.TypeOIDC
+		}
+		name := pc.DisplayName
+		if name == "" {
+			name = pc.Name
+		}
+		list = append(list, &model.ResOAuthProvider{Name: pc.Name, DisplayName: name, Type: typ})
+	}
+	return list
+}
+
+// AuthorizeURL 生成跳转到提供方的授权地址；binding 为写入浏览器 Cookie 的随机值，回调时必须一致；
+// linkUserUuid 不为空时回调为该用户关联外部身份而不是登录
+func (s *OAuthService) AuthorizeURL(ctx *app.Context, name, binding, linkUserUuid string) (string, error) {
+	p, _, err := s.provider(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	key, err := oauthStateKey(ctx)
+	if err != nil {
+		ctx.Logger.Error("Failed to get oauth state key", err)
+		return "", errors.New("failed to create login state")
+	}
+
+	verifier, challenge := oidc.NewPKCE()
+	st := &oauthState{
+		Provider: name,
+		Nonce:    oidc.RandomString(16),
+		Verifier: verifier,
+		Binding:  utils.SHA256Hex(binding),
+		UserUuid: linkUserUuid,
+		Expires:  time.Now().Add(oauthStateTTL(ctx)).Unix(),
+	}
+	b, _ := json.Marshal(st)
+	state, err := utils.EncryptString(key, string(b))
+	if err != nil {
+		ctx.Logger.Error("Failed to encrypt oauth state", err)
+		return "", errors.New("failed to create login state")
+	}
+	return p.AuthCodeURL(state, st.Nonce, challenge), nil
+}
+
+// Callback 校验 state，用授权码换取令牌并验证外部身份；登录时返回关联的用户，
+// 关联外部身份时返回发起关联的用户，linked 为 true
+func (s *OAuthService) Callback(ctx *app.Context, name, code, state, binding string) (user *model.User, linked bool, err error) {
+	st, err := s.parseState(ctx, name, state, binding)
+	if err != nil {
+		return nil, false, err
+	}
+	p, pc, err := s.provider(ctx, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tok, err := p.Exchange(ctx.Ctx, code, st.Verifier)
+	if err != nil {
+		ctx.Logger.Warnw("oauth code exchange failed", "provider", name, "reason", err.Error())
+		return nil, false, ErrOAuthVerifyFailed
+	}
+	id, err := p.Identity(ctx.Ctx, tok, st.Nonce)
+	if err != nil {
+		ctx.Logger.Warnw("oauth identity verification failed", "provider", name, "reason", err.Error())
+		return nil, false, ErrOAuthVerifyFailed
+	}
+	if !emailDomainAllowed(pc.AllowedDomains, id) {
+		return nil, false, ErrOAuthDomainNotAllowed
+	}
+
+	if st.UserUuid != "" {
+		user, err := NewUserService().GetUserByUUID(ctx, st.UserUuid)
+		if err != nil {
+			return nil, false, err
+		}
+		return user, true, s.link(ctx, user.Uuid, name, id)
+	}
+	user, err = s.resolveUser(ctx, pc, id)
+	return user, false, err
+}
+
+func (s *OAuthService) parseState(ctx *app.Context, name, state, binding string) (*oauthState, error) {
+	key, err := oauthStateKey(ctx)
+	if err != nil {
+		return nil, ErrOAuthInvalidState
+	}
+	plain, err := utils.DecryptString(key, state)
+	if err != nil {
+		return nil, ErrOAuthInvalidState
+	}
+	st := &oauthState{}
+	if err := json.Unmarshal([]byte(plain), st); err != nil {
+		return nil, ErrOAuthInvalidState
+	}
+	if st.Provider != name || time.Now().Unix() > st.Expires ||
+		subtle.ConstantTimeCompare([]byte(st.Binding), []byte(utils.SHA256Hex(binding))) != 1 {
+		return nil, ErrOAuthInvalidState
+	}
+	return st, nil
+}
+
+func emailDomainAllowed(domains []string, id *oidc.Identity) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(id.Email, "@")
+	if at < 0 || !id.EmailVerified {
+		return false
+	}
+	domain := strings.ToLower(id.Email[at+1:])
+	for _, d := range domains {
+		if strings.EqualFold(strings.TrimPrefix(d, "@"), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveUser 按外部身份查找关联的用户；未关联时按配置通过已验证的邮箱关联或自动创建用户
+func (s *OAuthService) resolveUser(ctx *app.Context, pc *config.OAuthProviderConfig, id *oidc.Identity) (*model.User, error) {
+	ident := &model.UserIdentity{}
+	err := ctx.DB.Where("provider = ? AND subject = ?", pc.Name, id.Subject).First(ident).Error
+	if err == nil {
+		s.touch(ctx, ident, id)
+		return NewUserService().GetUserByUUID(ctx, ident.UserUuid)
+	}
+	if err != gorm.ErrRecordNotFound {
+		ctx.Logger.Error("Failed to get user identity", err)
+		return nil, errors.New("failed to get user identity")
+	}
+
+	if pc.LinkByEmail && id.EmailVerified && id.Email != "" {
+		user := &model.User{}
+		err := ctx.DB.Where("email = ? AND is_deleted = ?", id.Email, 0).First(user).Error
+		if err == nil {
+			if err := s.link(ctx, user.Uuid, pc.Name, id); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			ctx.Logger.Error("Failed to get user by email", err)
+			return nil, errors.New("failed to get user by email")
+		}
+	}
+
+	if !pc.AutoCreate {
+		return nil, ErrOAuthNotLinked
+	}
+	return s.createUser(ctx, pc, id)
+}
+
+// createUser 为外部身份创建用户并分配默认角色；用户使用随机密码，需要本地登录时通过重置密码设置
+func (s *OAuthService) createUser(ctx *app.Context, pc *config.OAuthProviderConfig, id *oidc.Identity) (*model.User, error) {
+	if id.Email == "" {
+		return nil, ErrOAuthEmailRequired
+	}
+	var count int64
+	if err := ctx.DB.Model(&model.User{}).Where("email = ?", id.Email).Count(&count).Error; err != nil {
+		ctx.Logger.Error("Failed to check user email", err)
+		return nil, errors.New("failed to check user email")
+	}
+	if count > 0 {
+		return nil, ErrOAuthEmailInUse
+	}
+	username, err := s.uniqueUsername(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := passwd.Hash(utils.RandomToken(32), passwordParams(ctx))
+	if err != nil {
+		ctx.Logger.Error("Failed to hash password", err)
+		return nil, errors.New("failed to hash password")
+	}
+
+	now := time.Now()
+	user := &model.User{
+		Uuid:      uuid.New().String(),
+		Username:  username,
+		Email:     id.Email,
+		Password:  hashed,
+		Nickname:  truncate(id.Name, 50),
+		Status:    1,
+		CreatedAt: now.Format("2006-01-02 15:04:05"),
+		UpdatedAt: now.Format("2006-01-02 15:04:05"),
+	}
+	err = ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(newUserIdentity(user.Uuid, pc.Name, id, now)).Error; err != nil {
+			return err
+		}
+		if len(pc.DefaultRoles) == 0 {
+			return nil
+		}
+		var roles []*model.Role
+		if err := tx.Where("name IN ?", pc.DefaultRoles).Find(&roles).Error; err != nil {
+			return err
+		}
+		if len(roles) != len(pc.DefaultRoles) {
+			ctx.Logger.Warnw("some default roles not found", "provider", pc.Name, "roles", pc.DefaultRoles, "found", len(roles))
+		}
+		for _, r := range roles {
+			if err := tx.Create(&model.UserRole{UUID: uuid.New().String(), UserUUID: user.Uuid, RoleUUID: r.Uuid}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.Logger.Error("Failed to create external user", err)
+		return nil, errors.New("failed to create user")
+	}
+	ctx.Logger.Infow("user created from external identity", "provider", pc.Name, "user_uuid", user.Uuid, "username", user.Username)
+	return user, nil
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// uniqueUsername 依次尝试提供方的用户名、邮箱前缀，已存在时追加随机后缀
+func (s *OAuthService) uniqueUsername(ctx *app.Context, id *oidc.Identity) (string, error) {
+	base := id.Username
+	if base == "" {
+		base = strings.SplitN(id.Email, "@", 2)[0]
+	}
+	base = truncate(usernameInvalidChars.ReplaceAllString(base, ""), 40)
+	if base == "" {
+		base = "user"
+	}
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := ctx.DB.Model(&model.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			ctx.Logger.Error("Failed to check username", err)
+			return "", errors.New("failed to check username")
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = base + "_" + utils.RandomToken(3)
+	}
+	return "", errors.New("failed to allocate username")
+}
+
+func newUserIdentity(userUuid, provider string, id *oidc.Identity, now time.Time) *model.UserIdentity {
+	return &model.UserIdentity{
+		UserUuid:    userUuid,
+		Provider:    provider,
+		Subject:     id.Subject,
+		Email:       truncate(id.Email, 100),
+		Name:        truncate(id.Name, 100),
+		LastLoginAt: &now,
+	}
+}
+
+// touch 更新外部身份的邮箱、名称与最近登录时间，失败只记录日志
+func (s *OAuthService) touch(ctx *app.Context, ident *model.UserIdentity, id *oidc.Identity) {
+	now := time.Now()
+	err := ctx.DB.Model(ident).Updates(map[string]interface{}{
+		"email":         truncate(id.Email, 100),
+		"name":          truncate(id.Name, 100),
+		"last_login_at": &now,
+	}).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to update user identity", err)
+	}
+}
+
+// link 为用户关联外部身份；每个提供方只能关联一个账号
+func (s *OAuthService) link(ctx *app.Context, userUuid, provider string, id *oidc.Identity) error {
+	var existing []*model.UserIdentity
+	err := ctx.DB.Where("(provider = ? AND subject = ?) OR (provider = ? AND user_uuid = ?)", provider, id.Subject, provider, userUuid).
+		Find(&existing).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to get user identity", err)
+		return errors.New("failed to get user identity")
+	}
+	for _, e := range existing {
+		switch {
+		case e.Subject == id.Subject && e.UserUuid == userUuid:
+			s.touch(ctx, e, id)
+			return nil
+		case e.Subject == id.Subject:
+			return ErrOAuthAlreadyLinked
+		default:
+			return ErrOAuthProviderLinked
+		}
+	}
+	if err := ctx.DB.Create(newUserIdentity(userUuid, provider, id, time.Now())).Error; err != nil {
+		ctx.Logger.Error("Failed to create user identity", err)
+		return errors.New("failed to create user identity")
+	}
+	ctx.Logger.Infow("external identity linked", "provider", provider, "user_uuid", userUuid)
+	return nil
+}
+
+// ListIdentities 返回用户关联的外部身份
+func (s *OAuthService) ListIdentities(ctx *app.Context, userUuid string) ([]*model.UserIdentity, error) {
+	list := []*model.UserIdentity{}
+	if err := ctx.DB.Where("user_uuid = ?", userUuid).Order("id").Find(&list).Error; err != nil {
+		ctx.Logger.Error("Failed to list user identities", err)
+		return nil, errors.New("failed to list user identities")
+	}
+	return list, nil
+}
+
+// Unlink 解除用户与提供方的关联
+func (s *OAuthService) Unlink(ctx *app.Context, userUuid, provider string) error {
+	if err := ctx.DB.Where("user_uuid = ? AND provider = ?", userUuid, provider).Delete(&model.UserIdentity{}).Error; err != nil {
+		ctx.Logger.Error("Failed to unlink user identity", err)
+		return errors.New("failed to unlink user identity")
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/passwd"
+)
+
+func passwordParams(ctx app.AppContext) passwd.Params {
+	cfg := ctx.GetConfig()
+	if cfg == nil {
+		return passwd.Params{}
+	}
+	pc := cfg.Password
+	return passwd.Params{
+		Algorithm:  pc.Algorithm,
+		Time:       pc.Argon2Time,
+		Memory:     pc.Argon2Memory,
+		Threads:    pc.Argon2Threads,
+		BcryptCost: pc.BcryptCost,
+	}
+}
+
+// legacyPasswdKey 旧版本 HMAC 哈希使用的密钥，只用于验证未迁移的密码
+func legacyPasswdKey(ctx app.AppContext) string {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.PasswdKey != "" {
+		return cfg.PasswdKey
+	}
+	return "default-secret-key"
+}
+
+func passwordPolicy(ctx app.AppContext) passwd.Policy {
+	cfg := ctx.GetConfig()
+	if cfg == nil {
+		return passwd.Policy{}
+	}
+	return passwd.Policy{
+		MinLength:  cfg.Password.MinLength,
+		MaxLength:  cfg.Password.MaxLength,
+		MinClasses: cfg.Password.MinClasses,
+		Blocklist:  cfg.Password.Blocklist,
+	}
+}
+
+// hashNewPassword 按密码策略检查并哈希新密码，不满足策略时返回的错误满足 errors.Is(err, passwd.ErrWeakPassword)
+func hashNewPassword(ctx app.AppContext, password string, userInputs ...string) (string, error) {
+	if password == "" {
+		return "", &passwd.PolicyError{Reason: "密码不能为空"}
+	}
+	if err := passwordPolicy(ctx).Check(password, userInputs...); err != nil {
+		return "", err
+	}
+	h, err := passwd.Hash(password, passwordParams(ctx))
+	if err != nil {
+		ctx.GetLogger().Error("Failed to hash password", err)
+		return "", errors.New("failed to hash password")
+	}
+	return h, nil
+}
+
+// VerifyPassword 验证用户密码；验证成功且哈希为旧格式或参数已变化时重新哈希，失败只记录日志
+func (s *UserService) VerifyPassword(ctx *app.Context, user *model.User, password string) bool {
+	params := passwordParams(ctx)
+	ok, rehash := passwd.Verify(password, user.Password, params, legacyPasswdKey(ctx))
+	if !ok || !rehash {
+		return ok
+	}
+
+	h, err := passwd.Hash(password, params)
+	if err != nil {
+		ctx.Logger.Error("Failed to rehash password", err)
+		return true
+	}
+	// 条件中带上旧哈希，避免覆盖并发修改的密码
+	err = ctx.DB.Model(&model.User{}).
+		Where("uuid = ? AND password = ?", user.Uuid, user.Password).
+		Update("password", h).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to update password hash", err)
+		return true
+	}
+	user.Password = h
+	return true
+}
+
+// dummyHashKey 按 App 保存的假哈希，参数与该 App 的密码策略一致
+type dummyHashKey struct{}
+
+// SimulatePasswordCheck 在用户不存在时执行一次同等开销的哈希验证，避免通过响应时间判断用户是否存在
+func (s *UserService) SimulatePasswordCheck(ctx app.AppContext, password string) {
+	params := passwordParams(ctx)
+	dummyHash := app.AppOf(ctx).Value(dummyHashKey{}, func() interface{} {
+		h, _ := passwd.Hash("sgin-dummy-password", params)
+		return h
+	}).(string)
+	passwd.Verify(password, dummyHash, params, "")
+}
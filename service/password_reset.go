@@ -0,0 +1,315 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/config"
+	"github.com/luxingwen/sgin/pkg/mail"
+	"github.com/luxingwen/sgin/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetPurpose  = "password_reset"
+	redisPasswordResetKey = "sgin:pwreset:" // 请求计数，后接 kind:subject
+)
+
+var (
+	ErrPasswordResetDisabled     = errors.New("password reset is not configured")
+	ErrPasswordResetInvalidToken = errors.New("invalid or expired password reset link")
+)
+
+// PasswordResetLimitError 请求过于频繁
+type PasswordResetLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *PasswordResetLimitError) Error() string {
+	return "too many password reset requests"
+}
+
+var passwordResetMailContent = `
+<html>
+<body>
+    <h2>重置密码</h2>
+    <p>%s，您好：</p>
+    <p>我们收到了重置您账号密码的请求，请在 %d 分钟内点击下面的链接设置新密码：</p>
+    <p><a href="%s">%s</a></p>
+    <p>链接只能使用一次。如果这不是您本人的操作，请忽略本邮件，您的密码不会改变。</p>
+</body>
+</html>
+`
+
+var passwordChangedMailContent = `
+<html>
+<body>
+    <h2>密码已重置</h2>
+    <p>%s，您好：</p>
+    <p>您的账号密码已于 %s 通过邮件链接重置（IP：%s），所有设备上的登录已退出。</p>
+    <p>如果这不是您本人的操作，请立即联系管理员。</p>
+</body>
+</html>
+`
+
+// PasswordResetService 通过邮件中的一次性链接找回密码。
+// 链接中的令牌绑定当前密码哈希的摘要，密码修改后之前发出的链接全部失效
+type PasswordResetService struct {
+}
+
+func NewPasswordResetService() *PasswordResetService {
+	return &PasswordResetService{}
+}
+
+func passwordResetConfig(ctx app.AppContext) (config.PasswordResetConfig, bool) {
+	cfg := ctx.GetConfig()
+	if cfg == nil || cfg.PasswordReset.LinkURL == "" {
+		return config.PasswordResetConfig{}, false
+	}
+	rc := cfg.PasswordReset
+	if rc.TokenTTL <= 0 {
+		rc.TokenTTL = 30
+	}
+	if rc.Window <= 0 {
+		rc.Window = 60
+	}
+	if rc.MaxPerAddress <= 0 {
+		rc.MaxPerAddress = 3
+	}
+	if rc.MaxPerIP <= 0 {
+		rc.MaxPerIP = 20
+	}
+	if rc.Subject == "" {
+		rc.Subject = "重置密码"
+	}
+	if rc.NotifySubject == "" {
+		rc.NotifySubject = "密码已重置"
+	}
+	return rc, true
+}
+
+// passwordResetSubject 令牌的 subject 为用户UUID与密码哈希摘要，密码变化后令牌失效
+func passwordResetSubject(user *model.User) string {
+	return user.Uuid + "." + utils.SHA256Hex(user.Password)[:16]
+}
+
+// passwordResetCount 本机内存中的窗口计数
+type passwordResetCount struct {
+	n       int64
+	expires time.Time
+}
+
+// passwordResetCountersMax 本机计数条数上限，超出时先清理过期的，仍超出时整体清空
+const passwordResetCountersMax = 10000
+
+// passwordResetCounters 未配置 Redis 或 Redis 出错时在本机内存计数，多实例部署时各实例分别计数
+var passwordResetCounters = struct {
+	sync.Mutex
+	m map[string]*passwordResetCount
+}{m: map[string]*passwordResetCount{}}
+
+// incrLocal 本机计数加 1，返回窗口内的次数与窗口剩余时长
+func (s *PasswordResetService) incrLocal(key string, window time.Duration) (int64, time.Duration) {
+	now := time.Now()
+	passwordResetCounters.Lock()
+	defer passwordResetCounters.Unlock()
+	c := passwordResetCounters.m[key]
+	if c == nil || !now.Before(c.expires) {
+		if len(passwordResetCounters.m) >= passwordResetCountersMax {
+			for k, v := range passwordResetCounters.m {
+				if !now.Before(v.expires) {
+					delete(passwordResetCounters.m, k)
+				}
+			}
+			if len(passwordResetCounters.m) >= passwordResetCountersMax {
+				passwordResetCounters.m = map[string]*passwordResetCount{}
+			}
+		}
+		c = &passwordResetCount{expires: now.Add(window)}
+		passwordResetCounters.m[key] = c
+	}
+	c.n++
+	return c.n, c.expires.Sub(now)
+}
+
+// limit 在窗口内计数，超过 max 时返回剩余等待时长；Redis 不可用时在本机内存计数
+func (s *PasswordResetService) limit(ctx *app.Context, rc config.PasswordResetConfig, kind, subject string, max int) *PasswordResetLimitError {
+	key := redisPasswordResetKey + kind + ":" + subject
+	window := time.Duration(rc.Window) * time.Minute
+	if ctx.Redis != nil {
+		// 自增与设置过期时间原子执行，避免留下永不过期的计数
+		n, err := ctx.Redis.IncrWithExpire(ctx.Ctx, key, window)
+		if err == nil {
+			if n <= int64(max) {
+				return nil
+			}
+			ttl, err := ctx.Redis.TTL(ctx.Ctx, key)
+			if err != nil || ttl <= 0 {
+				ttl = window
+			}
+			return &PasswordResetLimitError{RetryAfter: ttl}
+		}
+		ctx.Logger.Error("Failed to count password reset request", err)
+	}
+	n, ttl := s.incrLocal(key, window)
+	if n <= int64(max) {
+		return nil
+	}
+	return &PasswordResetLimitError{RetryAfter: ttl}
+}
+
+// Request 向邮箱发送重置链接。邮箱未注册时同样返回 nil，响应不暴露邮箱是否存在；
+// 同一 IP 与同一邮箱的请求次数都计入频率限制
+func (s *PasswordResetService) Request(ctx *app.Context, email string) error {
+	rc, ok := passwordResetConfig(ctx)
+	if !ok {
+		return ErrPasswordResetDisabled
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if l := s.limit(ctx, rc, "ip", ctx.ClientIP(), rc.MaxPerIP); l != nil {
+		return l
+	}
+	if l := s.limit(ctx, rc, "addr", email, rc.MaxPerAddress); l != nil {
+		return l
+	}
+
+	user := &model.User{}
+	err := ctx.DB.Where("LOWER(email) = ? AND is_deleted = ?", email, 0).First(user).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.Logger.Infow("password reset requested for unknown email", "email", email, "client_ip", ctx.ClientIP())
+		return nil
+	}
+	if err != nil {
+		ctx.Logger.Error("Failed to get user by email", err)
+		return errors.New("failed to get user by email")
+	}
+	// 已禁用或删除的用户不发送重置邮件，响应与邮箱不存在一致
+	if !user.Active() {
+		return nil
+	}
+
+	token, err := utils.GenerateChallengeToken(passwordResetSubject(user), passwordResetPurpose, time.Duration(rc.TokenTTL)*time.Minute)
+	if err != nil {
+		ctx.Logger.Error("Failed to generate password reset token", err)
+		return errors.New("failed to generate password reset token")
+	}
+	u, err := url.Parse(rc.LinkURL)
+	if err != nil {
+		ctx.Logger.Error("Failed to parse PasswordReset.LinkURL", err)
+		return errors.New("failed to build password reset link")
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	link := html.EscapeString(u.String())
+
+	sendMailAsync(ctx, user.Email, rc.Subject,
+		fmt.Sprintf(passwordResetMailContent, html.EscapeString(user.Username), rc.TokenTTL, link, link))
+	s.opLog(ctx, user.Uuid, "请求通过邮件重置密码")
+	return nil
+}
+
+// Reset 校验重置链接中的令牌并设置新密码；成功后吊销用户全部会话与令牌，并邮件通知用户
+func (s *PasswordResetService) Reset(ctx *app.Context, token, password string) error {
+	rc, ok := passwordResetConfig(ctx)
+	if !ok {
+		return ErrPasswordResetDisabled
+	}
+	if l := s.limit(ctx, rc, "reset_ip", ctx.ClientIP(), rc.MaxPerIP); l != nil {
+		return l
+	}
+
+	sub, err := utils.ParseChallengeToken(token, passwordResetPurpose)
+	if err != nil {
+		return ErrPasswordResetInvalidToken
+	}
+	userUuid, _, _ := strings.Cut(sub, ".")
+	user := &model.User{}
+	err = ctx.DB.Where("uuid = ?", userUuid).First(user).Error
+	if err == gorm.ErrRecordNotFound {
+		return ErrPasswordResetInvalidToken
+	}
+	if err != nil {
+		ctx.Logger.Error("Failed to get user by UUID", err)
+		return errors.New("failed to get user by UUID")
+	}
+	if !user.Active() || passwordResetSubject(user) != sub {
+		return ErrPasswordResetInvalidToken
+	}
+
+	hashed, err := hashNewPassword(ctx, password, user.Username, user.Email)
+	if err != nil {
+		return err
+	}
+	// 条件中带上旧哈希，同一链接并发提交时只有一次生效
+	res := ctx.DB.Model(&model.User{}).
+		Where("uuid = ? AND password = ?", user.Uuid, user.Password).
+		Updates(map[string]interface{}{
+			"password":   hashed,
+			"updated_at": time.Now().Format("2006-01-02 15:04:05"),
+		})
+	if res.Error != nil {
+		ctx.Logger.Error("Failed to reset password", res.Error)
+		return errors.New("failed to reset password")
+	}
+	if res.RowsAffected == 0 {
+		return ErrPasswordResetInvalidToken
+	}
+
+	if err := NewAuthService().RevokeUserTokens(ctx, user.Uuid); err != nil {
+		return err
+	}
+	s.opLog(ctx, user.Uuid, "通过邮件重置密码")
+	sendMailAsync(ctx, user.Email, rc.NotifySubject,
+		fmt.Sprintf(passwordChangedMailContent, html.EscapeString(user.Username),
+			time.Now().Format("2006-01-02 15:04:05"), html.EscapeString(ctx.ClientIP())))
+	return nil
+}
+
+// opLog 找回密码的接口无需登录，操作日志由这里记录，不保存请求参数
+func (s *PasswordResetService) opLog(ctx *app.Context, userUuid, msg string) {
+	err := NewSysOpLogService().CreateSysOpLogAsync(ctx, &model.SysOpLog{
+		RequestId: ctx.TraceID,
+		UserUuid:  userUuid,
+		Path:      ctx.Request.URL.Path,
+		Method:    ctx.Request.Method,
+		Ip:        ctx.ClientIP(),
+		Status:    http.StatusOK,
+		Message:   msg,
+	})
+	if err != nil {
+		ctx.Logger.Warnw("Failed to create password reset operation log", "user_uuid", userUuid, "error", err)
+	}
+}
+
+// sendMailAsync 在后台发送邮件，发送结果只记录日志，响应耗时不暴露邮箱是否已注册
+func sendMailAsync(ctx *app.Context, to, subject, body string) {
+	if ctx.Config == nil || to == "" {
+		return
+	}
+	mc := ctx.Config.MailConfig
+	logger := ctx.Logger
+	go func() {
+		err := mail.Send(&mail.Options{
+			MailHost: mc.Host,
+			MailPort: mc.Port,
+			MailUser: mc.Username,
+			MailPass: mc.Password,
+			MailTo:   to,
+			Subject:  subject,
+			Body:     body,
+		})
+		if err != nil {
+			logger.Errorw("Failed to send mail", "to", to, "subject", subject, "error", err)
+		}
+	}()
+}
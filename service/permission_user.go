@@ -140,3 +140,20 @@ func (s *UserPermissionService) GetUserPermissionByUserUUID(ctx *app.Context, us
 	}
 	return userPermissions, nil
 }
+
+// HasAnyPermission 判断用户是否拥有 names 中任一名称的权限
+func (s *UserPermissionService) HasAnyPermission(ctx *app.Context, userUuid string, names []string) (bool, error) {
+	if userUuid == "" || len(names) == 0 {
+		return false, nil
+	}
+	var count int64
+	err := ctx.DB.Model(&model.UserPermission{}).
+		Joins("JOIN permissions ON permissions.uuid = user_permissions.permission_uuid").
+		Where("user_permissions.user_uuid = ? AND permissions.name IN ?", userUuid, names).
+		Count(&count).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to check user permission", err)
+		return false, errors.New("failed to check user permission")
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+
+	"github.com/mileusna/useragent"
+)
+
+// CtxSessionID LoginCheck 写入的当前会话ID
+const CtxSessionID = "session_id"
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionService 用户登录会话：登录时登记设备信息，访问时校验会话仍然有效，支持用户与管理员结束会话
+type SessionService struct {
+}
+
+func NewSessionService() *SessionService {
+	return &SessionService{}
+}
+
+func maxSessions(ctx app.AppContext) int {
+	if cfg := ctx.GetConfig(); cfg != nil && cfg.Auth.MaxSessions > 0 {
+		return cfg.Auth.MaxSessions
+	}
+	return 0
+}
+
+// save 按当前请求新建或延长会话
+func (s *SessionService) save(ctx *app.Context, store SessionStore, userUuid, sid string, expiresAt time.Time) error {
+	uaString := ctx.GetHeader("User-Agent")
+	ua := useragent.Parse(uaString)
+	now := time.Now()
+	sess := &model.UserSession{
+		Uuid:       sid,
+		UserUuid:   userUuid,
+		Ip:         ctx.ClientIP(),
+		UserAgent:  truncate(uaString, 255),
+		Browser:    truncate(ua.Name+" "+ua.Version, 100),
+		Os:         truncate(ua.OS, 100),
+		Device:     truncate(ua.Device, 100),
+		LastSeenAt: now,
+		LastSeenIp: ctx.ClientIP(),
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+	}
+	return store.SaveSession(ctx.Ctx, sess)
+}
+
+// Create 登录成功时登记会话；配置了 MaxSessions 时结束最久未活动的会话
+func (s *SessionService) Create(ctx *app.Context, userUuid, sid string, expiresAt time.Time) error {
+	store := NewSessionStore(ctx)
+	if store == nil {
+		return nil
+	}
+	if max := maxSessions(ctx); max > 0 {
+		list, err := store.ListSessions(ctx.Ctx, userUuid)
+		if err != nil {
+			ctx.Logger.Error("Failed to list user sessions", err)
+			return errors.New("failed to list user sessions")
+		}
+		// list 按最近活动时间倒序，结束末尾的会话
+		for i := len(list) - 1; i >= 0 && i >= max-1; i-- {
+			if err := s.Revoke(ctx, userUuid, list[i].Uuid); err != nil {
+				return err
+			}
+			ctx.Logger.Infow("session evicted by concurrent session limit", "user_uuid", userUuid, "session_id", list[i].Uuid)
+		}
+	}
+	if err := s.save(ctx, store, userUuid, sid, expiresAt); err != nil {
+		ctx.Logger.Error("Failed to save user session", err)
+		return errors.New("failed to save user session")
+	}
+	return nil
+}
+
+// Extend 刷新令牌时延长会话
+func (s *SessionService) Extend(ctx *app.Context, userUuid, sid string, expiresAt time.Time) {
+	store := NewSessionStore(ctx)
+	if store == nil {
+		return
+	}
+	if err := s.save(ctx, store, userUuid, sid, expiresAt); err != nil {
+		ctx.Logger.Warnw("Failed to extend user session", "user_uuid", userUuid, "session_id", sid, "error", err)
+	}
+}
+
+// Check 校验访问令牌所属的会话仍然有效，最近活动时间每分钟最多更新一次
+func (s *SessionService) Check(ctx *app.Context, userUuid, sid string) error {
+	store := NewSessionStore(ctx)
+	if store == nil {
+		return nil
+	}
+	sess, err := store.GetSession(ctx.Ctx, userUuid, sid)
+	if err != nil {
+		ctx.Logger.Error("Failed to get user session", err)
+		return errors.New("failed to get user session")
+	}
+	if sess == nil {
+		return ErrTokenRevoked
+	}
+	// 只更新已有的会话，并发的吊销不会被覆盖
+	if now := time.Now(); now.Sub(sess.LastSeenAt) >= time.Minute {
+		if err := store.TouchSession(ctx.Ctx, userUuid, sid, now, ctx.ClientIP()); err != nil {
+			ctx.Logger.Warnw("Failed to update session last seen time", "session_id", sid, "error", err)
+		}
+	}
+	return nil
+}
+
+// List 返回用户的会话，currentSid 对应的会话标记为当前会话
+func (s *SessionService) List(ctx *app.Context, userUuid, currentSid string) ([]*model.UserSession, error) {
+	store := NewSessionStore(ctx)
+	if store == nil {
+		return []*model.UserSession{}, nil
+	}
+	list, err := store.ListSessions(ctx.Ctx, userUuid)
+	if err != nil {
+		ctx.Logger.Error("Failed to list user sessions", err)
+		return nil, errors.New("failed to list user sessions")
+	}
+	for _, sess := range list {
+		sess.Current = sess.Uuid == currentSid
+	}
+	return list, nil
+}
+
+// Revoke 结束会话：删除会话并吊销其刷新令牌链，属于该会话的访问令牌随即失效
+func (s *SessionService) Revoke(ctx *app.Context, userUuid, sid string) error {
+	store := NewSessionStore(ctx)
+	if store == nil {
+		return ErrSessionNotFound
+	}
+	sess, err := store.GetSession(ctx.Ctx, userUuid, sid)
+	if err != nil {
+		ctx.Logger.Error("Failed to get user session", err)
+		return errors.New("failed to get user session")
+	}
+	if sess == nil {
+		return ErrSessionNotFound
+	}
+	if err := store.DeleteSession(ctx.Ctx, userUuid, sid); err != nil {
+		ctx.Logger.Error("Failed to delete user session", err)
+		return errors.New("failed to delete user session")
+	}
+	if tokens := NewTokenStore(ctx); tokens != nil {
+		if err := tokens.RevokeFamily(ctx.Ctx, sid, sess.ExpiresAt); err != nil {
+			ctx.Logger.Error("Failed to revoke token family", err)
+			return errors.New("failed to revoke token family")
+		}
+	}
+	return nil
+}
+
+// ForceLogout 管理员结束用户的会话，sid 为空时结束全部会话并吊销全部令牌
+func (s *SessionService) ForceLogout(ctx *app.Context, userUuid, sid string) error {
+	if sid == "" {
+		return NewAuthService().RevokeUserTokens(ctx, userUuid)
+	}
+	return s.Revoke(ctx, userUuid, sid)
+}
+
+// deleteUserSessions 删除用户的全部会话，令牌由调用方吊销
+func deleteUserSessions(ctx app.AppContext, userUuid string) error {
+	store := NewSessionStore(ctx)
+	if store == nil {
+		return nil
+	}
+	if err := store.DeleteUserSessions(ctx.GetCtx(), userUuid); err != nil {
+		ctx.GetLogger().Error("Failed to delete user sessions", err)
+		return errors.New("failed to delete user sessions")
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/redisop"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SessionStore 保存用户登录会话，查询时不返回已过期的会话
+type SessionStore interface {
+	// SaveSession 新建或更新会话
+	SaveSession(ctx context.Context, s *model.UserSession) error
+	// TouchSession 更新会话的最近活动时间与 IP，会话已删除或过期时不做任何修改
+	TouchSession(ctx context.Context, userUuid, sid string, lastSeen time.Time, ip string) error
+	// GetSession 查询会话，不存在或已过期时返回 nil
+	GetSession(ctx context.Context, userUuid, sid string) (*model.UserSession, error)
+	// ListSessions 返回用户的会话，最近活动的在前
+	ListSessions(ctx context.Context, userUuid string) ([]*model.UserSession, error)
+	DeleteSession(ctx context.Context, userUuid, sid string) error
+	DeleteUserSessions(ctx context.Context, userUuid string) error
+}
+
+// NewSessionStore 与 TokenStore 使用相同的存储（Auth.TokenStore）；均不可用时返回 nil，此时不记录会话
+func NewSessionStore(ctx app.AppContext) SessionStore {
+	cfg := ctx.GetConfig()
+	typ := ""
+	if cfg != nil {
+		typ = cfg.Auth.TokenStore
+	}
+	switch {
+	case (typ == "redis" || typ == "") && ctx.GetRedis() != nil:
+		return &redisSessionStore{rc: ctx.GetRedis()}
+	case typ != "redis" && ctx.GetDB() != nil:
+		return &dbSessionStore{db: ctx.GetDB()}
+	}
+	return nil
+}
+
+// dbSessionStore 基于数据库的 SessionStore
+type dbSessionStore struct {
+	db *gorm.DB
+}
+
+func (s *dbSessionStore) SaveSession(ctx context.Context, sess *model.UserSession) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uuid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_seen_at", "last_seen_ip", "expires_at"}),
+	}).Create(sess).Error
+}
+
+// TouchSession 只更新已有的会话，不会重新插入已被吊销的会话
+func (s *dbSessionStore) TouchSession(ctx context.Context, userUuid, sid string, lastSeen time.Time, ip string) error {
+	return s.db.WithContext(ctx).Model(&model.UserSession{}).
+		Where("uuid = ? AND user_uuid = ? AND expires_at > ?", sid, userUuid, time.Now()).
+		UpdateColumns(map[string]interface{}{"last_seen_at": lastSeen, "last_seen_ip": ip}).Error
+}
+
+func (s *dbSessionStore) GetSession(ctx context.Context, userUuid, sid string) (*model.UserSession, error) {
+	sess := &model.UserSession{}
+	err := s.db.WithContext(ctx).
+		Where("uuid = ? AND user_uuid = ? AND expires_at > ?", sid, userUuid, time.Now()).
+		First(sess).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (s *dbSessionStore) ListSessions(ctx context.Context, userUuid string) ([]*model.UserSession, error) {
+	var list []*model.UserSession
+	// 顺带清理该用户已过期的会话
+	if err := s.db.WithContext(ctx).Where("user_uuid = ? AND expires_at <= ?", userUuid, time.Now()).
+		Delete(&model.UserSession{}).Error; err != nil {
+		return nil, err
+	}
+	err := s.db.WithContext(ctx).Where("user_uuid = ?", userUuid).Order("last_seen_at desc").Find(&list).Error
+	return list, err
+}
+
+func (s *dbSessionStore) DeleteSession(ctx context.Context, userUuid, sid string) error {
+	return s.db.WithContext(ctx).Where("uuid = ? AND user_uuid = ?", sid, userUuid).Delete(&model.UserSession{}).Error
+}
+
+func (s *dbSessionStore) DeleteUserSessions(ctx context.Context, userUuid string) error {
+	return s.db.WithContext(ctx).Where("user_uuid = ?", userUuid).Delete(&model.UserSession{}).Error
+}
+
+// redisSessionStore 基于 Redis 的 SessionStore，用户的会话保存在一个 hash 中，字段为会话ID
+type redisSessionStore struct {
+	rc *redisop.RedisClient
+}
+
+const redisUserSessionsKey = "sgin:session:user:"
+
+func (s *redisSessionStore) SaveSession(ctx context.Context, sess *model.UserSession) error {
+	key := redisUserSessionsKey + sess.UserUuid
+	// 更新时保留登录时记录的设备信息
+	if old, err := s.GetSession(ctx, sess.UserUuid, sess.Uuid); err != nil {
+		return err
+	} else if old != nil {
+		old.LastSeenAt, old.LastSeenIp, old.ExpiresAt = sess.LastSeenAt, sess.LastSeenIp, sess.ExpiresAt
+		sess = old
+	}
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	if err := s.rc.HSet(ctx, key, sess.Uuid, string(b)); err != nil {
+		return err
+	}
+	// hash 的过期时间取最晚过期的会话
+	ttl, err := s.rc.TTL(ctx, key)
+	if err != nil {
+		return err
+	}
+	if d := ttlUntil(sess.ExpiresAt); ttl < d {
+		return s.rc.Expire(ctx, key, d)
+	}
+	return nil
+}
+
+// TouchSession 读出会话后按原值比较再写入，期间会话被删除或修改时放弃本次更新
+func (s *redisSessionStore) TouchSession(ctx context.Context, userUuid, sid string, lastSeen time.Time, ip string) error {
+	key := redisUserSessionsKey + userUuid
+	v, err := s.rc.HGet(ctx, key, sid)
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sess := &model.UserSession{}
+	if err := json.Unmarshal([]byte(v), sess); err != nil {
+		return err
+	}
+	if !time.Now().Before(sess.ExpiresAt) {
+		return nil
+	}
+	sess.LastSeenAt, sess.LastSeenIp = lastSeen, ip
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	_, err = s.rc.HCompareAndSet(ctx, key, sid, v, string(b))
+	return err
+}
+
+func (s *redisSessionStore) GetSession(ctx context.Context, userUuid, sid string) (*model.UserSession, error) {
+	v, err := s.rc.HGet(ctx, redisUserSessionsKey+userUuid, sid)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sess := &model.UserSession{}
+	if err := json.Unmarshal([]byte(v), sess); err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(sess.ExpiresAt) {
+		return nil, nil
+	}
+	return sess, nil
+}
+
+func (s *redisSessionStore) ListSessions(ctx context.Context, userUuid string) ([]*model.UserSession, error) {
+	key := redisUserSessionsKey + userUuid
+	all, err := s.rc.HGetAll(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := []*model.UserSession{}
+	expired := []string{}
+	for sid, v := range all {
+		sess := &model.UserSession{}
+		if err := json.Unmarshal([]byte(v), sess); err != nil || !now.Before(sess.ExpiresAt) {
+			expired = append(expired, sid)
+			continue
+		}
+		list = append(list, sess)
+	}
+	if len(expired) > 0 {
+		if err := s.rc.HDel(ctx, key, expired...); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastSeenAt.After(list[j].LastSeenAt) })
+	return list, nil
+}
+
+func (s *redisSessionStore) DeleteSession(ctx context.Context, userUuid, sid string) error {
+	return s.rc.HDel(ctx, redisUserSessionsKey+userUuid, sid)
+}
+
+func (s *redisSessionStore) DeleteUserSessions(ctx context.Context, userUuid string) error {
+	return s.rc.Del(ctx, redisUserSessionsKey+userUuid)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/sms"
+)
+
+// smsSender 按 App 保存的短信发送器及其创建结果
+type smsSender struct {
+	sender *sms.Sender
+	err    error
+}
+
+type smsSenderKey struct{}
+
+// SmsService 按 SMS 配置发送短信，每次发送都记录到 sms_logs
+type SmsService struct {
+}
+
+func NewSmsService() *SmsService {
+	return &SmsService{}
+}
+
+// sender 在 ctx 所属 App 上首次使用时按配置创建，之后复用
+func (s *SmsService) sender(ctx app.AppContext) (*sms.Sender, error) {
+	v := app.AppOf(ctx).Value(smsSenderKey{}, func() interface{} {
+		return newSmsSender(ctx)
+	}).(*smsSender)
+	return v.sender, v.err
+}
+
+func newSmsSender(ctx app.AppContext) *smsSender {
+	cfg := ctx.GetConfig()
+	if cfg == nil {
+		return &smsSender{err: sms.ErrNotConfigured}
+	}
+	if strings.EqualFold(cfg.SMS.Provider, "log") {
+		// log 类型通过应用日志记录，不输出验证码等参数值
+		return &smsSender{sender: sms.NewSender(sms.NewLogProvider(ctx.GetLogger().Named("sms").Infow), cfg.SMS)}
+	}
+	sender, err := sms.New(cfg.SMS)
+	if err != nil && err != sms.ErrNotConfigured {
+		ctx.GetLogger().Error("Failed to create sms sender", err)
+	}
+	return &smsSender{sender: sender, err: err}
+}
+
+// Enabled 是否配置了短信服务商
+func (s *SmsService) Enabled(ctx app.AppContext) bool {
+	sender, err := s.sender(ctx)
+	return err == nil && sender != nil
+}
+
+// Send 发送模板短信；号码无效时返回 sms.ErrInvalidPhone，未配置服务商时返回 sms.ErrNotConfigured
+func (s *SmsService) Send(ctx *app.Context, phone, template string, params map[string]string) error {
+	sender, err := s.sender(ctx)
+	if err != nil {
+		return err
+	}
+	normalized, err := sender.Normalize(phone)
+	if err != nil {
+		return err
+	}
+
+	err = sender.Send(ctx.Ctx, normalized, template, params)
+	log := &model.SmsLog{
+		RequestId: ctx.TraceID,
+		Phone:     normalized,
+		Template:  template,
+		Provider:  sender.Provider(),
+		Status:    model.SmsStatusSuccess,
+		Ip:        ctx.ClientIP(),
+	}
+	if err != nil {
+		log.Status = model.SmsStatusFail
+		log.Error = truncate(err.Error(), 500)
+	}
+	if dbErr := ctx.DB.Create(log).Error; dbErr != nil {
+		ctx.Logger.Warnw("Failed to create sms log", "phone", normalized, "error", dbErr)
+	}
+	if err != nil {
+		ctx.Logger.Errorw("Failed to send sms", "phone", normalized, "template", template, "provider", sender.Provider(), "error", err)
+		return errors.New("failed to send sms")
+	}
+	return nil
+}
+
+// SendVerificationCode 发送验证码短信
+func (s *SmsService) SendVerificationCode(ctx *app.Context, phone, code string, minutes int) error {
+	return s.Send(ctx, phone, sms.TemplateVerificationCode, map[string]string{
+		"code":    code,
+		"minutes": strconv.Itoa(minutes),
+	})
+}
+
+// normalizePhone 按 SMS.DefaultRegion 规范化手机号，与发送短信时使用的号码一致
+func normalizePhone(ctx app.AppContext, phone string) (string, error) {
+	region := ""
+	if cfg := ctx.GetConfig(); cfg != nil {
+		region = cfg.SMS.DefaultRegion
+	}
+	return sms.NormalizePhone(phone, region)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/audit"
+
+	"gorm.io/gorm"
+)
+
+type auditKey struct{}
+
+// InitAudit 在 App 的 DB 上注册实体变更审计回调（每个 App 一次），审计记录写入 sys_audit_logs
+func InitAudit(a *app.App) {
+	if a == nil || a.DB == nil || a.Config == nil || a.Config.Audit.Disable {
+		return
+	}
+	a.Once(auditKey{}, func() {
+		err := audit.Register(a.DB, audit.Options{
+			Store:   saveAuditEntries,
+			MaxRows: a.Config.Audit.MaxRows,
+		})
+		if err != nil {
+			a.Logger.Error("Failed to register audit callbacks", err)
+		}
+	})
+}
+
+func saveAuditEntries(tx *gorm.DB, entries []*audit.Entry) error {
+	logs := make([]*model.SysAuditLog, 0, len(entries))
+	for _, e := range entries {
+		changes, err := json.Marshal(e.Changes)
+		if err != nil {
+			return err
+		}
+		logs = append(logs, &model.SysAuditLog{
+			Entity:    e.Entity,
+			EntityId:  e.EntityID,
+			Operation: e.Operation,
+			Changes:   string(changes),
+			UserUuid:  e.Actor.UserID,
+			AppId:     e.Actor.AppID,
+			RequestId: e.Actor.TraceID,
+			Ip:        e.Actor.IP,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return tx.Create(&logs).Error
+}
+
+type SysAuditLogService struct {
+}
+
+func NewSysAuditLogService() *SysAuditLogService {
+	return &SysAuditLogService{}
+}
+
+// GetSysAuditLogList 按实体、操作人、请求与时间范围查询审计日志
+func (s *SysAuditLogService) GetSysAuditLogList(ctx *app.Context, params *model.ReqAuditLogQueryParam) (*model.PagedResponse, error) {
+	var (
+		logs  []*model.SysAuditLog
+		total int64
+	)
+
+	db := ctx.DB.Model(&model.SysAuditLog{})
+
+	if params.Entity != "" {
+		db = db.Where("entity = ?", params.Entity)
+	}
+	if params.EntityId != "" {
+		db = db.Where("entity_id = ?", params.EntityId)
+	}
+	if params.Operation != "" {
+		db = db.Where("operation = ?", params.Operation)
+	}
+	if params.UserUuid != "" {
+		db = db.Where("user_uuid = ?", params.UserUuid)
+	}
+	if params.RequestId != "" {
+		db = db.Where("request_id = ?", params.RequestId)
+	}
+	if params.StartTime != "" {
+		db = db.Where("created_at >= ?", params.StartTime)
+	}
+	if params.EndTime != "" {
+		db = db.Where("created_at <= ?", params.EndTime)
+	}
+
+	err := db.Count(&total).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to get audit log count", err)
+		return nil, errors.New("failed to get audit log count")
+	}
+
+	err = db.Order("id DESC").Offset(params.GetOffset()).Limit(params.PageSize).Find(&logs).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to get audit log list", err)
+		return nil, errors.New("failed to get audit log list")
+	}
+
+	userUuids := make([]string, 0)
+	for _, v := range logs {
+		if v.UserUuid != "" {
+			userUuids = append(userUuids, v.UserUuid)
+		}
+	}
+
+	userMap, err := NewUserService().GetUsersByUUIDs(ctx, userUuids)
+	if err != nil {
+		ctx.Logger.Error("Failed to get user list by UUIDs", err)
+		return nil, errors.New("failed to get user list by UUIDs")
+	}
+
+	res := make([]*model.SysAuditLogRes, 0, len(logs))
+	for _, log := range logs {
+		logRes := &model.SysAuditLogRes{
+			SysAuditLog: *log,
+		}
+		if user, ok := userMap[log.UserUuid]; ok {
+			logRes.Username = user.Nickname
+		}
+		res = append(res, logRes)
+	}
+
+	return &model.PagedResponse{
+		Total:    total,
+		Data:     res,
+		Current:  params.Current,
+		PageSize: params.PageSize,
+	}, nil
+}
@@ -28,6 +28,19 @@ func (s *SysOpLogService) CreateSysOpLog(ctx *app.Context, log *model.SysOpLog)
 	return nil
 }
 
+// 异步创建操作日志，启用异步日志管道时只放入缓冲区，否则同步写入
+func (s *SysOpLogService) CreateSysOpLogAsync(ctx *app.Context, log *model.SysOpLog) error {
+	p := logPipelinesOf(ctx)
+	if p == nil {
+		return s.CreateSysOpLog(ctx, log)
+	}
+	if log.CreatedAt == "" {
+		log.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+	p.op.Push(log)
+	return nil
+}
+
 func (s *SysOpLogService) GetSysOpLogByID(ctx *app.Context, id int64) (*model.SysOpLog, error) {
 	log := &model.SysOpLog{}
 	err := ctx.DB.Where("id = ?", id).First(log).Error
@@ -61,80 +74,128 @@ func (s *SysOpLogService) DeleteSysOpLog(ctx *app.Context, id int64) error {
 	return nil
 }
 
-// GetSysOpLogList retrieves a list of operation logs based on query parameters
-func (s *SysOpLogService) GetSysOpLogList(ctx *app.Context, params *model.ReqOpLogQueryParam) (*model.PagedResponse, error) {
-	var (
-		logs  []*model.SysOpLog
-		total int64
-	)
+// opLogSortFields 可排序的字段
+var opLogSortFields = map[string]string{
+	"created_at": "l.created_at",
+	"duration":   "l.duration",
+	"status":     "l.status",
+	"code":       "l.code",
+}
+
+// maxOpLogExportRows 单次导出的最大行数
+const maxOpLogExportRows = 100000
+
+// opLogQuery 按查询参数构造操作日志查询，关联用户与 API 表
+func (s *SysOpLogService) opLogQuery(ctx *app.Context, params *model.ReqOpLogQueryParam) *gorm.DB {
+	// 同一路径可能登记在多个服务下，按路径聚合后再关联，避免重复行
+	apis := ctx.DB.Model(&model.API{}).Select("path, MAX(module) AS module, MAX(name) AS name").Group("path")
 
-	db := ctx.DB.Model(&model.SysOpLog{})
+	db := ctx.DB.Table("sys_op_logs AS l").
+		Joins("LEFT JOIN users AS u ON u.uuid = l.user_uuid").
+		Joins("LEFT JOIN (?) AS a ON a.path = l.path", apis)
 
 	if params.UserName != "" {
-		//db = db.Where("user_name LIKE ?", "%"+params.UserName+"%")
+		like := "%" + params.UserName + "%"
+		db = db.Where("u.username LIKE ? OR u.nickname LIKE ?", like, like)
+	}
+	if params.UserUuid != "" {
+		db = db.Where("l.user_uuid = ?", params.UserUuid)
+	}
+	if params.Module != "" {
+		db = db.Where("a.module = ?", params.Module)
 	}
-
 	if params.Path != "" {
-		db = db.Where("path LIKE ?", "%"+params.Path+"%")
+		db = db.Where("l.path LIKE ?", "%"+params.Path+"%")
 	}
-
 	if params.Method != "" {
-		db = db.Where("method = ?", params.Method)
+		db = db.Where("l.method = ?", params.Method)
 	}
-
 	if params.Status != 0 {
-		db = db.Where("status = ?", params.Status)
+		db = db.Where("l.status = ?", params.Status)
+	}
+	if params.Code != 0 {
+		db = db.Where("l.code = ?", params.Code)
 	}
+	if params.MinDuration > 0 {
+		db = db.Where("l.duration >= ?", params.MinDuration)
+	}
+	if params.MaxDuration > 0 {
+		db = db.Where("l.duration <= ?", params.MaxDuration)
+	}
+	if params.StartTime != "" {
+		db = db.Where("l.created_at >= ?", params.StartTime)
+	}
+	if params.EndTime != "" {
+		db = db.Where("l.created_at <= ?", params.EndTime)
+	}
+	return db
+}
 
-	err := db.Count(&total).Error
+// opLogOrder 返回排序子句，未指定或字段不支持时按 ID 倒序
+func opLogOrder(params *model.ReqOpLogQueryParam) string {
+	order := "DESC"
+	if params.SortOrder == "asc" {
+		order = "ASC"
+	}
+	if field, ok := opLogSortFields[params.SortField]; ok {
+		return field + " " + order + ", l.id " + order
+	}
+	return "l.id " + order
+}
+
+const opLogSelect = "l.*, u.nickname AS username, a.module AS module, a.name AS name"
+
+// GetSysOpLogList retrieves a list of operation logs based on query parameters
+func (s *SysOpLogService) GetSysOpLogList(ctx *app.Context, params *model.ReqOpLogQueryParam) (*model.PagedResponse, error) {
+	var (
+		logs  []*model.SysOpLogRes
+		total int64
+	)
+
+	err := s.opLogQuery(ctx, params).Count(&total).Error
 	if err != nil {
 		ctx.Logger.Error("Failed to get operation log count", err)
 		return nil, errors.New("failed to get operation log count")
 	}
 
-	err = db.Order("id DESC").Offset(params.GetOffset()).Limit(params.PageSize).Find(&logs).Error
+	err = s.opLogQuery(ctx, params).Select(opLogSelect).Order(opLogOrder(params)).
+		Offset(params.GetOffset()).Limit(params.PageSize).Scan(&logs).Error
 	if err != nil {
 		ctx.Logger.Error("Failed to get operation log list", err)
 		return nil, errors.New("failed to get operation log list")
 	}
 
-	userUuids := make([]string, 0)
-	paths := make([]string, 0)
-	for _, v := range logs {
-		userUuids = append(userUuids, v.UserUuid)
-		paths = append(paths, v.Path)
-	}
-
-	userMap, err := NewUserService().GetUsersByUUIDs(ctx, userUuids)
-	if err != nil {
-		ctx.Logger.Error("Failed to get user list by UUIDs", err)
-		return nil, errors.New("failed to get user list by UUIDs")
-	}
+	return &model.PagedResponse{
+		Total:    total,
+		Data:     logs,
+		Current:  params.Current,
+		PageSize: params.PageSize,
+	}, nil
+}
 
-	apiMap, err := NewAPIService().GetAPIByPathList(ctx, paths)
+// ExportSysOpLogs 逐行读取满足条件的操作日志并交给 fn，不一次性加载到内存，最多 maxOpLogExportRows 行
+func (s *SysOpLogService) ExportSysOpLogs(ctx *app.Context, params *model.ReqOpLogQueryParam, fn func(*model.SysOpLogRes) error) error {
+	db := s.opLogQuery(ctx, params).Select(opLogSelect).Order(opLogOrder(params)).Limit(maxOpLogExportRows)
+	rows, err := db.Rows()
 	if err != nil {
-		ctx.Logger.Error("Failed to get API list by paths", err)
-		return nil, errors.New("failed to get API list by paths")
+		ctx.Logger.Error("Failed to export operation logs", err)
+		return errors.New("failed to export operation logs")
 	}
+	defer rows.Close()
 
-	res := make([]*model.SysOpLogRes, 0)
-	for _, log := range logs {
-		logRes := &model.SysOpLogRes{
-			SysOpLog: *log,
-		}
-		if user, ok := userMap[log.UserUuid]; ok {
-			logRes.Username = user.Nickname
+	for rows.Next() {
+		log := &model.SysOpLogRes{}
+		if err := db.ScanRows(rows, log); err != nil {
+			ctx.Logger.Error("Failed to scan operation log", err)
+			return errors.New("failed to scan operation log")
 		}
-
-		if api, ok := apiMap[log.Path]; ok {
-			logRes.Module = api.Module
-			logRes.Name = api.Name
+		if err := fn(log); err != nil {
+			return err
 		}
-		res = append(res, logRes)
 	}
-
-	return &model.PagedResponse{
-		Total: total,
-		Data:  res,
-	}, nil
+	if err := rows.Err(); err != nil {
+		ctx.Logger.Error("Failed to export operation logs", err)
+		return errors.New("failed to export operation logs")
+	}
+	return nil
 }
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/redisop"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenStore 保存刷新令牌与访问令牌的吊销信息
+type TokenStore interface {
+	SaveRefreshToken(ctx context.Context, hash string, t *model.RefreshToken) error
+	// GetRefreshToken 按摘要查询刷新令牌，不存在时返回 nil
+	GetRefreshToken(ctx context.Context, hash string) (*model.RefreshToken, error)
+	// UseRefreshToken 原子地将刷新令牌标记为已使用并返回记录；used 为 true 表示此前已被使用
+	UseRefreshToken(ctx context.Context, hash string) (t *model.RefreshToken, used bool, err error)
+	// RevokeFamily 吊销同一令牌链上的所有刷新令牌，until 之后可以清除记录
+	RevokeFamily(ctx context.Context, familyID string, until time.Time) error
+	RevokeAccessToken(ctx context.Context, jti string, until time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// RevokeUserTokens 使用户早于 before 签发的访问令牌与刷新令牌全部失效
+	RevokeUserTokens(ctx context.Context, userUuid string, before, until time.Time) error
+	// RevokedBefore 返回用户令牌的失效时间点，未吊销时返回零值
+	RevokedBefore(ctx context.Context, userUuid string) (time.Time, error)
+}
+
+// NewTokenStore 按 Auth.TokenStore 配置返回存储；未配置时有 Redis 用 Redis，否则用数据库；均不可用时返回 nil
+func NewTokenStore(ctx app.AppContext) TokenStore {
+	cfg := ctx.GetConfig()
+	typ := ""
+	if cfg != nil {
+		typ = cfg.Auth.TokenStore
+	}
+	switch {
+	case (typ == "redis" || typ == "") && ctx.GetRedis() != nil:
+		return &redisTokenStore{rc: ctx.GetRedis()}
+	case typ != "redis" && ctx.GetDB() != nil:
+		return &dbTokenStore{db: ctx.GetDB()}
+	}
+	return nil
+}
+
+// dbTokenStore 基于数据库的 TokenStore
+type dbTokenStore struct {
+	db *gorm.DB
+}
+
+func (s *dbTokenStore) SaveRefreshToken(ctx context.Context, hash string, t *model.RefreshToken) error {
+	t.TokenHash = hash
+	return s.db.WithContext(ctx).Create(t).Error
+}
+
+func (s *dbTokenStore) GetRefreshToken(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	t := &model.RefreshToken{}
+	err := s.db.WithContext(ctx).Where("token_hash = ?", hash).First(t).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *dbTokenStore) UseRefreshToken(ctx context.Context, hash string) (*model.RefreshToken, bool, error) {
+	t, err := s.GetRefreshToken(ctx, hash)
+	if err != nil || t == nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	res := s.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("token_hash = ? AND used_at IS NULL", hash).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	return t, res.RowsAffected == 0, nil
+}
+
+func (s *dbTokenStore) RevokeFamily(ctx context.Context, familyID string, until time.Time) error {
+	return s.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("family_id = ?", familyID).
+		Update("revoked", true).Error
+}
+
+func (s *dbTokenStore) RevokeAccessToken(ctx context.Context, jti string, until time.Time) error {
+	return s.upsertRevocation(ctx, &model.TokenRevocation{
+		Kind:      model.TokenRevocationJTI,
+		Subject:   jti,
+		ExpiresAt: until,
+	})
+}
+
+func (s *dbTokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var n int64
+	err := s.db.WithContext(ctx).Model(&model.TokenRevocation{}).
+		Where("kind = ? AND subject = ?", model.TokenRevocationJTI, jti).
+		Count(&n).Error
+	return n > 0, err
+}
+
+func (s *dbTokenStore) RevokeUserTokens(ctx context.Context, userUuid string, before, until time.Time) error {
+	if err := s.upsertRevocation(ctx, &model.TokenRevocation{
+		Kind:          model.TokenRevocationUser,
+		Subject:       userUuid,
+		RevokedBefore: before,
+		ExpiresAt:     until,
+	}); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("user_uuid = ? AND created_at <= ?", userUuid, before).
+		Update("revoked", true).Error
+}
+
+func (s *dbTokenStore) RevokedBefore(ctx context.Context, userUuid string) (time.Time, error) {
+	r := &model.TokenRevocation{}
+	err := s.db.WithContext(ctx).
+		Where("kind = ? AND subject = ?", model.TokenRevocationUser, userUuid).
+		First(r).Error
+	if err == gorm.ErrRecordNotFound {
+		return time.Time{}, nil
+	}
+	return r.RevokedBefore, err
+}
+
+func (s *dbTokenStore) upsertRevocation(ctx context.Context, r *model.TokenRevocation) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "subject"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "expires_at"}),
+	}).Create(r).Error
+}
+
+// redisTokenStore 基于 Redis 的 TokenStore，所有键按令牌有效期设置过期时间
+type redisTokenStore struct {
+	rc *redisop.RedisClient
+}
+
+const (
+	redisRefreshTokenKey  = "sgin:token:refresh:" // 刷新令牌记录
+	redisRefreshUsedKey   = "sgin:token:used:"    // 刷新令牌已使用标记
+	redisFamilyRevokedKey = "sgin:token:family:"  // 令牌链吊销标记
+	redisRevokedJTIKey    = "sgin:token:jti:"     // 访问令牌吊销标记
+	redisUserRevokedKey   = "sgin:token:user:"    // 用户令牌失效时间点（Unix 秒）
+)
+
+func (s *redisTokenStore) SaveRefreshToken(ctx context.Context, hash string, t *model.RefreshToken) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return s.rc.Set(ctx, redisRefreshTokenKey+hash, string(b), ttlUntil(t.ExpiresAt))
+}
+
+func (s *redisTokenStore) GetRefreshToken(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	v, err := s.rc.Get(ctx, redisRefreshTokenKey+hash)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &model.RefreshToken{}
+	if err := json.Unmarshal([]byte(v), t); err != nil {
+		return nil, err
+	}
+	t.TokenHash = hash
+	revoked, err := s.rc.Exists(ctx, redisFamilyRevokedKey+t.FamilyId)
+	if err != nil {
+		return nil, err
+	}
+	t.Revoked = t.Revoked || revoked
+	return t, nil
+}
+
+func (s *redisTokenStore) UseRefreshToken(ctx context.Context, hash string) (*model.RefreshToken, bool, error) {
+	t, err := s.GetRefreshToken(ctx, hash)
+	if err != nil || t == nil {
+		return nil, false, err
+	}
+	ok, err := s.rc.SetNX(ctx, redisRefreshUsedKey+hash, strconv.FormatInt(time.Now().Unix(), 10), ttlUntil(t.ExpiresAt))
+	if err != nil {
+		return nil, false, err
+	}
+	return t, !ok, nil
+}
+
+func (s *redisTokenStore) RevokeFamily(ctx context.Context, familyID string, until time.Time) error {
+	return s.rc.Set(ctx, redisFamilyRevokedKey+familyID, "1", ttlUntil(until))
+}
+
+func (s *redisTokenStore) RevokeAccessToken(ctx context.Context, jti string, until time.Time) error {
+	return s.rc.Set(ctx, redisRevokedJTIKey+jti, "1", ttlUntil(until))
+}
+
+func (s *redisTokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.rc.Exists(ctx, redisRevokedJTIKey+jti)
+}
+
+func (s *redisTokenStore) RevokeUserTokens(ctx context.Context, userUuid string, before, until time.Time) error {
+	return s.rc.Set(ctx, redisUserRevokedKey+userUuid, strconv.FormatInt(before.Unix(), 10), ttlUntil(until))
+}
+
+func (s *redisTokenStore) RevokedBefore(ctx context.Context, userUuid string) (time.Time, error) {
+	v, err := s.rc.Get(ctx, redisUserRevokedKey+userUuid)
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// ttlUntil 返回距 t 的时长，至少 1 秒，避免已过期的时间生成永不过期的键
+func ttlUntil(t time.Time) time.Duration {
+	if d := time.Until(t); d > time.Second {
+		return d
+	}
+	return time.Second
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/totp"
+	"github.com/luxingwen/sgin/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactorChallengePurpose 密码验证通过、等待两步验证的临时凭据用途
+const TwoFactorChallengePurpose = "2fa"
+
+var (
+	ErrTwoFactorInvalidCode     = errors.New("invalid two-factor code")
+	ErrTwoFactorNotEnrolled     = errors.New("two-factor authentication is not enrolled")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorRequired        = errors.New("two-factor authentication is required")
+	ErrTwoFactorInvalidArgument = errors.New("code or recovery code is required")
+)
+
+// TwoFactorService TOTP 两步验证：绑定、确认、校验与恢复码
+type TwoFactorService struct {
+}
+
+func NewTwoFactorService() *TwoFactorService {
+	return &TwoFactorService{}
+}
+
+func totpOptions(ctx app.AppContext) totp.Options {
+	if cfg := ctx.GetConfig(); cfg != nil {
+		return totp.Options{Skew: cfg.TwoFactor.Skew}
+	}
+	return totp.Options{}
+}
+
+// twoFactorKey 加密 TOTP 密钥的密钥，必须单独配置，不与 PasswdKey 共用
+func twoFactorKey(ctx app.AppContext) (string, error) {
+	cfg := ctx.GetConfig()
+	if cfg == nil || cfg.TwoFactor.EncryptionKey == "" {
+		return "", errors.New("TwoFactor.EncryptionKey is not configured")
+	}
+	return cfg.TwoFactor.EncryptionKey, nil
+}
+
+func (s *TwoFactorService) get(ctx *app.Context, userUuid string) (*model.UserTwoFactor, error) {
+	tf := &model.UserTwoFactor{}
+	err := ctx.DB.Where("user_uuid = ?", userUuid).First(tf).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		ctx.Logger.Error("Failed to get user two factor", err)
+		return nil, errors.New("failed to get user two factor")
+	}
+	return tf, nil
+}
+
+// IsRequired 用户是否因拥有 TwoFactor.RequiredPermissions 中的权限而必须启用两步验证
+func (s *TwoFactorService) IsRequired(ctx *app.Context, userUuid string) (bool, error) {
+	if ctx.Config == nil || len(ctx.Config.TwoFactor.RequiredPermissions) == 0 {
+		return false, nil
+	}
+	return NewUserPermissionService().HasAnyPermission(ctx, userUuid, ctx.Config.TwoFactor.RequiredPermissions)
+}
+
+// LoginState 返回登录时是否已启用两步验证、是否必须启用
+func (s *TwoFactorService) LoginState(ctx *app.Context, userUuid string) (enabled, required bool, err error) {
+	tf, err := s.get(ctx, userUuid)
+	if err != nil {
+		return false, false, err
+	}
+	if required, err = s.IsRequired(ctx, userUuid); err != nil {
+		return false, false, err
+	}
+	return tf != nil && tf.Enabled, required, nil
+}
+
+// Status 返回用户的两步验证状态
+func (s *TwoFactorService) Status(ctx *app.Context, userUuid string) (*model.ResTwoFactorStatus, error) {
+	enabled, required, err := s.LoginState(ctx, userUuid)
+	if err != nil {
+		return nil, err
+	}
+	res := &model.ResTwoFactorStatus{Enabled: enabled, Required: required}
+	if enabled {
+		err := ctx.DB.Model(&model.UserRecoveryCode{}).
+			Where("user_uuid = ? AND used_at IS NULL", userUuid).
+			Count(&res.RecoveryCodesLeft).Error
+		if err != nil {
+			ctx.Logger.Error("Failed to count recovery codes", err)
+			return nil, errors.New("failed to count recovery codes")
+		}
+	}
+	return res, nil
+}
+
+// Enroll 生成新的 TOTP 密钥，确认前不生效；已启用时返回 ErrTwoFactorAlreadyEnabled
+func (s *TwoFactorService) Enroll(ctx *app.Context, user *model.User) (*model.ResTwoFactorEnroll, error) {
+	tf, err := s.get(ctx, user.Uuid)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	key, err := twoFactorKey(ctx)
+	if err != nil {
+		ctx.Logger.Error("Failed to get two factor encryption key", err)
+		return nil, errors.New("two-factor authentication is not configured")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.Logger.Error("Failed to generate totp secret", err)
+		return nil, errors.New("failed to generate totp secret")
+	}
+	encrypted, err := utils.EncryptString(key, secret)
+	if err != nil {
+		ctx.Logger.Error("Failed to encrypt totp secret", err)
+		return nil, errors.New("failed to encrypt totp secret")
+	}
+
+	if tf == nil {
+		err = ctx.DB.Create(&model.UserTwoFactor{UserUuid: user.Uuid, Secret: encrypted}).Error
+	} else {
+		err = ctx.DB.Model(tf).Updates(map[string]interface{}{"secret": encrypted, "last_counter": 0}).Error
+	}
+	if err != nil {
+		ctx.Logger.Error("Failed to save user two factor", err)
+		return nil, errors.New("failed to save user two factor")
+	}
+
+	issuer := "sgin"
+	if ctx.Config != nil && ctx.Config.TwoFactor.Issuer != "" {
+		issuer = ctx.Config.TwoFactor.Issuer
+	}
+	account := user.Username
+	if account == "" {
+		account = user.Email
+	}
+	uri := totpOptions(ctx).URI(issuer, account, secret)
+	png, err := totp.QRCodePNG(uri, 256)
+	if err != nil {
+		ctx.Logger.Error("Failed to encode totp qr code", err)
+		return nil, errors.New("failed to encode totp qr code")
+	}
+	return &model.ResTwoFactorEnroll{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// Confirm 用验证码确认绑定并启用两步验证，返回恢复码
+func (s *TwoFactorService) Confirm(ctx *app.Context, userUuid, code string) ([]string, error) {
+	tf, err := s.get(ctx, userUuid)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err := s.checkCode(ctx, tf, code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = ctx.DB.Model(tf).Updates(map[string]interface{}{"enabled": true, "enabled_at": &now}).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to enable two factor", err)
+		return nil, errors.New("failed to enable two factor")
+	}
+	return s.RegenerateRecoveryCodes(ctx, userUuid)
+}
+
+// Verify 登录时校验验证码或恢复码，恢复码使用后失效
+func (s *TwoFactorService) Verify(ctx *app.Context, userUuid, code, recoveryCode string) error {
+	tf, err := s.get(ctx, userUuid)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+	switch {
+	case code != "":
+		return s.checkCode(ctx, tf, code)
+	case recoveryCode != "":
+		res := ctx.DB.Model(&model.UserRecoveryCode{}).
+			Where("user_uuid = ? AND code_hash = ? AND used_at IS NULL", userUuid, hashRecoveryCode(recoveryCode)).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			ctx.Logger.Error("Failed to use recovery code", res.Error)
+			return errors.New("failed to use recovery code")
+		}
+		if res.RowsAffected == 0 {
+			return ErrTwoFactorInvalidCode
+		}
+		ctx.Logger.Infow("recovery code used", "user_uuid", userUuid)
+		return nil
+	}
+	return ErrTwoFactorInvalidArgument
+}
+
+// checkCode 校验 TOTP 验证码，同一时间步的验证码只能使用一次
+func (s *TwoFactorService) checkCode(ctx *app.Context, tf *model.UserTwoFactor, code string) error {
+	key, err := twoFactorKey(ctx)
+	if err != nil {
+		ctx.Logger.Error("Failed to get two factor encryption key", err)
+		return errors.New("two-factor authentication is not configured")
+	}
+	secret, err := utils.DecryptString(key, tf.Secret)
+	if err != nil {
+		ctx.Logger.Error("Failed to decrypt totp secret", err)
+		return errors.New("failed to decrypt totp secret")
+	}
+	counter, ok := totpOptions(ctx).Validate(secret, code, time.Now())
+	if !ok || counter <= tf.LastCounter {
+		return ErrTwoFactorInvalidCode
+	}
+	// 条件更新，并发提交同一验证码时只有一次成功
+	res := ctx.DB.Model(&model.UserTwoFactor{}).
+		Where("id = ? AND last_counter < ?", tf.ID, counter).
+		Update("last_counter", counter)
+	if res.Error != nil {
+		ctx.Logger.Error("Failed to update totp counter", res.Error)
+		return errors.New("failed to update totp counter")
+	}
+	if res.RowsAffected == 0 {
+		return ErrTwoFactorInvalidCode
+	}
+	tf.LastCounter = counter
+	return nil
+}
+
+// RegenerateRecoveryCodes 生成新的恢复码并使旧恢复码失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx *app.Context, userUuid string) ([]string, error) {
+	n := 10
+	if ctx.Config != nil && ctx.Config.TwoFactor.RecoveryCodes > 0 {
+		n = ctx.Config.TwoFactor.RecoveryCodes
+	}
+	codes := make([]string, n)
+	rows := make([]*model.UserRecoveryCode, n)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		rows[i] = &model.UserRecoveryCode{UserUuid: userUuid, CodeHash: hashRecoveryCode(codes[i])}
+	}
+
+	err := ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ?", userUuid).Delete(&model.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		ctx.Logger.Error("Failed to save recovery codes", err)
+		return nil, errors.New("failed to save recovery codes")
+	}
+	return codes, nil
+}
+
+// Disable 关闭两步验证并删除恢复码
+func (s *TwoFactorService) Disable(ctx *app.Context, userUuid string) error {
+	err := ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ?", userUuid).Delete(&model.UserTwoFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_uuid = ?", userUuid).Delete(&model.UserRecoveryCode{}).Error
+	})
+	if err != nil {
+		ctx.Logger.Error("Failed to disable two factor", err)
+		return errors.New("failed to disable two factor")
+	}
+	return nil
+}
+
+// IssueChallenge 签发密码已验证、等待两步验证的临时凭据
+func (s *TwoFactorService) IssueChallenge(ctx *app.Context, userUuid string) (string, error) {
+	ttl := 5 * time.Minute
+	if ctx.Config != nil && ctx.Config.TwoFactor.ChallengeTTL > 0 {
+		ttl = time.Duration(ctx.Config.TwoFactor.ChallengeTTL) * time.Minute
+	}
+	tok, err := utils.GenerateChallengeToken(userUuid, TwoFactorChallengePurpose, ttl)
+	if err != nil {
+		ctx.Logger.Error("Failed to generate two factor challenge", err)
+		return "", errors.New("failed to generate two factor challenge")
+	}
+	return tok, nil
+}
+
+// ParseChallenge 校验临时凭据并返回用户UUID
+func (s *TwoFactorService) ParseChallenge(tokenString string) (string, error) {
+	return utils.ParseChallengeToken(tokenString, TwoFactorChallengePurpose)
+}
+
+// newRecoveryCode 生成形如 xxxxx-xxxxx 的恢复码（50 位熵）
+func newRecoveryCode() string {
+	const alphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	out := make([]byte, 0, 11)
+	for i, v := range b {
+		if i == 5 {
+			out = append(out, '-')
+		}
+		out = append(out, alphabet[v%32])
+	}
+	return string(out)
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return utils.SHA256Hex(code)
+}
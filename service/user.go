@@ -8,7 +8,6 @@ import (
 
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
-	"github.com/luxingwen/sgin/pkg/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -25,20 +24,19 @@ func (s *UserService) CreateUser(ctx *app.Context, user *model.User) error {
 	user.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
 	user.UpdatedAt = user.CreatedAt
 	user.Uuid = uuid.New().String()
-
-	if user.Password == "" {
-		user.Password = "123456"
+	// 状态零值表示禁用，新建用户默认启用
+	if user.Status == model.UserStatusDisabled {
+		user.Status = model.UserStatusEnabled
 	}
 
-	// 获取密码加密密钥，如果为空则使用默认值（与登录验证保持一致）
-	passwdKey := ctx.Config.PasswdKey
-	if passwdKey == "" {
-		passwdKey = "default-secret-key"
+	// 密码必须满足强度策略，不再使用默认密码
+	hashed, err := hashNewPassword(ctx, user.Password, user.Username, user.Email)
+	if err != nil {
+		return err
 	}
+	user.Password = hashed
 
-	user.Password = utils.HashPasswordWithSalt(user.Password, passwdKey)
-
-	err := ctx.DB.Create(user).Error
+	err = ctx.DB.Create(user).Error
 	if err != nil {
 		ctx.Logger.Error("Failed to create user", err)
 
@@ -71,20 +69,38 @@ func (s *UserService) UpdateUser(ctx *app.Context, user *model.User) error {
 	user.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
 
 	if user.Password != "" {
-		// 获取密码加密密钥，如果为空则使用默认值（与登录验证保持一致）
-		passwdKey := ctx.Config.PasswdKey
-		if passwdKey == "" {
-			passwdKey = "default-secret-key"
+		hashed, err := hashNewPassword(ctx, user.Password, user.Username, user.Email)
+		if err != nil {
+			return err
 		}
-		user.Password = utils.HashPasswordWithSalt(user.Password, passwdKey)
+		user.Password = hashed
 	}
 
-	err := ctx.DB.Where("uuid = ?", user.Uuid).Updates(user).Error
+	// Updates 会跳过零值，无法把状态改为禁用，状态统一通过 UpdateUserStatus 修改
+	err := ctx.DB.Where("uuid = ?", user.Uuid).Omit("status").Updates(user).Error
 	if err != nil {
 		ctx.Logger.Error("Failed to update user:", err)
 		return errors.New("failed to update user")
 	}
 
+	// 修改密码后，之前签发的令牌全部失效
+	if user.Password != "" {
+		return NewAuthService().RevokeUserTokens(ctx, user.Uuid)
+	}
+	return nil
+}
+
+// UpdateUserStatus 修改用户状态，禁用或删除时之前签发的令牌全部失效
+func (s *UserService) UpdateUserStatus(ctx *app.Context, uuid string, status int) error {
+	err := ctx.DB.Model(&model.User{}).Where("uuid = ?", uuid).UpdateColumn("status", status).Error
+	if err != nil {
+		ctx.Logger.Error("Failed to update user status", err)
+		return errors.New("failed to update user status")
+	}
+
+	if status != model.UserStatusEnabled {
+		return NewAuthService().RevokeUserTokens(ctx, uuid)
+	}
 	return nil
 }
 
@@ -95,7 +111,8 @@ func (s *UserService) DeleteUser(ctx *app.Context, uuid string) error {
 		return errors.New("failed to delete user")
 	}
 
-	return nil
+	// 已删除用户的令牌立即失效
+	return NewAuthService().RevokeUserTokens(ctx, uuid)
 }
 
 // 获取所有可用用户
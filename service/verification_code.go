@@ -1,83 +1,319 @@
 package service
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/luxingwen/sgin/model"
 	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/config"
 	"github.com/luxingwen/sgin/pkg/utils"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrVerificationCodePurpose = errors.New("invalid verification code purpose")
+	ErrVerificationCodeDest    = errors.New("email or phone is required")
+	ErrVerificationCodeKey     = errors.New("VerificationCode.HashKey is not configured")
+)
+
+// VerificationCodeLimitError 发送过于频繁
+type VerificationCodeLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *VerificationCodeLimitError) Error() string {
+	return e.Message
+}
+
+// VerificationCodeService 邮箱/短信验证码：按用途区分，只保存摘要，校验失败次数与发送频率受限
 type VerificationCodeService struct {
 }
 
-// CreateVerificationCode 创建验证码
-func (v *VerificationCodeService) CreateVerificationCode(ctx *app.Context, email string, phone string) (string, error) {
+func NewVerificationCodeService() *VerificationCodeService {
+	return &VerificationCodeService{}
+}
 
-	// 先获取最新的一条验证码是否过期
-	var vcode model.VerificationCode
-	err := ctx.DB.Where("email = ? OR phone = ?", email, phone).Order("created_at desc").First(&vcode).Error
+func verificationCodeConfig(ctx app.AppContext) config.VerificationCodeConfig {
+	var vc config.VerificationCodeConfig
+	if cfg := ctx.GetConfig(); cfg != nil {
+		vc = cfg.VerificationCode
+	}
+	if vc.TTL <= 0 {
+		vc.TTL = 5
+	}
+	if vc.ResendInterval <= 0 {
+		vc.ResendInterval = 60
+	}
+	if vc.MaxAttempts <= 0 {
+		vc.MaxAttempts = 5
+	}
+	if vc.Window <= 0 {
+		vc.Window = 60
+	}
+	if vc.MaxPerDestination <= 0 {
+		vc.MaxPerDestination = 10
+	}
+	if vc.MaxPerIP <= 0 {
+		vc.MaxPerIP = 30
+	}
+	if vc.CleanupInterval == 0 {
+		vc.CleanupInterval = 60
+	}
+	if vc.Retention <= 0 {
+		vc.Retention = 24
+	}
+	return vc
+}
+
+// TTLMinutes 验证码有效期（分钟），用于邮件与短信内容
+func (v *VerificationCodeService) TTLMinutes(ctx app.AppContext) int {
+	return verificationCodeConfig(ctx).TTL
+}
+
+func validVerificationPurpose(purpose string) bool {
+	for _, p := range model.VerificationPurposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// verificationDest 规范化接收方：有邮箱时发往邮箱，否则发往手机号
+func verificationDest(ctx app.AppContext, email, phone string) (string, string, error) {
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		return "email", email, nil
+	}
+	if phone == "" {
+		return "", "", ErrVerificationCodeDest
+	}
+	p, err := normalizePhone(ctx, phone)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	return "phone", p, nil
+}
+
+// verificationCodeKey 计算验证码摘要的密钥，必须单独配置，不与 PasswdKey 共用
+func verificationCodeKey(ctx app.AppContext) (string, error) {
+	cfg := ctx.GetConfig()
+	if cfg == nil || cfg.VerificationCode.HashKey == "" {
+		return "", ErrVerificationCodeKey
+	}
+	return cfg.VerificationCode.HashKey, nil
+}
+
+// verificationCodeHash 验证码只有 6 位数字，使用带密钥的 HMAC 保存，泄露的摘要无法离线穷举
+func verificationCodeHash(key string, c *model.VerificationCode, code string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(c.UUID + ":" + c.Purpose + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (v *VerificationCodeService) store(ctx *app.Context) (VerificationCodeStore, error) {
+	store := NewVerificationCodeStore(ctx)
+	if store == nil {
+		return nil, errors.New("verification code store is not available")
 	}
+	return store, nil
+}
 
-	if vcode.Status == 0 && time.Now().Sub(vcode.CreatedAt).Minutes() < 1 {
-		err := errors.New("验证码已发送，请稍后再试")
+// CreateVerificationCode 创建 purpose 用途的验证码并返回明文，由调用方发送。
+// 同一接收方在 ResendInterval 内不能重复申请，接收方与 IP 在窗口内的申请次数受限；新验证码发出后之前的验证码作废
+func (v *VerificationCodeService) CreateVerificationCode(ctx *app.Context, purpose, email, phone string) (string, error) {
+	if !validVerificationPurpose(purpose) {
+		return "", ErrVerificationCodePurpose
+	}
+	field, value, err := verificationDest(ctx, email, phone)
+	if err != nil {
 		return "", err
 	}
+	// 未配置摘要密钥时不发送验证码
+	key, err := verificationCodeKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	store, err := v.store(ctx)
+	if err != nil {
+		return "", err
+	}
+	vc := verificationCodeConfig(ctx)
+	now := time.Now()
 
-	code := utils.GenerateVerificationCode()
+	latest, err := store.Latest(ctx.Ctx, purpose, field, value)
+	if err != nil {
+		ctx.Logger.Error("Failed to get latest verification code", err)
+		return "", errors.New("failed to get latest verification code")
+	}
+	if latest != nil && latest.Status == model.VerificationCodeUnused {
+		if wait := latest.CreatedAt.Add(time.Duration(vc.ResendInterval) * time.Second).Sub(now); wait > 0 {
+			return "", &VerificationCodeLimitError{Message: "验证码已发送，请稍后再试", RetryAfter: wait}
+		}
+	}
+
+	window := time.Duration(vc.Window) * time.Minute
+	byDest, byIP, err := store.CountSend(ctx.Ctx, field, value, ctx.ClientIP(), window)
+	if err != nil {
+		ctx.Logger.Error("Failed to count verification code requests", err)
+		return "", errors.New("failed to count verification code requests")
+	}
+	if byDest > int64(vc.MaxPerDestination) || byIP > int64(vc.MaxPerIP) {
+		return "", &VerificationCodeLimitError{Message: "验证码申请过于频繁，请稍后再试", RetryAfter: window}
+	}
 
-	vcode1 := model.VerificationCode{
+	code := utils.GenerateVerificationCode()
+	c := &model.VerificationCode{
 		UUID:      uuid.New().String(),
-		Code:      code,
-		Email:     email,
-		Phone:     phone,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Status:    0,
+		Purpose:   purpose,
+		Ip:        ctx.ClientIP(),
+		ExpiresAt: now.Add(time.Duration(vc.TTL) * time.Minute),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Status:    model.VerificationCodeUnused,
+	}
+	if field == "email" {
+		c.Email = value
+	} else {
+		c.Phone = value
 	}
+	c.CodeHash = verificationCodeHash(key, c, code)
 
-	err = ctx.DB.Create(&vcode1).Error
-	return code, err
+	if err := store.Save(ctx.Ctx, c); err != nil {
+		ctx.Logger.Error("Failed to save verification code", err)
+		return "", errors.New("failed to save verification code")
+	}
+	return code, nil
+}
 
+// CheckVerificationCode 检查验证码是否正确，不标记为已使用；错误的验证码计入失败次数
+func (v *VerificationCodeService) CheckVerificationCode(ctx *app.Context, purpose, code, email, phone string) (bool, error) {
+	return v.verify(ctx, purpose, code, email, phone, false)
 }
 
-// CheckVerificationCode 检查验证码
-func (v *VerificationCodeService) CheckVerificationCode(ctx *app.Context, code string, email string, phone string) (bool, error) {
+// ConsumeVerificationCode 检查验证码并标记为已使用，同一验证码只能成功一次
+func (v *VerificationCodeService) ConsumeVerificationCode(ctx *app.Context, purpose, code, email, phone string) (bool, error) {
+	return v.verify(ctx, purpose, code, email, phone, true)
+}
 
-	var vcode model.VerificationCode
-	err := ctx.DB.Where("code = ? AND (email = ? OR phone = ?)", code, email, phone).First(&vcode).Error
+func (v *VerificationCodeService) verify(ctx *app.Context, purpose, code, email, phone string, consume bool) (bool, error) {
+	if !validVerificationPurpose(purpose) || code == "" {
+		return false, nil
+	}
+	field, value, err := verificationDest(ctx, email, phone)
+	if err != nil {
+		return false, nil
+	}
+	key, err := verificationCodeKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	store, err := v.store(ctx)
 	if err != nil {
 		return false, err
 	}
 
-	if vcode.Status == 1 {
+	// 只校验最近一次发送的验证码
+	c, err := store.Latest(ctx.Ctx, purpose, field, value)
+	if err != nil {
+		ctx.Logger.Error("Failed to get latest verification code", err)
+		return false, errors.New("failed to get latest verification code")
+	}
+	if c == nil || c.Status != model.VerificationCodeUnused || !time.Now().Before(c.ExpiresAt) {
 		return false, nil
 	}
-
-	if time.Now().Sub(vcode.CreatedAt).Minutes() > 5 {
+	// 先原子地占用一次校验机会再比较，并发请求不会超出失败次数上限
+	max := verificationCodeConfig(ctx).MaxAttempts
+	attempts, ok, err := store.Attempt(ctx.Ctx, c, max)
+	if err != nil {
+		ctx.Logger.Error("Failed to record verification code attempt", err)
+		return false, errors.New("failed to record verification code attempt")
+	}
+	if !ok {
+		return false, nil
+	}
+	if !hmac.Equal([]byte(verificationCodeHash(key, c, code)), []byte(c.CodeHash)) {
+		if attempts >= max {
+			if err := store.Invalidate(ctx.Ctx, c); err != nil {
+				ctx.Logger.Error("Failed to invalidate verification code", err)
+			}
+		}
+		ctx.Logger.Infow("verification code mismatch", "purpose", purpose, field, value, "attempts", attempts)
 		return false, nil
 	}
+	if err := store.Release(ctx.Ctx, c); err != nil {
+		ctx.Logger.Error("Failed to release verification code attempt", err)
+		return false, errors.New("failed to release verification code attempt")
+	}
+	if !consume {
+		return true, nil
+	}
+	ok, err = store.Use(ctx.Ctx, c, max)
+	if err != nil {
+		ctx.Logger.Error("Failed to mark verification code used", err)
+		return false, errors.New("failed to mark verification code used")
+	}
+	return ok, nil
+}
 
-	return true, nil
+// CleanupVerificationCodes 删除过期超过 Retention 小时的验证码
+func (v *VerificationCodeService) CleanupVerificationCodes(ctx app.AppContext) (int64, error) {
+	store := NewVerificationCodeStore(ctx)
+	if store == nil {
+		return 0, nil
+	}
+	before := time.Now().Add(-time.Duration(verificationCodeConfig(ctx).Retention) * time.Hour)
+	n, err := store.Cleanup(ctx.GetCtx(), before)
+	if err != nil {
+		ctx.GetLogger().Error("Failed to clean up verification codes", err)
+		return 0, errors.New("failed to clean up verification codes")
+	}
+	return n, nil
 }
 
-// UpdateVerificationCode 更新验证码
-func (v *VerificationCodeService) UpdateVerificationCode(ctx *app.Context, code string, email string, phone string) error {
+type verificationCleanupKey struct{}
 
-	var vcode model.VerificationCode
-	err := ctx.DB.Where("code = ? AND email = ? AND phone = ?", code, email, phone).First(&vcode).Error
-	if err != nil {
-		return err
+// StartVerificationCodeCleanup 按 VerificationCode.CleanupInterval 定期删除过期的验证码，App 退出时停止
+func StartVerificationCodeCleanup(a *app.App) {
+	if a == nil || a.DB == nil || a.Config == nil {
+		return
+	}
+	vc := verificationCodeConfig(app.NewBackgroundContextFromApp(a))
+	if vc.CleanupInterval < 0 {
+		return
 	}
+	a.Once(verificationCleanupKey{}, func() {
+		bg := app.NewBackgroundContextFromApp(a)
+		bg.Logger = a.Logger.Named("vcode")
+		runCtx, cancel := context.WithCancel(context.Background())
+		bg.Ctx = runCtx
 
-	vcode.Status = 1
-	vcode.UpdatedAt = time.Now()
+		go func() {
+			ticker := time.NewTicker(time.Duration(vc.CleanupInterval) * time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					n, err := NewVerificationCodeService().CleanupVerificationCodes(bg)
+					if err == nil && n > 0 {
+						bg.Logger.Infow("expired verification codes deleted", "deleted", n)
+					}
+				case <-runCtx.Done():
+					return
+				}
+			}
+		}()
 
-	err = ctx.DB.Save(&vcode).Error
-	return err
+		a.OnShutdown(func(ctx context.Context) error {
+			cancel()
+			return nil
+		})
+	})
 }
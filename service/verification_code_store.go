@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/app"
+	"github.com/luxingwen/sgin/pkg/redisop"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VerificationCodeStore 保存验证码。field 为接收方类型（email 或 phone），value 为规范化后的地址
+type VerificationCodeStore interface {
+	// Save 保存新验证码，同一用途与接收方之前的验证码随之作废
+	Save(ctx context.Context, c *model.VerificationCode) error
+	// Latest 返回用途与接收方最近一次发送的验证码，不存在或已过期清除时返回 nil
+	Latest(ctx context.Context, purpose, field, value string) (*model.VerificationCode, error)
+	// Attempt 先占用一次校验机会再比较验证码：未使用、未作废且 attempts < max 时次数加 1 并返回累计次数与 true，
+	// 否则返回 false；并发请求最多比较 max 次
+	Attempt(ctx context.Context, c *model.VerificationCode, max int) (int, bool, error)
+	// Release 校验通过时归还占用的次数，正确的验证码不计入失败次数
+	Release(ctx context.Context, c *model.VerificationCode) error
+	// Invalidate 作废未使用的验证码，失败次数达到上限时调用
+	Invalidate(ctx context.Context, c *model.VerificationCode) error
+	// Use 将未使用、未作废且 attempts < max 的验证码标记为已使用，否则返回 false
+	Use(ctx context.Context, c *model.VerificationCode, max int) (bool, error)
+	// CountSend 记录一次发送申请，返回 window 内发给该接收方与来自 ip 的次数（含本次）
+	CountSend(ctx context.Context, field, value, ip string, window time.Duration) (int64, int64, error)
+	// Cleanup 删除 before 之前过期的验证码与发送计数并返回删除的验证码数量，自动过期的存储返回 0
+	Cleanup(ctx context.Context, before time.Time) (int64, error)
+}
+
+// NewVerificationCodeStore 按 VerificationCode.Store 配置返回存储；未配置时有 Redis 用 Redis，否则用数据库；
+// 均不可用时返回 nil
+func NewVerificationCodeStore(ctx app.AppContext) VerificationCodeStore {
+	cfg := ctx.GetConfig()
+	typ := ""
+	if cfg != nil {
+		typ = cfg.VerificationCode.Store
+	}
+	switch {
+	case (typ == "redis" || typ == "") && ctx.GetRedis() != nil:
+		return &redisVerificationCodeStore{rc: ctx.GetRedis()}
+	case typ != "redis" && ctx.GetDB() != nil:
+		return &dbVerificationCodeStore{db: ctx.GetDB()}
+	}
+	return nil
+}
+
+// dbVerificationCodeStore 基于 verification_codes 表
+type dbVerificationCodeStore struct {
+	db *gorm.DB
+}
+
+func (s *dbVerificationCodeStore) Save(ctx context.Context, c *model.VerificationCode) error {
+	field, value := verificationCodeDest(c)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.VerificationCode{}).
+			Where("purpose = ? AND "+field+" = ? AND status = ?", c.Purpose, value, model.VerificationCodeUnused).
+			Update("status", model.VerificationCodeInvalidated).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(c).Error
+	})
+}
+
+func (s *dbVerificationCodeStore) Latest(ctx context.Context, purpose, field, value string) (*model.VerificationCode, error) {
+	c := &model.VerificationCode{}
+	err := s.db.WithContext(ctx).
+		Where("purpose = ? AND "+field+" = ?", purpose, value).
+		Order("created_at desc, id desc").
+		First(c).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *dbVerificationCodeStore) Attempt(ctx context.Context, c *model.VerificationCode, max int) (int, bool, error) {
+	db := s.db.WithContext(ctx)
+	res := db.Model(&model.VerificationCode{}).
+		Where("id = ? AND status = ? AND attempts < ?", c.Id, model.VerificationCodeUnused, max).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil || res.RowsAffected != 1 {
+		return 0, false, res.Error
+	}
+	var attempts int
+	if err := db.Model(&model.VerificationCode{}).Where("id = ?", c.Id).Select("attempts").Scan(&attempts).Error; err != nil {
+		return 0, false, err
+	}
+	return attempts, true, nil
+}
+
+func (s *dbVerificationCodeStore) Release(ctx context.Context, c *model.VerificationCode) error {
+	return s.db.WithContext(ctx).Model(&model.VerificationCode{}).
+		Where("id = ? AND attempts > 0", c.Id).
+		UpdateColumn("attempts", gorm.Expr("attempts - 1")).Error
+}
+
+func (s *dbVerificationCodeStore) Invalidate(ctx context.Context, c *model.VerificationCode) error {
+	return s.db.WithContext(ctx).Model(&model.VerificationCode{}).
+		Where("id = ? AND status = ?", c.Id, model.VerificationCodeUnused).
+		Update("status", model.VerificationCodeInvalidated).Error
+}
+
+func (s *dbVerificationCodeStore) Use(ctx context.Context, c *model.VerificationCode, max int) (bool, error) {
+	res := s.db.WithContext(ctx).Model(&model.VerificationCode{}).
+		Where("id = ? AND status = ? AND attempts < ?", c.Id, model.VerificationCodeUnused, max).
+		Update("status", model.VerificationCodeUsed)
+	return res.RowsAffected == 1, res.Error
+}
+
+// CountSend 与 Redis 存储一样按固定窗口计数：计数在一条 upsert 语句中自增，窗口结束后重新从 1 开始，
+// 并发申请不会读到相同的计数
+func (s *dbVerificationCodeStore) CountSend(ctx context.Context, field, value, ip string, window time.Duration) (int64, int64, error) {
+	counts := make([]int64, 2)
+	for i, subject := range []string{field + ":" + value, "ip:" + ip} {
+		n, err := s.incrSend(ctx, subject, window)
+		if err != nil {
+			return 0, 0, err
+		}
+		counts[i] = n
+	}
+	return counts[0], counts[1], nil
+}
+
+func (s *dbVerificationCodeStore) incrSend(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	now := time.Now()
+	db := s.db.WithContext(ctx)
+	// MySQL 按顺序赋值，hits 必须在 expires_at 之前更新
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "subject"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "hits"}, Value: gorm.Expr("CASE WHEN expires_at <= ? THEN 1 ELSE hits + 1 END", now)},
+			{Column: clause.Column{Name: "expires_at"}, Value: gorm.Expr("CASE WHEN expires_at <= ? THEN ? ELSE expires_at END", now, now.Add(window))},
+		},
+	}).Create(&model.VerificationSendCount{Subject: subject, Hits: 1, ExpiresAt: now.Add(window)}).Error
+	if err != nil {
+		return 0, err
+	}
+	var hits int64
+	err = db.Model(&model.VerificationSendCount{}).Where("subject = ?", subject).Select("hits").Scan(&hits).Error
+	return hits, err
+}
+
+func (s *dbVerificationCodeStore) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.VerificationCode{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	err := s.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.VerificationSendCount{}).Error
+	return res.RowsAffected, err
+}
+
+// redisVerificationCodeStore 基于 Redis：验证码保存在以 UUID 为键的 hash 中，
+// 用途与接收方对应的键指向最近一次发送的验证码，两个键都在验证码过期时自动删除。
+// 两个键使用相同的 hash tag（用途与接收方），集群模式下位于同一个槽，可以在一个脚本中写入
+type redisVerificationCodeStore struct {
+	rc *redisop.RedisClient
+}
+
+const (
+	redisVerificationCodeKey     = "sgin:vcode:id:"   // 验证码，后接 {purpose:field:value}:UUID
+	redisVerificationCodeDestKey = "sgin:vcode:dest:" // 最近一次发送的验证码 UUID，后接 {purpose:field:value}
+	redisVerificationCodeSendKey = "sgin:vcode:send:" // 发送次数，后接 field:value 或 ip:IP
+)
+
+// redisVerificationDestTag 用途与接收方组成的 hash tag
+func redisVerificationDestTag(purpose, field, value string) string {
+	return "{" + purpose + ":" + field + ":" + value + "}"
+}
+
+// redisVerificationKey 验证码 c 的 hash 键
+func redisVerificationKey(c *model.VerificationCode) string {
+	field, value := verificationCodeDest(c)
+	return redisVerificationCodeKey + redisVerificationDestTag(c.Purpose, field, value) + ":" + c.UUID
+}
+
+func (s *redisVerificationCodeStore) Save(ctx context.Context, c *model.VerificationCode) error {
+	field, value := verificationCodeDest(c)
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	keys := []string{
+		redisVerificationKey(c),
+		redisVerificationCodeDestKey + redisVerificationDestTag(c.Purpose, field, value),
+	}
+	return s.rc.RunScript(ctx, redisVerificationSaveScript, keys, string(b), ttlUntil(c.ExpiresAt).Milliseconds(), c.UUID).Err()
+}
+
+func (s *redisVerificationCodeStore) Latest(ctx context.Context, purpose, field, value string) (*model.VerificationCode, error) {
+	tag := redisVerificationDestTag(purpose, field, value)
+	id, err := s.rc.Get(ctx, redisVerificationCodeDestKey+tag)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.rc.HGetAll(ctx, redisVerificationCodeKey+tag+":"+id)
+	if err != nil {
+		return nil, err
+	}
+	if m["data"] == "" {
+		return nil, nil
+	}
+	c := &model.VerificationCode{}
+	if err := json.Unmarshal([]byte(m["data"]), c); err != nil {
+		return nil, err
+	}
+	c.Attempts, _ = strconv.Atoi(m["attempts"])
+	switch {
+	case m["used"] != "":
+		c.Status = model.VerificationCodeUsed
+	case m["invalid"] != "":
+		c.Status = model.VerificationCodeInvalidated
+	}
+	return c, nil
+}
+
+var (
+	// redisVerificationSaveScript 写入验证码 hash 与最近一次发送的指针，两者设置相同的过期时间（ARGV[2] 毫秒）
+	redisVerificationSaveScript = redis.NewScript(`
+redis.call('HSET', KEYS[1], 'data', ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+redis.call('SET', KEYS[2], ARGV[3], 'PX', ARGV[2])
+return 1`)
+)
+
+// 以下脚本在验证码的 hash 已过期删除时不做任何修改，避免重新创建没有过期时间的 key
+var (
+	// redisVerificationAttemptScript 未使用、未作废且 attempts < ARGV[1] 时次数加 1 并返回，否则返回 -1
+	redisVerificationAttemptScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 or redis.call('HEXISTS', KEYS[1], 'used') == 1 or redis.call('HEXISTS', KEYS[1], 'invalid') == 1 then
+	return -1
+end
+if tonumber(redis.call('HGET', KEYS[1], 'attempts') or '0') >= tonumber(ARGV[1]) then
+	return -1
+end
+return redis.call('HINCRBY', KEYS[1], 'attempts', 1)`)
+
+	// redisVerificationFieldScript hash 存在时将字段 ARGV[1] 加 ARGV[2]
+	redisVerificationFieldScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])`)
+
+	// redisVerificationUseScript 未作废且 attempts < ARGV[1] 时使用次数加 1 并返回，首次使用返回 1
+	redisVerificationUseScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 or redis.call('HEXISTS', KEYS[1], 'invalid') == 1 then
+	return 0
+end
+if tonumber(redis.call('HGET', KEYS[1], 'attempts') or '0') >= tonumber(ARGV[1]) then
+	return 0
+end
+return redis.call('HINCRBY', KEYS[1], 'used', 1)`)
+)
+
+func (s *redisVerificationCodeStore) Attempt(ctx context.Context, c *model.VerificationCode, max int) (int, bool, error) {
+	n, err := s.rc.RunScript(ctx, redisVerificationAttemptScript, []string{redisVerificationKey(c)}, max).Int64()
+	if err != nil || n < 0 {
+		return 0, false, err
+	}
+	return int(n), true, nil
+}
+
+func (s *redisVerificationCodeStore) Release(ctx context.Context, c *model.VerificationCode) error {
+	return s.rc.RunScript(ctx, redisVerificationFieldScript, []string{redisVerificationKey(c)}, "attempts", -1).Err()
+}
+
+func (s *redisVerificationCodeStore) Invalidate(ctx context.Context, c *model.VerificationCode) error {
+	return s.rc.RunScript(ctx, redisVerificationFieldScript, []string{redisVerificationKey(c)}, "invalid", 1).Err()
+}
+
+func (s *redisVerificationCodeStore) Use(ctx context.Context, c *model.VerificationCode, max int) (bool, error) {
+	// 并发使用同一验证码时只有第一次计数为 1
+	n, err := s.rc.RunScript(ctx, redisVerificationUseScript, []string{redisVerificationKey(c)}, max).Int64()
+	return n == 1, err
+}
+
+func (s *redisVerificationCodeStore) CountSend(ctx context.Context, field, value, ip string, window time.Duration) (int64, int64, error) {
+	counts := make([]int64, 2)
+	for i, key := range []string{
+		redisVerificationCodeSendKey + field + ":" + value,
+		redisVerificationCodeSendKey + "ip:" + ip,
+	} {
+		// 自增与设置过期时间原子执行，避免留下永不过期的计数
+		n, err := s.rc.IncrWithExpire(ctx, key, window)
+		if err != nil {
+			return 0, 0, err
+		}
+		counts[i] = n
+	}
+	return counts[0], counts[1], nil
+}
+
+func (s *redisVerificationCodeStore) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// verificationCodeDest 返回验证码的接收方：有邮箱时为邮箱，否则为手机号
+func verificationCodeDest(c *model.VerificationCode) (string, string) {
+	if c.Email != "" {
+		return "email", c.Email
+	}
+	return "phone", c.Phone
+}
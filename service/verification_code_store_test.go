@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/luxingwen/sgin/model"
+	"github.com/luxingwen/sgin/pkg/redisop"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDBVerificationCodeStore(t *testing.T) *dbVerificationCodeStore {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "vcode.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.VerificationCode{}, &model.VerificationSendCount{}); err != nil {
+		t.Fatal(err)
+	}
+	return &dbVerificationCodeStore{db: db}
+}
+
+func newTestRedisVerificationCodeStore(t *testing.T) (*redisVerificationCodeStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	return &redisVerificationCodeStore{rc: redisop.NewRedisClient(mr.Addr(), "", 0)}, mr
+}
+
+func newTestVerificationCode(email string, ttl time.Duration) *model.VerificationCode {
+	now := time.Now()
+	return &model.VerificationCode{
+		UUID:      uuid.New().String(),
+		Purpose:   model.VerificationPurposeRegister,
+		Email:     email,
+		Ip:        "10.0.0.1",
+		CodeHash:  "hash",
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Status:    model.VerificationCodeUnused,
+	}
+}
+
+// testVerificationCodeStore 两种存储共同的行为
+func testVerificationCodeStore(t *testing.T, store VerificationCodeStore) {
+	ctx := context.Background()
+	const max = 3
+
+	first := newTestVerificationCode("a@example.com", time.Minute)
+	if err := store.Save(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	second := newTestVerificationCode("a@example.com", time.Minute)
+	if err := store.Save(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只返回最近一次发送的验证码
+	c, err := store.Latest(ctx, model.VerificationPurposeRegister, "email", "a@example.com")
+	if err != nil || c == nil || c.UUID != second.UUID || c.Status != model.VerificationCodeUnused {
+		t.Fatalf("latest = %+v, err = %v", c, err)
+	}
+	if c, err := store.Latest(ctx, model.VerificationPurposeReset, "email", "a@example.com"); err != nil || c != nil {
+		t.Fatalf("other purpose: %+v, %v", c, err)
+	}
+
+	// 占用次数达到上限后不能再比较
+	for i := 1; i <= max; i++ {
+		n, ok, err := store.Attempt(ctx, c, max)
+		if err != nil || !ok || n != i {
+			t.Fatalf("attempt %d = %d, %v, %v", i, n, ok, err)
+		}
+	}
+	if _, ok, err := store.Attempt(ctx, c, max); err != nil || ok {
+		t.Fatalf("attempt over max: %v, %v", ok, err)
+	}
+	if ok, err := store.Use(ctx, c, max); err != nil || ok {
+		t.Fatalf("use over max: %v, %v", ok, err)
+	}
+
+	// 归还的次数可以再次使用
+	if err := store.Release(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok, err := store.Attempt(ctx, c, max); err != nil || !ok || n != max {
+		t.Fatalf("attempt after release = %d, %v, %v", n, ok, err)
+	}
+	if err := store.Release(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Use(ctx, c, max); err != nil || !ok {
+		t.Fatalf("use = %v, %v", ok, err)
+	}
+	if ok, err := store.Use(ctx, c, max); err != nil || ok {
+		t.Fatalf("second use = %v, %v", ok, err)
+	}
+	if c, err := store.Latest(ctx, model.VerificationPurposeRegister, "email", "a@example.com"); err != nil || c.Status != model.VerificationCodeUsed {
+		t.Fatalf("used code = %+v, %v", c, err)
+	}
+
+	// 作废后不能校验
+	third := newTestVerificationCode("b@example.com", time.Minute)
+	if err := store.Save(ctx, third); err != nil {
+		t.Fatal(err)
+	}
+	c, _ = store.Latest(ctx, model.VerificationPurposeRegister, "email", "b@example.com")
+	if err := store.Invalidate(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := store.Attempt(ctx, c, max); err != nil || ok {
+		t.Fatalf("attempt on invalidated code: %v, %v", ok, err)
+	}
+	if ok, err := store.Use(ctx, c, max); err != nil || ok {
+		t.Fatalf("use on invalidated code: %v, %v", ok, err)
+	}
+
+	// 发送次数按接收方与 IP 分别计数
+	for i := int64(1); i <= 3; i++ {
+		byDest, byIP, err := store.CountSend(ctx, "email", "c@example.com", "10.0.0.2", time.Minute)
+		if err != nil || byDest != i || byIP != i {
+			t.Fatalf("count %d = %d, %d, %v", i, byDest, byIP, err)
+		}
+	}
+	if byDest, byIP, err := store.CountSend(ctx, "email", "d@example.com", "10.0.0.2", time.Minute); err != nil || byDest != 1 || byIP != 4 {
+		t.Fatalf("count other dest = %d, %d, %v", byDest, byIP, err)
+	}
+}
+
+func TestDBVerificationCodeStore(t *testing.T) {
+	store := newTestDBVerificationCodeStore(t)
+	testVerificationCodeStore(t, store)
+
+	ctx := context.Background()
+	// 之前的验证码在保存新验证码时作废
+	var invalidated int64
+	store.db.Model(&model.VerificationCode{}).Where("email = ? AND status = ?", "a@example.com", model.VerificationCodeInvalidated).Count(&invalidated)
+	if invalidated != 1 {
+		t.Fatalf("invalidated = %d, want 1", invalidated)
+	}
+
+	// 窗口结束后重新计数
+	if _, _, err := store.CountSend(ctx, "email", "e@example.com", "10.0.0.3", time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if byDest, byIP, err := store.CountSend(ctx, "email", "e@example.com", "10.0.0.3", time.Minute); err != nil || byDest != 1 || byIP != 1 {
+		t.Fatalf("count after window = %d, %d, %v", byDest, byIP, err)
+	}
+}
+
+func TestDBVerificationCodeStoreCleanup(t *testing.T) {
+	store := newTestDBVerificationCodeStore(t)
+	ctx := context.Background()
+
+	expired := newTestVerificationCode("a@example.com", -2*time.Hour)
+	valid := newTestVerificationCode("b@example.com", time.Minute)
+	for _, c := range []*model.VerificationCode{expired, valid} {
+		if err := store.Save(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := store.CountSend(ctx, "email", "a@example.com", "10.0.0.1", -2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.CountSend(ctx, "email", "b@example.com", "10.0.0.2", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := store.Cleanup(ctx, time.Now().Add(-time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("cleanup = %d, %v", n, err)
+	}
+	if c, _ := store.Latest(ctx, model.VerificationPurposeRegister, "email", "a@example.com"); c != nil {
+		t.Fatalf("expired code not deleted: %+v", c)
+	}
+	if c, _ := store.Latest(ctx, model.VerificationPurposeRegister, "email", "b@example.com"); c == nil {
+		t.Fatal("valid code deleted")
+	}
+	var counts int64
+	store.db.Model(&model.VerificationSendCount{}).Count(&counts)
+	if counts != 2 {
+		t.Fatalf("send counts = %d, want 2", counts)
+	}
+}
+
+func TestRedisVerificationCodeStore(t *testing.T) {
+	store, mr := newTestRedisVerificationCodeStore(t)
+	testVerificationCodeStore(t, store)
+
+	// 验证码与最近一次发送的指针都设置了过期时间
+	for _, key := range mr.Keys() {
+		if mr.TTL(key) <= 0 {
+			t.Fatalf("key %s has no ttl", key)
+		}
+	}
+	mr.FastForward(2 * time.Minute)
+	if c, err := store.Latest(context.Background(), model.VerificationPurposeRegister, "email", "a@example.com"); err != nil || c != nil {
+		t.Fatalf("expired code: %+v, %v", c, err)
+	}
+	if n, err := store.Cleanup(context.Background(), time.Now()); err != nil || n != 0 {
+		t.Fatalf("cleanup = %d, %v", n, err)
+	}
+}
+
+func TestRedisVerificationCodeAttemptConcurrent(t *testing.T) {
+	store, _ := newTestRedisVerificationCodeStore(t)
+	ctx := context.Background()
+	const max = 5
+
+	c := newTestVerificationCode("a@example.com", time.Minute)
+	if err := store.Save(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	// 并发校验最多占用 max 次
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		ok int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, reserved, err := store.Attempt(ctx, c, max); err == nil && reserved {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if ok != max {
+		t.Fatalf("reserved %d attempts, want %d", ok, max)
+	}
+}
@@ -142,5 +142,12 @@ func Start(a *app.App, addr string) error {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	return srv.Shutdown(ctx)
+	err := srv.Shutdown(ctx)
+	// 执行清理函数（如写入异步日志管道中的剩余数据）
+	if herr := a.Shutdown(ctx); err == nil {
+		err = herr
+	}
+	// 推送尚在缓冲区中的日志（如 http、syslog 输出）并停止其后台协程
+	a.Logger.Close()
+	return err
 }